@@ -2,9 +2,16 @@ package blscosi
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
 )
 
 // Client is a structure to communicate with the CoSi
@@ -21,10 +28,51 @@ func NewClient() *Client {
 // SignatureRequest sends a CoSi sign request to the Cothority defined by the given
 // Roster
 func (c *Client) SignatureRequest(r *onet.Roster, msg []byte) (*SignatureResponse, error) {
-	serviceReq := &SignatureRequest{
+	return c.sendSignatureRequest(&SignatureRequest{
 		Roster:  r,
 		Message: msg,
+	})
+}
+
+// SignedSignatureRequest is like SignatureRequest, but the request is signed
+// with the Ed25519 key pair of the client, as needed by the nodes whose
+// policy restricts the clients.
+func (c *Client) SignedSignatureRequest(r *onet.Roster, msg []byte, client *key.Pair) (*SignatureResponse, error) {
+	public, err := client.Public.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal client key: %v", err)
+	}
+	auth := &ClientAuth{Public: public, Timestamp: time.Now().Unix()}
+	auth.Signature, err = schnorr.Sign(cothority.Suite, client.Private,
+		requestHash(msg, auth.Timestamp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't sign request: %v", err)
 	}
+	return c.sendSignatureRequest(&SignatureRequest{
+		Roster:  r,
+		Message: msg,
+		Auth:    auth,
+	})
+}
+
+// SetPolicy replaces the signing policy of the node. The request is signed
+// with the private key of the blscosi service of the node, so it needs the
+// private.toml of the node.
+func (c *Client) SetPolicy(who *network.ServerIdentity, policy Policy) error {
+	req := &SetPolicy{Policy: policy, Timestamp: time.Now().Unix()}
+	msg, err := req.Hash()
+	if err != nil {
+		return err
+	}
+	req.Signature, err = bls.Sign(suite, who.ServicePrivate(ServiceName), msg)
+	if err != nil {
+		return fmt.Errorf("couldn't sign policy: %v", err)
+	}
+	return c.SendProtobuf(who, req, &SetPolicyResponse{})
+}
+
+func (c *Client) sendSignatureRequest(serviceReq *SignatureRequest) (*SignatureResponse, error) {
+	r := serviceReq.Roster
 	if len(r.List) == 0 {
 		return nil, errors.New("Got an empty roster-list")
 	}
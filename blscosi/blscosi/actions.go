@@ -13,6 +13,8 @@ import (
 	cli "github.com/urfave/cli"
 	"go.dedis.ch/cothority/v3/blscosi"
 	"go.dedis.ch/cothority/v3/blscosi/blscosi/check"
+	"go.dedis.ch/cothority/v3/blscosi/thresholdbls"
+	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/onet/v3/app"
 	"go.dedis.ch/onet/v3/log"
 )
@@ -22,6 +24,13 @@ type sigHex struct {
 	Signature string
 }
 
+var thresholdSuite = pairing.NewSuiteBn256()
+
+// thresholdSigHex is the JSON format of a threshold signature.
+type thresholdSigHex struct {
+	Signature string
+}
+
 // check contacts all servers and verifies if it receives a valid
 // signature from each.
 func checkRequest(c *cli.Context) error {
@@ -144,13 +153,7 @@ func verify(fileName, sigFileName, groupToml string) error {
 
 	// Read the JSON signature file
 	log.Lvl4("Reading signature")
-	var sigBytes []byte
-	if sigFileName == "" {
-		log.Print("[+] Reading signature from standard input ...")
-		sigBytes, err = ioutil.ReadAll(os.Stdin)
-	} else {
-		sigBytes, err = ioutil.ReadFile(sigFileName)
-	}
+	sigBytes, err := readSignature(sigFileName)
 	if err != nil {
 		return err
 	}
@@ -184,3 +187,67 @@ func verify(fileName, sigFileName, groupToml string) error {
 	log.Lvlf4("Verifying signature %x %x", b, sig.Signature)
 	return check.VerifySignatureHash(b, sig, g.Roster)
 }
+
+func verifyThresholdFile(c *cli.Context) error {
+	if len(c.Args().First()) == 0 {
+		return errors.New("Please give the 'msgFile'")
+	}
+	if c.String("key") == "" {
+		return errors.New("Please give the public key of the group")
+	}
+
+	err := verifyThreshold(c.Args().First(), c.String("signature"), c.String("key"))
+	if err != nil {
+		return fmt.Errorf("Invalid: Signature verification failed: %s", err.Error())
+	}
+
+	fmt.Fprintln(c.App.Writer, "[+] OK: Signature is valid.")
+	return nil
+}
+
+// verifyThreshold checks the threshold signature of a file with the public
+// key of the group. If sigFileName is empty, the signature is read from
+// STDIN.
+func verifyThreshold(fileName, sigFileName, keyHex string) error {
+	log.Lvl4("Reading file " + fileName)
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return errors.New("Couldn't open msgFile: " + err.Error())
+	}
+
+	keyBytes, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return fmt.Errorf("Couldn't decode key: %s", err.Error())
+	}
+	public := thresholdSuite.G2().Point()
+	if err = public.UnmarshalBinary(keyBytes); err != nil {
+		return fmt.Errorf("Couldn't decode key: %s", err.Error())
+	}
+
+	log.Lvl4("Reading signature")
+	sigBytes, err := readSignature(sigFileName)
+	if err != nil {
+		return err
+	}
+	sigStr := &thresholdSigHex{}
+	if err = json.Unmarshal(sigBytes, sigStr); err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(sigStr.Signature)
+	if err != nil {
+		return err
+	}
+
+	log.Lvlf4("Verifying signature %x %x", b, sig)
+	return thresholdbls.Verify(thresholdSuite, public, b, sig)
+}
+
+// readSignature reads the content of the signature file, or STDIN if
+// sigFileName is empty.
+func readSignature(sigFileName string) ([]byte, error) {
+	if sigFileName == "" {
+		log.Print("[+] Reading signature from standard input ...")
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(sigFileName)
+}
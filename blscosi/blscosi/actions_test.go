@@ -1,12 +1,16 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/thresholdbls"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/app"
@@ -85,3 +89,47 @@ func TestMain_Sign(t *testing.T) {
 	err = cliApp.Run([]string{"", "verify", "-g", publicToml, "-s", signatureFile, signatureFile})
 	require.Error(t, err)
 }
+
+// TestMain_ThresholdVerify checks if the CLI command to verify threshold
+// signatures works correctly
+func TestMain_ThresholdVerify(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmp)
+
+	msgFile := path.Join(tmp, "msg.txt")
+	signatureFile := path.Join(tmp, "sig.json")
+	msg := []byte("My Test Message File")
+	require.NoError(t, ioutil.WriteFile(msgFile, msg, 0644))
+
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	_, roster, _ := local.GenTree(4, true)
+	client := thresholdbls.NewClient()
+	setup, err := client.Setup(roster, nil)
+	require.NoError(t, err)
+	reply, err := client.SignatureRequest(roster, msg)
+	require.NoError(t, err)
+
+	buf, err := json.Marshal(thresholdSigHex{
+		Signature: hex.EncodeToString(reply.Signature),
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(signatureFile, buf, 0644))
+	keyBuf, err := setup.Public.MarshalBinary()
+	require.NoError(t, err)
+	keyHex := hex.EncodeToString(keyBuf)
+
+	cliApp := createApp()
+	require.NotNil(t, cliApp)
+
+	err = cliApp.Run([]string{"", "threshold", "verify", "-k", keyHex, "-s", signatureFile, msgFile})
+	require.NoError(t, err)
+
+	// missing key
+	err = cliApp.Run([]string{"", "threshold", "verify", "-s", signatureFile, msgFile})
+	require.Error(t, err)
+
+	// wrong file to verify
+	err = cliApp.Run([]string{"", "threshold", "verify", "-k", keyHex, "-s", signatureFile, signatureFile})
+	require.Error(t, err)
+}
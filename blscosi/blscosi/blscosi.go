@@ -90,6 +90,30 @@ func createApp() *cli.App {
 				},
 			}...),
 		},
+		{
+			Name:    "threshold",
+			Aliases: []string{"t"},
+			Usage:   "Work with the threshold signatures of a group",
+			Subcommands: []cli.Command{
+				{
+					Name:      "verify",
+					Aliases:   []string{"v"},
+					Usage:     "Verify a threshold signature of a 'file' with the public key of the group; signature is read from STDIN by default",
+					ArgsUsage: "file",
+					Action:    verifyThresholdFile,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "key, k",
+							Usage: "Public key of the group in hexadecimal",
+						},
+						cli.StringFlag{
+							Name:  "signature, s",
+							Usage: "Read signature from 'file.sig' instead of STDIN",
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:    "check",
 			Aliases: []string{"c"},
@@ -0,0 +1,283 @@
+package blscosi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/protobuf"
+)
+
+// maxRequestAge is how old the timestamp of a signed request can be.
+const maxRequestAge = 60 * time.Second
+
+// Policy restricts the requests a node accepts to sign. The zero policy
+// accepts everything, which is the default of a node.
+type Policy struct {
+	// Prefixes are the allowed prefixes of the messages. If empty, any
+	// message is allowed.
+	Prefixes [][]byte
+	// Schemas are the allowed JSON objects of the messages. If empty, the
+	// messages don't need to be JSON.
+	Schemas []Schema
+	// MaxLength is the maximal length of the messages, or 0 for no limit.
+	MaxLength int
+	// Clients are the marshalled Ed25519 public keys allowed to request
+	// signatures. If empty, the requests don't need to be signed.
+	Clients [][]byte
+	// RateLimit is the maximal number of requests a client key can make
+	// during RatePeriod, or 0 for no limit. Unsigned requests share the
+	// same limit.
+	RateLimit int
+	// RatePeriod is in seconds.
+	RatePeriod int64
+}
+
+// Schema describes a JSON object: it has every field of the schema, with a
+// value of the given type, and no other field.
+type Schema struct {
+	Fields []SchemaField
+}
+
+// SchemaField is a field of a JSON object. Type is one of "string",
+// "number", "boolean", "object", "array" or "null".
+type SchemaField struct {
+	Name string
+	Type string
+}
+
+// jsonTypes are the types a SchemaField can have.
+var jsonTypes = []string{"string", "number", "boolean", "object", "array", "null"}
+
+// verify returns an error if a field of the schema has an unknown type.
+func (s *Schema) verify() error {
+	for _, f := range s.Fields {
+		known := false
+		for _, t := range jsonTypes {
+			if f.Type == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown type %q of field %q", f.Type, f.Name)
+		}
+	}
+	return nil
+}
+
+// matches returns true if msg is a JSON object described by the schema.
+func (s *Schema) matches(msg []byte) bool {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &obj); err != nil || obj == nil {
+		return false
+	}
+	if len(obj) != len(s.Fields) {
+		return false
+	}
+	for _, f := range s.Fields {
+		v, ok := obj[f.Name]
+		if !ok || jsonType(v) != f.Type {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonType returns the type of a valid JSON value.
+func jsonType(v json.RawMessage) string {
+	switch bytes.TrimSpace(v)[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// verify returns an error if the policy can't be enforced.
+func (p *Policy) verify() error {
+	for i := range p.Schemas {
+		if err := p.Schemas[i].verify(); err != nil {
+			return fmt.Errorf("invalid schema %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// ClientAuth authenticates the client of a signature request. It is sent to
+// every node of the roster as the data of the protocol.
+type ClientAuth struct {
+	// Public is the marshalled Ed25519 public key of the client.
+	Public []byte
+	// Timestamp is in seconds since the Unix epoch.
+	Timestamp int64
+	// Signature is the Schnorr signature of requestHash.
+	Signature []byte
+}
+
+// SetPolicy replaces the signing policy of a node. It must be signed with the
+// private BLS key of the blscosi service of the node, as found in
+// private.toml.
+type SetPolicy struct {
+	Policy Policy
+	// Timestamp is in seconds since the Unix epoch. Requests older than a
+	// minute are refused.
+	Timestamp int64
+	Signature []byte
+}
+
+// SetPolicyResponse is empty.
+type SetPolicyResponse struct{}
+
+// Hash returns the message signed by the node in a SetPolicy request.
+func (req *SetPolicy) Hash() ([]byte, error) {
+	buf, err := protobuf.Encode(&req.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("encoding policy: %v", err)
+	}
+	h := sha256.New()
+	h.Write([]byte("blscosi policy"))
+	h.Write(buf)
+	binary.Write(h, binary.LittleEndian, req.Timestamp)
+	return h.Sum(nil), nil
+}
+
+// requestHash returns the message signed by the client of a request.
+func requestHash(msg []byte, timestamp int64) []byte {
+	h := sha256.New()
+	h.Write([]byte("blscosi request"))
+	binary.Write(h, binary.LittleEndian, timestamp)
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// checkTimestamp returns an error if the timestamp is too far from now.
+func checkTimestamp(timestamp int64) error {
+	age := time.Since(time.Unix(timestamp, 0)).Seconds()
+	if math.Abs(age) > maxRequestAge.Seconds() {
+		return errors.New("signature is too old")
+	}
+	return nil
+}
+
+// policyChecker enforces a policy and keeps the recent requests of every
+// client for the rate limit.
+type policyChecker struct {
+	sync.Mutex
+	policy Policy
+	// requests holds the times of the accepted requests, per client key.
+	requests map[string][]time.Time
+}
+
+func newPolicyChecker(p Policy) *policyChecker {
+	return &policyChecker{policy: p, requests: make(map[string][]time.Time)}
+}
+
+// check returns an error if the message, with the authentication of its
+// client in data, doesn't follow the policy. An accepted request counts for
+// the rate limit of its client.
+func (pc *policyChecker) check(msg, data []byte) error {
+	pc.Lock()
+	defer pc.Unlock()
+	p := &pc.policy
+
+	if p.MaxLength > 0 && len(msg) > p.MaxLength {
+		return fmt.Errorf("message is longer than %d bytes", p.MaxLength)
+	}
+	if len(p.Prefixes) > 0 {
+		found := false
+		for _, prefix := range p.Prefixes {
+			if bytes.HasPrefix(msg, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("message has no allowed prefix")
+		}
+	}
+	if len(p.Schemas) > 0 {
+		found := false
+		for i := range p.Schemas {
+			if p.Schemas[i].matches(msg) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("message matches no allowed schema")
+		}
+	}
+
+	var auth ClientAuth
+	if len(data) > 0 {
+		if err := protobuf.Decode(data, &auth); err != nil {
+			return fmt.Errorf("decoding client authentication: %v", err)
+		}
+		if err := auth.verify(msg); err != nil {
+			return err
+		}
+	}
+	if len(p.Clients) > 0 {
+		found := false
+		for _, client := range p.Clients {
+			if len(auth.Public) > 0 && bytes.Equal(client, auth.Public) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("client is not allowed to request signatures")
+		}
+	}
+
+	if p.RateLimit > 0 {
+		key := string(auth.Public)
+		now := time.Now()
+		from := now.Add(-time.Duration(p.RatePeriod) * time.Second)
+		var recent []time.Time
+		for _, t := range pc.requests[key] {
+			if t.After(from) {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) >= p.RateLimit {
+			pc.requests[key] = recent
+			return errors.New("rate limit of the client reached")
+		}
+		pc.requests[key] = append(recent, now)
+	}
+	return nil
+}
+
+// verify checks the signature of the client on the message.
+func (auth *ClientAuth) verify(msg []byte) error {
+	if err := checkTimestamp(auth.Timestamp); err != nil {
+		return err
+	}
+	public := cothority.Suite.Point()
+	if err := public.UnmarshalBinary(auth.Public); err != nil {
+		return fmt.Errorf("invalid client key: %v", err)
+	}
+	err := schnorr.Verify(cothority.Suite, public,
+		requestHash(msg, auth.Timestamp), auth.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid client signature: %v", err)
+	}
+	return nil
+}
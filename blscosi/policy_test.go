@@ -0,0 +1,73 @@
+package blscosi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/protobuf"
+)
+
+func TestPolicyChecker_Check(t *testing.T) {
+	pc := newPolicyChecker(Policy{})
+	require.NoError(t, pc.check([]byte("anything"), nil))
+
+	pc = newPolicyChecker(Policy{
+		Prefixes:  [][]byte{[]byte("a:"), []byte("b:")},
+		MaxLength: 8,
+	})
+	require.NoError(t, pc.check([]byte("a:hello"), nil))
+	require.NoError(t, pc.check([]byte("b:"), nil))
+	require.Error(t, pc.check([]byte("c:hello"), nil))
+	require.Error(t, pc.check([]byte("a:too long"), nil))
+
+	pc = newPolicyChecker(Policy{
+		Schemas: []Schema{{Fields: []SchemaField{
+			{Name: "id", Type: "number"},
+			{Name: "tags", Type: "array"},
+		}}},
+	})
+	require.NoError(t, pc.check([]byte(`{"id": 1, "tags": []}`), nil))
+	require.Error(t, pc.check([]byte(`{"id": "1", "tags": []}`), nil))
+	require.Error(t, pc.check([]byte(`{"id": 1}`), nil))
+	require.Error(t, pc.check([]byte(`{"id": 1, "tags": [], "x": null}`), nil))
+	require.Error(t, pc.check([]byte(`[1]`), nil))
+	require.Error(t, pc.check([]byte("hello"), nil))
+	require.Error(t, (&Policy{Schemas: []Schema{{Fields: []SchemaField{
+		{Name: "id", Type: "integer"},
+	}}}}).verify())
+
+	client := key.NewKeyPair(cothority.Suite)
+	public, err := client.Public.MarshalBinary()
+	require.NoError(t, err)
+	pc = newPolicyChecker(Policy{
+		Clients:    [][]byte{public},
+		RateLimit:  2,
+		RatePeriod: 60,
+	})
+	msg := []byte("hello")
+	require.Error(t, pc.check(msg, nil))
+	require.Error(t, pc.check(msg, signedAuth(t, key.NewKeyPair(cothority.Suite), msg, time.Now())))
+	require.Error(t, pc.check(msg, signedAuth(t, client, msg, time.Now().Add(-time.Hour))))
+	data := signedAuth(t, client, msg, time.Now())
+	require.Error(t, pc.check([]byte("other"), data))
+	require.NoError(t, pc.check(msg, data))
+	require.NoError(t, pc.check(msg, data))
+	// The rate limit is reached.
+	require.Error(t, pc.check(msg, data))
+}
+
+func signedAuth(t *testing.T, client *key.Pair, msg []byte, now time.Time) []byte {
+	public, err := client.Public.MarshalBinary()
+	require.NoError(t, err)
+	auth := &ClientAuth{Public: public, Timestamp: now.Unix()}
+	auth.Signature, err = schnorr.Sign(cothority.Suite, client.Private,
+		requestHash(msg, auth.Timestamp))
+	require.NoError(t, err)
+	buf, err := protobuf.Encode(auth)
+	require.NoError(t, err)
+	return buf
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"go.dedis.ch/cothority/v3/metrics"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
@@ -38,6 +39,14 @@ type SignFn func(suite pairing.Suite, secret kyber.Scalar, msg []byte) ([]byte,
 // mask of the peer's participation
 type AggregateFn func(suite pairing.Suite, mask *sign.Mask, sigs [][]byte) ([]byte, error)
 
+// CombineFn is called by the root to combine the responses of the subtrees
+// into the final signature, followed by the participation mask
+type CombineFn func(suite pairing.Suite, publics []kyber.Point, responses ResponseMap) ([]byte, error)
+
+// ErrTooManyRefusals is why the protocol stops when the nodes refusing to
+// sign make the threshold unreachable.
+var ErrTooManyRefusals = errors.New("too many refusals")
+
 // BlsCosi holds the parameters of the protocol.
 // It also defines a channel that will receive the final signature.
 // This protocol should only exist on the root node.
@@ -49,6 +58,9 @@ type BlsCosi struct {
 	Verify         VerifyFn
 	Sign           SignFn
 	Aggregate      AggregateFn
+	// Combine is optional and replaces the addition of the aggregated
+	// signatures of the subtrees when it is set.
+	Combine CombineFn
 	// Timeout is not a global timeout for the protocol, but a timeout used
 	// for waiting for responses for sub protocols.
 	Timeout           time.Duration
@@ -63,6 +75,8 @@ type BlsCosi struct {
 	verificationFn   VerificationFn
 	suite            *pairing.SuiteBn256
 	subTrees         BlsProtocolTree
+	errLock          sync.Mutex
+	err              error
 }
 
 // CreateProtocolFunction is a function type which creates a new protocol
@@ -156,6 +170,22 @@ func (p *BlsCosi) Shutdown() error {
 	return nil
 }
 
+// Err returns why the protocol stopped without a signature, once
+// FinalSignature is closed. It is nil if the protocol was shut down.
+func (p *BlsCosi) Err() error {
+	p.errLock.Lock()
+	defer p.errLock.Unlock()
+	return p.err
+}
+
+// fail keeps the error stopping the protocol for Err.
+func (p *BlsCosi) fail(err error) {
+	log.Error(err)
+	p.errLock.Lock()
+	p.err = err
+	p.errLock.Unlock()
+}
+
 // Dispatch is not used for the main protocol
 func (p *BlsCosi) Dispatch() error {
 	// This protocol relies only on the start call to spin up sub-protocols
@@ -185,11 +215,12 @@ func (p *BlsCosi) Start() error {
 
 func (p *BlsCosi) runSubProtocols() {
 	defer p.Done()
+	start := time.Now()
 
 	// Verification of the data is done before contacting the children
 	if ok := p.verificationFn(p.Msg, p.Data); !ok {
 		// root should not fail the verification otherwise it would not have started the protocol
-		log.Errorf("verification failed on root node")
+		p.fail(errors.New("verification failed on root node"))
 		return
 	}
 
@@ -202,7 +233,7 @@ func (p *BlsCosi) runSubProtocols() {
 		p.subProtocols[i], err = p.startSubProtocol(tree)
 		if err != nil {
 			p.subProtocolsLock.Unlock()
-			log.Error(err)
+			p.fail(err)
 			return
 		}
 	}
@@ -212,7 +243,7 @@ func (p *BlsCosi) runSubProtocols() {
 	// Wait and collect all the signature responses
 	responses, err := p.collectSignatures()
 	if err != nil {
-		log.Error(err)
+		p.fail(err)
 		return
 	}
 
@@ -221,10 +252,13 @@ func (p *BlsCosi) runSubProtocols() {
 	// generate root signature
 	sig, err := p.generateSignature(responses)
 	if err != nil {
-		log.Error(err)
+		p.fail(err)
 		return
 	}
 
+	metrics.ForServer(p.ServerIdentity()).Histogram("blscosi_round_seconds",
+		"Duration of the successful BLS CoSi rounds led by the conode", nil,
+		"protocol").Since(start, p.ProtocolName())
 	p.FinalSignature <- sig
 }
 
@@ -397,8 +431,8 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 	}
 
 	if p.checkFailureThreshold(numFailure) {
-		return nil, fmt.Errorf("too many refusals (got %d), the threshold of %d cannot be achieved",
-			numFailure, p.Threshold)
+		return nil, fmt.Errorf("%w (got %d), the threshold of %d cannot be achieved",
+			ErrTooManyRefusals, numFailure, p.Threshold)
 	}
 
 	return responseMap, nil
@@ -460,6 +494,10 @@ func searchPublicKey(p *onet.TreeNodeInstance, servID *network.ServerIdentity) (
 // makeAggregateResponse takes all the responses from the children and the subleader to
 // aggregate the signature and the mask
 func (p *BlsCosi) makeAggregateResponse(suite pairing.Suite, publics []kyber.Point, responses ResponseMap) (BlsSignature, error) {
+	if p.Combine != nil {
+		return p.Combine(suite, publics, responses)
+	}
+
 	finalMask, err := sign.NewMask(suite, publics, nil)
 	if err != nil {
 		return nil, err
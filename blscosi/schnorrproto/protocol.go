@@ -0,0 +1,118 @@
+// Package schnorrproto implements a multi-signature made of the Schnorr
+// signatures of the participants. The signature grows with the number of
+// signers, so it is meant for small rosters, but it is not vulnerable to
+// rogue public-key attacks and needs no pairing to be verified.
+package schnorrproto
+
+import (
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/onet/v3"
+)
+
+// SchnorrProtocolName is the name of the main protocol for the Schnorr
+// multi-signature scheme.
+const SchnorrProtocolName = "schnorrCoSiProto"
+
+// SchnorrSubProtocolName is the name of the subprotocol for the Schnorr
+// multi-signature scheme.
+const SchnorrSubProtocolName = "schnorrSubCosiProto"
+
+// GlobalRegisterSchnorrProtocols registers both protocol to the global register.
+func GlobalRegisterSchnorrProtocols() {
+	onet.GlobalProtocolRegister(SchnorrProtocolName, NewSchnorrProtocol)
+	onet.GlobalProtocolRegister(SchnorrSubProtocolName, NewSubSchnorrProtocol)
+}
+
+// NewSchnorrProtocol is used to register the protocol with an always-true
+// verification.
+func NewSchnorrProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	vf := func(a, b []byte) bool { return true }
+	return NewSchnorrCosi(n, vf, SchnorrSubProtocolName, pairing.NewSuiteBn256())
+}
+
+// NewSchnorrCosi makes a protocol instance for the Schnorr CoSi protocol.
+func NewSchnorrCosi(n *onet.TreeNodeInstance, vf protocol.VerificationFn, subProtocolName string, suite *pairing.SuiteBn256) (onet.ProtocolInstance, error) {
+	c, err := protocol.NewBlsCosi(n, vf, subProtocolName, suite)
+	if err != nil {
+		return nil, err
+	}
+
+	mbc := c.(*protocol.BlsCosi)
+	mbc.Sign = Sign
+	mbc.Verify = Verify
+	mbc.Aggregate = aggregate
+	mbc.Combine = combine
+
+	return mbc, nil
+}
+
+// NewSubSchnorrProtocol is the default sub-protocol function used for
+// registration with an always-true verification.
+func NewSubSchnorrProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	vf := func(a, b []byte) bool { return true }
+	return NewSubSchnorrCosi(n, vf, pairing.NewSuiteBn256())
+}
+
+// NewSubSchnorrCosi uses the default sub-protocol to make one compatible
+// with the Schnorr multi-signature.
+func NewSubSchnorrCosi(n *onet.TreeNodeInstance, vf protocol.VerificationFn, suite *pairing.SuiteBn256) (onet.ProtocolInstance, error) {
+	pi, err := protocol.NewSubBlsCosi(n, vf, suite)
+	if err != nil {
+		return nil, err
+	}
+
+	subCosi := pi.(*protocol.SubBlsCosi)
+	subCosi.Sign = Sign
+	subCosi.Verify = Verify
+	subCosi.Aggregate = aggregate
+
+	return subCosi, nil
+}
+
+// aggregate concatenates the signatures, which are given in the order of the
+// mask.
+func aggregate(suite pairing.Suite, mask *sign.Mask, sigs [][]byte) ([]byte, error) {
+	var agg []byte
+	for _, sig := range sigs {
+		agg = append(agg, sig...)
+	}
+	return agg, nil
+}
+
+// combine puts the signatures of all the subtrees in the order of the roster
+// and appends the final mask.
+func combine(suite pairing.Suite, publics []kyber.Point, responses protocol.ResponseMap) ([]byte, error) {
+	finalMask, err := sign.NewMask(suite, publics, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := make([][]byte, len(publics))
+	for _, res := range responses {
+		if res == nil || len(res.Signature) == 0 {
+			continue
+		}
+
+		parts, err := split(suite, res.Signature, res.Mask, len(publics))
+		if err != nil {
+			return nil, err
+		}
+		for i, sig := range parts {
+			sigs[i] = sig
+		}
+
+		err = finalMask.Merge(res.Mask)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var final []byte
+	for _, sig := range sigs {
+		final = append(final, sig...)
+	}
+	return append(final, finalMask.Mask()...), nil
+}
@@ -0,0 +1,163 @@
+package schnorrproto
+
+import (
+	"crypto/sha512"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// domain separates the challenges of this scheme from other uses of the
+// keys. The signatures are computed in G2, where the public keys of the
+// BLS-based protocols live, so that the same keys can be used.
+var domain = []byte("schnorrproto")
+
+// SchnorrSignature is the concatenation of the Schnorr signatures of the
+// participants, in the order of the roster, followed by the participation
+// mask.
+type SchnorrSignature []byte
+
+// GetMask creates and returns the mask associated with the signature.
+func (sig SchnorrSignature) GetMask(suite pairing.Suite, pubkeys []kyber.Point) (*sign.Mask, error) {
+	mask, err := sign.NewMask(suite, pubkeys, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) < mask.Len() {
+		return nil, errors.New("invalid signature length")
+	}
+
+	err = mask.SetMask(sig[len(sig)-mask.Len():])
+	if err != nil {
+		return nil, err
+	}
+	return mask, nil
+}
+
+// Verify returns an error if the signature can't be verified or nil if it
+// matches. A default threshold of f missing signatures with
+// len(pubkeys) = 3f + 1 is assumed.
+func (sig SchnorrSignature) Verify(suite pairing.Suite, msg []byte, pubkeys []kyber.Point) error {
+	policy := sign.NewThresholdPolicy(protocol.DefaultThreshold(len(pubkeys)))
+
+	return sig.VerifyWithPolicy(suite, msg, pubkeys, policy)
+}
+
+// VerifyWithPolicy checks that every signature of the participants is
+// correct and that the number of signers matches the policy.
+func (sig SchnorrSignature) VerifyWithPolicy(suite pairing.Suite, msg []byte, pubkeys []kyber.Point, policy sign.Policy) error {
+	mask, err := sig.GetMask(suite, pubkeys)
+	if err != nil {
+		return err
+	}
+
+	parts, err := split(suite, sig[:len(sig)-mask.Len()], mask.Mask(), len(pubkeys))
+	if err != nil {
+		return err
+	}
+	for i, part := range parts {
+		if err := Verify(suite, pubkeys[i], msg, part); err != nil {
+			return fmt.Errorf("didn't get a valid signature for node %d: %s", i, err)
+		}
+	}
+
+	if !policy.Check(mask) {
+		return fmt.Errorf("the policy is not fulfilled: %d", mask.CountEnabled())
+	}
+
+	return nil
+}
+
+// Sign creates the Schnorr signature of the message with the secret.
+func Sign(suite pairing.Suite, secret kyber.Scalar, msg []byte) ([]byte, error) {
+	g := suite.G2()
+	k := g.Scalar().Pick(random.New())
+	r := g.Point().Mul(k, nil)
+	public := g.Point().Mul(secret, nil)
+
+	e, err := challenge(g, r, public, msg)
+	if err != nil {
+		return nil, err
+	}
+	s := g.Scalar().Add(k, g.Scalar().Mul(e, secret))
+
+	buf, err := r.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sBuf, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, sBuf...), nil
+}
+
+// Verify checks the Schnorr signature of a single participant.
+func Verify(suite pairing.Suite, pub kyber.Point, msg []byte, sig []byte) error {
+	g := suite.G2()
+	if len(sig) != signatureLen(suite) {
+		return errors.New("invalid signature length")
+	}
+
+	r := g.Point()
+	if err := r.UnmarshalBinary(sig[:g.PointLen()]); err != nil {
+		return err
+	}
+	s := g.Scalar()
+	if err := s.UnmarshalBinary(sig[g.PointLen():]); err != nil {
+		return err
+	}
+
+	e, err := challenge(g, r, pub, msg)
+	if err != nil {
+		return err
+	}
+	left := g.Point().Mul(s, nil)
+	right := g.Point().Add(r, g.Point().Mul(e, pub))
+	if !left.Equal(right) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func challenge(g kyber.Group, r, public kyber.Point, msg []byte) (kyber.Scalar, error) {
+	h := sha512.New()
+	h.Write(domain)
+	for _, p := range []kyber.Point{r, public} {
+		if _, err := p.MarshalTo(h); err != nil {
+			return nil, err
+		}
+	}
+	h.Write(msg)
+	return g.Scalar().SetBytes(h.Sum(nil)), nil
+}
+
+func signatureLen(suite pairing.Suite) int {
+	return suite.G2().PointLen() + suite.G2().ScalarLen()
+}
+
+// split returns the signatures of the participants enabled in the mask, by
+// index in the roster.
+func split(suite pairing.Suite, sig []byte, mask []byte, n int) (map[int][]byte, error) {
+	l := signatureLen(suite)
+	parts := make(map[int][]byte)
+	for i := 0; i < n; i++ {
+		if i>>3 >= len(mask) || mask[i>>3]&(byte(1)<<uint(i&7)) == 0 {
+			continue
+		}
+		start := len(parts) * l
+		if len(sig) < start+l {
+			return nil, errors.New("signature is too short for the mask")
+		}
+		parts[i] = sig[start : start+l]
+	}
+	if len(sig) != len(parts)*l {
+		return nil, errors.New("signature doesn't match the mask")
+	}
+	return parts, nil
+}
@@ -0,0 +1,75 @@
+package schnorrproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func TestSchnorrSignature_Verify(t *testing.T) {
+	msg := []byte("abc")
+	suite := bn256.NewSuite()
+	var sks []kyber.Scalar
+	var pubkeys []kyber.Point
+	for i := 0; i < 4; i++ {
+		sk := suite.G2().Scalar().Pick(random.New())
+		sks = append(sks, sk)
+		pubkeys = append(pubkeys, suite.G2().Point().Mul(sk, nil))
+	}
+
+	sig0, err := Sign(suite, sks[0], msg)
+	require.NoError(t, err)
+	require.NoError(t, Verify(suite, pubkeys[0], msg, sig0))
+	require.Error(t, Verify(suite, pubkeys[1], msg, sig0))
+	require.Error(t, Verify(suite, pubkeys[0], []byte("cba"), sig0))
+
+	// Two subtrees of responses are combined in the order of the roster.
+	responses := protocol.ResponseMap{}
+	for _, sub := range [][]int{{3, 1}, {0}} {
+		mask, err := sign.NewMask(suite, pubkeys, nil)
+		require.NoError(t, err)
+		var sigs [][]byte
+		for _, i := range sub {
+			require.NoError(t, mask.SetBit(i, true))
+		}
+		for i := range pubkeys {
+			enabled := false
+			for _, j := range sub {
+				enabled = enabled || i == j
+			}
+			if enabled {
+				s, err := Sign(suite, sks[i], msg)
+				require.NoError(t, err)
+				sigs = append(sigs, s)
+			}
+		}
+		agg, err := aggregate(suite, mask, sigs)
+		require.NoError(t, err)
+		responses[sub[0]] = &protocol.Response{Signature: agg, Mask: mask.Mask()}
+	}
+	final, err := combine(suite, pubkeys, responses)
+	require.NoError(t, err)
+	sig := SchnorrSignature(final)
+	mask, err := sig.GetMask(suite, pubkeys)
+	require.NoError(t, err)
+	require.Equal(t, 3, mask.CountEnabled())
+	require.NoError(t, sig.Verify(suite, msg, pubkeys))
+	require.Error(t, sig.Verify(suite, []byte("cba"), pubkeys))
+
+	// A higher threshold is not fulfilled.
+	require.Error(t, sig.VerifyWithPolicy(suite, msg, pubkeys,
+		sign.NewThresholdPolicy(4)))
+
+	// Tampering with a signature or the mask is detected.
+	tampered := append(SchnorrSignature{}, sig...)
+	tampered[0] ^= 1
+	require.Error(t, tampered.Verify(suite, msg, pubkeys))
+	tampered = append(SchnorrSignature{}, sig...)
+	tampered[len(tampered)-1] ^= 4
+	require.Error(t, tampered.Verify(suite, msg, pubkeys))
+}
@@ -4,18 +4,24 @@ package blscosi
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign/bls"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
 )
 
 const protocolTimeout = 20 * time.Second
 
+var storageKey = []byte("storage")
+
 var suite = suites.MustFind("bn256.adapter").(*pairing.SuiteBn256)
 
 // ServiceID is the key to get the service later
@@ -28,6 +34,7 @@ func init() {
 	ServiceID, _ = onet.RegisterNewServiceWithSuite(ServiceName, suite, newCoSiService)
 	network.RegisterMessage(&SignatureRequest{})
 	network.RegisterMessage(&SignatureResponse{})
+	network.RegisterMessages(&SetPolicy{}, &SetPolicyResponse{}, &storage{})
 }
 
 // Service is the service that handles collective signing operations
@@ -37,12 +44,23 @@ type Service struct {
 	Threshold int
 	NSubtrees int
 	Timeout   time.Duration
+
+	storage *storage
+	checker *policyChecker
+}
+
+type storage struct {
+	Policy Policy
+	sync.Mutex
 }
 
 // SignatureRequest is what the Cosi service is expected to receive from clients.
 type SignatureRequest struct {
 	Message []byte
 	Roster  *onet.Roster
+	// Auth is only needed if the policy of the nodes requires signed
+	// requests.
+	Auth *ClientAuth
 }
 
 // SignatureResponse is what the Cosi service will reply to clients.
@@ -51,8 +69,24 @@ type SignatureResponse struct {
 	Signature protocol.BlsSignature
 }
 
-// SignatureRequest treats external request to this service.
+// SignatureRequest treats external request to this service. The request is
+// checked against the policy of every node, and the nodes refusing it are
+// missing from the mask of the signature. The leader checks the request here
+// and not in the verification function of the protocol, so that every
+// request counts once for its rate limit.
 func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, error) {
+	var data []byte
+	if req.Auth != nil {
+		var err error
+		data, err = protobuf.Encode(req.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't encode client authentication: %v", err)
+		}
+	}
+	if err := s.getChecker().check(req.Message, data); err != nil {
+		return nil, fmt.Errorf("request refused by the policy: %v", err)
+	}
+
 	// generate the tree
 	nNodes := len(req.Roster.List)
 	rooted := req.Roster.NewRosterWithRoot(s.ServerIdentity())
@@ -73,6 +107,7 @@ func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, erro
 	p.CreateProtocol = s.CreateProtocol
 	p.Timeout = s.Timeout
 	p.Msg = req.Message
+	p.Data = data
 
 	// Threshold before the subtrees so that we can optimize situation
 	// like a threshold of one
@@ -94,8 +129,22 @@ func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, erro
 		return nil, err
 	}
 
-	// wait for reply. This will always eventually return.
-	sig := <-p.FinalSignature
+	// wait for reply. The protocol stops without a signature as soon as
+	// too many nodes refused to sign.
+	var sig []byte
+	select {
+	case sig = <-p.FinalSignature:
+		if sig == nil {
+			err := p.Err()
+			if errors.Is(err, protocol.ErrTooManyRefusals) {
+				return nil, fmt.Errorf("request refused by the policy of too many nodes: %v", err)
+			}
+			return nil, fmt.Errorf("couldn't sign the request: %v", err)
+		}
+	case <-time.After(2 * p.Timeout):
+		return nil, errors.New("timeout while waiting for the signature, " +
+			"too many nodes might have refused the request")
+	}
 
 	// The hash is the message blscosi actually signs, we recompute it the
 	// same way as blscosi and then return it.
@@ -104,6 +153,50 @@ func (s *Service) SignatureRequest(req *SignatureRequest) (network.Message, erro
 	return &SignatureResponse{h.Sum(nil), sig}, nil
 }
 
+// SetPolicy replaces the signing policy of the node, which is enforced on
+// all the requests the node signs.
+func (s *Service) SetPolicy(req *SetPolicy) (*SetPolicyResponse, error) {
+	if err := checkTimestamp(req.Timestamp); err != nil {
+		return nil, err
+	}
+	if err := req.Policy.verify(); err != nil {
+		return nil, err
+	}
+	msg, err := req.Hash()
+	if err != nil {
+		return nil, err
+	}
+	public := s.ServerIdentity().ServicePublic(ServiceName)
+	if err := bls.Verify(s.suite, public, msg, req.Signature); err != nil {
+		return nil, fmt.Errorf("signature verification failed: %v", err)
+	}
+
+	s.storage.Lock()
+	s.storage.Policy = req.Policy
+	s.checker = newPolicyChecker(req.Policy)
+	s.storage.Unlock()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	log.Lvl2(s.ServerIdentity(), "stored new signing policy")
+	return &SetPolicyResponse{}, nil
+}
+
+// verifyRequest is the verification function of the cosigners.
+func (s *Service) verifyRequest(msg, data []byte) bool {
+	if err := s.getChecker().check(msg, data); err != nil {
+		log.Lvl2(s.ServerIdentity(), "refusing to sign:", err)
+		return false
+	}
+	return true
+}
+
+func (s *Service) getChecker() *policyChecker {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	return s.checker
+}
+
 // NewProtocol is called on all nodes of a Tree (except the root, since it is
 // the one starting the protocol) so it's the Service that will be called to
 // generate the PI on all others node.
@@ -111,9 +204,13 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 	log.Lvl3("Cosi Service received on", s.ServerIdentity(), "received new protocol event-", tn.ProtocolName())
 	switch tn.ProtocolName() {
 	case protocol.DefaultProtocolName:
-		return protocol.NewDefaultProtocol(tn)
+		// Only the root runs this protocol, and SignatureRequest already
+		// checked the request against the policy. Checking it again would
+		// count it twice for the rate limit.
+		return protocol.NewBlsCosi(tn, func(msg, data []byte) bool { return true },
+			protocol.DefaultSubProtocolName, suite)
 	case protocol.DefaultSubProtocolName:
-		return protocol.NewDefaultSubProtocol(tn)
+		return protocol.NewSubBlsCosi(tn, s.verifyRequest, suite)
 	}
 	return nil, errors.New("no such protocol " + tn.ProtocolName())
 }
@@ -125,10 +222,44 @@ func newCoSiService(c *onet.Context) (onet.Service, error) {
 		Timeout:          protocolTimeout,
 	}
 
-	if err := s.RegisterHandler(s.SignatureRequest); err != nil {
+	if err := s.RegisterHandlers(s.SignatureRequest, s.SetPolicy); err != nil {
 		log.Error("couldn't register message:", err)
 		return nil, err
 	}
+	if err := s.tryLoad(); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
+
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	err := s.Save(storageKey, s.storage)
+	if err != nil {
+		log.Error("Couldn't save data:", err)
+		return fmt.Errorf("saving data: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	defer func() {
+		s.checker = newPolicyChecker(s.storage.Policy)
+	}()
+	msg, err := s.Load(storageKey)
+	if err != nil {
+		return fmt.Errorf("loading storage: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	var ok bool
+	s.storage, ok = msg.(*storage)
+	if !ok {
+		return errors.New("data of wrong type")
+	}
+	return nil
+}
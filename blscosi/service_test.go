@@ -4,8 +4,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 )
@@ -68,3 +70,65 @@ func TestService_SignatureRequest(t *testing.T) {
 	// verify the response still
 	require.Nil(t, res.Signature.VerifyWithPolicy(testSuite, msg, publics, sign.NewThresholdPolicy(1)))
 }
+
+func TestService_Policy(t *testing.T) {
+	local := onet.NewTCPTest(testSuite)
+	hosts, roster, _ := local.GenTree(4, false)
+	defer local.CloseAll()
+	client := NewClient()
+	publics := roster.ServicePublics(ServiceName)
+
+	// Only the node itself can change its policy.
+	other := *roster.List[3]
+	other.ServiceIdentities = roster.List[2].ServiceIdentities
+	require.Error(t, client.SetPolicy(&other, Policy{}))
+
+	// A node refusing the message is missing from the mask.
+	require.NoError(t, client.SetPolicy(roster.List[3], Policy{
+		Prefixes: [][]byte{[]byte("hello")},
+	}))
+	msg := []byte("bye blscosi service")
+	reply, err := client.SignatureRequest(roster, msg)
+	require.NoError(t, err)
+	require.NoError(t, reply.Signature.Verify(testSuite, msg, publics))
+	mask, err := reply.Signature.GetMask(testSuite, publics)
+	require.NoError(t, err)
+	require.Equal(t, 3, mask.CountEnabled())
+	require.Equal(t, []byte{0x07}, mask.Mask())
+
+	// Past the threshold, the refusals fail the request.
+	require.NoError(t, client.SetPolicy(roster.List[2], Policy{
+		Prefixes: [][]byte{[]byte("hello")},
+	}))
+	service := hosts[0].Service(ServiceName).(*Service)
+	_, err = service.SignatureRequest(&SignatureRequest{Roster: roster, Message: msg})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "refused by the policy")
+	require.NoError(t, client.SetPolicy(roster.List[2], Policy{}))
+
+	// The leader needs a signed request from a known client.
+	kp := key.NewKeyPair(cothority.Suite)
+	public, err := kp.Public.MarshalBinary()
+	require.NoError(t, err)
+	// Every request uses one slot of the rate limit of the leader.
+	require.NoError(t, client.SetPolicy(roster.List[0], Policy{
+		Clients:    [][]byte{public},
+		RateLimit:  2,
+		RatePeriod: 60,
+	}))
+	_, err = client.SignatureRequest(roster, msg)
+	require.Error(t, err)
+	_, err = client.SignedSignatureRequest(roster, msg, key.NewKeyPair(cothority.Suite))
+	require.Error(t, err)
+	for i := 0; i < 2; i++ {
+		reply, err = client.SignedSignatureRequest(roster, msg, kp)
+		require.NoError(t, err)
+		require.NoError(t, reply.Signature.Verify(testSuite, msg, publics))
+	}
+	_, err = client.SignedSignatureRequest(roster, msg, kp)
+	require.Error(t, err)
+
+	// The policy survives a restart of the service.
+	require.NoError(t, service.tryLoad())
+	require.Equal(t, 2, service.getChecker().policy.RateLimit)
+}
@@ -0,0 +1,72 @@
+package thresholdbls
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"golang.org/x/xerrors"
+)
+
+// Client is a structure to communicate with the threshold BLS service.
+type Client struct {
+	*onet.Client
+}
+
+// NewClient instantiates a new thresholdbls.Client
+func NewClient() *Client {
+	return &Client{Client: onet.NewClient(suite, ServiceName)}
+}
+
+// Setup asks the nodes of the roster to create the key of the group and
+// returns its public key. The owner is the Ed25519 public key allowed to
+// reshare the group, and can be nil.
+func (c *Client) Setup(r *onet.Roster, owner kyber.Point) (*SetupResponse, error) {
+	if len(r.List) == 0 {
+		return nil, xerrors.New("got an empty roster-list")
+	}
+	req := &SetupRequest{Roster: r}
+	if owner != nil {
+		buf, err := owner.MarshalBinary()
+		if err != nil {
+			return nil, xerrors.Errorf("marshaling owner: %v", err)
+		}
+		req.Owner = buf
+	}
+	reply := &SetupResponse{}
+	err := c.SendProtobuf(r.List[0], req, reply)
+	return reply, cothority.ErrorOrNil(err, "sending setup request")
+}
+
+// SignatureRequest asks the group of the roster for a threshold signature
+// of the message. The signature is verified with the public key of the
+// group, see Verify.
+func (c *Client) SignatureRequest(r *onet.Roster, msg []byte) (*SignatureResponse, error) {
+	if len(r.List) == 0 {
+		return nil, xerrors.New("got an empty roster-list")
+	}
+	reply := &SignatureResponse{}
+	err := c.SendProtobuf(r.List[0], &SignatureRequest{
+		Roster:  r,
+		Message: msg,
+	}, reply)
+	return reply, cothority.ErrorOrNil(err, "sending signature request")
+}
+
+// Reshare moves the group of the roster to the new roster, whose first node
+// must be part of the current roster. The owner is the private key matching
+// the public key given to Setup.
+func (c *Client) Reshare(r, newRoster *onet.Roster, owner kyber.Scalar) (*ReshareResponse, error) {
+	if len(newRoster.List) == 0 {
+		return nil, xerrors.New("got an empty roster-list")
+	}
+	req := &ReshareRequest{Roster: r, NewRoster: newRoster}
+	sig, err := schnorr.Sign(cothority.Suite, owner, req.Hash())
+	if err != nil {
+		return nil, xerrors.Errorf("signing request: %v", err)
+	}
+	req.Signature = sig
+	reply := &ReshareResponse{}
+	err = c.SendProtobuf(newRoster.List[0], req, reply)
+	return reply, cothority.ErrorOrNil(err, "sending reshare request")
+}
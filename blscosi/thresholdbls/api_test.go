@@ -0,0 +1,31 @@
+package thresholdbls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestClient_SignatureRequest(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	_, roster, _ := local.GenTree(4, false)
+	client := NewClient()
+	msg := []byte("hello threshold bls")
+
+	_, err := client.Setup(&onet.Roster{}, nil)
+	require.Error(t, err)
+	setup, err := client.Setup(roster, nil)
+	require.NoError(t, err)
+
+	reply, err := client.SignatureRequest(roster, msg)
+	require.NoError(t, err)
+	require.True(t, setup.Public.Equal(reply.Public))
+	require.NoError(t, Verify(suite, setup.Public, msg, reply.Signature))
+
+	// Without owner, the group cannot be reshared.
+	_, err = client.Reshare(roster, roster, cothority.Suite.Scalar().One())
+	require.Error(t, err)
+}
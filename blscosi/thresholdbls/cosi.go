@@ -0,0 +1,261 @@
+package thresholdbls
+
+import (
+	"sync"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+	"go.dedis.ch/onet/v3"
+	"golang.org/x/xerrors"
+)
+
+// certificates holds the certificates of the groups collected by the node,
+// indexed by the ID of the roster and the name of the service. They can be
+// collected again, so they are not saved.
+type certificates struct {
+	sync.Mutex
+	certs map[string][]byte
+}
+
+func certificateKey(roster *onet.Roster, service string) string {
+	return roster.ID.String() + "/" + service
+}
+
+// signCertificate returns the signature of the certificate of the group of
+// the roster by the node, with its key for the service.
+func (s *Service) signCertificate(roster *onet.Roster, service string) ([]byte, error) {
+	if !s.ServerIdentity().HasServiceKeyPair(service) {
+		return nil, xerrors.Errorf("no key pair for service %s", service)
+	}
+	msg, err := s.certificateMessage(roster, service)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := bdn.Sign(suite, s.ServerIdentity().ServicePrivate(service), msg)
+	return sig, cothority.ErrorOrNil(err, "signing certificate")
+}
+
+// certificateMessage returns the certificate of the group of the roster for
+// the keys of the service.
+func (s *Service) certificateMessage(roster *onet.Roster, service string) ([]byte, error) {
+	g := s.getGroup(roster.ID.String())
+	if g == nil {
+		return nil, xerrors.New("no group for this roster")
+	}
+	dks, err := g.distKeyShare()
+	if err != nil {
+		return nil, xerrors.Errorf("reading group: %v", err)
+	}
+	// The stored roster is used, so that the keys are the ones of the
+	// nodes that ran the DKG.
+	msg, err := CertificateMessage(g.Roster.ServicePublics(service),
+		dks.Public())
+	return msg, cothority.ErrorOrNil(err, "creating certificate")
+}
+
+// Prepare makes sure that the roster has a group, and that the node has the
+// certificate of the group by the keys of the nodes for the given service.
+// It must be called before the node leads a protocol created with NewCosi.
+func (s *Service) Prepare(roster *onet.Roster, service string) error {
+	if s.getGroup(roster.ID.String()) == nil {
+		var err error
+		req := &SetupRequest{Roster: roster}
+		if roster.List[0].Equal(s.ServerIdentity()) {
+			_, err = s.Setup(req)
+		} else {
+			_, err = NewClient().Setup(roster, nil)
+		}
+		if err != nil {
+			return xerrors.Errorf("setting up group: %v", err)
+		}
+	}
+	if s.getCertificate(roster, service) != nil {
+		return nil
+	}
+	cert, err := s.certify(roster, service)
+	if err != nil {
+		return xerrors.Errorf("certifying group: %v", err)
+	}
+	s.certificates.Lock()
+	s.certificates.certs[certificateKey(roster, service)] = cert
+	s.certificates.Unlock()
+	return nil
+}
+
+func (s *Service) getCertificate(roster *onet.Roster, service string) []byte {
+	s.certificates.Lock()
+	defer s.certificates.Unlock()
+	return s.certificates.certs[certificateKey(roster, service)]
+}
+
+// certify runs the protocol collecting the signatures of the certificate of
+// the group by the nodes of the roster.
+func (s *Service) certify(roster *onet.Roster, service string) ([]byte, error) {
+	msg, err := s.certificateMessage(roster, service)
+	if err != nil {
+		return nil, err
+	}
+	tree := roster.GenerateNaryTreeWithRoot(len(roster.List),
+		s.ServerIdentity())
+	if tree == nil {
+		return nil, xerrors.New("we're not in the roster")
+	}
+	pi, err := s.CreateProtocol(CertifyProtocolName, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating certify protocol: %v", err)
+	}
+	p := pi.(*CertifyProtocol)
+	p.Service = service
+	p.Msg = msg
+	p.Publics = roster.ServicePublics(service)
+	p.Sign = func(service string) ([]byte, error) {
+		return s.signCertificate(roster, service)
+	}
+	if err := p.Start(); err != nil {
+		return nil, xerrors.Errorf("starting certify protocol: %v", err)
+	}
+
+	cert := <-p.FinalSignature
+	if cert == nil {
+		return nil, xerrors.New("couldn't collect enough signatures")
+	}
+	return cert, nil
+}
+
+// NewCosi makes a protocol instance of the BLS CoSi protocol where the
+// nodes sign with their share of the group of the roster. The final
+// signature is a GroupSignature, which can be verified with the keys of the
+// nodes for the service that registered the protocol. The root must call
+// Prepare before.
+func NewCosi(n *onet.TreeNodeInstance, vf protocol.VerificationFn, subProtocolName string, cosiSuite *pairing.SuiteBn256) (onet.ProtocolInstance, error) {
+	g, err := newCosiGroup(n)
+	if err != nil {
+		return nil, err
+	}
+	c, err := protocol.NewBlsCosi(n, vf, subProtocolName, cosiSuite)
+	if err != nil {
+		return nil, err
+	}
+
+	mbc := c.(*protocol.BlsCosi)
+	mbc.Sign = g.sign
+	mbc.Verify = g.verify
+	mbc.Aggregate = g.aggregate
+	mbc.Combine = func(_ pairing.Suite, _ []kyber.Point, responses protocol.ResponseMap) ([]byte, error) {
+		return g.combine(mbc.Msg, responses)
+	}
+	return mbc, nil
+}
+
+// NewSubCosi makes the sub-protocol of NewCosi.
+func NewSubCosi(n *onet.TreeNodeInstance, vf protocol.VerificationFn, cosiSuite *pairing.SuiteBn256) (onet.ProtocolInstance, error) {
+	g, err := newCosiGroup(n)
+	if err != nil {
+		return nil, err
+	}
+	pi, err := protocol.NewSubBlsCosi(n, vf, cosiSuite)
+	if err != nil {
+		return nil, err
+	}
+
+	subCosi := pi.(*protocol.SubBlsCosi)
+	subCosi.Sign = g.sign
+	subCosi.Verify = g.verify
+	subCosi.Aggregate = g.aggregate
+	return subCosi, nil
+}
+
+// cosiGroup signs with the share of the node and checks the signature
+// shares of the other nodes in the BLS CoSi protocol. The keys of the nodes
+// given to the functions are not used.
+type cosiGroup struct {
+	service *Service
+	roster  *onet.Roster
+	// name is the name of the service of the protocol, whose keys
+	// certify the group.
+	name   string
+	share  *share.PriShare
+	public *share.PubPoly
+	key    kyber.Point
+}
+
+func newCosiGroup(n *onet.TreeNodeInstance) (*cosiGroup, error) {
+	s, ok := n.Host().Service(ServiceName).(*Service)
+	if !ok {
+		return nil, xerrors.New("threshold BLS service not found")
+	}
+	g := s.getGroup(n.Roster().ID.String())
+	if g == nil {
+		return nil, xerrors.New("no group for this roster")
+	}
+	dks, err := g.distKeyShare()
+	if err != nil {
+		return nil, xerrors.Errorf("reading group: %v", err)
+	}
+	return &cosiGroup{
+		service: s,
+		roster:  n.Roster(),
+		name:    onet.ServiceFactory.Name(n.Token().ServiceID),
+		share:   dks.Share,
+		public:  share.NewPubPoly(suite, suite.Point().Base(), dks.Commits),
+		key:     dks.Public(),
+	}, nil
+}
+
+func (g *cosiGroup) sign(_ pairing.Suite, _ kyber.Scalar, msg []byte) ([]byte, error) {
+	return tbls.Sign(suite, g.share, msg)
+}
+
+func (g *cosiGroup) verify(_ pairing.Suite, _ kyber.Point, msg, sig []byte) error {
+	if len(sig) != shareLen() {
+		return xerrors.New("invalid signature share length")
+	}
+	return tbls.Verify(suite, g.public, msg, sig)
+}
+
+// aggregate concatenates the signature shares.
+func (g *cosiGroup) aggregate(_ pairing.Suite, _ *sign.Mask, sigs [][]byte) ([]byte, error) {
+	var agg []byte
+	for _, sig := range sigs {
+		agg = append(agg, sig...)
+	}
+	return agg, nil
+}
+
+// combine recovers the signature from the valid shares of the responses.
+func (g *cosiGroup) combine(msg []byte, responses protocol.ResponseMap) ([]byte, error) {
+	cert := g.service.getCertificate(g.roster, g.name)
+	if cert == nil {
+		return nil, xerrors.New("missing certificate of the group")
+	}
+
+	var shares [][]byte
+	for _, res := range responses {
+		if res == nil {
+			continue
+		}
+		for sig := res.Signature; len(sig) >= shareLen(); sig = sig[shareLen():] {
+			if tbls.Verify(suite, g.public, msg, sig[:shareLen()]) == nil {
+				shares = append(shares, sig[:shareLen()])
+			}
+		}
+	}
+	n := len(g.roster.List)
+	sig, err := tbls.Recover(suite, g.public, msg, shares, threshold(n), n)
+	if err != nil {
+		return nil, xerrors.Errorf("recovering signature: %v", err)
+	}
+	return newGroupSignature(g.key, sig, cert)
+}
+
+// shareLen returns the length of a signature share, which is the index of
+// the share followed by the signature.
+func shareLen() int {
+	return 2 + suite.G1().PointLen()
+}
@@ -0,0 +1,327 @@
+package thresholdbls
+
+import (
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bdn"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"golang.org/x/xerrors"
+)
+
+// SignProtocolName is the name of the protocol collecting the signature
+// shares of a group.
+const SignProtocolName = "ThresholdBlsSign"
+
+// CertifyProtocolName is the name of the protocol collecting the signatures
+// of the certificate of a group.
+const CertifyProtocolName = "ThresholdBlsCertify"
+
+func init() {
+	onet.GlobalProtocolRegister(SignProtocolName, NewSignProtocol)
+	onet.GlobalProtocolRegister(CertifyProtocolName, NewCertifyProtocol)
+}
+
+// SignProtocol asks the nodes of a group for their signature shares and
+// recovers the threshold signature on the root. Before calling Start, Msg,
+// Share and Public must be set on the root. The other nodes need their
+// Share to be set by the service.
+type SignProtocol struct {
+	*onet.TreeNodeInstance
+	Msg []byte
+	// Share is the private share of the node.
+	Share *share.PriShare
+	// Public is the public polynomial of the group, used to check the
+	// signature shares.
+	Public *share.PubPoly
+	// Threshold is the number of shares needed to recover the signature.
+	Threshold int
+	Timeout   time.Duration
+	// FinalSignature receives the threshold signature, or nil if not enough
+	// shares could be collected.
+	FinalSignature chan []byte
+
+	shares   [][]byte
+	failures int
+	timeout  *time.Timer
+	doneOnce sync.Once
+}
+
+// NewSignProtocol initialises the structure for use in one round.
+func NewSignProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	p := &SignProtocol{
+		TreeNodeInstance: n,
+		Threshold:        threshold(len(n.Roster().List)),
+		Timeout:          protocolTimeout,
+		FinalSignature:   make(chan []byte, 1),
+	}
+
+	err := p.RegisterHandlers(p.announcement, p.reply)
+	if err != nil {
+		return nil, xerrors.Errorf("registering handlers: %v", err)
+	}
+	return p, nil
+}
+
+// Start signs the message with the share of the root and asks the other
+// nodes for theirs.
+func (p *SignProtocol) Start() error {
+	if p.Share == nil || p.Public == nil {
+		p.finish(nil)
+		return xerrors.New("share and public polynomial must be set")
+	}
+	sig, err := tbls.Sign(suite, p.Share, p.Msg)
+	if err != nil {
+		p.finish(nil)
+		return xerrors.Errorf("signing: %v", err)
+	}
+	p.timeout = time.AfterFunc(p.Timeout, func() {
+		log.Lvl1("threshold signature timeout")
+		p.finish(nil)
+	})
+	p.addShare(sig)
+
+	errs := p.Broadcast(&Announcement{Msg: p.Msg})
+	if len(errs) > len(p.Roster().List)-p.Threshold {
+		log.Errorf("Some nodes failed with error(s) %v", errs)
+		return xerrors.New("too many nodes failed in broadcast")
+	}
+	return nil
+}
+
+// announcement is received by the children, which reply with their
+// signature share.
+func (p *SignProtocol) announcement(a structAnnouncement) error {
+	defer p.Done()
+
+	reply := &Reply{}
+	if p.Share == nil {
+		log.Lvl2(p.ServerIdentity(), "has no share for this group")
+	} else {
+		sig, err := tbls.Sign(suite, p.Share, a.Msg)
+		if err != nil {
+			log.Error(p.ServerIdentity(), "couldn't sign:", err)
+		}
+		reply.Share = sig
+	}
+	return cothority.ErrorOrNil(p.SendToParent(reply),
+		"sending Reply to parent")
+}
+
+// reply is received by the root, which checks the signature shares until
+// enough of them can be combined.
+func (p *SignProtocol) reply(r structReply) error {
+	if len(r.Share) > 0 {
+		err := tbls.Verify(suite, p.Public, p.Msg, r.Share)
+		if err != nil {
+			log.Lvl2("Invalid share from", r.ServerIdentity, err)
+			r.Share = nil
+		}
+	}
+	if len(r.Share) == 0 {
+		p.failures++
+		if p.failures > len(p.Roster().List)-p.Threshold {
+			log.Lvl2(r.ServerIdentity, "couldn't get enough shares")
+			p.finish(nil)
+		}
+		return nil
+	}
+	p.addShare(r.Share)
+	return nil
+}
+
+// addShare stores a valid signature share and recovers the signature once
+// there are enough of them.
+func (p *SignProtocol) addShare(sig []byte) {
+	p.shares = append(p.shares, sig)
+	if len(p.shares) != p.Threshold {
+		return
+	}
+	final, err := tbls.Recover(suite, p.Public, p.Msg, p.shares, p.Threshold,
+		len(p.Roster().List))
+	if err != nil {
+		log.Error("couldn't recover signature:", err)
+		final = nil
+	}
+	p.finish(final)
+}
+
+func (p *SignProtocol) finish(sig []byte) {
+	if p.timeout != nil {
+		p.timeout.Stop()
+	}
+	select {
+	case p.FinalSignature <- sig:
+	default:
+		// The protocol already finished.
+	}
+	p.doneOnce.Do(func() { p.Done() })
+}
+
+// CertifyProtocol asks the nodes of a group to sign its certificate with
+// their key for a service, and aggregates the signatures on the root. Before
+// calling Start, Service, Msg and Publics must be set on the root. All nodes
+// need Sign to be set by the service.
+type CertifyProtocol struct {
+	*onet.TreeNodeInstance
+	// Service is the name of the service whose keys sign the certificate.
+	Service string
+	// Msg is the certificate, see CertificateMessage.
+	Msg []byte
+	// Publics are the keys of the nodes of the group for the service, in
+	// the order of the roster of the group.
+	Publics []kyber.Point
+	// Sign returns the signature of the certificate of the group by the
+	// node for the service.
+	Sign      func(service string) ([]byte, error)
+	Threshold int
+	Timeout   time.Duration
+	// FinalSignature receives the BDN signature of the certificate followed
+	// by the mask of the signers, or nil if not enough nodes signed.
+	FinalSignature chan []byte
+
+	sigs     map[int][]byte
+	mask     *sign.Mask
+	failures int
+	timeout  *time.Timer
+	doneOnce sync.Once
+}
+
+// NewCertifyProtocol initialises the structure for use in one round.
+func NewCertifyProtocol(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+	p := &CertifyProtocol{
+		TreeNodeInstance: n,
+		Threshold:        threshold(len(n.Roster().List)),
+		Timeout:          protocolTimeout,
+		FinalSignature:   make(chan []byte, 1),
+	}
+
+	err := p.RegisterHandlers(p.announcement, p.reply)
+	if err != nil {
+		return nil, xerrors.Errorf("registering handlers: %v", err)
+	}
+	return p, nil
+}
+
+// Start signs the certificate and asks the other nodes to sign it.
+func (p *CertifyProtocol) Start() error {
+	if p.Sign == nil || len(p.Publics) != len(p.Roster().List) {
+		p.finish(nil)
+		return xerrors.New("sign function and public keys must be set")
+	}
+	mask, err := sign.NewMask(suite, p.Publics, nil)
+	if err != nil {
+		p.finish(nil)
+		return xerrors.Errorf("creating mask: %v", err)
+	}
+	p.mask = mask
+	p.sigs = make(map[int][]byte)
+	sig, err := p.Sign(p.Service)
+	if err != nil {
+		p.finish(nil)
+		return xerrors.Errorf("signing: %v", err)
+	}
+	p.timeout = time.AfterFunc(p.Timeout, func() {
+		log.Lvl1("certificate signature timeout")
+		p.finish(nil)
+	})
+	p.addSignature(p.TreeNode().RosterIndex, sig)
+
+	errs := p.Broadcast(&CertifyAnnouncement{Service: p.Service})
+	if len(errs) > len(p.Roster().List)-p.Threshold {
+		log.Errorf("Some nodes failed with error(s) %v", errs)
+		return xerrors.New("too many nodes failed in broadcast")
+	}
+	return nil
+}
+
+// announcement is received by the children, which reply with their
+// signature of the certificate.
+func (p *CertifyProtocol) announcement(a structCertifyAnnouncement) error {
+	defer p.Done()
+
+	reply := &CertifyReply{}
+	if p.Sign == nil {
+		log.Lvl2(p.ServerIdentity(), "has no group for this roster")
+	} else {
+		sig, err := p.Sign(a.Service)
+		if err != nil {
+			log.Error(p.ServerIdentity(), "couldn't sign:", err)
+		}
+		reply.Signature = sig
+	}
+	return cothority.ErrorOrNil(p.SendToParent(reply),
+		"sending CertifyReply to parent")
+}
+
+// reply is received by the root, which checks the signatures until enough
+// of them can be aggregated.
+func (p *CertifyProtocol) reply(r structCertifyReply) error {
+	index := r.TreeNode.RosterIndex
+	if len(r.Signature) > 0 {
+		err := bdn.Verify(suite, p.Publics[index], p.Msg, r.Signature)
+		if err != nil {
+			log.Lvl2("Invalid signature from", r.ServerIdentity, err)
+			r.Signature = nil
+		}
+	}
+	if len(r.Signature) == 0 {
+		p.failures++
+		if p.failures > len(p.Roster().List)-p.Threshold {
+			log.Lvl2(r.ServerIdentity, "couldn't get enough signatures")
+			p.finish(nil)
+		}
+		return nil
+	}
+	p.addSignature(index, r.Signature)
+	return nil
+}
+
+// addSignature stores a valid signature and aggregates the signatures once
+// there are enough of them.
+func (p *CertifyProtocol) addSignature(index int, sig []byte) {
+	if err := p.mask.SetBit(index, true); err != nil {
+		log.Error("couldn't set mask:", err)
+		return
+	}
+	p.sigs[index] = sig
+	if len(p.sigs) != p.Threshold {
+		return
+	}
+	// The signatures are aggregated in the order of the mask.
+	var sigs [][]byte
+	for i := range p.Publics {
+		if sig, ok := p.sigs[i]; ok {
+			sigs = append(sigs, sig)
+		}
+	}
+	var final []byte
+	agg, err := bdn.AggregateSignatures(suite, sigs, p.mask)
+	if err == nil {
+		final, err = agg.MarshalBinary()
+	}
+	if err != nil {
+		log.Error("couldn't aggregate signatures:", err)
+		p.finish(nil)
+		return
+	}
+	p.finish(append(final, p.mask.Mask()...))
+}
+
+func (p *CertifyProtocol) finish(sig []byte) {
+	if p.timeout != nil {
+		p.timeout.Stop()
+	}
+	select {
+	case p.FinalSignature <- sig:
+	default:
+		// The protocol already finished.
+	}
+	p.doneOnce.Do(func() { p.Done() })
+}
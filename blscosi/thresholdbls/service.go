@@ -0,0 +1,487 @@
+// Package thresholdbls implements a service issuing threshold BLS signatures.
+// The nodes of a roster run a DKG once to share the private key of the
+// group, then any threshold of them can sign messages. The signatures are
+// verified with the public key of the group only, which doesn't change when
+// the shares are moved to another roster.
+package thresholdbls
+
+import (
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	dkgprotocol "go.dedis.ch/cothority/v3/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/share"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ServiceName is the name to refer to the threshold BLS service.
+const ServiceName = "thresholdBlsService"
+
+const (
+	dkgProtocolName     = "ThresholdBlsDKG"
+	reshareProtocolName = "ThresholdBlsReshare"
+	protocolTimeout     = 20 * time.Second
+	dkgTimeout          = time.Minute
+)
+
+var suite = suites.MustFind("bn256.adapter").(*pairing.SuiteBn256)
+
+// ServiceID is the key to get the service later
+var ServiceID onet.ServiceID
+
+func init() {
+	var err error
+	ServiceID, err = onet.RegisterNewServiceWithSuite(ServiceName, suite, newService)
+	log.ErrFatal(err)
+	_, err = onet.GlobalProtocolRegister(dkgProtocolName, dkgprotocol.NewSetup)
+	log.ErrFatal(err)
+	_, err = onet.GlobalProtocolRegister(reshareProtocolName, dkgprotocol.NewSetup)
+	log.ErrFatal(err)
+}
+
+// Service holds the shares of the groups the node is part of.
+type Service struct {
+	*onet.ServiceProcessor
+	storage      *storage
+	certificates certificates
+}
+
+// Setup runs a DKG with the nodes of the roster and returns the public key
+// of the group. If the roster already has a group, its key is returned
+// without running a new DKG.
+func (s *Service) Setup(req *SetupRequest) (*SetupResponse, error) {
+	if req.Roster == nil || len(req.Roster.List) < 2 {
+		return nil, xerrors.New("need a roster with at least two nodes")
+	}
+	if !req.Roster.List[0].Equal(s.ServerIdentity()) {
+		return nil, xerrors.New("the request must be sent to the first node of the roster")
+	}
+	if g := s.getGroup(req.Roster.ID.String()); g != nil {
+		dks, err := g.distKeyShare()
+		if err != nil {
+			return nil, xerrors.Errorf("reading group: %v", err)
+		}
+		return &SetupResponse{Public: dks.Public()}, nil
+	}
+
+	cfg := setupConfig{Owner: req.Owner}
+	cfgBuf, err := protobuf.Encode(&cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding configuration: %v", err)
+	}
+	tree := req.Roster.GenerateNaryTree(len(req.Roster.List))
+	if tree == nil {
+		return nil, xerrors.New("failed to generate tree")
+	}
+	pi, err := s.CreateProtocol(dkgProtocolName, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating dkg protocol: %v", err)
+	}
+	setup := pi.(*dkgprotocol.Setup)
+	setup.Wait = true
+	s.initSetup(setup, req.Roster, cfg.Owner)
+	err = setup.SetConfig(&onet.GenericConfig{Data: cfgBuf})
+	if err != nil {
+		return nil, xerrors.Errorf("setting dkg configuration: %v", err)
+	}
+	if err := setup.Start(); err != nil {
+		return nil, xerrors.Errorf("starting dkg protocol: %v", err)
+	}
+
+	select {
+	case <-setup.Finished:
+		shared, _, err := setup.SharedSecret()
+		if err != nil {
+			return nil, xerrors.Errorf("getting shared secret: %v", err)
+		}
+		log.Lvlf2("%v created group for roster %v", s.ServerIdentity(),
+			req.Roster.ID)
+		return &SetupResponse{Public: shared.X}, nil
+	case <-time.After(dkgTimeout):
+		return nil, xerrors.New("dkg didn't finish in time")
+	}
+}
+
+// Reshare moves the shares of a group to a new roster, keeping the same
+// public key. All nodes of both rosters must be online.
+func (s *Service) Reshare(req *ReshareRequest) (*ReshareResponse, error) {
+	if req.Roster == nil || req.NewRoster == nil || len(req.NewRoster.List) < 2 {
+		return nil, xerrors.New("need a new roster with at least two nodes")
+	}
+	if !req.NewRoster.List[0].Equal(s.ServerIdentity()) {
+		return nil, xerrors.New("the request must be sent to the first node of the new roster")
+	}
+	g := s.getGroup(req.Roster.ID.String())
+	if g == nil {
+		return nil, xerrors.New("no group for this roster")
+	}
+	if err := verifyOwner(g.Owner, req); err != nil {
+		return nil, xerrors.Errorf("verifying request: %v", err)
+	}
+	old, err := g.distKeyShare()
+	if err != nil {
+		return nil, xerrors.Errorf("reading group: %v", err)
+	}
+	commits, err := marshalPoints(old.Commits)
+	if err != nil {
+		return nil, xerrors.Errorf("marshaling commits: %v", err)
+	}
+	cfg := &reshareConfig{
+		ReshareRequest: *req,
+		Owner:          g.Owner,
+		Commits:        commits,
+	}
+	cfgBuf, err := protobuf.Encode(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding configuration: %v", err)
+	}
+
+	roster := reshareRoster(req.Roster, req.NewRoster)
+	tree := roster.GenerateNaryTree(len(roster.List))
+	if tree == nil {
+		return nil, xerrors.New("failed to generate tree")
+	}
+	pi, err := s.CreateProtocol(reshareProtocolName, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating reshare protocol: %v", err)
+	}
+	setup := pi.(*dkgprotocol.Setup)
+	setup.Wait = true
+	if err := s.initReshare(setup, cfg, old); err != nil {
+		return nil, xerrors.Errorf("initializing reshare: %v", err)
+	}
+	err = setup.SetConfig(&onet.GenericConfig{Data: cfgBuf})
+	if err != nil {
+		return nil, xerrors.Errorf("setting dkg configuration: %v", err)
+	}
+	if err := setup.Start(); err != nil {
+		return nil, xerrors.Errorf("starting reshare protocol: %v", err)
+	}
+
+	select {
+	case <-setup.Finished:
+		log.Lvlf2("%v reshared group of roster %v to %v", s.ServerIdentity(),
+			req.Roster.ID, req.NewRoster.ID)
+		return &ReshareResponse{Public: old.Public()}, nil
+	case <-time.After(dkgTimeout):
+		return nil, xerrors.New("resharing didn't finish in time")
+	}
+}
+
+// SignatureRequest asks the group of the roster for a threshold signature
+// of the message.
+func (s *Service) SignatureRequest(req *SignatureRequest) (*SignatureResponse, error) {
+	if req.Roster == nil {
+		return nil, xerrors.New("missing roster")
+	}
+	g := s.getGroup(req.Roster.ID.String())
+	if g == nil {
+		return nil, xerrors.New("no group for this roster, it must be set up first")
+	}
+	dks, err := g.distKeyShare()
+	if err != nil {
+		return nil, xerrors.Errorf("reading group: %v", err)
+	}
+
+	tree := req.Roster.GenerateNaryTreeWithRoot(len(req.Roster.List),
+		s.ServerIdentity())
+	if tree == nil {
+		return nil, xerrors.New("we're not in the roster")
+	}
+	pi, err := s.CreateProtocol(SignProtocolName, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating sign protocol: %v", err)
+	}
+	p := pi.(*SignProtocol)
+	p.Msg = req.Message
+	p.Share = dks.Share
+	p.Public = share.NewPubPoly(suite, suite.Point().Base(), dks.Commits)
+	p.Threshold = threshold(len(req.Roster.List))
+	err = p.SetConfig(&onet.GenericConfig{Data: []byte(req.Roster.ID.String())})
+	if err != nil {
+		return nil, xerrors.Errorf("setting configuration: %v", err)
+	}
+	if err := p.Start(); err != nil {
+		return nil, xerrors.Errorf("starting sign protocol: %v", err)
+	}
+
+	sig := <-p.FinalSignature
+	if sig == nil {
+		return nil, xerrors.New("couldn't collect enough signature shares")
+	}
+	return &SignatureResponse{Public: dks.Public(), Signature: sig}, nil
+}
+
+// NewProtocol prepares the DKG and the signing protocols on the nodes
+// other than the root.
+func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfig) (onet.ProtocolInstance, error) {
+	log.Lvl3(s.ServerIdentity(), tn.ProtocolName(), conf)
+	switch tn.ProtocolName() {
+	case dkgProtocolName:
+		if conf == nil {
+			return nil, xerrors.New("missing configuration")
+		}
+		var cfg setupConfig
+		if err := protobuf.Decode(conf.Data, &cfg); err != nil {
+			return nil, xerrors.Errorf("decoding configuration: %v", err)
+		}
+		if s.getGroup(tn.Roster().ID.String()) != nil {
+			return nil, xerrors.New("the roster already has a group")
+		}
+		pi, err := dkgprotocol.NewSetup(tn)
+		if err != nil {
+			return nil, xerrors.Errorf("setting up dkg: %v", err)
+		}
+		setup := pi.(*dkgprotocol.Setup)
+		s.initSetup(setup, tn.Roster(), cfg.Owner)
+		return setup, nil
+	case reshareProtocolName:
+		if conf == nil {
+			return nil, xerrors.New("missing configuration")
+		}
+		var cfg reshareConfig
+		err := protobuf.DecodeWithConstructors(conf.Data, &cfg,
+			network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return nil, xerrors.Errorf("decoding configuration: %v", err)
+		}
+		if cfg.Roster == nil || cfg.NewRoster == nil {
+			return nil, xerrors.New("missing rosters in configuration")
+		}
+		if !reshareRoster(cfg.Roster, cfg.NewRoster).ID.Equal(tn.Roster().ID) {
+			return nil, xerrors.New("the tree doesn't match the rosters")
+		}
+
+		// The nodes of the current roster check the request against their
+		// owner, the new nodes can only check it is consistent.
+		var old *dkg.DistKeyShare
+		owner := cfg.Owner
+		if g := s.getGroup(cfg.Roster.ID.String()); g != nil {
+			owner = g.Owner
+			old, err = g.distKeyShare()
+			if err != nil {
+				return nil, xerrors.Errorf("reading group: %v", err)
+			}
+		}
+		if err := verifyOwner(owner, &cfg.ReshareRequest); err != nil {
+			return nil, xerrors.Errorf("verifying request: %v", err)
+		}
+		cfg.Owner = owner
+
+		pi, err := dkgprotocol.NewSetup(tn)
+		if err != nil {
+			return nil, xerrors.Errorf("setting up dkg: %v", err)
+		}
+		setup := pi.(*dkgprotocol.Setup)
+		if err := s.initReshare(setup, &cfg, old); err != nil {
+			return nil, xerrors.Errorf("initializing reshare: %v", err)
+		}
+		return setup, nil
+	case SignProtocolName:
+		pi, err := NewSignProtocol(tn)
+		if err != nil {
+			return nil, xerrors.Errorf("creating sign protocol: %v", err)
+		}
+		if conf != nil {
+			if g := s.getGroup(string(conf.Data)); g != nil {
+				dks, err := g.distKeyShare()
+				if err != nil {
+					return nil, xerrors.Errorf("reading group: %v", err)
+				}
+				pi.(*SignProtocol).Share = dks.Share
+			}
+		}
+		return pi, nil
+	case CertifyProtocolName:
+		pi, err := NewCertifyProtocol(tn)
+		if err != nil {
+			return nil, xerrors.Errorf("creating certify protocol: %v", err)
+		}
+		if s.getGroup(tn.Roster().ID.String()) != nil {
+			pi.(*CertifyProtocol).Sign = func(service string) ([]byte, error) {
+				return s.signCertificate(tn.Roster(), service)
+			}
+		}
+		return pi, nil
+	}
+	return nil, nil
+}
+
+// initSetup prepares a DKG for a new group. The DKG runs on G2 with the
+// service keys, the key pair of the setup protocol is only used to exchange
+// the network keys. The share is stored before the protocol ends.
+func (s *Service) initSetup(setup *dkgprotocol.Setup, roster *onet.Roster, owner []byte) {
+	setup.KeyPair = s.networkKeyPair()
+	setup.NewDKG = func() (*dkg.DistKeyGenerator, error) {
+		d, err := dkg.NewDistKeyGenerator(suite,
+			s.ServerIdentity().ServicePrivate(ServiceName),
+			roster.ServicePublics(ServiceName), threshold(len(roster.List)))
+		return d, cothority.ErrorOrNil(err,
+			"creating distributed key generator")
+	}
+	setup.OnCertified = func() error {
+		_, dks, err := setup.SharedSecret()
+		if err != nil {
+			return xerrors.Errorf("getting shared secret: %v", err)
+		}
+		return s.replaceGroup(nil, roster, owner, dks)
+	}
+}
+
+// initReshare prepares the resharing of a group, old being the share of
+// the node if it is part of the current roster. Nodes leaving the group
+// drop their share, the others store their new share before the protocol
+// ends.
+func (s *Service) initReshare(setup *dkgprotocol.Setup, cfg *reshareConfig, old *dkg.DistKeyShare) error {
+	commits, err := unmarshalPoints(cfg.Commits)
+	if err != nil {
+		return xerrors.Errorf("reading commits: %v", err)
+	}
+	if len(commits) == 0 {
+		return xerrors.New("missing commits")
+	}
+	oldn := len(cfg.Roster.List)
+	n := len(cfg.NewRoster.List)
+	c := &dkg.Config{
+		Suite:        suite,
+		Longterm:     s.ServerIdentity().ServicePrivate(ServiceName),
+		OldNodes:     cfg.Roster.ServicePublics(ServiceName),
+		NewNodes:     cfg.NewRoster.ServicePublics(ServiceName),
+		Threshold:    threshold(n),
+		OldThreshold: threshold(oldn),
+	}
+	if old != nil {
+		c.Share = old
+	} else {
+		c.PublicCoeffs = commits
+	}
+
+	setup.KeyPair = s.networkKeyPair()
+	setup.NewDKG = func() (*dkg.DistKeyGenerator, error) {
+		d, err := dkg.NewDistKeyHandler(c)
+		return d, cothority.ErrorOrNil(err,
+			"creating distributed key generator")
+	}
+	setup.OnCertified = func() error {
+		if i, _ := cfg.NewRoster.Search(s.ServerIdentity().ID); i < 0 {
+			return s.replaceGroup(cfg.Roster, nil, nil, nil)
+		}
+		_, dks, err := setup.SharedSecret()
+		if err != nil {
+			return xerrors.Errorf("getting shared secret: %v", err)
+		}
+		if !dks.Public().Equal(commits[0]) {
+			return xerrors.New("the reshared public key is different")
+		}
+		return s.replaceGroup(cfg.Roster, cfg.NewRoster, cfg.Owner, dks)
+	}
+	return nil
+}
+
+func (s *Service) networkKeyPair() *key.Pair {
+	return &key.Pair{
+		Public:  s.ServerIdentity().Public,
+		Private: s.ServerIdentity().GetPrivate(),
+	}
+}
+
+func (s *Service) getGroup(id string) *group {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	return s.storage.Groups[id]
+}
+
+// replaceGroup removes the group of the old roster, if any, and stores the
+// share of the group of the new roster, if any.
+func (s *Service) replaceGroup(old, roster *onet.Roster, owner []byte, dks *dkg.DistKeyShare) error {
+	var g *group
+	if roster != nil {
+		var err error
+		g, err = newGroup(roster, owner, dks)
+		if err != nil {
+			return xerrors.Errorf("creating group: %v", err)
+		}
+	}
+
+	s.storage.Lock()
+	if old != nil {
+		delete(s.storage.Groups, old.ID.String())
+	}
+	if g != nil {
+		s.storage.Groups[roster.ID.String()] = g
+	}
+	s.storage.Unlock()
+	return s.save()
+}
+
+// reshareRoster returns the roster of the resharing protocol: the nodes of
+// the new roster, in the same order so that the deals go to the right
+// nodes, followed by the nodes leaving the group.
+func reshareRoster(old, roster *onet.Roster) *onet.Roster {
+	list := append([]*network.ServerIdentity{}, roster.List...)
+	for _, si := range old.List {
+		if i, _ := roster.Search(si.ID); i < 0 {
+			list = append(list, si)
+		}
+	}
+	return onet.NewRoster(list)
+}
+
+func threshold(n int) int {
+	return n - (n-1)/3
+}
+
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	err := s.Save(storageKey, s.storage)
+	if err != nil {
+		log.Error("Couldn't save data:", err)
+		return xerrors.Errorf("saving data: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	defer func() {
+		if s.storage.Groups == nil {
+			s.storage.Groups = make(map[string]*group)
+		}
+	}()
+	msg, err := s.Load(storageKey)
+	if err != nil {
+		return xerrors.Errorf("loading storage: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	var ok bool
+	s.storage, ok = msg.(*storage)
+	if !ok {
+		return xerrors.New("data of wrong type")
+	}
+	return nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+		certificates:     certificates{certs: make(map[string][]byte)},
+	}
+	if err := s.RegisterHandlers(s.Setup, s.Reshare, s.SignatureRequest); err != nil {
+		return nil, xerrors.Errorf("registering handlers: %v", err)
+	}
+	if err := s.tryLoad(); err != nil {
+		return nil, xerrors.Errorf("loading configuration: %v", err)
+	}
+	return s, nil
+}
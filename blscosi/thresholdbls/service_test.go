@@ -0,0 +1,115 @@
+package thresholdbls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_SignatureRequest(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(5, false)
+	services := local.GetServices(hosts, ServiceID)
+	msg := []byte("hello threshold bls")
+
+	_, err := services[0].(*Service).SignatureRequest(&SignatureRequest{
+		Roster:  roster,
+		Message: msg,
+	})
+	require.Error(t, err)
+	_, err = services[1].(*Service).Setup(&SetupRequest{Roster: roster})
+	require.Error(t, err)
+
+	setup, err := services[0].(*Service).Setup(&SetupRequest{Roster: roster})
+	require.NoError(t, err)
+	// The DKG runs only once per roster.
+	again, err := services[0].(*Service).Setup(&SetupRequest{Roster: roster})
+	require.NoError(t, err)
+	require.True(t, setup.Public.Equal(again.Public))
+
+	// Every node can lead the signature, which is unique.
+	var sig []byte
+	for _, s := range services {
+		reply, err := s.(*Service).SignatureRequest(&SignatureRequest{
+			Roster:  roster,
+			Message: msg,
+		})
+		require.NoError(t, err)
+		require.True(t, setup.Public.Equal(reply.Public))
+		require.NoError(t, Verify(suite, setup.Public, msg, reply.Signature))
+		require.Error(t, Verify(suite, setup.Public, []byte("hello"), reply.Signature))
+		if sig != nil {
+			require.Equal(t, sig, reply.Signature)
+		}
+		sig = reply.Signature
+	}
+
+	// A threshold of the nodes is enough.
+	hosts[4].Pause()
+	reply, err := services[0].(*Service).SignatureRequest(&SignatureRequest{
+		Roster:  roster,
+		Message: msg,
+	})
+	require.NoError(t, err)
+	require.Equal(t, sig, reply.Signature)
+	hosts[4].Unpause()
+}
+
+func TestService_Reshare(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(6, false)
+	services := local.GetServices(hosts, ServiceID)
+	owner := key.NewKeyPair(cothority.Suite)
+	msg := []byte("hello threshold bls")
+
+	oldRoster := onet.NewRoster(roster.List[:4])
+	ownerBuf, err := owner.Public.MarshalBinary()
+	require.NoError(t, err)
+	setup, err := services[0].(*Service).Setup(&SetupRequest{
+		Roster: oldRoster,
+		Owner:  ownerBuf,
+	})
+	require.NoError(t, err)
+
+	// The first node leaves and two nodes join.
+	newRoster := onet.NewRoster(roster.List[1:])
+	_, err = services[0].(*Service).Reshare(&ReshareRequest{
+		Roster:    oldRoster,
+		NewRoster: newRoster,
+	})
+	require.Error(t, err)
+
+	other := key.NewKeyPair(cothority.Suite)
+	_, err = NewClient().Reshare(oldRoster, newRoster, other.Private)
+	require.Error(t, err)
+	reply, err := NewClient().Reshare(oldRoster, newRoster, owner.Private)
+	require.NoError(t, err)
+	require.True(t, setup.Public.Equal(reply.Public))
+
+	// The group moved to the new roster and keeps its public key.
+	for _, s := range services {
+		require.Nil(t, s.(*Service).getGroup(oldRoster.ID.String()))
+	}
+	require.Nil(t, services[0].(*Service).getGroup(newRoster.ID.String()))
+	sigReply, err := services[5].(*Service).SignatureRequest(&SignatureRequest{
+		Roster:  newRoster,
+		Message: msg,
+	})
+	require.NoError(t, err)
+	require.NoError(t, Verify(suite, setup.Public, msg, sigReply.Signature))
+	_, err = services[1].(*Service).SignatureRequest(&SignatureRequest{
+		Roster:  oldRoster,
+		Message: msg,
+	})
+	require.Error(t, err)
+}
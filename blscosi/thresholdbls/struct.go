@@ -0,0 +1,276 @@
+package thresholdbls
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	network.RegisterMessages(&SetupRequest{}, &SetupResponse{},
+		&ReshareRequest{}, &ReshareResponse{},
+		&SignatureRequest{}, &SignatureResponse{},
+		&Announcement{}, &Reply{},
+		&CertifyAnnouncement{}, &CertifyReply{}, &storage{})
+}
+
+// SetupRequest asks the nodes of the roster to run a DKG and to create the
+// key of the group. It must be sent to the first node of the roster.
+type SetupRequest struct {
+	Roster *onet.Roster
+	// Owner is the marshalled Ed25519 public key allowed to reshare the
+	// group. A group without owner cannot be reshared.
+	Owner []byte
+}
+
+// SetupResponse holds the public key of the group.
+type SetupResponse struct {
+	Public kyber.Point
+}
+
+// ReshareRequest asks the nodes of a group to move their shares to a new
+// roster. It must be sent to the first node of the new roster, which must
+// be part of the current roster.
+type ReshareRequest struct {
+	Roster    *onet.Roster
+	NewRoster *onet.Roster
+	// Signature is the Schnorr signature of Hash by the owner of the group.
+	Signature []byte
+}
+
+// ReshareResponse holds the public key of the group, which doesn't change
+// with the roster.
+type ReshareResponse struct {
+	Public kyber.Point
+}
+
+// SignatureRequest asks the group of the roster to sign the message.
+type SignatureRequest struct {
+	Roster  *onet.Roster
+	Message []byte
+}
+
+// SignatureResponse holds the threshold signature of the message, which is
+// a BLS signature verifiable with the public key of the group.
+type SignatureResponse struct {
+	Public    kyber.Point
+	Signature []byte
+}
+
+// Hash returns the message the owner of the group signs to authorise the
+// resharing.
+func (req *ReshareRequest) Hash() []byte {
+	h := sha256.New()
+	h.Write([]byte("thresholdbls reshare"))
+	h.Write(req.Roster.ID[:])
+	h.Write(req.NewRoster.ID[:])
+	return h.Sum(nil)
+}
+
+// Verify returns nil if sig is a threshold signature of msg by the group
+// with the given public key.
+func Verify(suite pairing.Suite, public kyber.Point, msg, sig []byte) error {
+	return bls.Verify(suite, public, msg, sig)
+}
+
+// CertificateMessage returns the message the nodes with the given public
+// keys sign to certify that their group has the given public key.
+func CertificateMessage(publics []kyber.Point, public kyber.Point) ([]byte, error) {
+	h := sha256.New()
+	h.Write([]byte("thresholdbls certificate"))
+	for _, p := range publics {
+		if _, err := p.MarshalTo(h); err != nil {
+			return nil, xerrors.Errorf("hashing public key: %v", err)
+		}
+	}
+	if _, err := public.MarshalTo(h); err != nil {
+		return nil, xerrors.Errorf("hashing public key: %v", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// GroupSignature is a threshold signature that can be verified with the
+// public keys of the nodes of the group. It is the concatenation of the
+// public key of the group, of the BLS signature and of the certificate of
+// the group, which is a BDN signature of CertificateMessage by the nodes.
+type GroupSignature []byte
+
+func newGroupSignature(public kyber.Point, sig, cert []byte) (GroupSignature, error) {
+	buf, err := public.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("marshaling public key: %v", err)
+	}
+	return append(append(buf, sig...), cert...), nil
+}
+
+// Public returns the public key of the group.
+func (gs GroupSignature) Public(suite pairing.Suite) (kyber.Point, error) {
+	if len(gs) < suite.G2().PointLen()+suite.G1().PointLen() {
+		return nil, xerrors.New("invalid signature length")
+	}
+	public := suite.G2().Point()
+	err := public.UnmarshalBinary(gs[:suite.G2().PointLen()])
+	return public, cothority.ErrorOrNil(err, "unmarshaling public key")
+}
+
+// Verify returns nil if the signature is a signature of msg by a group
+// certified by a threshold of the nodes with the given public keys.
+func (gs GroupSignature) Verify(suite pairing.Suite, msg []byte, publics []kyber.Point) error {
+	public, err := gs.Public(suite)
+	if err != nil {
+		return err
+	}
+	certMsg, err := CertificateMessage(publics, public)
+	if err != nil {
+		return err
+	}
+	pubLen := suite.G2().PointLen()
+	sigLen := suite.G1().PointLen()
+	cert := bdnproto.BdnSignature(gs[pubLen+sigLen:])
+	if err := cert.Verify(suite, certMsg, publics); err != nil {
+		return xerrors.Errorf("invalid certificate: %v", err)
+	}
+	return Verify(suite, public, msg, gs[pubLen:pubLen+sigLen])
+}
+
+// Announcement asks a node to sign the message with its share.
+type Announcement struct {
+	Msg []byte
+}
+
+type structAnnouncement struct {
+	*onet.TreeNode
+	Announcement
+}
+
+// Reply holds the signature share of a node, or nothing if the node
+// couldn't sign.
+type Reply struct {
+	Share []byte
+}
+
+type structReply struct {
+	*onet.TreeNode
+	Reply
+}
+
+// CertifyAnnouncement asks a node to sign the certificate of its group with
+// its key for the given service.
+type CertifyAnnouncement struct {
+	Service string
+}
+
+type structCertifyAnnouncement struct {
+	*onet.TreeNode
+	CertifyAnnouncement
+}
+
+// CertifyReply holds the BDN signature of the certificate by a node, or
+// nothing if the node couldn't sign.
+type CertifyReply struct {
+	Signature []byte
+}
+
+type structCertifyReply struct {
+	*onet.TreeNode
+	CertifyReply
+}
+
+// setupConfig is given to the nodes running the DKG of a new group.
+type setupConfig struct {
+	Owner []byte
+}
+
+// reshareConfig is given to the nodes taking part in a resharing.
+type reshareConfig struct {
+	ReshareRequest
+	Owner []byte
+	// Commits are the marshalled public coefficients of the current
+	// polynomial, which the new nodes need to check their deals.
+	Commits [][]byte
+}
+
+var storageKey = []byte("storage")
+
+// storage holds the groups this node is part of, indexed by the ID of
+// their current roster.
+type storage struct {
+	Groups map[string]*group
+
+	sync.Mutex
+}
+
+// group holds the share of the node in a group.
+type group struct {
+	Roster *onet.Roster
+	Owner  []byte
+	// Share is the encoded DistKeyShare of the node. As its points are on
+	// G2, it cannot be decoded together with the roster.
+	Share []byte
+}
+
+func newGroup(roster *onet.Roster, owner []byte, dks *dkg.DistKeyShare) (*group, error) {
+	buf, err := protobuf.Encode(dks)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding share: %v", err)
+	}
+	return &group{Roster: roster, Owner: owner, Share: buf}, nil
+}
+
+func (g *group) distKeyShare() (*dkg.DistKeyShare, error) {
+	dks := &dkg.DistKeyShare{}
+	err := protobuf.DecodeWithConstructors(g.Share, dks,
+		network.DefaultConstructors(suite))
+	if err != nil {
+		return nil, xerrors.Errorf("decoding share: %v", err)
+	}
+	return dks, nil
+}
+
+// verifyOwner returns nil if the request is signed by the owner.
+func verifyOwner(owner []byte, req *ReshareRequest) error {
+	if len(owner) == 0 {
+		return xerrors.New("the group has no owner and cannot be reshared")
+	}
+	pub := cothority.Suite.Point()
+	if err := pub.UnmarshalBinary(owner); err != nil {
+		return xerrors.Errorf("invalid owner: %v", err)
+	}
+	return cothority.ErrorOrNil(
+		schnorr.Verify(cothority.Suite, pub, req.Hash(), req.Signature),
+		"verifying signature of the owner")
+}
+
+func marshalPoints(points []kyber.Point) ([][]byte, error) {
+	bufs := make([][]byte, len(points))
+	for i, p := range points {
+		buf, err := p.MarshalBinary()
+		if err != nil {
+			return nil, xerrors.Errorf("marshaling point: %v", err)
+		}
+		bufs[i] = buf
+	}
+	return bufs, nil
+}
+
+func unmarshalPoints(bufs [][]byte) ([]kyber.Point, error) {
+	points := make([]kyber.Point, len(bufs))
+	for i, buf := range bufs {
+		points[i] = suite.Point()
+		if err := points[i].UnmarshalBinary(buf); err != nil {
+			return nil, xerrors.Errorf("unmarshaling point: %v", err)
+		}
+	}
+	return points, nil
+}
@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -450,6 +451,120 @@ func dbRemove(c *cli.Context) error {
 	return fb.db.Close()
 }
 
+// dbPrune drops the payloads of old blocks, keeping the headers and the
+// forward-links.
+func dbPrune(c *cli.Context) error {
+	fb, err := newFetchBlocks(c)
+	if err != nil {
+		return xerrors.Errorf("couldn't create fetchBlock: %+v", err)
+	}
+
+	if fb.bcID == nil {
+		return xerrors.New("need bcID")
+	}
+
+	latest, err := fb.db.GetLatestByID(*fb.bcID)
+	if err != nil {
+		return xerrors.Errorf("couldn't get latest block: %v", err)
+	}
+	index := latest.Index - c.Int("keep") + 1
+	var checkpoint skipchain.SkipBlockID
+	if cp := c.String("checkpoint"); cp != "" {
+		checkpoint, err = hex.DecodeString(cp)
+		if err != nil {
+			return xerrors.Errorf("couldn't decode checkpoint: %v", err)
+		}
+		sb := fb.db.GetByID(checkpoint)
+		if sb == nil {
+			return xerrors.New("didn't find checkpoint in db")
+		}
+		index = sb.Index
+	} else if c.Int("keep") < 1 {
+		return xerrors.New("need to keep at least one block")
+	}
+
+	// The block of the trie index and the blocks that are not yet applied to
+	// the trie must be kept, else the node cannot catch up anymore.
+	tr := trie.NewDiskDB(fb.boltDB, fb.trieBucketName)
+	err = tr.View(func(b trie.Bucket) error {
+		buf := b.Get([]byte("trieIndexKey"))
+		if buf == nil {
+			return errors.New("couldn't get index key")
+		}
+		if index > int(binary.LittleEndian.Uint32(buf)) {
+			return errors.New("need to keep blocks from the trie-state on." +
+				"\nUse `bcadmin db replay --write` to update the trie first.")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var pruned int
+	if checkpoint != nil {
+		pruned, err = fb.db.PruneBefore(checkpoint)
+	} else {
+		pruned, err = fb.db.Prune(*fb.bcID, c.Int("keep"))
+	}
+	if err != nil {
+		return xerrors.Errorf("couldn't prune blocks: %v", err)
+	}
+	log.Infof("Pruned the payload of %d blocks", pruned)
+
+	return fb.db.Close()
+}
+
+// dbExport writes the blocks of the chain to an archive.
+func dbExport(c *cli.Context) error {
+	if c.String("out") == "" {
+		return xerrors.New("please give the archive file with --out")
+	}
+	fb, err := newFetchBlocks(c)
+	if err != nil {
+		return xerrors.Errorf("couldn't create fetchBlock: %+v", err)
+	}
+
+	f, err := os.Create(c.String("out"))
+	if err != nil {
+		return xerrors.Errorf("couldn't create archive: %v", err)
+	}
+	defer f.Close()
+	n, err := fb.db.Export(f, *fb.bcID, c.Int("start"), c.Int("end"))
+	if err != nil {
+		return xerrors.Errorf("couldn't export blocks: %v", err)
+	}
+	log.Infof("Exported %d blocks", n)
+	return fb.db.Close()
+}
+
+// dbImport verifies the blocks of an archive and stores them in the db.
+func dbImport(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return xerrors.New("please give the following arguments: " +
+			"conode.db archive")
+	}
+	f, err := os.Open(c.Args().Get(1))
+	if err != nil {
+		return xerrors.Errorf("couldn't open archive: %v", err)
+	}
+	defer f.Close()
+
+	// The db can be empty, so we don't use newFetchBlocks here.
+	log.Info("Opening database", c.Args().First())
+	db, _, err := (&fetchBlocks{}).openDB(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("couldn't open DB: %+v", err)
+	}
+	header, n, err := db.Import(f)
+	if err != nil {
+		return xerrors.Errorf("couldn't import archive: %v", err)
+	}
+	log.Infof("Imported %d blocks of chain %x, up to index %d", n,
+		header.SkipChainID, header.End)
+	return db.Close()
+}
+
 // dbCheck verifies all the hashes and links from the blocks.
 func dbCheck(c *cli.Context) error {
 	fb, err := newFetchBlocks(c)
@@ -715,6 +715,52 @@ var cmds = cli.Commands{
 				ArgsUsage: "conode.db [bcID] [blocks]",
 				Action:    dbRemove,
 			},
+			{
+				Name: "prune",
+				Usage: "drops the payload of old blocks, keeping the headers" +
+					" and forward-links",
+				ArgsUsage: "conode.db [bcID]",
+				Action:    dbPrune,
+				Flags: []cli.Flag{
+					cli.IntFlag{
+						Name:  "keep",
+						Usage: "number of latest blocks whose payload is kept",
+						Value: 1000,
+					},
+					cli.StringFlag{
+						Name: "checkpoint",
+						Usage: "hex id of a block - the payloads of all older" +
+							" blocks are dropped",
+					},
+				},
+			},
+			{
+				Name:      "export",
+				Usage:     "writes the blocks to a verifiable archive",
+				ArgsUsage: "conode.db [bcID]",
+				Action:    dbExport,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "out, o",
+						Usage: "file to write the archive to",
+					},
+					cli.IntFlag{
+						Name:  "start",
+						Usage: "index of the first block to export",
+					},
+					cli.IntFlag{
+						Name:  "end",
+						Usage: "index of the last block to export (default: latest block)",
+						Value: -1,
+					},
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "verifies the blocks of an archive and stores them",
+				ArgsUsage: "conode.db archive",
+				Action:    dbImport,
+			},
 			{
 				Name: "check",
 				Usage: "Check that the chain is in a correct state with" +
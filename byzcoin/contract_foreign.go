@@ -0,0 +1,247 @@
+package byzcoin
+
+import (
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractForeignChainID is the ID of the foreign chain contract. An instance
+// of this contract holds the trusted head of another skipchain, typically the
+// one of another ByzCoin instance. Other contracts can then verify proofs
+// coming from this foreign chain with VerifyForeignProof, which allows for
+// cross-chain token locks and attestations.
+//
+// The instance is spawned with the "block" argument, which must be a block of
+// the foreign chain the darc trusts, usually its genesis block. Use
+// ForeignChainSpawnArgs to create it.
+//
+// The trusted head is moved forward by invoking "update" with the "proof"
+// argument, which must be a skipchain.CompactProof starting at the current
+// head. As the forward-links of the proof are verified, the instance never
+// trusts more than the darc trusted when spawning it. Use
+// ForeignChainUpdateArgs to create it.
+const ContractForeignChainID = "foreignchain"
+
+// ForeignChainBody is the data stored in a foreign chain instance.
+type ForeignChainBody struct {
+	// SkipChainID is the ID of the foreign skipchain.
+	SkipChainID skipchain.SkipBlockID
+	// Head is the header of the latest trusted block of the foreign chain.
+	Head *skipchain.CompactBlock
+}
+
+// ForeignChainUpdate holds the proof sent to the "update" command of a
+// foreign chain instance.
+type ForeignChainUpdate struct {
+	Proof skipchain.CompactProof
+}
+
+type contractForeignChain struct {
+	BasicContract
+	ForeignChainBody
+}
+
+// String returns a human readable string representation of the foreign chain
+// body.
+func (fc ForeignChainBody) String() string {
+	out := new(strings.Builder)
+	out.WriteString("- ForeignChainBody:\n")
+	fmt.Fprintf(out, "-- SkipChainID: %x\n", fc.SkipChainID)
+	if fc.Head != nil && fc.Head.SkipBlockFix != nil {
+		fmt.Fprintf(out, "-- Head: %x (index %d)\n", fc.Head.Hash(), fc.Head.Index)
+	}
+	return out.String()
+}
+
+func contractForeignChainFromBytes(in []byte) (Contract, error) {
+	c := &contractForeignChain{}
+	err := protobuf.DecodeWithConstructors(in, &c.ForeignChainBody,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("decoding: %v", err)
+	}
+	return c, nil
+}
+
+// ForeignChainSpawnArgs returns the arguments to spawn a foreign chain
+// instance trusting the given block.
+func ForeignChainSpawnArgs(sb *skipchain.SkipBlock) (Arguments, error) {
+	buf, err := protobuf.Encode(&skipchain.CompactBlock{
+		SkipBlockFix:    sb.SkipBlockFix.Copy(),
+		SignatureScheme: sb.SignatureScheme,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("encoding: %v", err)
+	}
+	return Arguments{{Name: "block", Value: buf}}, nil
+}
+
+// ForeignChainUpdateArgs returns the arguments to move the head of a foreign
+// chain instance to the last block of the proof.
+func ForeignChainUpdateArgs(cp skipchain.CompactProof) (Arguments, error) {
+	buf, err := protobuf.Encode(&ForeignChainUpdate{Proof: cp})
+	if err != nil {
+		return nil, xerrors.Errorf("encoding: %v", err)
+	}
+	return Arguments{{Name: "proof", Value: buf}}, nil
+}
+
+func (c *contractForeignChain) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+
+	head := &skipchain.CompactBlock{}
+	err = protobuf.DecodeWithConstructors(inst.Spawn.Args.Search("block"), head,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("couldn't decode block: %v", err)
+	}
+	if head.SkipBlockFix == nil || head.Roster == nil {
+		return nil, nil, xerrors.New("block must have a header and a roster")
+	}
+	head.Link = nil
+	scID := head.GenesisID
+	if head.Index == 0 {
+		scID = head.Hash()
+	}
+
+	buf, err := protobuf.Encode(&ForeignChainBody{SkipChainID: scID, Head: head})
+	if err != nil {
+		return nil, nil, xerrors.Errorf("encoding: %v", err)
+	}
+	return StateChanges{NewStateChange(Create, inst.DeriveID(""),
+		ContractForeignChainID, buf, darcID)}, coins, nil
+}
+
+func (c *contractForeignChain) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+
+	switch inst.Invoke.Command {
+	case "update":
+		var update ForeignChainUpdate
+		err = protobuf.DecodeWithConstructors(inst.Invoke.Args.Search("proof"),
+			&update, network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return nil, nil, xerrors.Errorf("couldn't decode proof: %v", err)
+		}
+		cp := update.Proof
+		if len(cp) < 2 {
+			return nil, nil, xerrors.New("proof doesn't go further than the head")
+		}
+		if err := cp.VerifyFromID(c.Head.Hash()); err != nil {
+			return nil, nil, xerrors.Errorf("invalid proof: %v", err)
+		}
+		head := cp[len(cp)-1]
+		if head.Roster == nil {
+			return nil, nil, xerrors.New("missing roster in the new head")
+		}
+		head.Link = nil
+
+		buf, err := protobuf.Encode(&ForeignChainBody{
+			SkipChainID: c.SkipChainID,
+			Head:        head,
+		})
+		if err != nil {
+			return nil, nil, xerrors.Errorf("encoding: %v", err)
+		}
+		return StateChanges{NewStateChange(Update, inst.InstanceID,
+			ContractForeignChainID, buf, darcID)}, coins, nil
+	default:
+		return nil, nil, xerrors.New("invalid invoke command: " + inst.Invoke.Command)
+	}
+}
+
+func (c *contractForeignChain) Delete(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) ([]StateChange, []Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading trie: %v", err)
+	}
+	return StateChanges{NewStateChange(Remove, inst.InstanceID,
+		ContractForeignChainID, nil, darcID)}, coins, nil
+}
+
+// GetForeignChain returns the body of the foreign chain instance with the
+// given ID.
+func GetForeignChain(rst ReadOnlyStateTrie, id InstanceID) (*ForeignChainBody, error) {
+	buf, _, cID, _, err := rst.GetValues(id.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("reading trie: %v", err)
+	}
+	if cID != ContractForeignChainID {
+		return nil, xerrors.Errorf("instance is a %s, not a %s", cID,
+			ContractForeignChainID)
+	}
+	fc := &ForeignChainBody{}
+	err = protobuf.DecodeWithConstructors(buf, fc,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("decoding: %v", err)
+	}
+	return fc, nil
+}
+
+// VerifyForeignProof verifies a proof coming from the foreign chain stored
+// in the instance with the given ID. The proof is accepted if its latest
+// block is the trusted head of the instance, or if its forward-links go
+// through the trusted head. A proof for a block older than the head is
+// refused, so clients should update the instance first, which can be done
+// in the same transaction. As for Proof.Verify, it is up to the caller to
+// check the key and the value of the proof.
+func VerifyForeignProof(rst ReadOnlyStateTrie, id InstanceID, p Proof) error {
+	fc, err := GetForeignChain(rst, id)
+	if err != nil {
+		return xerrors.Errorf("couldn't get foreign chain: %v", err)
+	}
+	return fc.VerifyProof(p)
+}
+
+// VerifyProof verifies that the proof comes from the foreign chain and is
+// valid with regard to the trusted head.
+func (fc ForeignChainBody) VerifyProof(p Proof) error {
+	if fc.Head == nil || fc.Head.SkipBlockFix == nil {
+		return xerrors.New("missing trusted head")
+	}
+	if p.Latest.SkipBlockFix == nil {
+		return xerrors.New("missing latest block in proof")
+	}
+	if !p.Latest.SkipChainID().Equal(fc.SkipChainID) {
+		return xerrors.New("proof is for another chain")
+	}
+
+	head := fc.Head.Hash()
+	if p.Latest.CalculateHash().Equal(head) {
+		return cothority.ErrorOrNil(p.VerifyInclusionProof(&p.Latest),
+			"verifying inclusion proof")
+	}
+	for i, l := range p.Links {
+		if !l.To.Equal(head) {
+			continue
+		}
+		// The links before the head are not needed, as the head is
+		// trusted, and its roster is used for the next link.
+		links := []skipchain.ForwardLink{{
+			From:      []byte{},
+			To:        head,
+			NewRoster: fc.Head.Roster,
+		}}
+		sub := Proof{
+			InclusionProof: p.InclusionProof,
+			Latest:         p.Latest,
+			Links:          append(links, p.Links[i+1:]...),
+		}
+		return cothority.ErrorOrNil(sub.Verify(head), "verifying proof")
+	}
+	return xerrors.New("proof doesn't go through the trusted head, " +
+		"the foreign chain needs to be updated")
+}
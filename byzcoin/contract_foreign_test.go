@@ -0,0 +1,119 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"golang.org/x/xerrors"
+)
+
+func TestContractForeignChain(t *testing.T) {
+	// The chain created by ser is the foreign chain.
+	s := newSer(t, 2, testInterval)
+	defer s.local.CloseAll()
+	foreignID := NewInstanceID(s.tx.Instructions[0].Hash())
+	foreignProof := s.waitProof(t, foreignID)
+
+	// The local chain runs on the same nodes.
+	genesisMsg, err := DefaultGenesisMsg(CurrentVersion, s.roster,
+		[]string{"spawn:" + ContractForeignChainID,
+			"invoke:" + ContractForeignChainID + ".update"},
+		s.signer.Identity())
+	require.NoError(t, err)
+	genesisMsg.BlockInterval = testInterval
+	resp, err := s.service().CreateGenesisBlock(genesisMsg)
+	require.NoError(t, err)
+	localID := resp.Skipblock.SkipChainID()
+	sendLocal := func(counter uint64, instr Instruction) (InstanceID, error) {
+		instr.SignerCounter = []uint64{counter}
+		ctx, err := combineInstrsAndSign(s.signer, instr)
+		require.NoError(t, err)
+		resp, err := s.service().AddTransaction(&AddTxRequest{
+			Version:       CurrentVersion,
+			SkipchainID:   localID,
+			Transaction:   ctx,
+			InclusionWait: 10,
+		})
+		if err != nil {
+			return InstanceID{}, err
+		}
+		if resp.Error != "" {
+			return InstanceID{}, xerrors.New(resp.Error)
+		}
+		return ctx.Instructions[0].DeriveID(""), nil
+	}
+
+	args, err := ForeignChainSpawnArgs(s.genesis)
+	require.NoError(t, err)
+	spawn := Instruction{
+		InstanceID: NewInstanceID(genesisMsg.GenesisDarc.GetBaseID()),
+		Spawn: &Spawn{
+			ContractID: ContractForeignChainID,
+			Args:       args,
+		},
+	}
+	fcID, err := sendLocal(1, spawn)
+	require.NoError(t, err)
+
+	rst, err := s.service().GetReadOnlyStateTrie(localID)
+	require.NoError(t, err)
+	fc, err := GetForeignChain(rst, fcID)
+	require.NoError(t, err)
+	require.True(t, fc.SkipChainID.Equal(s.genesis.SkipChainID()))
+	require.True(t, fc.Head.Hash().Equal(s.genesis.Hash))
+
+	// The proof goes through the genesis block.
+	require.NoError(t, VerifyForeignProof(rst, fcID, foreignProof))
+	tampered := foreignProof
+	tampered.Latest = *foreignProof.Latest.Copy()
+	tampered.Latest.Data = append([]byte{}, tampered.Latest.Data...)
+	tampered.Latest.Data[len(tampered.Latest.Data)-1] ^= 1
+	require.Error(t, VerifyForeignProof(rst, fcID, tampered))
+	// A proof from the local chain is refused.
+	localProof, err := s.service().GetProof(&GetProof{
+		Version: CurrentVersion,
+		Key:     fcID.Slice(),
+		ID:      localID,
+	})
+	require.NoError(t, err)
+	require.Error(t, VerifyForeignProof(rst, fcID, localProof.Proof))
+
+	// Add a block to the foreign chain and move the head to it.
+	tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract,
+		s.value, s.signer, 2)
+	require.NoError(t, err)
+	s.sendTxAndWait(t, tx, 10)
+	newProof := s.waitProof(t, NewInstanceID(tx.Instructions[0].Hash()))
+	require.True(t, newProof.Latest.Index > foreignProof.Latest.Index)
+
+	cp, err := s.service().skService().GetCompactProof(
+		&skipchain.GetCompactProof{Trusted: fc.Head.Hash()})
+	require.NoError(t, err)
+	require.True(t, cp.Proof.Latest().Equal(newProof.Latest.Hash))
+	args, err = ForeignChainUpdateArgs(cp.Proof)
+	require.NoError(t, err)
+	update := Instruction{
+		InstanceID: fcID,
+		Invoke: &Invoke{
+			ContractID: ContractForeignChainID,
+			Command:    "update",
+			Args:       args,
+		},
+	}
+	_, err = sendLocal(2, update)
+	require.NoError(t, err)
+
+	rst, err = s.service().GetReadOnlyStateTrie(localID)
+	require.NoError(t, err)
+	fc, err = GetForeignChain(rst, fcID)
+	require.NoError(t, err)
+	require.True(t, fc.Head.Hash().Equal(newProof.Latest.Hash))
+	require.NoError(t, VerifyForeignProof(rst, fcID, newProof))
+	// Proofs older than the head are refused.
+	require.Error(t, VerifyForeignProof(rst, fcID, foreignProof))
+
+	// The proof must start at the current head.
+	_, err = sendLocal(3, update)
+	require.Error(t, err)
+}
@@ -170,6 +170,37 @@ func RegisterGlobalContract(contractID string, f ContractFn) error {
 	return cothority.ErrorOrNil(err, "registration failed")
 }
 
+// MakeAttrInterpreterFn returns the callback evaluating an attribute of a
+// darc rule for the given instruction.
+type MakeAttrInterpreterFn func(rst ReadOnlyStateTrie, inst Instruction) func(string) error
+
+// globalAttrInterpreter is an attribute interpreter added to the ones of the
+// BasicContract, and the version of ByzCoin from which on it is used.
+type globalAttrInterpreter struct {
+	version Version
+	make    MakeAttrInterpreterFn
+}
+
+// globalAttrInterpreters holds the attribute interpreters added to the ones of
+// the BasicContract.
+var globalAttrInterpreters = map[string]globalAttrInterpreter{}
+
+// RegisterGlobalAttrInterpreter adds an interpreter for the attribute name to
+// the ones returned by BasicContract.MakeAttrInterpreters, so that every
+// contract using it can have this attribute in its rules. This should be
+// called during module initialization, as the interpreters are not protected
+// against concurrent access.
+// The interpreter is only used by chains running at least the given version,
+// so that the nodes of older chains all evaluate the rules the same way.
+func RegisterGlobalAttrInterpreter(name string, version Version,
+	f MakeAttrInterpreterFn) error {
+	if _, exists := globalAttrInterpreters[name]; exists || name == "block" {
+		return xerrors.New("attribute interpreter already registered")
+	}
+	globalAttrInterpreters[name] = globalAttrInterpreter{version, f}
+	return nil
+}
+
 // RegisterContract stores the contract in the service registry which
 // makes it only available to byzcoin.
 //
@@ -238,8 +269,15 @@ func (b BasicContract) VerifyDeferredInstruction(rst ReadOnlyStateTrie, inst Ins
 
 // MakeAttrInterpreters provides one default attribute verification which check
 // whether the transaction is sent after a certain block index and before
-// another block index.
+// another block index. The interpreters registered with
+// RegisterGlobalAttrInterpreter are also returned.
 func (b BasicContract) MakeAttrInterpreters(rst ReadOnlyStateTrie, inst Instruction) darc.AttrInterpreters {
+	attrs := darc.AttrInterpreters{}
+	for name, gai := range globalAttrInterpreters {
+		if rst.GetVersion() >= gai.version {
+			attrs[name] = gai.make(rst, inst)
+		}
+	}
 	cb := func(attr string) error {
 		vals, err := url.ParseQuery(attr)
 		if err != nil {
@@ -277,7 +315,8 @@ func (b BasicContract) MakeAttrInterpreters(rst ReadOnlyStateTrie, inst Instruct
 		}
 		return xerrors.Errorf("the current block index is %d which does not fit in the interval (%d, %d)", rst.GetIndex(), after, before)
 	}
-	return darc.AttrInterpreters{"block": cb}
+	attrs["block"] = cb
+	return attrs
 }
 
 // Spawn is not implmented in a BasicContract. Types which embed BasicContract
@@ -36,3 +36,30 @@ func TestContracts_Registry(t *testing.T) {
 	require.Error(t, r.register("c", testContractFn, false))
 	require.NoError(t, r.register("c", testContractFn, true))
 }
+
+// Test that the registered attribute interpreters are given to the contracts.
+func TestContracts_AttrInterpreter(t *testing.T) {
+	called := false
+	f := func(ReadOnlyStateTrie, Instruction) func(string) error {
+		return func(string) error {
+			called = true
+			return nil
+		}
+	}
+	require.NoError(t, RegisterGlobalAttrInterpreter("test", CurrentVersion, f))
+	defer delete(globalAttrInterpreters, "test")
+	require.Error(t, RegisterGlobalAttrInterpreter("test", CurrentVersion, f))
+	require.Error(t, RegisterGlobalAttrInterpreter("block", CurrentVersion, f))
+
+	rst := NewROSTSimul()
+	attrs := BasicContract{}.MakeAttrInterpreters(rst, Instruction{})
+	require.NotNil(t, attrs["block"])
+	require.NoError(t, attrs["test"](""))
+	require.True(t, called)
+
+	// Older chains don't know about the interpreter.
+	rst.Version = CurrentVersion - 1
+	attrs = BasicContract{}.MakeAttrInterpreters(rst, Instruction{})
+	require.NotNil(t, attrs["block"])
+	require.Nil(t, attrs["test"])
+}
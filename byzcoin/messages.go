@@ -24,7 +24,7 @@ func init() {
 type Version int
 
 // CurrentVersion is what we're running now
-const CurrentVersion Version = VersionSpawnerCoins
+const CurrentVersion Version = VersionPopPartyAttr
 
 const (
 	// VersionInstructionHash is the first version and indicates that a new,
@@ -41,4 +41,13 @@ const (
 	// VersionSpawnerCoins indicates a fixed spawner contract that will treat
 	// the coins correctly
 	VersionSpawnerCoins = 6
+	// VersionCredentialRecover indicates a fixed credential contract that
+	// correctly reads the signatures of the trustees when recovering
+	VersionCredentialRecover = 7
+	// VersionSpawnerPrices lets the spawner contract use configurable costs
+	// for any contract and discounts for credential holders
+	VersionSpawnerPrices = 8
+	// VersionPopPartyAttr adds the popparty attribute to the darcs of all
+	// contracts using the attribute interpreters of the BasicContract
+	VersionPopPartyAttr = 9
 )
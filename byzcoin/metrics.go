@@ -0,0 +1,53 @@
+package byzcoin
+
+import (
+	"go.dedis.ch/cothority/v3/metrics"
+)
+
+// serviceMetrics holds the metrics of the service, which are served by the
+// status service of the conode.
+type serviceMetrics struct {
+	// blockLatency is the time the leader needs to create and store a new
+	// block.
+	blockLatency *metrics.Histogram
+	// instructions counts the instructions of the stored blocks, per
+	// contract and result.
+	instructions *metrics.Counter
+	viewChanges  *metrics.Counter
+	// catchupRemaining is the number of blocks left to fetch by the
+	// current catch up.
+	catchupRemaining *metrics.Gauge
+}
+
+func newServiceMetrics(s *Service) serviceMetrics {
+	r := metrics.ForServer(s.ServerIdentity())
+	mempool := r.Gauge("byzcoin_mempool_transactions",
+		"Transactions waiting to be included in a block")
+	r.OnCollect(func() {
+		mempool.Set(float64(s.txBuffer.size()))
+	})
+	return serviceMetrics{
+		blockLatency: r.Histogram("byzcoin_block_latency_seconds",
+			"Time to create and store a new block, on the leader", nil),
+		instructions: r.Counter("byzcoin_instructions_total",
+			"Instructions of the stored blocks", "contract", "result"),
+		viewChanges: r.Counter("byzcoin_view_changes_total",
+			"View changes stored in the chains"),
+		catchupRemaining: r.Gauge("byzcoin_catchup_remaining_blocks",
+			"Blocks left to fetch by the current catch up"),
+	}
+}
+
+// countInstructions updates the metrics with the transactions of a stored
+// block.
+func (m *serviceMetrics) countInstructions(txs TxResults) {
+	for _, tx := range txs {
+		result := "accepted"
+		if !tx.Accepted {
+			result = "rejected"
+		}
+		for _, instr := range tx.ClientTransaction.Instructions {
+			m.instructions.Inc(instr.ContractID(), result)
+		}
+	}
+}
@@ -136,8 +136,18 @@ func (p Proof) Verify(sbID skipchain.SkipBlockID) error {
 	// before against the block with ID stored in the To field by the caller.
 	publics := p.Links[0].NewRoster.ServicePublics(skipchain.ServiceName)
 
-	for _, l := range p.Links[1:] {
-		if err = l.VerifyWithScheme(pairing.NewSuiteBn256(), publics, p.Latest.SignatureScheme); err != nil {
+	// Each link is signed with the scheme of the block it comes from, which
+	// is found going back from the latest block, as the links record the
+	// scheme when it changes.
+	schemes := make([]uint32, len(p.Links))
+	scheme := p.Latest.SignatureScheme
+	for i := len(p.Links) - 1; i > 0; i-- {
+		scheme = p.Links[i].FromSignatureScheme(scheme)
+		schemes[i] = scheme
+	}
+
+	for i, l := range p.Links[1:] {
+		if err = l.VerifyWithScheme(pairing.NewSuiteBn256(), publics, schemes[i+1]); err != nil {
 			return cothority.WrapError(ErrorVerifySkipchain)
 		}
 		if !l.From.Equal(sbID) {
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/blscosi/schnorrproto"
 	"go.dedis.ch/cothority/v3/byzcoinx"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/kyber/v3"
@@ -58,6 +59,65 @@ func TestVerify(t *testing.T) {
 	require.True(t, xerrors.Is(p.Verify(s.genesis.SkipChainID()), ErrorVerifyTrieRoot))
 }
 
+// Verifies a proof going through a block migrating to another signature
+// scheme, whose forward-link is signed with the previous scheme.
+func TestVerify_Migration(t *testing.T) {
+	s := createSC(t)
+	suite := pairing.NewSuiteBn256()
+
+	sb2 := skipchain.NewSkipBlock()
+	sb2.Index = 1
+	sb2.Height = 1
+	sb2.SignatureScheme = skipchain.SchnorrSignatureSchemeIndex
+	var privs []kyber.Scalar
+	sb2.Roster, privs = genRoster(1)
+	sb2.Hash = sb2.CalculateHash()
+
+	sb3 := skipchain.NewSkipBlock()
+	sb3.Index = 2
+	sb3.Height = 1
+	sb3.SignatureScheme = skipchain.SchnorrSignatureSchemeIndex
+	sb3.Roster = sb2.Roster
+	var err error
+	sb3.Data, err = protobuf.Encode(&DataHeader{
+		TrieRoot: s.c.GetRoot(),
+	})
+	require.NoError(t, err)
+	sb3.Hash = sb3.CalculateHash()
+
+	// The genesis block signs the migration with BLS, then the new roster
+	// signs with Schnorr.
+	link1 := genForwardLink(t, s.genesis, sb2, s.genesisPrivs)[0]
+	require.NotNil(t, link1.SignatureScheme)
+	link2 := skipchain.NewForwardLink(sb2, sb3)
+	require.Nil(t, link2.SignatureScheme)
+	sig, err := schnorrproto.Sign(suite, privs[0], link2.Hash())
+	require.NoError(t, err)
+	link2.Signature = byzcoinx.FinalSignature{
+		Msg: link2.Hash(),
+		Sig: append(sig, 1),
+	}
+
+	pr, err := s.c.GetProof(s.key)
+	require.NoError(t, err)
+	p := Proof{
+		InclusionProof: *pr,
+		Latest:         *sb3,
+		Links: []skipchain.ForwardLink{{
+			To:        s.genesis.Hash,
+			NewRoster: s.genesis.Roster,
+		}, *link1, *link2},
+	}
+	require.NoError(t, p.Verify(s.genesis.SkipChainID()))
+
+	// The scheme of the link is covered by its signature.
+	scheme := skipchain.SchnorrSignatureSchemeIndex
+	p.Links[1].SignatureScheme = &scheme
+	require.True(t, xerrors.Is(p.Verify(s.genesis.SkipChainID()), ErrorVerifySkipchain))
+	p.Links[1].SignatureScheme = nil
+	require.True(t, xerrors.Is(p.Verify(s.genesis.SkipChainID()), ErrorVerifySkipchain))
+}
+
 type sc struct {
 	c            *stateTrie             // a usable collectionDB to store key/value pairs
 	s            *skipchain.SkipBlockDB // a usable skipchain DB to store blocks
@@ -130,13 +190,7 @@ func createSC(t *testing.T) (s sc) {
 }
 
 func genForwardLink(t *testing.T, from, to *skipchain.SkipBlock, privs []kyber.Scalar) []*skipchain.ForwardLink {
-	fwd := &skipchain.ForwardLink{
-		From: from.Hash,
-		To:   to.Hash,
-	}
-	if !from.Roster.ID.Equal(to.Roster.ID) {
-		fwd.NewRoster = to.Roster
-	}
+	fwd := skipchain.NewForwardLink(from, to)
 	sig, err := bls.Sign(pairing.NewSuiteBn256(), privs[0], fwd.Hash())
 	fwd.Signature = byzcoinx.FinalSignature{
 		Msg: fwd.Hash(),
@@ -77,9 +77,16 @@ func (s *ROSTSimul) LoadConfig() (*ChainConfig, error) {
 	return nil, errors.New("not implemented")
 }
 
-// LoadDarc is not implemented
+// LoadDarc returns the darc stored in the instance id.
 func (s *ROSTSimul) LoadDarc(id darc.ID) (*darc.Darc, error) {
-	return nil, errors.New("not implemented")
+	darcBuf, _, cid, _, err := s.GetValues(id)
+	if err != nil {
+		return nil, err
+	}
+	if cid != ContractDarcID {
+		return nil, errors.New("instance is not a darc")
+	}
+	return darc.NewFromProtobuf(darcBuf)
 }
 
 // StoreAllToReplica stores all stateChanges, without checking for validity!
@@ -97,6 +97,10 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	err = RegisterGlobalContract(ContractForeignChainID, contractForeignChainFromBytes)
+	if err != nil {
+		panic(err)
+	}
 }
 
 // GenNonce returns a random nonce.
@@ -171,6 +175,8 @@ type Service struct {
 	// ByzCoin chains.
 	defaultVersion     Version
 	defaultVersionLock sync.Mutex
+
+	metrics serviceMetrics
 }
 
 type downloadState struct {
@@ -1001,6 +1007,7 @@ func (s *Service) SetPropagationTimeout(p time.Duration) {
 // inform all nodes to update their internal trie
 // to include the new transactions.
 func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx []TxResult) (*skipchain.SkipBlock, error) {
+	start := time.Now()
 	var sb *skipchain.SkipBlock
 	var mr []byte
 	var sst *stagingStateTrie
@@ -1130,6 +1137,7 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 		return nil, xerrors.Errorf("storing block: %v", err)
 	}
 
+	s.metrics.blockLatency.Since(start)
 	return ssbReply.Latest, nil
 }
 
@@ -1517,8 +1525,10 @@ func (s *Service) catchUp(sb *skipchain.SkipBlock) {
 	latest := reply.SkipBlock
 
 	// Fetch all missing blocks to fill the hole
+	defer s.metrics.catchupRemaining.Set(0)
 	for trieIndex < sb.Index {
 		log.Lvlf2("%s: our index: %d - latest known index: %d", s.ServerIdentity(), trieIndex, sb.Index)
+		s.metrics.catchupRemaining.Set(float64(sb.Index - trieIndex))
 		updates, err := cl.GetUpdateChainLevel(sb.Roster, latest.Hash, 1, catchupFetchBlocks)
 		if err != nil {
 			log.Error("Couldn't update blocks:", err)
@@ -1724,6 +1734,9 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 
 		// If it is a view-change transaction, confirm it's done
 		view := isViewChangeTx(body.TxResults)
+		if view != nil {
+			s.metrics.viewChanges.Inc()
+		}
 
 		if s.viewChangeMan.started(sb.SkipChainID()) && view != nil {
 			s.viewChangeMan.done(*view)
@@ -1755,6 +1768,7 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 
 	// Notify all waiting channels for processed ClientTransactions.
 	s.notifications.informBlock(sb, body.TxResults)
+	s.metrics.countInstructions(body.TxResults)
 
 	// At this point everything should be stored.
 	s.streamingMan.notify(string(sb.SkipChainID()), sb)
@@ -2745,6 +2759,7 @@ func (s *Service) startAllChains() error {
 	s.pollChanMut.Unlock()
 
 	s.skService().RegisterStoreSkipblockCallback(s.updateTrieCallback)
+	s.skService().RegisterPruneLimit(s.pruneLimit)
 
 	// All the logic necessary to start the chains is delayed to a goroutine so that
 	// the other services can start immediately and are not blocked by Byzcoin.
@@ -2894,6 +2909,23 @@ func (s *Service) startChain(genesisID skipchain.SkipBlockID) error {
 	return nil
 }
 
+// pruneLimit returns the index of the first block of the chain that must not
+// be pruned. The block of the trie index is kept, as it is needed to catch
+// up, as well as all the blocks that have not been applied to the trie yet.
+func (s *Service) pruneLimit(scID skipchain.SkipBlockID) int {
+	if !s.hasByzCoinVerification(scID) {
+		return -1
+	}
+	if !s.hasStateTrie(scID) {
+		return 0
+	}
+	st, err := s.getStateTrie(scID)
+	if err != nil {
+		return 0
+	}
+	return st.GetIndex()
+}
+
 // checks that a given chain has a verifier we recognize
 func (s *Service) hasByzCoinVerification(gen skipchain.SkipBlockID) bool {
 	sb := s.db().GetByID(gen)
@@ -3069,6 +3101,7 @@ func newService(c *onet.Context) (onet.Service, error) {
 		// where each block might be 1 MB in size and each tx is 1 KB.
 		txErrorBuf: newRingBuf(2048),
 	}
+	s.metrics = newServiceMetrics(s)
 
 	err := s.RegisterHandlers(
 		s.GetAllByzCoinIDs,
@@ -757,6 +757,17 @@ func (r *txBuffer) take(key string, max int) []ClientTransaction {
 	return ret
 }
 
+// size returns the number of transactions in the buffer, for all the chains.
+func (r *txBuffer) size() (n int) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, txs := range r.txsMap {
+		n += len(txs)
+	}
+	return
+}
+
 func (r *txBuffer) add(key string, newTx ClientTransaction) {
 	r.Lock()
 	defer r.Unlock()
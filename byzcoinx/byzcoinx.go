@@ -13,6 +13,9 @@ import (
 
 	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/blscosi/schnorrproto"
+	"go.dedis.ch/cothority/v3/blscosi/thresholdbls"
+	"go.dedis.ch/cothority/v3/metrics"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/onet/v3"
@@ -57,6 +60,8 @@ type ByzCoinX struct {
 	// verifySignature takes the given signature and verifies it against
 	// the message
 	verifier VerifierFn
+	// start is when the prepare phase started, for the metrics
+	start time.Time
 }
 
 // FinalSignature holds the message Msg and its signature
@@ -86,6 +91,7 @@ func (bft *ByzCoinX) Start() error {
 
 	// prepare phase (part 1)
 	log.Lvl3("Starting prepare phase")
+	bft.start = time.Now()
 	prepProto, err := bft.initCosiProtocol(phasePrep)
 	if err != nil {
 		return err
@@ -197,6 +203,9 @@ func (bft *ByzCoinX) Dispatch() error {
 		return errors.New("Commit signature is wrong")
 	}
 
+	metrics.ForServer(bft.ServerIdentity()).Histogram("byzcoinx_round_seconds",
+		"Duration of the successful ByzCoinX rounds led by the conode", nil,
+		"protocol").Since(bft.start, bft.ProtocolName())
 	bft.FinalSignatureChan <- FinalSignature{bft.Msg, commitSig}
 	return nil
 }
@@ -284,6 +293,68 @@ func makeBdnProtocols(vf, ack protocol.VerificationFn, protoName string, suite *
 	return protocolMap
 }
 
+func makeSchnorrProtocols(vf, ack protocol.VerificationFn, protoName string, suite *pairing.SuiteBn256) map[string]onet.NewProtocol {
+	protocolMap := make(map[string]onet.NewProtocol)
+
+	prepCosiProtoName := protoName + "_cosi_prep"
+	prepCosiSubProtoName := protoName + "_subcosi_prep"
+	commitCosiProtoName := protoName + "_cosi_commit"
+	commitCosiSubProtoName := protoName + "_subcosi_commit"
+
+	verifier := func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point) error {
+		return schnorrproto.SchnorrSignature(sig).Verify(suite, msg, pubkeys)
+	}
+
+	protocolMap[protoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return NewByzCoinX(n, prepCosiProtoName, commitCosiProtoName, suite, verifier)
+	}
+	protocolMap[prepCosiProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return schnorrproto.NewSchnorrCosi(n, vf, prepCosiSubProtoName, suite)
+	}
+	protocolMap[prepCosiSubProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return schnorrproto.NewSubSchnorrCosi(n, vf, suite)
+	}
+	protocolMap[commitCosiProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return schnorrproto.NewSchnorrCosi(n, ack, commitCosiSubProtoName, suite)
+	}
+	protocolMap[commitCosiSubProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return schnorrproto.NewSubSchnorrCosi(n, ack, suite)
+	}
+
+	return protocolMap
+}
+
+func makeThresholdBlsProtocols(vf, ack protocol.VerificationFn, protoName string, suite *pairing.SuiteBn256) map[string]onet.NewProtocol {
+	protocolMap := make(map[string]onet.NewProtocol)
+
+	prepCosiProtoName := protoName + "_cosi_prep"
+	prepCosiSubProtoName := protoName + "_subcosi_prep"
+	commitCosiProtoName := protoName + "_cosi_commit"
+	commitCosiSubProtoName := protoName + "_subcosi_commit"
+
+	verifier := func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point) error {
+		return thresholdbls.GroupSignature(sig).Verify(suite, msg, pubkeys)
+	}
+
+	protocolMap[protoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return NewByzCoinX(n, prepCosiProtoName, commitCosiProtoName, suite, verifier)
+	}
+	protocolMap[prepCosiProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return thresholdbls.NewCosi(n, vf, prepCosiSubProtoName, suite)
+	}
+	protocolMap[prepCosiSubProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return thresholdbls.NewSubCosi(n, vf, suite)
+	}
+	protocolMap[commitCosiProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return thresholdbls.NewCosi(n, ack, commitCosiSubProtoName, suite)
+	}
+	protocolMap[commitCosiSubProtoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return thresholdbls.NewSubCosi(n, ack, suite)
+	}
+
+	return protocolMap
+}
+
 // GlobalInitBFTCoSiProtocol creates and registers the protocols required to run
 // BFTCoSi globally.
 func GlobalInitBFTCoSiProtocol(suite *pairing.SuiteBn256, vf, ack protocol.VerificationFn, protoName string) error {
@@ -332,6 +403,32 @@ func InitBDNCoSiProtocol(suite *pairing.SuiteBn256, c *onet.Context, vf, ack pro
 	return nil
 }
 
+// InitSchnorrCoSiProtocol creates and registers the protocols required to run
+// BFTCoSi to the context c over the Schnorr multi-signature scheme
+func InitSchnorrCoSiProtocol(suite *pairing.SuiteBn256, c *onet.Context, vf, ack protocol.VerificationFn, protoName string) error {
+	protocolMap := makeSchnorrProtocols(vf, ack, protoName, suite)
+	for protoName, proto := range protocolMap {
+		if _, err := c.ProtocolRegister(protoName, proto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InitThresholdBlsCoSiProtocol creates and registers the protocols required
+// to run BFTCoSi to the context c over the threshold BLS signature scheme of
+// the thresholdbls service. The root must prepare the group of the roster
+// with the service before starting the protocol.
+func InitThresholdBlsCoSiProtocol(suite *pairing.SuiteBn256, c *onet.Context, vf, ack protocol.VerificationFn, protoName string) error {
+	protocolMap := makeThresholdBlsProtocols(vf, ack, protoName, suite)
+	for protoName, proto := range protocolMap {
+		if _, err := c.ProtocolRegister(protoName, proto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // FaultThreshold computes the number of faults that byzcoinx tolerates.
 func FaultThreshold(n int) int {
 	return protocol.DefaultFaultyThreshold(n)
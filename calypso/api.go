@@ -93,6 +93,87 @@ func (c *Client) CreateLTS(ltsRoster *onet.Roster, darcID darc.ID, signers []dar
 	return reply, nil
 }
 
+// ReshareLTS moves the long-term secret of the LTS instance ltsID to
+// newRoster. It first sends a transaction invoking "reshare" on the LTS
+// instance, signed by signers, then asks a node present in both the old and
+// the new roster to run the resharing protocol. The aggregate public key X
+// must stay the same, else an error is returned.
+func (c *Client) ReshareLTS(newRoster *onet.Roster, ltsID byzcoin.InstanceID,
+	signers []darc.Signer, counters []uint64) (reply *ReshareLTSReply, err error) {
+	// Get the current roster of the LTS, so we can find a node that already
+	// holds a share and can start the resharing.
+	pr, err := c.bcClient.GetProof(ltsID.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("getting LTS proof: %v", err)
+	}
+	var oldInfo LtsInstanceInfo
+	if err := pr.Proof.VerifyAndDecode(cothority.Suite,
+		ContractLongTermSecretID, &oldInfo); err != nil {
+		return nil, xerrors.Errorf("decoding LTS instance: %v", err)
+	}
+	var root *network.ServerIdentity
+	for _, si := range newRoster.List {
+		if i, _ := oldInfo.Roster.Search(si.ID); i >= 0 {
+			root = si
+			break
+		}
+	}
+	if root == nil {
+		return nil, xerrors.New("new roster has no node in common with the current LTS roster")
+	}
+	oldReply := &CreateLTSReply{}
+	err = c.c.SendProtobuf(root, &GetLTSReply{LTSID: ltsID}, oldReply)
+	if err != nil {
+		return nil, xerrors.Errorf("getting current LTS: %v", err)
+	}
+
+	// Update the roster stored in the LTS instance and get its proof
+	buf, err := protobuf.Encode(&LtsInstanceInfo{*newRoster})
+	if err != nil {
+		return nil, xerrors.Errorf("encoding roster: %v", err)
+	}
+	inst := byzcoin.Instruction{
+		InstanceID: ltsID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractLongTermSecretID,
+			Command:    "reshare",
+			Args: []byzcoin.Argument{
+				{
+					Name:  "lts_instance_info",
+					Value: buf,
+				},
+			},
+		},
+		SignerCounter: counters,
+	}
+	tx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion, inst)
+	if err := tx.FillSignersAndSignWith(signers...); err != nil {
+		return nil, xerrors.Errorf("signing txn: %v", err)
+	}
+
+	atr, err := c.bcClient.AddTransactionAndWait(tx, 10)
+	if err != nil {
+		return nil, xerrors.Errorf("adding transaction: %v", err)
+	}
+	resp, err := c.bcClient.GetProofAfter(ltsID.Slice(), true, &atr.Proof.Latest)
+	if err != nil {
+		return nil, xerrors.Errorf("getting txn proof: %v", err)
+	}
+
+	// Start the resharing
+	reply = &ReshareLTSReply{}
+	err = c.c.SendProtobuf(root, &ReshareLTS{
+		Proof: resp.Proof,
+	}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("send ReshareLTS message: %v", err)
+	}
+	if reply.X == nil || !reply.X.Equal(oldReply.X) {
+		return nil, xerrors.New("public key of the LTS changed during resharing")
+	}
+	return reply, nil
+}
+
 // Authorise adds a ByzCoinID to the list of authorized IDs. It can only be called
 // from localhost, except if the COTHORITY_ALLOW_INSECURE_ADMIN is set to 'true'.
 // Deprecated: please use Authorize.
@@ -141,6 +222,41 @@ func (c *Client) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error)
 	return reply, cothority.ErrorOrNil(err, "sending DecryptKey message")
 }
 
+// DecryptKeys is the batch version of DecryptKey. All the read/write pairs
+// are verified and re-encrypted in one request. The replies are returned in
+// the same order as the requests.
+func (c *Client) DecryptKeys(dkrs []DecryptKey) (replies []DecryptKeyReply, err error) {
+	reply := &DecryptKeysReply{}
+	err = c.c.SendProtobuf(c.bcClient.Roster.List[0], &DecryptKeys{Requests: dkrs}, reply)
+	if err != nil {
+		return nil, xerrors.Errorf("sending DecryptKeys message: %v", err)
+	}
+	if len(reply.Replies) != len(dkrs) {
+		return nil, xerrors.New("got wrong number of replies")
+	}
+	return reply.Replies, nil
+}
+
+// GetPostQuantumKeys returns the ML-KEM-768 keys of the nodes of the LTS
+// for NewWritePostQuantum, in the order of the roster. The roster must be the
+// one stored in the LTS instance, as every key is verified against the
+// calypso key of its node.
+func (c *Client) GetPostQuantumKeys(ltsid byzcoin.InstanceID, roster *onet.Roster) ([][]byte, error) {
+	keys := make([][]byte, len(roster.List))
+	for i, si := range roster.List {
+		reply := &GetPostQuantumKeyReply{}
+		err := c.c.SendProtobuf(si, &GetPostQuantumKey{LTSID: ltsid}, reply)
+		if err != nil {
+			return nil, xerrors.Errorf("getting key of %v: %v", si, err)
+		}
+		if err := verifyPostQuantumKey(ltsid, si, reply); err != nil {
+			return nil, xerrors.Errorf("invalid key of %v: %v", si, err)
+		}
+		keys[i] = reply.Key
+	}
+	return keys, nil
+}
+
 // WaitProof calls the byzcoin client's wait proof
 func (c *Client) WaitProof(id byzcoin.InstanceID, interval time.Duration,
 	value []byte) (*byzcoin.Proof, error) {
@@ -202,11 +318,25 @@ func (c *Client) AddWrite(write *Write, signer darc.Signer, signerCtr uint64,
 //   - reply - ReadReply containing the transaction response and instance id
 //   - err - Error if any, nil otherwise.
 func (c *Client) AddRead(proof *byzcoin.Proof, signer darc.Signer, signerCtr uint64, wait int) (
+	reply *ReadReply, err error) {
+	return c.addRead(proof, signer, signerCtr, nil, wait)
+}
+
+// AddReadPostQuantum is like AddRead, but also stores the ML-KEM-768
+// encapsulation key ek of the reader, as returned by
+// GeneratePostQuantumKey. It must be used to read a hybrid write.
+func (c *Client) AddReadPostQuantum(proof *byzcoin.Proof, signer darc.Signer, signerCtr uint64, ek []byte, wait int) (
+	reply *ReadReply, err error) {
+	return c.addRead(proof, signer, signerCtr, ek, wait)
+}
+
+func (c *Client) addRead(proof *byzcoin.Proof, signer darc.Signer, signerCtr uint64, ek []byte, wait int) (
 	reply *ReadReply, err error) {
 	var readBuf []byte
 	read := &Read{
-		Write: byzcoin.NewInstanceID(proof.InclusionProof.Key()),
-		Xc:    signer.Ed25519.Point,
+		Write:          byzcoin.NewInstanceID(proof.InclusionProof.Key()),
+		Xc:             signer.Ed25519.Point,
+		PostQuantumKey: ek,
 	}
 	reply = &ReadReply{}
 	readBuf, err = protobuf.Encode(read)
@@ -291,6 +421,15 @@ func (c *Client) SpawnDarc(signer darc.Signer, signerCtr uint64,
 //   - key - the re-assembled key
 //   - err - a possible error when trying to recover the data from the point
 func (r *DecryptKeyReply) RecoverKey(xc kyber.Scalar) (key []byte, err error) {
+	if len(r.PostQuantum) > 0 {
+		return nil, xerrors.New("hybrid write: use RecoverKeyPostQuantum")
+	}
+	return r.recoverKey(xc)
+}
+
+// recoverKey returns the key embedded in C, which is masked for a hybrid
+// write.
+func (r *DecryptKeyReply) recoverKey(xc kyber.Scalar) (key []byte, err error) {
 	xcInv := xc.Clone().Neg(xc)
 	XhatDec := r.X.Clone().Mul(xcInv, r.X)
 	Xhat := XhatDec.Clone().Add(r.XhatEnc, XhatDec)
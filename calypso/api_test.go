@@ -98,7 +98,43 @@ func TestClient_Authorize(t *testing.T) {
 	require.NoError(t, err)
 }
 
-// TODO(jallen): Write TestClient_Reshare (and add api.go part too, I guess)
+// Tests the client function ReshareLTS by adding one node to the LTS roster.
+func TestClient_ReshareLTS(t *testing.T) {
+	l := onet.NewTCPTest(cothority.Suite)
+	_, roster, _ := l.GenTree(5, true)
+	defer l.CloseAll()
+	bcRoster := onet.NewRoster(roster.List[:4])
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, bcRoster,
+		[]string{"spawn:" + ContractLongTermSecretID,
+			"invoke:" + ContractLongTermSecretID + ".reshare"},
+		signer.Identity())
+	require.NoError(t, err)
+	msg.BlockInterval = 500 * time.Millisecond
+	d := msg.GenesisDarc
+
+	c, _, err := byzcoin.NewLedger(msg, false)
+	require.NoError(t, err)
+	calypsoClient := NewClient(c)
+	for _, who := range roster.List {
+		require.NoError(t, calypsoClient.Authorize(who, c.ID))
+	}
+
+	ltsReply, err := calypsoClient.CreateLTS(bcRoster, d.GetBaseID(),
+		[]darc.Signer{signer}, []uint64{1})
+	require.NoError(t, err)
+
+	// A disjoint roster must be refused.
+	_, err = calypsoClient.ReshareLTS(onet.NewRoster(roster.List[4:]),
+		ltsReply.InstanceID, []darc.Signer{signer}, []uint64{2})
+	require.Error(t, err)
+
+	reply, err := calypsoClient.ReshareLTS(roster, ltsReply.InstanceID,
+		[]darc.Signer{signer}, []uint64{2})
+	require.NoError(t, err)
+	require.True(t, reply.X.Equal(ltsReply.X))
+}
 
 // Tests the client api's AddRead, AddWrite, DecryptKey
 func TestClient_Calypso(t *testing.T) {
@@ -215,5 +251,20 @@ func TestClient_Calypso(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, key1, keyCopy1)
 
+	// Decrypt both keys in one batch
+	_, err = calypsoClient.DecryptKeys([]DecryptKey{
+		{Read: *prRe1, Write: *prWr1}, {Read: *prRe1, Write: *prWr2}})
+	require.Error(t, err)
+	dks, err := calypsoClient.DecryptKeys([]DecryptKey{
+		{Read: *prRe1, Write: *prWr1}, {Read: *prRe2, Write: *prWr2}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(dks))
+	keyCopy1, err = dks[0].RecoverKey(reader1.Ed25519.Secret)
+	require.NoError(t, err)
+	require.Equal(t, key1, keyCopy1)
+	keyCopy2, err := dks[1].RecoverKey(reader2.Ed25519.Secret)
+	require.NoError(t, err)
+	require.Equal(t, key2, keyCopy2)
+
 	// use keyCopy to unlock the stuff in writeInstance.Data
 }
@@ -1,6 +1,8 @@
 package calypso
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
@@ -36,6 +38,14 @@ func (w Write) String() string {
 	fmt.Fprintf(out, "-- ExtraData: %s\n", w.ExtraData)
 	fmt.Fprintf(out, "-- LTSID: %s\n", w.LTSID)
 	fmt.Fprintf(out, "-- Cost: %x\n", w.Cost)
+	if w.Policy != nil {
+		fmt.Fprintf(out, "-- Policy:\n")
+		fmt.Fprintf(out, "--- MaxReads: %d\n", w.Policy.MaxReads)
+		fmt.Fprintf(out, "--- NotBefore: %d\n", w.Policy.NotBefore)
+		fmt.Fprintf(out, "--- Credential: %s/%s %x\n", w.Policy.CredentialName,
+			w.Policy.CredentialAttribute, w.Policy.CredentialValue)
+		fmt.Fprintf(out, "-- Reads: %d\n", w.Reads)
+	}
 
 	return out.String()
 }
@@ -79,6 +89,10 @@ func (c ContractWrite) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruc
 			err = xerrors.Errorf("proof of write failed: %v", err)
 			return
 		}
+		if c.Write.Reads != 0 {
+			err = xerrors.New("a new write cannot have reads")
+			return
+		}
 		instID, err := inst.DeriveIDArg("", "preID")
 		if err != nil {
 			return nil, nil, xerrors.Errorf(
@@ -99,6 +113,9 @@ func (c ContractWrite) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruc
 		if !rd.Write.Equal(inst.InstanceID) {
 			return nil, nil, xerrors.New("the read request doesn't reference this write-instance")
 		}
+		if c.PostQuantum != nil && len(rd.PostQuantumKey) == 0 {
+			return nil, nil, xerrors.New("a hybrid write needs a reader with a post-quantum key")
+		}
 		if c.Cost.Value > 0 {
 			for i, coin := range cout {
 				if coin.Name.Equal(c.Cost.Name) {
@@ -118,6 +135,17 @@ func (c ContractWrite) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruc
 		}
 		sc = byzcoin.StateChanges{byzcoin.NewStateChange(byzcoin.Create,
 			instID, ContractReadID, r, darcID)}
+		if c.Policy != nil {
+			// The policy has been checked in VerifyInstruction, only
+			// the number of reads needs to be updated.
+			c.Reads++
+			writeBuf, err := protobuf.Encode(&c.Write)
+			if err != nil {
+				return nil, nil, xerrors.Errorf("encoding write: %v", err)
+			}
+			sc = append(sc, byzcoin.NewStateChange(byzcoin.Update,
+				inst.InstanceID, ContractWriteID, writeBuf, darcID))
+		}
 	default:
 		err = xerrors.New("can only spawn writes and reads")
 	}
@@ -270,6 +298,12 @@ func intersectRosters(r1, r2 *onet.Roster) int {
 func (c ContractWrite) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
 	if inst.GetType() == byzcoin.SpawnType && inst.Spawn.ContractID == ContractReadID {
 
+		if c.Policy != nil {
+			if err := c.Policy.verify(rst, inst, ctxHash, c.Reads); err != nil {
+				return xerrors.Errorf("read policy: %v", err)
+			}
+		}
+
 		evalAttr := darc.AttrInterpreters{}
 		for _, makeAttrInterpreterWrapper := range readMakeAttrInterpreter {
 			evalAttr[makeAttrInterpreterWrapper.name] = makeAttrInterpreterWrapper.interpreter(c, rst, inst)
@@ -278,3 +312,83 @@ func (c ContractWrite) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byz
 	}
 	return inst.VerifyWithOption(rst, ctxHash, nil)
 }
+
+// verify checks that the read-request in inst fulfills the policy, given
+// the number of reads already spawned.
+func (p ReadPolicy) verify(rst byzcoin.ReadOnlyStateTrie,
+	inst byzcoin.Instruction, ctxHash []byte, reads uint64) error {
+	if p.MaxReads > 0 && reads >= p.MaxReads {
+		return xerrors.Errorf("maximum of %d reads reached", p.MaxReads)
+	}
+
+	if p.NotBefore > 0 {
+		tr, ok := rst.(byzcoin.TimeReader)
+		if !ok {
+			return xerrors.New("internal error: cannot convert " +
+				"ReadOnlyStateTrie to TimeReader")
+		}
+		if tr.GetCurrentBlockTimestamp() < p.NotBefore {
+			return xerrors.New("reading is not allowed yet")
+		}
+	}
+
+	if p.CredentialAttribute != "" {
+		if credentialAttribute == nil {
+			return xerrors.New("credentials are not supported by this node")
+		}
+		credID := inst.Spawn.Args.Search("credentialID")
+		if len(credID) != len(byzcoin.InstanceID{}) {
+			return xerrors.New("need a 'credentialID' argument")
+		}
+		value, err := credentialAttribute(rst, byzcoin.NewInstanceID(credID),
+			p.CredentialName, p.CredentialAttribute)
+		if err != nil {
+			return xerrors.Errorf("getting attribute: %v", err)
+		}
+		if p.CredentialValue != nil && !bytes.Equal(value, p.CredentialValue) {
+			return xerrors.New("attribute has a wrong value")
+		}
+		if err := verifyCredentialOwner(rst, credID, inst, ctxHash); err != nil {
+			return xerrors.Errorf("reader doesn't hold credential: %v", err)
+		}
+	}
+	return nil
+}
+
+// verifyCredentialOwner checks that the signers of the instruction are
+// allowed to sign for the darc controlling the credential instance.
+func verifyCredentialOwner(rst byzcoin.ReadOnlyStateTrie, credID []byte,
+	inst byzcoin.Instruction, ctxHash []byte) error {
+	_, _, _, darcID, err := rst.GetValues(credID)
+	if err != nil {
+		return xerrors.Errorf("getting credential: %v", err)
+	}
+	d, err := rst.LoadDarc(darcID)
+	if err != nil {
+		return xerrors.Errorf("getting darc of credential: %v", err)
+	}
+
+	var ids []string
+	for i := range inst.Signatures {
+		if i < len(inst.SignerIdentities) &&
+			inst.SignerIdentities[i].Verify(ctxHash, inst.Signatures[i]) == nil {
+			ids = append(ids, inst.SignerIdentities[i].String())
+		}
+	}
+	getDarc := func(str string, latest bool) *darc.Darc {
+		if len(str) < 5 || string(str[0:5]) != "darc:" {
+			return nil
+		}
+		darcID, err := hex.DecodeString(str[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := rst.LoadDarc(darcID)
+		if err != nil {
+			return nil
+		}
+		return d
+	}
+	return cothority.ErrorOrNil(darc.EvalExpr(d.Rules.GetSignExpr(), getDarc,
+		ids...), "evaluating darc")
+}
@@ -1,8 +1,13 @@
 package calypso
 
 import (
+	"errors"
+
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 	"testing"
 )
@@ -48,3 +53,120 @@ func TestContractWrite_Invoke(t *testing.T) {
 	require.NoError(t, protobuf.Decode(scs[0].Value, &cwNew))
 	require.Equal(t, []byte("newExtraData"), cwNew.ExtraData)
 }
+
+func TestContractWrite_ReadPolicy(t *testing.T) {
+	rost := byzcoin.NewROSTSimul()
+
+	cw := ContractWrite{
+		Write: Write{
+			Data:   []byte("data"),
+			Policy: &ReadPolicy{MaxReads: 1},
+		},
+	}
+	cwID, err := rost.CreateRandomInstance(ContractWriteID, &cw.Write, nil)
+	require.NoError(t, err)
+	reader := darc.NewSignerEd25519(nil, nil)
+	readBuf, err := protobuf.Encode(&Read{Write: cwID, Xc: reader.Ed25519.Point})
+	require.NoError(t, err)
+	instr := byzcoin.Instruction{
+		InstanceID: cwID,
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractReadID,
+			Args:       byzcoin.Arguments{{Name: "read", Value: readBuf}},
+		}}
+
+	// The first read is allowed and counted.
+	require.NoError(t, cw.Policy.verify(rost, instr, nil, cw.Reads))
+	scs, _, err := cw.Spawn(rost, instr, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(scs))
+	require.Equal(t, byzcoin.Update, scs[1].StateAction)
+	var wNew Write
+	require.NoError(t, protobuf.DecodeWithConstructors(scs[1].Value, &wNew,
+		network.DefaultConstructors(cothority.Suite)))
+	require.Equal(t, uint64(1), wNew.Reads)
+
+	// The second read is refused.
+	require.Error(t, wNew.Policy.verify(rost, instr, nil, wNew.Reads))
+
+	// Time- and credential-policies cannot be fulfilled here.
+	require.Error(t, ReadPolicy{NotBefore: 1}.verify(rost, instr, nil, 0))
+	require.Error(t, ReadPolicy{CredentialName: "personhood",
+		CredentialAttribute: "ed25519"}.verify(rost, instr, nil, 0))
+
+	// A reader holding the attribute gets the read spawned, and it is
+	// counted.
+	defer func(f CredentialAttributeFunc) {
+		credentialAttribute = f
+	}(credentialAttribute)
+	pub, err := reader.Ed25519.Point.MarshalBinary()
+	require.NoError(t, err)
+	credentialAttribute = func(rst byzcoin.ReadOnlyStateTrie,
+		credID byzcoin.InstanceID, cred, attr string) ([]byte, error) {
+		_, _, cid, _, err := rst.GetValues(credID.Slice())
+		if err != nil {
+			return nil, err
+		}
+		if cid != "credential" || cred != "personhood" || attr != "ed25519" {
+			return nil, errors.New("no such attribute")
+		}
+		return pub, nil
+	}
+	readerID := reader.Identity()
+	credDarc, err := rost.CreateBasicDarc(&readerID, "credential")
+	require.NoError(t, err)
+	credID, err := rost.CreateRandomInstance("credential", &Read{},
+		credDarc.GetBaseID())
+	require.NoError(t, err)
+
+	cw.Write.Policy = &ReadPolicy{MaxReads: 2, CredentialName: "personhood",
+		CredentialAttribute: "ed25519", CredentialValue: pub}
+	cw.Write.Reads = 0
+	cwID, err = rost.CreateRandomInstance(ContractWriteID, &cw.Write, nil)
+	require.NoError(t, err)
+	newInstr := func(signer darc.Signer) (byzcoin.Instruction, []byte) {
+		readBuf, err := protobuf.Encode(&Read{Write: cwID, Xc: reader.Ed25519.Point})
+		require.NoError(t, err)
+		inst := byzcoin.Instruction{
+			InstanceID: cwID,
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractReadID,
+				Args: byzcoin.Arguments{
+					{Name: "read", Value: readBuf},
+					{Name: "credentialID", Value: credID.Slice()},
+				},
+			},
+			SignerIdentities: []darc.Identity{signer.Identity()},
+			SignerCounter:    []uint64{1},
+		}
+		ctxHash := inst.Hash()
+		sig, err := signer.Sign(ctxHash)
+		require.NoError(t, err)
+		inst.Signatures = [][]byte{sig}
+		return inst, ctxHash
+	}
+	instr, ctxHash := newInstr(reader)
+	require.NoError(t, cw.Policy.verify(rost, instr, ctxHash, cw.Reads))
+	scs, _, err = cw.Spawn(rost, instr, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(scs))
+	require.Equal(t, byzcoin.Create, scs[0].StateAction)
+	require.Equal(t, ContractReadID, scs[0].ContractID)
+	_, err = rost.StoreAllToReplica(scs)
+	require.NoError(t, err)
+	wBuf, _, _, _, err := rost.GetValues(cwID.Slice())
+	require.NoError(t, err)
+	var wRead Write
+	require.NoError(t, protobuf.DecodeWithConstructors(wBuf, &wRead,
+		network.DefaultConstructors(cothority.Suite)))
+	require.Equal(t, uint64(1), wRead.Reads)
+
+	// Other signers and other values of the attribute are refused.
+	instr, ctxHash = newInstr(darc.NewSignerEd25519(nil, nil))
+	require.Error(t, wRead.Policy.verify(rost, instr, ctxHash, wRead.Reads))
+	instr, ctxHash = newInstr(reader)
+	wrongValue := *wRead.Policy
+	wrongValue.CredentialValue = []byte("other")
+	require.Error(t, wrongValue.verify(rost, instr, ctxHash, wRead.Reads))
+	require.NoError(t, wRead.Policy.verify(rost, instr, ctxHash, wRead.Reads))
+}
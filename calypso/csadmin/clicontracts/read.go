@@ -25,6 +25,8 @@ import (
 // specified by the --instid argument. By default, the function uses the public
 // key of the signer to encrypt the requested data. However, a different public
 // key can be given a an hexadecimal string representation with --key.
+// If the write instance has a read policy requiring a credential, the
+// credential instance is given with --credentialID.
 // With the --export option, the instance id is sent to STDOUT.
 func ReadSpawn(c *cli.Context) error {
 	bcArg := c.String("bc")
@@ -118,6 +120,19 @@ func ReadSpawn(c *cli.Context) error {
 		return xerrors.New("failed to decode the projectInstID string")
 	}
 
+	args := byzcoin.Arguments{
+		{Name: "read", Value: readBuf},
+		{Name: "projectInstID", Value: projectInstIDBuff},
+	}
+	if credID := c.String("credentialID"); credID != "" {
+		credIDBuf, err := hex.DecodeString(credID)
+		if err != nil {
+			return xerrors.Errorf("failed to decode the credentialID: %v", err)
+		}
+		args = append(args, byzcoin.Argument{Name: "credentialID",
+			Value: credIDBuf})
+	}
+
 	counters, err := cl.GetSignerCounters(signer.Identity().String())
 	if err != nil {
 		return xerrors.Errorf("failed to get the signer counters: %v", err)
@@ -128,10 +143,7 @@ func ReadSpawn(c *cli.Context) error {
 			InstanceID: byzcoin.NewInstanceID(proof.InclusionProof.Key()),
 			Spawn: &byzcoin.Spawn{
 				ContractID: calypso.ContractReadID,
-				Args: byzcoin.Arguments{
-					{Name: "read", Value: readBuf},
-					{Name: "projectInstID", Value: projectInstIDBuff},
-				},
+				Args:       args,
 			},
 			SignerCounter: []uint64{counters.Counters[0] + 1},
 		},
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"go.dedis.ch/onet/v3/log"
 	"golang.org/x/xerrors"
@@ -121,6 +122,10 @@ func WriteSpawn(c *cli.Context) error {
 	}
 	write.Data = dataBuf
 	write.ExtraData = extraDataBuf
+	write.Policy, err = readPolicy(c)
+	if err != nil {
+		return xerrors.Errorf("failed to parse the read policy: %v", err)
+	}
 	writeBuf, err := protobuf.Encode(write)
 	if err != nil {
 		return xerrors.Errorf("failed to encode Write struct: %v", err)
@@ -187,6 +192,36 @@ func WriteSpawn(c *cli.Context) error {
 	return nil
 }
 
+// readPolicy returns the read policy given by the flags, or nil if no
+// restriction is given.
+func readPolicy(c *cli.Context) (*calypso.ReadPolicy, error) {
+	p := &calypso.ReadPolicy{
+		MaxReads:            c.Uint64("maxReads"),
+		CredentialName:      c.String("credential"),
+		CredentialAttribute: c.String("attribute"),
+	}
+	if nb := c.String("notBefore"); nb != "" {
+		t, err := time.Parse(time.RFC3339, nb)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to parse --notBefore: %v", err)
+		}
+		p.NotBefore = t.UnixNano()
+	}
+	if v := c.String("attributeValue"); v != "" {
+		if p.CredentialAttribute == "" {
+			return nil, xerrors.New("--attributeValue needs --attribute")
+		}
+		p.CredentialValue = []byte(v)
+	}
+	if (p.CredentialName == "") != (p.CredentialAttribute == "") {
+		return nil, xerrors.New("--credential and --attribute must be given together")
+	}
+	if p.MaxReads == 0 && p.NotBefore == 0 && p.CredentialAttribute == "" {
+		return nil, nil
+	}
+	return p, nil
+}
+
 // WriteGet checks the proof and prints the content of the Write contract.
 func WriteGet(c *cli.Context) error {
 
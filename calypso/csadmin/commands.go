@@ -55,6 +55,30 @@ var cmds = cli.Commands{
 			},
 		},
 	},
+	{
+		Name:   "reshare",
+		Usage:  "reshare the long-term secret of an LTS instance to a new roster",
+		Action: reshare,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "bc",
+				EnvVar: "BC",
+				Usage:  "the ByzCoin config to use (required)",
+			},
+			cli.StringFlag{
+				Name:  "instid, i",
+				Usage: "the instance id of the LTS contract (required)",
+			},
+			cli.StringFlag{
+				Name:  "roster",
+				Usage: "the path of the file holding the new roster (required)",
+			},
+			cli.StringFlag{
+				Name:  "sign, s",
+				Usage: "public key of the signing entity (default is the admin)",
+			},
+		},
+	},
 	{
 		Name:   "reencrypt",
 		Usage:  "decrypt and reencrypt the secret of a write instance given the proofs of write and read instances",
@@ -99,6 +123,79 @@ var cmds = cli.Commands{
 			},
 		},
 	},
+	{
+		Name:  "file",
+		Usage: "encrypts files of any size and stores them off-chain",
+		Subcommands: cli.Commands{
+			{
+				Name:      "put",
+				Usage:     "encrypt a file to the store and spawn a write instance with its key",
+				ArgsUsage: "file",
+				Action:    filePut,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "darc",
+						Usage: "DARC with the right to create a Write instance (default is the admin DARC)",
+					},
+					cli.StringFlag{
+						Name:  "sign, s",
+						Usage: "public key of the signing entity (default is the admin)",
+					},
+					cli.StringFlag{
+						Name:  "instid, i",
+						Usage: "the instance id of the spawned LTS contract",
+					},
+					cli.StringFlag{
+						Name:  "key",
+						Usage: "hexadecimal LTS public key",
+					},
+					cli.StringFlag{
+						Name:  "store",
+						Value: "calypso-files",
+						Usage: "directory holding the encrypted files",
+					},
+				},
+			},
+			{
+				Name:   "get",
+				Usage:  "reencrypt the key of a write instance and decrypt its file from the store",
+				Action: fileGet,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "bc",
+						EnvVar: "BC",
+						Usage:  "the ByzCoin config to use (required)",
+					},
+					cli.StringFlag{
+						Name:  "writeid, w",
+						Usage: "instance id of the write instance",
+					},
+					cli.StringFlag{
+						Name:  "readid, r",
+						Usage: "instance id of the read instance",
+					},
+					cli.StringFlag{
+						Name:  "key",
+						Usage: "path to the private.toml file (default is admin key)",
+					},
+					cli.StringFlag{
+						Name:  "store",
+						Value: "calypso-files",
+						Usage: "directory holding the encrypted files",
+					},
+					cli.StringFlag{
+						Name:  "out, o",
+						Usage: "file to write the decrypted data to (default is STDOUT)",
+					},
+				},
+			},
+		},
+	},
 	{
 		Name:  "contract",
 		Usage: "Provides cli interface for contracts",
@@ -188,6 +285,26 @@ var cmds = cli.Commands{
 								Name:  "key",
 								Usage: "hexadecimal LTS public key",
 							},
+							cli.Uint64Flag{
+								Name:  "maxReads",
+								Usage: "read policy: maximum number of read instances (optional)",
+							},
+							cli.StringFlag{
+								Name:  "notBefore",
+								Usage: "read policy: RFC3339 time before which no read instance can be spawned (optional)",
+							},
+							cli.StringFlag{
+								Name:  "credential",
+								Usage: "read policy: name of the credential the reader must hold (optional)",
+							},
+							cli.StringFlag{
+								Name:  "attribute",
+								Usage: "read policy: attribute the credential of the reader must have, used with --credential",
+							},
+							cli.StringFlag{
+								Name:  "attributeValue",
+								Usage: "read policy: value the attribute of the reader must have (optional)",
+							},
 							cli.BoolFlag{
 								Name:  "export, x",
 								Usage: "export the instance id to STDOUT",
@@ -246,6 +363,10 @@ var cmds = cli.Commands{
 								Name:  "export, x",
 								Usage: "export the instance id to STDOUT",
 							},
+							cli.StringFlag{
+								Name:  "credentialID",
+								Usage: "the instance id of the credential of the reader, if required by the read policy (optional)",
+							},
 							cli.StringFlag{
 								Name:  "projectInstID, pid",
 								Usage: "The project instance ID, which contains the metadata for verification (optional). This option is not directly used in the contract, it is only useful during the verification process in the case you registered a custom makeAttrInterpreter.",
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/calypso/file"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"golang.org/x/xerrors"
+)
+
+// filePut encrypts a file, stores the ciphertext in the directory given by
+// --store, and spawns a write instance holding the key of the file and the
+// hash of the ciphertext.
+func filePut(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return xerrors.New("please give the file to store")
+	}
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return xerrors.Errorf("failed to load config: %v", err)
+	}
+
+	dstr := c.String("darc")
+	if dstr == "" {
+		dstr = cfg.AdminDarc.GetIdentityString()
+	}
+	d, err := lib.GetDarcByString(cl, dstr)
+	if err != nil {
+		return xerrors.Errorf("failed to get darc by string: %v", err)
+	}
+
+	var signer *darc.Signer
+	sstr := c.String("sign")
+	if sstr == "" {
+		signer, err = lib.LoadKey(cfg.AdminIdentity)
+	} else {
+		signer, err = lib.LoadKeyFromString(sstr)
+	}
+	if err != nil {
+		return xerrors.Errorf("failed to parse the signer: %v", err)
+	}
+
+	instid, err := hex.DecodeString(c.String("instid"))
+	if err != nil || len(instid) == 0 {
+		return xerrors.New("please provide the LTS instance ID with --instid")
+	}
+	keyBuf, err := hex.DecodeString(c.String("key"))
+	if err != nil || len(keyBuf) == 0 {
+		return xerrors.New("please provide the hex string public key with --key")
+	}
+	X := cothority.Suite.Point()
+	err = X.UnmarshalBinary(keyBuf)
+	if err != nil {
+		return xerrors.Errorf("failed to unmarshal key: %v", err)
+	}
+
+	store, err := file.NewDirStore(c.String("store"))
+	if err != nil {
+		return xerrors.Errorf("failed to open store: %v", err)
+	}
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+
+	write, err := file.Put(cothority.Suite, store, byzcoin.NewInstanceID(instid),
+		d.GetBaseID(), X, filepath.Base(f.Name()), f)
+	if err != nil {
+		return xerrors.Errorf("failed to store file: %v", err)
+	}
+
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return xerrors.Errorf("failed to get the signer counters: %v", err)
+	}
+	reply, err := calypso.NewClient(cl).AddWrite(write, *signer,
+		counters.Counters[0]+1, *d, 10)
+	if err != nil {
+		return xerrors.Errorf("failed to spawn write instance: %v", err)
+	}
+
+	err = lib.WaitPropagation(c, cl)
+	if err != nil {
+		return xerrors.Errorf("waiting for block propagation: %v", err)
+	}
+
+	log.Infof("Stored file in a new write instance. "+
+		"Its instance id is:\n%x", reply.InstanceID.Slice())
+	return nil
+}
+
+// fileGet re-encrypts the key of a file stored with filePut to the reader,
+// recovers it, and decrypts the file from the directory given by --store.
+func fileGet(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return xerrors.Errorf("failed to load config: %v", err)
+	}
+
+	getProof := func(iidArg string) (*byzcoin.Proof, error) {
+		iid, err := hex.DecodeString(c.String(iidArg))
+		if err != nil || len(iid) == 0 {
+			return nil, xerrors.Errorf("please provide the instance id "+
+				"with --%s", iidArg)
+		}
+		pr, err := cl.GetProofFromLatest(iid)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't get proof: %v", err)
+		}
+		if !pr.Proof.InclusionProof.Match(iid) {
+			return nil, xerrors.New("proof does not match")
+		}
+		return &pr.Proof, nil
+	}
+	writeProof, err := getProof("writeid")
+	if err != nil {
+		return xerrors.Errorf("failed to get write proof: %v", err)
+	}
+	readProof, err := getProof("readid")
+	if err != nil {
+		return xerrors.Errorf("failed to get read proof: %v", err)
+	}
+	var write calypso.Write
+	err = writeProof.VerifyAndDecode(cothority.Suite, calypso.ContractWriteID,
+		&write)
+	if err != nil {
+		return xerrors.Errorf("didn't get a write instance: %v", err)
+	}
+
+	keyPath := c.String("key")
+	var signer *darc.Signer
+	if keyPath == "" {
+		signer, err = lib.LoadKey(cfg.AdminIdentity)
+	} else {
+		signer, err = lib.LoadSigner(keyPath)
+	}
+	if err != nil {
+		return xerrors.Errorf("failed to load key file: %v", err)
+	}
+	xc, err := signer.GetPrivate()
+	if err != nil {
+		return xerrors.Errorf("failed to get private key: %v", err)
+	}
+
+	dkr, err := calypso.NewClient(cl).DecryptKey(&calypso.DecryptKey{
+		Read: *readProof, Write: *writeProof})
+	if err != nil {
+		return xerrors.Errorf("failed to reencrypt key: %v", err)
+	}
+	key, err := dkr.RecoverKey(xc)
+	if err != nil {
+		return xerrors.Errorf("failed to recover the key: %v", err)
+	}
+
+	store, err := file.NewDirStore(c.String("store"))
+	if err != nil {
+		return xerrors.Errorf("failed to open store: %v", err)
+	}
+	var out io.Writer = os.Stdout
+	if o := c.String("out"); o != "" {
+		f, err := os.Create(o)
+		if err != nil {
+			return xerrors.Errorf("failed to create output file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	info, err := file.Get(store, &write, key, out)
+	if err != nil {
+		return xerrors.Errorf("failed to get file: %v", err)
+	}
+	if c.String("out") != "" {
+		log.Infof("Decrypted file %s to %s", info.Name, c.String("out"))
+	}
+	return nil
+}
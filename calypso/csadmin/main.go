@@ -180,6 +180,68 @@ func dkgStart(c *cli.Context) error {
 	return nil
 }
 
+// reshare moves the long-term secret of an LTS instance to a new roster. The
+// roster stored in the instance is updated with a transaction, then the
+// nodes are asked to reshare their secret shares. The public key X stays
+// the same.
+func reshare(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return xerrors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return xerrors.Errorf("failed to load config: %v", err)
+	}
+
+	instidstr := c.String("instid")
+	if instidstr == "" {
+		return xerrors.New("please provide an LTS instance ID with --instid")
+	}
+	instid, err := hex.DecodeString(instidstr)
+	if err != nil {
+		return xerrors.Errorf("failed to decode LTS instance id: %v", err)
+	}
+
+	rFile := c.String("roster")
+	if rFile == "" {
+		return xerrors.New("please provide the new roster with --roster")
+	}
+	roster, err := lib.ReadRoster(rFile)
+	if err != nil {
+		return xerrors.Errorf("couldn't load roster: %v", err)
+	}
+
+	var signer *darc.Signer
+	sstr := c.String("sign")
+	if sstr == "" {
+		signer, err = lib.LoadKey(cfg.AdminIdentity)
+	} else {
+		signer, err = lib.LoadKeyFromString(sstr)
+	}
+	if err != nil {
+		return xerrors.Errorf("failed to parse the signer: %v", err)
+	}
+
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return xerrors.Errorf("failed to get the signer counters: %v", err)
+	}
+
+	reply, err := calypso.NewClient(cl).ReshareLTS(roster,
+		byzcoin.NewInstanceID(instid), []darc.Signer{*signer},
+		[]uint64{counters.Counters[0] + 1})
+	if err != nil {
+		return xerrors.Errorf("failed to reshare LTS: %v", err)
+	}
+
+	log.Infof("LTS reshared:\n"+
+		"- InstanceID: %x\n- X: %s\n- Roster: %v",
+		instid, reply.X, roster.List)
+	return nil
+}
+
 // dkgInfo - prints information about the lts stored in the given instance
 func dkgInfo(c *cli.Context) error {
 	bcArg := c.String("bc")
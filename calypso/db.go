@@ -27,6 +27,9 @@ type storage struct {
 	Rosters map[byzcoin.InstanceID]*onet.Roster
 	Replies map[byzcoin.InstanceID]*CreateLTSReply
 	DKS     map[byzcoin.InstanceID]*dkg.DistKeyShare
+	// PostQuantumSeeds holds the seed of the ML-KEM-768 key of the node for
+	// every LTS. It is random and independent of the calypso key.
+	PostQuantumSeeds map[byzcoin.InstanceID][]byte
 
 	sync.Mutex
 }
@@ -69,6 +72,9 @@ func (s *Service) tryLoad() error {
 		if len(s.storage.DKS) == 0 {
 			s.storage.DKS = make(map[byzcoin.InstanceID]*dkg.DistKeyShare)
 		}
+		if len(s.storage.PostQuantumSeeds) == 0 {
+			s.storage.PostQuantumSeeds = make(map[byzcoin.InstanceID][]byte)
+		}
 		if len(s.storage.AuthorisedByzCoinIDs) == 0 {
 			s.storage.AuthorisedByzCoinIDs = make(map[string]bool)
 		}
@@ -0,0 +1,174 @@
+// Package file helps applications to store files of any size with calypso.
+// The file is encrypted in chunks with AES-GCM under a random symmetric key,
+// and the ciphertext is kept off-chain in a Store. Only the symmetric key is
+// stored in a calypso Write instance, together with an Info in
+// Write.ExtraData that commits to the hash of the ciphertext.
+//
+// The format of an encrypted file is a header with a magic string and the
+// chunk size, followed by the chunks. Every chunk starts with a flag telling
+// if it is the last one and the length of the ciphertext. The flag and the
+// length are authenticated, and the nonce is the index of the chunk, so that
+// chunks can neither be reordered, nor removed at the end of the file.
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+
+	"go.dedis.ch/kyber/v3/util/random"
+	"golang.org/x/xerrors"
+)
+
+// KeyLen is the length of the symmetric keys. It is short enough to be
+// embedded in a point of the ed25519 suite by calypso.NewWrite.
+const KeyLen = 16
+
+// DefaultChunkSize is the size of the plaintext chunks used by Encrypt.
+const DefaultChunkSize = 1 << 16
+
+// maxChunkSize protects the decryption from allocating too much memory with
+// a corrupted header.
+const maxChunkSize = 1 << 24
+
+var magic = []byte("calypsoF")
+
+const (
+	chunkMore  = byte(0)
+	chunkFinal = byte(1)
+)
+
+// NewKey returns a random symmetric key of KeyLen bytes.
+func NewKey() []byte {
+	return random.Bits(KeyLen*8, true, random.New())
+}
+
+// Encrypt reads the plaintext from src until EOF and writes the encrypted
+// file to dst, using chunks of DefaultChunkSize.
+func Encrypt(key []byte, dst io.Writer, src io.Reader) error {
+	return EncryptChunks(key, DefaultChunkSize, dst, src)
+}
+
+// EncryptChunks is like Encrypt, but with a given chunk size.
+func EncryptChunks(key []byte, chunkSize int, dst io.Writer, src io.Reader) error {
+	if chunkSize <= 0 || chunkSize > maxChunkSize {
+		return xerrors.New("invalid chunk size")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return xerrors.Errorf("creating cipher: %v", err)
+	}
+
+	header := make([]byte, len(magic)+4)
+	copy(header, magic)
+	binary.LittleEndian.PutUint32(header[len(magic):], uint32(chunkSize))
+	if _, err := dst.Write(header); err != nil {
+		return xerrors.Errorf("writing header: %v", err)
+	}
+
+	// We read one chunk ahead to know whether the current chunk is the last
+	// one.
+	buf := make([]byte, chunkSize)
+	next := make([]byte, chunkSize)
+	n, err := io.ReadFull(src, buf)
+	for index := uint64(0); ; index++ {
+		flag := chunkMore
+		var nNext int
+		switch err {
+		case nil:
+			nNext, err = io.ReadFull(src, next)
+			if err == io.EOF {
+				flag = chunkFinal
+			}
+		case io.EOF, io.ErrUnexpectedEOF:
+			flag = chunkFinal
+		default:
+			return xerrors.Errorf("reading plaintext: %v", err)
+		}
+
+		chunkHeader := make([]byte, 5)
+		chunkHeader[0] = flag
+		binary.LittleEndian.PutUint32(chunkHeader[1:], uint32(n+aead.Overhead()))
+		ct := aead.Seal(nil, nonce(aead, index), buf[:n], chunkHeader)
+		if _, err := dst.Write(append(chunkHeader, ct...)); err != nil {
+			return xerrors.Errorf("writing chunk: %v", err)
+		}
+		if flag == chunkFinal {
+			return nil
+		}
+		buf, next = next, buf
+		n = nNext
+	}
+}
+
+// Decrypt reads an encrypted file from src and writes the plaintext to dst.
+// As the file is decrypted chunk by chunk, an error can happen after some of
+// the plaintext has already been written to dst.
+func Decrypt(key []byte, dst io.Writer, src io.Reader) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return xerrors.Errorf("creating cipher: %v", err)
+	}
+
+	header := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return xerrors.Errorf("reading header: %v", err)
+	}
+	if string(header[:len(magic)]) != string(magic) {
+		return xerrors.New("not an encrypted calypso file")
+	}
+	chunkSize := int(binary.LittleEndian.Uint32(header[len(magic):]))
+	if chunkSize <= 0 || chunkSize > maxChunkSize {
+		return xerrors.New("invalid chunk size")
+	}
+
+	buf := make([]byte, chunkSize+aead.Overhead())
+	chunkHeader := make([]byte, 5)
+	for index := uint64(0); ; index++ {
+		if _, err := io.ReadFull(src, chunkHeader); err != nil {
+			return xerrors.Errorf("reading chunk %d: %v", index, err)
+		}
+		l := int(binary.LittleEndian.Uint32(chunkHeader[1:]))
+		if l < aead.Overhead() || l > len(buf) {
+			return xerrors.Errorf("chunk %d has invalid length", index)
+		}
+		if _, err := io.ReadFull(src, buf[:l]); err != nil {
+			return xerrors.Errorf("reading chunk %d: %v", index, err)
+		}
+		pt, err := aead.Open(buf[:0], nonce(aead, index), buf[:l], chunkHeader)
+		if err != nil {
+			return xerrors.Errorf("decrypting chunk %d: %v", index, err)
+		}
+		if _, err := dst.Write(pt); err != nil {
+			return xerrors.Errorf("writing plaintext: %v", err)
+		}
+		switch chunkHeader[0] {
+		case chunkMore:
+		case chunkFinal:
+			if n, _ := src.Read(make([]byte, 1)); n > 0 {
+				return xerrors.New("got data after the last chunk")
+			}
+			return nil
+		default:
+			return xerrors.Errorf("chunk %d has invalid flag", index)
+		}
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeyLen {
+		return nil, xerrors.Errorf("key must be %d bytes long", KeyLen)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(aead cipher.AEAD, index uint64) []byte {
+	n := make([]byte, aead.NonceSize())
+	binary.LittleEndian.PutUint64(n, index)
+	return n
+}
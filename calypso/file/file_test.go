@@ -0,0 +1,87 @@
+package file
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := NewKey()
+	chunkSize := 16
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1,
+		3 * chunkSize, 3*chunkSize + 5} {
+		pt := make([]byte, size)
+		random.New().XORKeyStream(pt, pt)
+		var ct bytes.Buffer
+		require.NoError(t, EncryptChunks(key, chunkSize, &ct, bytes.NewReader(pt)))
+
+		var out bytes.Buffer
+		require.NoError(t, Decrypt(key, &out, bytes.NewReader(ct.Bytes())))
+		require.True(t, bytes.Equal(pt, out.Bytes()), "size %d", size)
+
+		require.Error(t, Decrypt(NewKey(), ioutil.Discard, bytes.NewReader(ct.Bytes())))
+
+		// Removing the last chunk must be detected.
+		if size > chunkSize {
+			lastChunk := 5 + (size%chunkSize + 16)
+			if size%chunkSize == 0 {
+				lastChunk = 5 + chunkSize + 16
+			}
+			truncated := ct.Bytes()[:ct.Len()-lastChunk]
+			require.Error(t, Decrypt(key, ioutil.Discard, bytes.NewReader(truncated)))
+		}
+
+		// Any modification must be detected.
+		tampered := append([]byte{}, ct.Bytes()...)
+		tampered[len(tampered)-1] ^= 1
+		require.Error(t, Decrypt(key, ioutil.Discard, bytes.NewReader(tampered)))
+	}
+}
+
+func TestPutGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calypso-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ds, err := NewDirStore(dir)
+	require.NoError(t, err)
+
+	for _, store := range []Store{NewMemoryStore(), ds} {
+		// Instead of running a DKG, we use a single secret for the LTS.
+		x := cothority.Suite.Scalar().Pick(random.New())
+		X := cothority.Suite.Point().Mul(x, nil)
+		ltsID := byzcoin.NewInstanceID([]byte("lts"))
+		data := random.Bits(3*DefaultChunkSize*8+8, true, random.New())
+
+		write, err := Put(cothority.Suite, store, ltsID, []byte("darc"), X,
+			"test.bin", bytes.NewReader(data))
+		require.NoError(t, err)
+		require.NoError(t, write.CheckProof(cothority.Suite, []byte("darc")))
+
+		keyPoint := cothority.Suite.Point().Sub(write.C,
+			cothority.Suite.Point().Mul(x, write.U))
+		key, err := keyPoint.Data()
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		info, err := Get(store, write, key, &out)
+		require.NoError(t, err)
+		require.Equal(t, "test.bin", info.Name)
+		require.Equal(t, data, out.Bytes())
+
+		// A write pointing to another file must fail.
+		other, err := Put(cothority.Suite, store, ltsID, []byte("darc"), X,
+			"", bytes.NewReader([]byte("other")))
+		require.NoError(t, err)
+		wrong := &calypso.Write{ExtraData: other.ExtraData}
+		_, err = Get(store, wrong, key, ioutil.Discard)
+		require.Error(t, err)
+	}
+}
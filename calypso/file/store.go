@@ -0,0 +1,209 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// Store keeps encrypted files off-chain. Files are addressed by the sha256
+// hash of their content.
+type Store interface {
+	// Put stores the content of r and returns its hash.
+	Put(r io.Reader) (hash []byte, err error)
+	// Get returns a reader for the file with the given hash. The caller
+	// must close it.
+	Get(hash []byte) (io.ReadCloser, error)
+}
+
+// Info is stored in the ExtraData of the Write instance holding the key of
+// the file. It commits to the encrypted file in the store.
+type Info struct {
+	// Hash is the sha256 hash of the encrypted file.
+	Hash []byte
+	// Size is the size of the encrypted file.
+	Size int64
+	// Name is the name of the file, if given.
+	Name string `protobuf:"opt"`
+}
+
+// Put encrypts the content of src under a new random key and stores it in
+// store. It returns a Write, encrypting the key for the LTS with public key
+// X, whose ExtraData holds the Info of the stored file.
+func Put(suite suites.Suite, store Store, ltsID byzcoin.InstanceID,
+	writeDarc darc.ID, X kyber.Point, name string, src io.Reader) (*calypso.Write, error) {
+	key := NewKey()
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(Encrypt(key, pw, src))
+	}()
+	cr := &countReader{r: pr}
+	hash, err := store.Put(cr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return nil, xerrors.Errorf("storing file: %v", err)
+	}
+
+	write := calypso.NewWrite(suite, ltsID, writeDarc, X, key)
+	if write == nil {
+		return nil, xerrors.New("key is too long to be embedded")
+	}
+	write.ExtraData, err = protobuf.Encode(&Info{
+		Hash: hash,
+		Size: cr.n,
+		Name: name,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("encoding info: %v", err)
+	}
+	return write, nil
+}
+
+// Get fetches the file referenced by the ExtraData of write from store,
+// checks its hash, and decrypts it to dst using the key recovered with
+// calypso.DecryptKeyReply.RecoverKey.
+func Get(store Store, write *calypso.Write, key []byte, dst io.Writer) (*Info, error) {
+	var info Info
+	if err := protobuf.Decode(write.ExtraData, &info); err != nil {
+		return nil, xerrors.Errorf("decoding info: %v", err)
+	}
+	rc, err := store.Get(info.Hash)
+	if err != nil {
+		return nil, xerrors.Errorf("getting file: %v", err)
+	}
+	defer rc.Close()
+
+	// The plaintext is only written to dst once the hash of the ciphertext
+	// has been checked.
+	h := sha256.New()
+	tmp, err := ioutil.TempFile("", "calypso-file")
+	if err != nil {
+		return nil, xerrors.Errorf("creating temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(io.MultiWriter(h, tmp), rc); err != nil {
+		return nil, xerrors.Errorf("reading file: %v", err)
+	}
+	if !bytes.Equal(h.Sum(nil), info.Hash) {
+		return nil, xerrors.New("hash of the file doesn't match")
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, xerrors.Errorf("seeking file: %v", err)
+	}
+	if err := Decrypt(key, dst, tmp); err != nil {
+		return nil, xerrors.Errorf("decrypting file: %v", err)
+	}
+	return &info, nil
+}
+
+// DirStore is a Store keeping the files in a local directory, named by the
+// hexadecimal representation of their hash.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore returns a DirStore in dir, creating the directory if needed.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, xerrors.Errorf("creating directory: %v", err)
+	}
+	return &DirStore{Dir: dir}, nil
+}
+
+// Put implements Store. The file is first written to a temporary file and
+// then renamed, so that no partial file is ever visible under its hash.
+func (ds *DirStore) Put(r io.Reader) ([]byte, error) {
+	tmp, err := ioutil.TempFile(ds.Dir, ".tmp")
+	if err != nil {
+		return nil, xerrors.Errorf("creating file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(h, tmp), r)
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("writing file: %v", err)
+	}
+	hash := h.Sum(nil)
+	if err := os.Rename(tmp.Name(), ds.path(hash)); err != nil {
+		return nil, xerrors.Errorf("renaming file: %v", err)
+	}
+	return hash, nil
+}
+
+// Get implements Store.
+func (ds *DirStore) Get(hash []byte) (io.ReadCloser, error) {
+	f, err := os.Open(ds.path(hash))
+	if err != nil {
+		return nil, xerrors.Errorf("opening file: %v", err)
+	}
+	return f, nil
+}
+
+func (ds *DirStore) path(hash []byte) string {
+	return filepath.Join(ds.Dir, hex.EncodeToString(hash))
+}
+
+// MemoryStore is a Store keeping the files in memory. It is mostly useful
+// for tests.
+type MemoryStore struct {
+	files map[string][]byte
+	sync.Mutex
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{files: make(map[string][]byte)}
+}
+
+// Put implements Store.
+func (ms *MemoryStore) Put(r io.Reader) ([]byte, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, xerrors.Errorf("reading file: %v", err)
+	}
+	hash := sha256.Sum256(buf)
+	ms.Lock()
+	ms.files[string(hash[:])] = buf
+	ms.Unlock()
+	return hash[:], nil
+}
+
+// Get implements Store.
+func (ms *MemoryStore) Get(hash []byte) (io.ReadCloser, error) {
+	ms.Lock()
+	buf, ok := ms.files[string(hash)]
+	ms.Unlock()
+	if !ok {
+		return nil, xerrors.New("file not found")
+	}
+	return ioutil.NopCloser(bytes.NewReader(buf)), nil
+}
+
+// countReader counts the bytes read.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
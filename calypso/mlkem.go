@@ -0,0 +1,46 @@
+//go:build go1.24
+// +build go1.24
+
+package calypso
+
+import (
+	"crypto/mlkem"
+
+	"go.dedis.ch/cothority/v3"
+	"golang.org/x/xerrors"
+)
+
+// mlkemSeedSize is the size of the seed of a decapsulation key.
+const mlkemSeedSize = mlkem.SeedSize
+
+// mlkemPublic returns the ML-KEM-768 encapsulation key of the decapsulation
+// key derived from the seed.
+func mlkemPublic(seed []byte) ([]byte, error) {
+	dk, err := mlkem.NewDecapsulationKey768(seed)
+	if err != nil {
+		return nil, xerrors.Errorf("creating decapsulation key: %v", err)
+	}
+	return dk.EncapsulationKey().Bytes(), nil
+}
+
+// mlkemEncapsulate returns a new shared key and its encapsulation to the
+// ML-KEM-768 encapsulation key ek.
+func mlkemEncapsulate(ek []byte) (key, ct []byte, err error) {
+	pub, err := mlkem.NewEncapsulationKey768(ek)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("invalid encapsulation key: %v", err)
+	}
+	key, ct = pub.Encapsulate()
+	return key, ct, nil
+}
+
+// mlkemDecapsulate returns the shared key encapsulated in ct to the
+// decapsulation key derived from the seed.
+func mlkemDecapsulate(seed, ct []byte) ([]byte, error) {
+	dk, err := mlkem.NewDecapsulationKey768(seed)
+	if err != nil {
+		return nil, xerrors.Errorf("creating decapsulation key: %v", err)
+	}
+	key, err := dk.Decapsulate(ct)
+	return key, cothority.ErrorOrNil(err, "decapsulating")
+}
@@ -0,0 +1,23 @@
+//go:build !go1.24
+// +build !go1.24
+
+package calypso
+
+import "golang.org/x/xerrors"
+
+// mlkemSeedSize is the size of the seed of a decapsulation key.
+const mlkemSeedSize = 64
+
+var errNoMLKEM = xerrors.New("ML-KEM needs a binary built with go1.24 or later")
+
+func mlkemPublic(seed []byte) ([]byte, error) {
+	return nil, errNoMLKEM
+}
+
+func mlkemEncapsulate(ek []byte) (key, ct []byte, err error) {
+	return nil, nil, errNoMLKEM
+}
+
+func mlkemDecapsulate(seed, ct []byte) ([]byte, error) {
+	return nil, errNoMLKEM
+}
@@ -0,0 +1,274 @@
+package calypso
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
+)
+
+// postQuantumThreshold returns how many shares of a PostQuantumSecret with n
+// shares are needed to recover it. It is the same as the threshold of the
+// LTS.
+func postQuantumThreshold(n int) int {
+	return n - (n-1)/3
+}
+
+// GeneratePostQuantumKey returns a new ML-KEM-768 key pair for a reader of
+// hybrid writes. The seed is the private key, to be given to
+// RecoverKeyPostQuantum, and ek the encapsulation key, to be given to
+// AddReadPostQuantum.
+func GeneratePostQuantumKey() (seed, ek []byte, err error) {
+	seed = make([]byte, mlkemSeedSize)
+	if _, err = rand.Read(seed); err != nil {
+		return nil, nil, xerrors.Errorf("reading random seed: %v", err)
+	}
+	ek, err = mlkemPublic(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return seed, ek, nil
+}
+
+// NewWritePostQuantum is like NewWrite, but creates a hybrid write, which
+// stays confidential as long as either the discrete logarithm problem or
+// ML-KEM are hard. The key embedded in C is masked with a random secret,
+// which is shared between the nodes of the LTS and encapsulated to their
+// ML-KEM-768 keys. The keys must be the ones returned by
+// Client.GetPostQuantumKeys, in the same order.
+func NewWritePostQuantum(suite suites.Suite, ltsid byzcoin.InstanceID, writeDarc darc.ID, X kyber.Point, keys [][]byte, key []byte) (*Write, error) {
+	if len(keys) == 0 {
+		return nil, xerrors.New("need the keys of the nodes of the LTS")
+	}
+	secret := suite.Scalar().Pick(suite.RandomStream())
+	poly := share.NewPriPoly(suite, postQuantumThreshold(len(keys)), secret,
+		suite.RandomStream())
+	pq := &PostQuantumSecret{}
+	for i, sh := range poly.Shares(len(keys)) {
+		buf, err := sh.V.MarshalBinary()
+		if err != nil {
+			return nil, xerrors.Errorf("marshalling share: %v", err)
+		}
+		kemKey, ct, err := mlkemEncapsulate(keys[i])
+		if err != nil {
+			return nil, xerrors.Errorf("key %d: %v", i, err)
+		}
+		sealed, err := seal(kemKey, postQuantumShareData(ltsid, writeDarc, i), buf)
+		if err != nil {
+			return nil, err
+		}
+		keyHash := sha256.Sum256(keys[i])
+		shareHash := sha256.Sum256(buf)
+		pq.Shares = append(pq.Shares, PostQuantumShare{
+			Key:           keyHash[:],
+			Encapsulation: ct,
+			Share:         sealed,
+			Hash:          shareHash[:],
+		})
+	}
+	masked, err := maskKey(secret, key)
+	if err != nil {
+		return nil, err
+	}
+	wr := newWrite(suite, ltsid, writeDarc, X, masked, pq)
+	if wr == nil {
+		return nil, xerrors.New("key is too long to be embedded")
+	}
+	return wr, nil
+}
+
+// hash returns the hash of the shares, which is covered by the proof of the
+// write.
+func (pq *PostQuantumSecret) hash() []byte {
+	h := sha256.New()
+	for _, sh := range pq.Shares {
+		for _, b := range [][]byte{sh.Key, sh.Encapsulation, sh.Share, sh.Hash} {
+			binary.Write(h, binary.LittleEndian, uint32(len(b)))
+			h.Write(b)
+		}
+	}
+	return h.Sum(nil)
+}
+
+// share returns the index of the share encapsulated to the key, and the
+// share.
+func (pq *PostQuantumSecret) share(key []byte) (int, *PostQuantumShare, error) {
+	keyHash := sha256.Sum256(key)
+	for i := range pq.Shares {
+		if bytes.Equal(pq.Shares[i].Key, keyHash[:]) {
+			return i, &pq.Shares[i], nil
+		}
+	}
+	return 0, nil, xerrors.New("no share for this key")
+}
+
+// postQuantumShareData returns the additional data of the encryption of a
+// share by the writer, so that it cannot be copied to another write.
+func postQuantumShareData(ltsid byzcoin.InstanceID, writeDarc darc.ID, index int) []byte {
+	h := sha256.New()
+	h.Write(ltsid[:])
+	h.Write(writeDarc)
+	binary.Write(h, binary.LittleEndian, uint32(index))
+	return h.Sum(nil)
+}
+
+// postQuantumKeyMessage returns the message signed by a node for its key.
+func postQuantumKeyMessage(ltsid byzcoin.InstanceID, key []byte) []byte {
+	return append(append([]byte{}, ltsid[:]...), key...)
+}
+
+// verifyPostQuantumKey checks the signature of the key of a node.
+func verifyPostQuantumKey(ltsid byzcoin.InstanceID, si *network.ServerIdentity,
+	reply *GetPostQuantumKeyReply) error {
+	return schnorr.Verify(cothority.Suite, si.ServicePublic(ServiceName),
+		postQuantumKeyMessage(ltsid, reply.Key), reply.Signature)
+}
+
+// maskKey XORs the key with a mask derived from the secret.
+func maskKey(secret kyber.Scalar, key []byte) ([]byte, error) {
+	buf, err := secret.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("marshalling secret: %v", err)
+	}
+	mask := sha256.Sum256(append([]byte("calypso hybrid"), buf...))
+	if len(key) > len(mask) {
+		return nil, xerrors.New("key is too long to be masked")
+	}
+	masked := make([]byte, len(key))
+	for i := range key {
+		masked[i] = key[i] ^ mask[i]
+	}
+	return masked, nil
+}
+
+// seal encrypts the data with AES-GCM. Every key is only used once, so the
+// nonce is always zero.
+func seal(key, ad, data []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, aead.NonceSize()), data, ad), nil
+}
+
+// open decrypts data encrypted by seal.
+func open(key, ad, data []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := aead.Open(nil, make([]byte, aead.NonceSize()), data, ad)
+	return buf, cothority.ErrorOrNil(err, "decrypting share")
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("creating cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, cothority.ErrorOrNil(err, "creating AEAD")
+}
+
+// readerKey derives the key encrypting a share to the reader from the
+// ML-KEM key and the Diffie-Hellman point, so that both must be broken to
+// read it.
+func readerKey(kemKey []byte, R, dh kyber.Point) []byte {
+	h := sha256.New()
+	h.Write(kemKey)
+	R.MarshalTo(h)
+	dh.MarshalTo(h)
+	return h.Sum(nil)
+}
+
+// postQuantumReplyData returns the additional data of the encryption of a
+// share to the reader.
+func postQuantumReplyData(index uint32, R kyber.Point) []byte {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, index)
+	R.MarshalTo(h)
+	return h.Sum(nil)
+}
+
+// newPostQuantumReply encrypts the share at index to the keys of the reader.
+func newPostQuantumReply(read *Read, index int, sh []byte) (*PostQuantumReply, error) {
+	if len(read.PostQuantumKey) == 0 {
+		return nil, xerrors.New("the reader has no post-quantum key")
+	}
+	kemKey, ct, err := mlkemEncapsulate(read.PostQuantumKey)
+	if err != nil {
+		return nil, xerrors.Errorf("encapsulating to the reader: %v", err)
+	}
+	r := cothority.Suite.Scalar().Pick(cothority.Suite.RandomStream())
+	reply := &PostQuantumReply{
+		Index:         uint32(index),
+		R:             cothority.Suite.Point().Mul(r, nil),
+		Encapsulation: ct,
+	}
+	dh := cothority.Suite.Point().Mul(r, read.Xc)
+	reply.Share, err = seal(readerKey(kemKey, reply.R, dh),
+		postQuantumReplyData(reply.Index, reply.R), sh)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// RecoverKeyPostQuantum is RecoverKey for a hybrid write. It needs the
+// private key xc of the reader, the seed returned by
+// GeneratePostQuantumKey, and the write, as stored in ByzCoin.
+func (r *DecryptKeyReply) RecoverKeyPostQuantum(xc kyber.Scalar, seed []byte, wr *Write) ([]byte, error) {
+	if wr.PostQuantum == nil {
+		return nil, xerrors.New("not a hybrid write")
+	}
+	n := len(wr.PostQuantum.Shares)
+	var shares []*share.PriShare
+	seen := make(map[uint32]bool)
+	for _, pqr := range r.PostQuantum {
+		if int(pqr.Index) >= n || pqr.R == nil || seen[pqr.Index] {
+			continue
+		}
+		kemKey, err := mlkemDecapsulate(seed, pqr.Encapsulation)
+		if err != nil {
+			continue
+		}
+		dh := cothority.Suite.Point().Mul(xc, pqr.R)
+		buf, err := open(readerKey(kemKey, pqr.R, dh),
+			postQuantumReplyData(pqr.Index, pqr.R), pqr.Share)
+		if err != nil {
+			continue
+		}
+		hash := sha256.Sum256(buf)
+		if !bytes.Equal(hash[:], wr.PostQuantum.Shares[pqr.Index].Hash) {
+			continue
+		}
+		v := cothority.Suite.Scalar()
+		if err := v.UnmarshalBinary(buf); err != nil {
+			continue
+		}
+		seen[pqr.Index] = true
+		shares = append(shares, &share.PriShare{I: int(pqr.Index), V: v})
+	}
+	secret, err := share.RecoverSecret(cothority.Suite, shares,
+		postQuantumThreshold(n), n)
+	if err != nil {
+		return nil, xerrors.Errorf("recovering post-quantum secret: %v", err)
+	}
+	masked, err := r.recoverKey(xc)
+	if err != nil {
+		return nil, err
+	}
+	return maskKey(secret, masked)
+}
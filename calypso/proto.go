@@ -47,6 +47,58 @@ type Write struct {
 	LTSID byzcoin.InstanceID
 	// Cost reflects how many coins you'll have to pay for a read-request
 	Cost byzcoin.Coin `protobuf:"opt"`
+	// Policy restricts who can spawn a read-request and when.
+	Policy *ReadPolicy `protobuf:"opt"`
+	// Reads counts the read-requests spawned from this write. It is only
+	// updated if Policy is set.
+	Reads uint64 `protobuf:"opt"`
+	// PostQuantum is set for a hybrid write. The key embedded in C is then
+	// masked with a second secret, encapsulated to the ML-KEM-768 keys of
+	// the LTS nodes.
+	PostQuantum *PostQuantumSecret `protobuf:"opt"`
+}
+
+// PostQuantumSecret is a random scalar, shared between the nodes of the LTS
+// with a Shamir secret sharing. Every share is encrypted to the ML-KEM-768
+// key of one node.
+type PostQuantumSecret struct {
+	// Shares holds the encrypted shares, in the order of the keys given to
+	// NewWritePostQuantum.
+	Shares []PostQuantumShare
+}
+
+// PostQuantumShare is the share of a PostQuantumSecret for one node.
+type PostQuantumShare struct {
+	// Key is the hash of the ML-KEM-768 encapsulation key of the node.
+	Key []byte
+	// Encapsulation is the ML-KEM-768 ciphertext of the key encrypting
+	// the share.
+	Encapsulation []byte
+	// Share is the share, encrypted with AES-GCM.
+	Share []byte
+	// Hash is the SHA-256 hash of the share, so that the reader can check
+	// the shares it receives.
+	Hash []byte
+}
+
+// ReadPolicy holds declarative restrictions on the read-requests of a write
+// instance. They are checked by the write contract when spawning a read
+// instance, on top of the rules of the darc. Restrictions with a zero value
+// are not checked.
+type ReadPolicy struct {
+	// MaxReads is the maximum number of read-requests that can be spawned.
+	MaxReads uint64 `protobuf:"opt"`
+	// NotBefore is the time, in nanoseconds since the epoch, before which no
+	// read-request can be spawned. It is compared to the block timestamp.
+	NotBefore int64 `protobuf:"opt"`
+	// CredentialName and CredentialAttribute require the reader to hold a
+	// credential with this attribute. The credential instance is given in
+	// the "credentialID" argument of the read-request and must be controlled
+	// by the signers of the read-request.
+	CredentialName      string `protobuf:"opt"`
+	CredentialAttribute string `protobuf:"opt"`
+	// CredentialValue, if set, is the value the attribute must have.
+	CredentialValue []byte `protobuf:"opt"`
 }
 
 // Read is the data stored in a read instance. It has a pointer to the write
@@ -54,6 +106,9 @@ type Write struct {
 type Read struct {
 	Write byzcoin.InstanceID
 	Xc    kyber.Point
+	// PostQuantumKey is the ML-KEM-768 encapsulation key of the reader. It
+	// is needed to read a hybrid write.
+	PostQuantumKey []byte `protobuf:"opt"`
 }
 
 // ***
@@ -110,6 +165,8 @@ type ReshareLTS struct {
 // ReshareLTSReply is returned upon successful resharing. The LTSID and the
 // public key X should remain the same.
 type ReshareLTSReply struct {
+	// X is the public key of the LTS after resharing.
+	X kyber.Point `protobuf:"opt"`
 }
 
 // Message used to update the set of valid peers.
@@ -139,6 +196,37 @@ type DecryptKeyReply struct {
 	XhatEnc kyber.Point
 	// X is the aggregate public key of the LTS used.
 	X kyber.Point
+	// PostQuantum holds the shares of the PostQuantumSecret of a hybrid
+	// write, encrypted to the reader.
+	PostQuantum []PostQuantumReply `protobuf:"opt"`
+}
+
+// PostQuantumReply is the share of the PostQuantumSecret held by one node,
+// encrypted with a key derived from both an ML-KEM-768 encapsulation to the
+// PostQuantumKey of the reader and a Diffie-Hellman exchange with Xc.
+type PostQuantumReply struct {
+	// Index is the index of the share in the PostQuantumSecret.
+	Index uint32
+	// R is the ephemeral public key of the Diffie-Hellman exchange.
+	R kyber.Point
+	// Encapsulation is the ML-KEM-768 ciphertext.
+	Encapsulation []byte
+	// Share is the share, encrypted with AES-GCM.
+	Share []byte
+}
+
+// DecryptKeys is sent by a reader to re-encrypt many secrets in one request.
+// All write instances must use the same LTS.
+type DecryptKeys struct {
+	// Requests holds the pairs of Read- and Write-proofs.
+	Requests []DecryptKey
+}
+
+// DecryptKeysReply is returned if the service verified successfully that all
+// decryption requests are valid. The replies are in the same order as the
+// requests.
+type DecryptKeysReply struct {
+	Replies []DecryptKeyReply
 }
 
 // GetLTSReply asks for the shared public key of the corresponding LTSID
@@ -147,6 +235,22 @@ type GetLTSReply struct {
 	LTSID byzcoin.InstanceID
 }
 
+// GetPostQuantumKey asks a node of an LTS for its ML-KEM-768 key.
+type GetPostQuantumKey struct {
+	// LTSID is the id of the LTS instance.
+	LTSID byzcoin.InstanceID
+}
+
+// GetPostQuantumKeyReply holds the ML-KEM-768 encapsulation key of a node
+// for an LTS.
+type GetPostQuantumKeyReply struct {
+	// Key is the encapsulation key.
+	Key []byte
+	// Signature is the schnorr signature of the LTSID and the key by the
+	// calypso key of the node.
+	Signature []byte
+}
+
 // LtsInstanceInfo is the information stored in an LTS instance.
 type LtsInstanceInfo struct {
 	Roster onet.Roster
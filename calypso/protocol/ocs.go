@@ -24,13 +24,16 @@ func init() {
 }
 
 // OCS is only used to re-encrypt a public point. Before calling `Start`,
-// DKG and U must be initialized by the caller.
+// DKG and U must be initialized by the caller. To re-encrypt many points in
+// one round, Us and Xcs can be initialized instead of U and Xc.
 type OCS struct {
 	*onet.TreeNodeInstance
 	Shared    *dkgprotocol.SharedSecret // Shared represents the private key
 	Poly      *share.PubPoly            // Represents all public keys
 	U         kyber.Point               // U is the encrypted secret
 	Xc        kyber.Point               // The client's public key
+	Us        []kyber.Point             // Us are the encrypted secrets of a batch
+	Xcs       []kyber.Point             // Xcs are the clients' public keys of a batch
 	Threshold int                       // How many replies are needed to re-create the secret
 	// VerificationData is given to the VerifyRequest and has to hold everything
 	// needed to verify the request is valid.
@@ -39,10 +42,18 @@ type OCS struct {
 	// Can be set by the service to decide whether or not to
 	// do the reencryption
 	Verify VerifyRequest
+	// Can be set by the service to add data to the replies of the nodes
+	Extra ExtraReply
+	// Extras holds the non-empty data added by the root and the nodes that
+	// sent a valid share, when Extra is set.
+	Extras [][]byte
 	// Reencrypted receives a 'true'-value when the protocol finished successfully,
 	// or 'false' if not enough shares have been collected.
 	Reencrypted chan bool
 	Uis         []*share.PubShare // re-encrypted shares
+	// BatchUis holds the re-encrypted shares for every pair of Us and Xcs.
+	// BatchUis[0] is the same as Uis.
+	BatchUis [][]*share.PubShare
 	// private fields
 	replies  []ReencryptReply
 	timeout  *time.Timer
@@ -71,13 +82,22 @@ func (o *OCS) Start() error {
 		o.finish(false)
 		return xerrors.New("please initialize Shared first")
 	}
+	if len(o.Us) > 0 {
+		if len(o.Us) != len(o.Xcs) {
+			o.finish(false)
+			return xerrors.New("Us and Xcs must have the same length")
+		}
+		o.U, o.Xc = o.Us[0], o.Xcs[0]
+	}
 	if o.U == nil {
 		o.finish(false)
 		return xerrors.New("please initialize U first")
 	}
 	rc := &Reencrypt{
-		U:  o.U,
-		Xc: o.Xc,
+		U:   o.U,
+		Xc:  o.Xc,
+		Us:  o.Us,
+		Xcs: o.Xcs,
 	}
 	if len(o.VerificationData) > 0 {
 		rc.VerificationData = &o.VerificationData
@@ -88,6 +108,16 @@ func (o *OCS) Start() error {
 			return xerrors.New("refused to reencrypt")
 		}
 	}
+	if o.Extra != nil {
+		extra, err := o.Extra(rc)
+		if err != nil {
+			o.finish(false)
+			return xerrors.Errorf("refused to reencrypt: %v", err)
+		}
+		if len(extra) > 0 {
+			o.Extras = append(o.Extras, extra)
+		}
+	}
 	o.timeout = time.AfterFunc(1*time.Minute, func() {
 		log.Lvl1("OCS protocol timeout")
 		o.finish(false)
@@ -106,7 +136,12 @@ func (o *OCS) reencrypt(r structReencrypt) error {
 	log.Lvl3(o.Name() + ": starting reencrypt")
 	defer o.Done()
 
-	ui := o.getUI(r.U, r.Xc)
+	us, xcs := r.pairs()
+	if len(us) != len(xcs) {
+		log.Lvl2(o.ServerIdentity(), "got batch of wrong length")
+		return cothority.ErrorOrNil(o.SendToParent(&ReencryptReply{}),
+			"sending ReencryptReply to parent")
+	}
 
 	if o.Verify != nil {
 		if !o.Verify(&r.Reencrypt) {
@@ -116,9 +151,42 @@ func (o *OCS) reencrypt(r structReencrypt) error {
 		}
 	}
 
+	var extra []byte
+	if o.Extra != nil {
+		var err error
+		extra, err = o.Extra(&r.Reencrypt)
+		if err != nil {
+			log.Lvl2(o.ServerIdentity(), "refused to reencrypt:", err)
+			return cothority.ErrorOrNil(o.SendToParent(&ReencryptReply{}),
+				"sending ReencryptReply to parent")
+		}
+	}
+
+	shares := make([]ReencryptShare, len(us))
+	for i := range us {
+		shares[i] = o.reencryptShare(us[i], xcs[i])
+	}
+	reply := &ReencryptReply{
+		Ui:    shares[0].Ui,
+		Ei:    shares[0].Ei,
+		Fi:    shares[0].Fi,
+		Extra: extra,
+	}
+	if len(r.Us) > 0 {
+		reply.Shares = shares
+	}
+	return cothority.ErrorOrNil(o.SendToParent(reply),
+		"sending ReencryptReply to parent")
+}
+
+// reencryptShare calculates the re-encryption share of U to Xc, together
+// with the proof that it has been computed with our share of the secret.
+func (o *OCS) reencryptShare(U, Xc kyber.Point) ReencryptShare {
+	ui := o.getUI(U, Xc)
+
 	// Calculating proofs
 	si := cothority.Suite.Scalar().Pick(o.Suite().RandomStream())
-	uiHat := cothority.Suite.Point().Mul(si, cothority.Suite.Point().Add(r.U, r.Xc))
+	uiHat := cothority.Suite.Point().Mul(si, cothority.Suite.Point().Add(U, Xc))
 	hiHat := cothority.Suite.Point().Mul(si, nil)
 	hash := sha256.New()
 	ui.V.MarshalTo(hash)
@@ -126,14 +194,11 @@ func (o *OCS) reencrypt(r structReencrypt) error {
 	hiHat.MarshalTo(hash)
 	ei := cothority.Suite.Scalar().SetBytes(hash.Sum(nil))
 
-	return cothority.ErrorOrNil(
-		o.SendToParent(&ReencryptReply{
-			Ui: ui,
-			Ei: ei,
-			Fi: cothority.Suite.Scalar().Add(si, cothority.Suite.Scalar().Mul(ei, o.Shared.V)),
-		}),
-		"sending ReencryptReply to parent",
-	)
+	return ReencryptShare{
+		Ui: ui,
+		Ei: ei,
+		Fi: cothority.Suite.Scalar().Add(si, cothority.Suite.Scalar().Mul(ei, o.Shared.V)),
+	}
 }
 
 // reencryptReply is the root-node waiting for all replies and generating
@@ -152,30 +217,34 @@ func (o *OCS) reencryptReply(rr structReencryptReply) error {
 
 	// minus one to exclude the root
 	if len(o.replies) >= int(o.Threshold-1) {
-		o.Uis = make([]*share.PubShare, len(o.List()))
-		o.Uis[0] = o.getUI(o.U, o.Xc)
+		us, xcs := []kyber.Point{o.U}, []kyber.Point{o.Xc}
+		if len(o.Us) > 0 {
+			us, xcs = o.Us, o.Xcs
+		}
+		o.BatchUis = make([][]*share.PubShare, len(us))
+		for i := range us {
+			o.BatchUis[i] = make([]*share.PubShare, len(o.List()))
+			o.BatchUis[i][0] = o.getUI(us[i], xcs[i])
+		}
 
 		for _, r := range o.replies {
-			// Verify proofs
-			ufi := cothority.Suite.Point().Mul(r.Fi, cothority.Suite.Point().Add(o.U, o.Xc))
-			uiei := cothority.Suite.Point().Mul(cothority.Suite.Scalar().Neg(r.Ei), r.Ui.V)
-			uiHat := cothority.Suite.Point().Add(ufi, uiei)
-
-			gfi := cothority.Suite.Point().Mul(r.Fi, nil)
-			gxi := o.Poly.Eval(r.Ui.I).V
-			hiei := cothority.Suite.Point().Mul(cothority.Suite.Scalar().Neg(r.Ei), gxi)
-			hiHat := cothority.Suite.Point().Add(gfi, hiei)
-			hash := sha256.New()
-			r.Ui.V.MarshalTo(hash)
-			uiHat.MarshalTo(hash)
-			hiHat.MarshalTo(hash)
-			e := cothority.Suite.Scalar().SetBytes(hash.Sum(nil))
-			if e.Equal(r.Ei) {
-				o.Uis[r.Ui.I] = r.Ui
-			} else {
-				log.Lvl1("Received invalid share from node", r.Ui.I)
+			shares := r.shares(len(us))
+			if shares == nil {
+				log.Lvl1("Received wrong number of shares from node", r.Ui.I)
+				continue
+			}
+			for i, sh := range shares {
+				if o.verifyShare(us[i], xcs[i], sh) {
+					o.BatchUis[i][sh.Ui.I] = sh.Ui
+					if i == 0 && len(r.Extra) > 0 {
+						o.Extras = append(o.Extras, r.Extra)
+					}
+				} else {
+					log.Lvl1("Received invalid share from node", r.Ui.I)
+				}
 			}
 		}
+		o.Uis = o.BatchUis[0]
 		o.finish(true)
 	}
 
@@ -189,6 +258,27 @@ func (o *OCS) reencryptReply(rr structReencryptReply) error {
 	return nil
 }
 
+// verifyShare checks the proof of a re-encryption share of U to Xc.
+func (o *OCS) verifyShare(U, Xc kyber.Point, r ReencryptShare) bool {
+	if r.Ui == nil || r.Ui.I < 0 || r.Ui.I >= len(o.List()) {
+		return false
+	}
+	ufi := cothority.Suite.Point().Mul(r.Fi, cothority.Suite.Point().Add(U, Xc))
+	uiei := cothority.Suite.Point().Mul(cothority.Suite.Scalar().Neg(r.Ei), r.Ui.V)
+	uiHat := cothority.Suite.Point().Add(ufi, uiei)
+
+	gfi := cothority.Suite.Point().Mul(r.Fi, nil)
+	gxi := o.Poly.Eval(r.Ui.I).V
+	hiei := cothority.Suite.Point().Mul(cothority.Suite.Scalar().Neg(r.Ei), gxi)
+	hiHat := cothority.Suite.Point().Add(gfi, hiei)
+	hash := sha256.New()
+	r.Ui.V.MarshalTo(hash)
+	uiHat.MarshalTo(hash)
+	hiHat.MarshalTo(hash)
+	e := cothority.Suite.Scalar().SetBytes(hash.Sum(nil))
+	return e.Equal(r.Ei)
+}
+
 func (o *OCS) getUI(U, Xc kyber.Point) *share.PubShare {
 	v := cothority.Suite.Point().Mul(o.Shared.V, U)
 	v.Add(v, cothority.Suite.Point().Mul(o.Shared.V, Xc))
@@ -24,6 +24,11 @@ func init() {
 // allow reencryption.
 type VerifyRequest func(rc *Reencrypt) bool
 
+// ExtraReply is a callback-function that can be set by a service to add
+// data to the reply of a node, once the request has been verified. If it
+// returns an error, the node refuses to reencrypt.
+type ExtraReply func(rc *Reencrypt) ([]byte, error)
+
 // Reencrypt asks for a re-encryption share from a node
 type Reencrypt struct {
 	// U is the point from the write-request
@@ -33,6 +38,19 @@ type Reencrypt struct {
 	// VerificationData is optional and can be any slice of bytes, so that each
 	// node can verify if the reencryption request is valid or not.
 	VerificationData *[]byte
+	// Us and Xcs are set for a batch request. They hold all points to
+	// re-encrypt, U and Xc being a copy of the first ones.
+	Us  []kyber.Point `protobuf:"opt"`
+	Xcs []kyber.Point `protobuf:"opt"`
+}
+
+// pairs returns the points to re-encrypt with the corresponding public keys
+// of the readers.
+func (r *Reencrypt) pairs() ([]kyber.Point, []kyber.Point) {
+	if len(r.Us) > 0 {
+		return r.Us, r.Xcs
+	}
+	return []kyber.Point{r.U}, []kyber.Point{r.Xc}
 }
 
 type structReencrypt struct {
@@ -45,6 +63,31 @@ type ReencryptReply struct {
 	Ui *share.PubShare
 	Ei kyber.Scalar
 	Fi kyber.Scalar
+	// Shares holds one share per pair of a batch request. The first one is
+	// the same as Ui, Ei and Fi.
+	Shares []ReencryptShare `protobuf:"opt"`
+	// Extra is the data returned by the ExtraReply of the node.
+	Extra []byte `protobuf:"opt"`
+}
+
+// ReencryptShare is the re-encryption share of one node for one secret,
+// together with the proof that it has been correctly computed.
+type ReencryptShare struct {
+	Ui *share.PubShare
+	Ei kyber.Scalar
+	Fi kyber.Scalar
+}
+
+// shares returns the re-encryption shares of the reply for n pairs, or nil
+// if the reply holds a different number of shares.
+func (rr *ReencryptReply) shares(n int) []ReencryptShare {
+	if len(rr.Shares) == 0 && n == 1 {
+		return []ReencryptShare{{Ui: rr.Ui, Ei: rr.Ei, Fi: rr.Fi}}
+	}
+	if len(rr.Shares) != n {
+		return nil
+	}
+	return rr.Shares
 }
 
 type structReencryptReply struct {
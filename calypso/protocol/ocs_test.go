@@ -52,6 +52,61 @@ func TestRefuse(t *testing.T) {
 	ocs(t, 3, 2, 32, 0, true)
 }
 
+// Tests the re-encryption of many secrets in one round
+func TestOCSBatch(t *testing.T) {
+	nbrNodes, threshold, batch := 4, 3, 5
+	local := onet.NewLocalTest(tSuite)
+	defer local.CloseAll()
+	servers, _, tree := local.GenBigTree(nbrNodes, nbrNodes, nbrNodes, true)
+
+	dkgs, err := CreateDKGs(tSuite.(dkg.Suite), nbrNodes, threshold)
+	require.NoError(t, err)
+	services := local.GetServices(servers, testServiceID)
+	for i := range services {
+		services[i].(*testService).Shared, _, err = dkgprotocol.NewSharedSecret(dkgs[i])
+		require.NoError(t, err)
+	}
+	dks, err := dkgs[0].DistKeyShare()
+	require.NoError(t, err)
+	X := dks.Public()
+
+	var keys [][]byte
+	var Cs [][]kyber.Point
+	var xcs []*key.Pair
+	pi, err := services[0].(*testService).createOCS(tree, threshold)
+	require.NoError(t, err)
+	protocol := pi.(*OCS)
+	for i := 0; i < batch; i++ {
+		k := make([]byte, 16)
+		random.Bytes(k, random.New())
+		U, C := EncodeKey(tSuite, X, k)
+		xc := key.NewKeyPair(cothority.Suite)
+		keys = append(keys, k)
+		Cs = append(Cs, C)
+		xcs = append(xcs, xc)
+		protocol.Us = append(protocol.Us, U)
+		protocol.Xcs = append(protocol.Xcs, xc.Public)
+	}
+	protocol.Poly = share.NewPubPoly(suite, suite.Point().Base(), dks.Commits)
+	protocol.VerificationData = []byte("correct block")
+	require.NoError(t, protocol.Start())
+	select {
+	case ok := <-protocol.Reencrypted:
+		require.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("Didn't finish in time")
+	}
+
+	require.Equal(t, batch, len(protocol.BatchUis))
+	for i := range keys {
+		XhatEnc, err := share.RecoverCommit(suite, protocol.BatchUis[i], threshold, nbrNodes)
+		require.NoError(t, err)
+		keyHat, err := DecodeKey(suite, X, Cs[i], XhatEnc, xcs[i].Private)
+		require.NoError(t, err)
+		require.Equal(t, keys[i], keyHat)
+	}
+}
+
 func TestOCSKeyLengths(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Testing all keylengths takes some time...")
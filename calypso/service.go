@@ -22,12 +22,13 @@
 // on an existing Write instance, with the proposed Read request in the "read"
 // argument.
 //
-// TODO: correctly handle multi signatures for read requests: to whom should
-// the secret be re-encrypted to? Perhaps for multi signatures we only want to
-// have ephemeral keys.
+// The secret is always re-encrypted to the key Xc stored in the read
+// instance. Ephemeral keys, chosen by the reader when asking for the
+// decryption, are not supported.
 package calypso
 
 import (
+	"crypto/rand"
 	"encoding/binary"
 	"fmt"
 	"math"
@@ -73,6 +74,16 @@ var allowInsecureAdmin = false
 // verify.
 var readMakeAttrInterpreter = make([]makeAttrInterpreterWrapper, 0)
 
+// credentialAttribute is used by read policies to get the value of the
+// attribute of a credential. It is set by the package implementing the
+// credential contract.
+var credentialAttribute CredentialAttributeFunc
+
+// CredentialAttributeFunc returns the value of the attribute attr of the
+// credential cred stored in the instance credID.
+type CredentialAttributeFunc func(rst byzcoin.ReadOnlyStateTrie,
+	credID byzcoin.InstanceID, cred, attr string) ([]byte, error)
+
 // makeAttrInterpreterWrapper holds the data needed to register a
 // MakeAttrInterpreter.
 type makeAttrInterpreterWrapper struct {
@@ -133,13 +144,19 @@ type pubPoly struct {
 	Commits []kyber.Point
 }
 
-// vData is sent to all nodes when re-encryption takes place. If Ephemeral
-// is non-nil, Signature needs to hold a valid signature from the reader
-// in the Proof.
+// vData is sent to all nodes when re-encryption takes place. Ephemeral and
+// Signature are kept for compatibility: ephemeral keys are not supported,
+// and requests setting them are refused.
 type vData struct {
 	Proof     byzcoin.Proof
 	Ephemeral kyber.Point
 	Signature *darc.Signature
+	// Proofs holds the read proofs of a batch request, in the same order as
+	// the points to re-encrypt.
+	Proofs []byzcoin.Proof
+	// Write is the write proof of a single request, needed by the nodes to
+	// find their share of a hybrid write.
+	Write *byzcoin.Proof
 }
 
 // AddReadAttrInterpreter adds a new AttrInterpreters that will be evaluated
@@ -151,6 +168,14 @@ func AddReadAttrInterpreter(name string, interpreter func(c ContractWrite,
 	readMakeAttrInterpreter = append(readMakeAttrInterpreter, makeAttrInterpreterWrapper{name, interpreter})
 }
 
+// SetCredentialAttribute sets the function used by read policies to get the
+// attributes of credentials. As calypso cannot depend on the credential
+// contract, it must be set by the package implementing it. This function is
+// not thread safe and should only be called in an init().
+func SetCredentialAttribute(f CredentialAttributeFunc) {
+	credentialAttribute = f
+}
+
 // ProcessClientRequest implements onet.Service. We override the version
 // we normally get from embeddeding onet.ServiceProcessor in order to
 // hook it and get a look at the http.Request.
@@ -305,7 +330,11 @@ func (s *Service) CreateLTS(req *CreateLTS) (reply *CreateLTSReply, err error) {
 		s.storage.Rosters[instID] = roster
 		s.storage.Replies[instID] = reply
 		s.storage.DKS[instID] = dks
+		err = s.addPostQuantumSeed(instID)
 		s.storage.Unlock()
+		if err != nil {
+			return nil, err
+		}
 		err = s.save()
 		if err != nil {
 			return nil, xerrors.Errorf("save dkg state: %v", err)
@@ -433,7 +462,11 @@ func (s *Service) ReshareLTS(req *ReshareLTS) (*ReshareLTSReply, error) {
 		s.storage.Polys[id] = &pubPoly{s.Suite().Point().Base(), dks.Commits}
 		s.storage.Rosters[id] = roster
 		s.storage.DKS[id] = dks
+		err = s.addPostQuantumSeed(id)
 		s.storage.Unlock()
+		if err != nil {
+			return nil, err
+		}
 		err = s.save()
 		if err != nil {
 			return nil, xerrors.Errorf("saving dkg state: %v", err)
@@ -447,7 +480,7 @@ func (s *Service) ReshareLTS(req *ReshareLTS) (*ReshareLTSReply, error) {
 
 	log.Lvl2(s.ServerIdentity(), "resharing protocol finished")
 	log.Lvlf2("%v Reshared LTS with ID: %v, pk %v", s.ServerIdentity(), id, pk)
-	return &ReshareLTSReply{}, nil
+	return &ReshareLTSReply{X: pk}, nil
 }
 
 // Private service endpoint that sets the valid peers according to the roster
@@ -529,41 +562,116 @@ func (s *Service) getLtsRoster(proof *byzcoin.Proof) (*onet.Roster, byzcoin.Inst
 // requests match and then re-encrypts the secret to the public key given
 // in the Read-instance.
 func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error) {
-	reply = &DecryptKeyReply{}
 	log.Lvl2(s.ServerIdentity(), "Re-encrypt the key to the public key of the reader")
 
+	read, write, err := s.verifyDecryptKey(dkr)
+	if err != nil {
+		return nil, err
+	}
+	verificationData := &vData{
+		Proof: dkr.Read,
+		Write: &dkr.Write,
+	}
+	replies, err := s.reencrypt(write.LTSID, []*Read{read}, []*Write{write},
+		verificationData)
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// DecryptKeys is the batch version of DecryptKey. It verifies all pairs of
+// Read- and Write-proofs and then re-encrypts all secrets in one round of
+// the OCS protocol. All Write-instances must use the same LTS.
+func (s *Service) DecryptKeys(dkrs *DecryptKeys) (*DecryptKeysReply, error) {
+	log.Lvl2(s.ServerIdentity(), "Re-encrypt", len(dkrs.Requests),
+		"keys to the public keys of the readers")
+	if len(dkrs.Requests) == 0 {
+		return nil, xerrors.New("no decryption request given")
+	}
+
+	reads := make([]*Read, len(dkrs.Requests))
+	writes := make([]*Write, len(dkrs.Requests))
+	verificationData := &vData{
+		Proof: dkrs.Requests[0].Read,
+	}
+	for i := range dkrs.Requests {
+		var err error
+		reads[i], writes[i], err = s.verifyDecryptKey(&dkrs.Requests[i])
+		if err != nil {
+			return nil, xerrors.Errorf("request %d: %v", i, err)
+		}
+		if !writes[i].LTSID.Equal(writes[0].LTSID) {
+			return nil, xerrors.Errorf("request %d uses a different LTS", i)
+		}
+		if writes[i].PostQuantum != nil {
+			return nil, xerrors.Errorf("request %d is a hybrid write, "+
+				"which must be decrypted with DecryptKey", i)
+		}
+		verificationData.Proofs = append(verificationData.Proofs,
+			dkrs.Requests[i].Read)
+	}
+
+	replies, err := s.reencrypt(writes[0].LTSID, reads, writes,
+		verificationData)
+	if err != nil {
+		return nil, err
+	}
+	reply := &DecryptKeysReply{}
+	for _, r := range replies {
+		reply.Replies = append(reply.Replies, *r)
+	}
+	return reply, nil
+}
+
+// verifyDecryptKey checks the Read- and Write-proofs of a decryption request
+// and returns the decoded instances.
+func (s *Service) verifyDecryptKey(dkr *DecryptKey) (*Read, *Write, error) {
 	var read Read
 	if err := dkr.Read.VerifyAndDecode(cothority.Suite, ContractReadID, &read); err != nil {
-		return nil, xerrors.New("didn't get a read instance: " + err.Error())
+		return nil, nil, xerrors.New("didn't get a read instance: " + err.Error())
 	}
 
 	var write Write
 	if err := dkr.Write.VerifyAndDecode(cothority.Suite, ContractWriteID, &write); err != nil {
-		return nil, xerrors.New("didn't get a write instance: " + err.Error())
+		return nil, nil, xerrors.New("didn't get a write instance: " + err.Error())
 	}
 	if !read.Write.Equal(byzcoin.NewInstanceID(dkr.Write.InclusionProof.Key())) {
-		return nil, xerrors.New("read doesn't point to passed write")
+		return nil, nil, xerrors.New("read doesn't point to passed write")
 	}
 	s.storage.Lock()
 	id := write.LTSID
 	roster := s.storage.Rosters[id]
 	if roster == nil {
 		s.storage.Unlock()
-		return nil,
+		return nil, nil,
 			xerrors.Errorf("don't know the LTSID '%v' stored in write", id)
 	}
 	s.storage.Unlock()
 
-	if err = s.verifyProof(&dkr.Read); err != nil {
-		return nil, xerrors.Errorf(
+	if err := s.verifyProof(&dkr.Read); err != nil {
+		return nil, nil, xerrors.Errorf(
 			"read proof cannot be verified to come from scID: %v",
 			err)
 	}
-	if err = s.verifyProof(&dkr.Write); err != nil {
-		return nil, xerrors.Errorf(
+	if err := s.verifyProof(&dkr.Write); err != nil {
+		return nil, nil, xerrors.Errorf(
 			"write proof cannot be verified to come from scID: %v",
 			err)
 	}
+	return &read, &write, nil
+}
+
+// reencrypt runs one round of the OCS protocol to re-encrypt the secrets of
+// the writes to the public keys of the corresponding reads.
+func (s *Service) reencrypt(id byzcoin.InstanceID, reads []*Read,
+	writes []*Write, verificationData *vData) ([]*DecryptKeyReply, error) {
+	s.storage.Lock()
+	roster := s.storage.Rosters[id]
+	s.storage.Unlock()
+	if roster == nil {
+		return nil, xerrors.Errorf("don't know the LTSID '%v'", id)
+	}
 
 	// Start ocs-protocol to re-encrypt the file's symmetric key under the
 	// reader's public key.
@@ -575,12 +683,17 @@ func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error
 		return nil, xerrors.Errorf("failed to create ocs-protocol: %v", err)
 	}
 	ocsProto := pi.(*protocol.OCS)
-	ocsProto.U = write.U
-	verificationData := &vData{
-		Proof: dkr.Read,
+	if len(reads) == 1 {
+		ocsProto.U = writes[0].U
+		ocsProto.Xc = reads[0].Xc
+		log.Lvlf2("%v Public key is: %s", s.ServerIdentity(), ocsProto.Xc)
+	} else {
+		for i := range reads {
+			ocsProto.Us = append(ocsProto.Us, writes[i].U)
+			ocsProto.Xcs = append(ocsProto.Xcs, reads[i].Xc)
+		}
 	}
-	ocsProto.Xc = read.Xc
-	log.Lvlf2("%v Public key is: %s", s.ServerIdentity(), ocsProto.Xc)
+	ocsProto.Extra = s.postQuantumShare
 	ocsProto.VerificationData, err = protobuf.Encode(verificationData)
 	if err != nil {
 		return nil,
@@ -592,7 +705,7 @@ func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error
 	s.storage.Lock()
 	ocsProto.Shared = s.storage.Shared[id]
 	pp := s.storage.Polys[id]
-	reply.X = s.storage.Shared[id].X.Clone()
+	X := s.storage.Shared[id].X.Clone()
 	var commits []kyber.Point
 	for _, c := range pp.Commits {
 		commits = append(commits, c.Clone())
@@ -614,14 +727,37 @@ func (s *Service) DecryptKey(dkr *DecryptKey) (reply *DecryptKeyReply, err error
 		return nil, xerrors.New("reencryption got refused")
 	}
 	log.Lvl3("Reencryption protocol is done.")
-	reply.XhatEnc, err = share.RecoverCommit(cothority.Suite, ocsProto.Uis,
-		threshold, nodes)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to recover commit: %v", err)
+
+	replies := make([]*DecryptKeyReply, len(writes))
+	for i := range writes {
+		replies[i] = &DecryptKeyReply{
+			C: writes[i].C,
+			X: X.Clone(),
+		}
+		replies[i].XhatEnc, err = share.RecoverCommit(cothority.Suite,
+			ocsProto.BatchUis[i], threshold, nodes)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to recover commit: %v", err)
+		}
+	}
+	if writes[0].PostQuantum != nil {
+		pqs := writes[0].PostQuantum.Shares
+		for _, buf := range ocsProto.Extras {
+			var pqr PostQuantumReply
+			err = protobuf.DecodeWithConstructors(buf, &pqr,
+				network.DefaultConstructors(cothority.Suite))
+			if err != nil {
+				log.Lvl2("invalid post-quantum share:", err)
+				continue
+			}
+			replies[0].PostQuantum = append(replies[0].PostQuantum, pqr)
+		}
+		if len(replies[0].PostQuantum) < postQuantumThreshold(len(pqs)) {
+			return nil, xerrors.New("not enough post-quantum shares")
+		}
 	}
-	reply.C = write.C
 	log.Lvl3("Successfully reencrypted the key")
-	return
+	return replies, nil
 }
 
 // GetLTSReply returns the CreateLTSReply message of a previous LTS.
@@ -640,6 +776,126 @@ func (s *Service) GetLTSReply(req *GetLTSReply) (*CreateLTSReply, error) {
 	}, nil
 }
 
+// GetPostQuantumKey returns the ML-KEM-768 key of the node for an LTS it is
+// part of. The key is created at random together with the shares of the LTS.
+func (s *Service) GetPostQuantumKey(req *GetPostQuantumKey) (*GetPostQuantumKeyReply, error) {
+	s.storage.Lock()
+	_, ok := s.storage.Shared[req.LTSID]
+	s.storage.Unlock()
+	if !ok {
+		return nil, xerrors.Errorf("didn't find this LTS: %v", req.LTSID)
+	}
+	seed, err := s.postQuantumSeed(req.LTSID)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := mlkemPublic(seed)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := schnorr.Sign(cothority.Suite, s.getKeyPair().Private,
+		postQuantumKeyMessage(req.LTSID, ek))
+	if err != nil {
+		return nil, xerrors.Errorf("signing key: %v", err)
+	}
+	return &GetPostQuantumKeyReply{Key: ek, Signature: sig}, nil
+}
+
+// postQuantumSeed returns the seed of the ML-KEM-768 key of the node for the
+// LTS.
+func (s *Service) postQuantumSeed(ltsid byzcoin.InstanceID) ([]byte, error) {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	seed, ok := s.storage.PostQuantumSeeds[ltsid]
+	if !ok {
+		return nil, xerrors.Errorf("no post-quantum key for LTS %v", ltsid)
+	}
+	return seed, nil
+}
+
+// addPostQuantumSeed creates a random seed for the ML-KEM-768 key of the node
+// for the LTS, unless it already has one. It must be called with the storage
+// locked.
+func (s *Service) addPostQuantumSeed(ltsid byzcoin.InstanceID) error {
+	if _, ok := s.storage.PostQuantumSeeds[ltsid]; ok {
+		return nil
+	}
+	seed := make([]byte, mlkemSeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return xerrors.Errorf("reading random seed: %v", err)
+	}
+	s.storage.PostQuantumSeeds[ltsid] = seed
+	return nil
+}
+
+// postQuantumShare returns the share of the node of a hybrid write,
+// encrypted to the reader, once the request has been verified. It returns
+// nil for the other writes.
+func (s *Service) postQuantumShare(rc *protocol.Reencrypt) ([]byte, error) {
+	var verificationData vData
+	err := protobuf.DecodeWithConstructors(*rc.VerificationData,
+		&verificationData, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("decoding verification data: %v", err)
+	}
+	if verificationData.Write == nil {
+		return nil, nil
+	}
+	var read Read
+	if err := verificationData.Proof.VerifyAndDecode(cothority.Suite,
+		ContractReadID, &read); err != nil {
+		return nil, xerrors.Errorf("decoding read: %v", err)
+	}
+	_, v0, contractID, darcID, err := verificationData.Write.KeyValue()
+	if err != nil {
+		return nil, xerrors.Errorf("proof cannot return values: %v", err)
+	}
+	if contractID != ContractWriteID {
+		return nil, xerrors.New("proof doesn't point to write instance")
+	}
+	if !read.Write.Equal(byzcoin.NewInstanceID(
+		verificationData.Write.InclusionProof.Key())) {
+		return nil, xerrors.New("read doesn't point to passed write")
+	}
+	var write Write
+	err = protobuf.DecodeWithConstructors(v0, &write,
+		network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't decode write data: %v", err)
+	}
+	if write.PostQuantum == nil {
+		return nil, nil
+	}
+
+	seed, err := s.postQuantumSeed(write.LTSID)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := mlkemPublic(seed)
+	if err != nil {
+		return nil, err
+	}
+	i, pqs, err := write.PostQuantum.share(ek)
+	if err != nil {
+		return nil, err
+	}
+	kemKey, err := mlkemDecapsulate(seed, pqs.Encapsulation)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := open(kemKey, postQuantumShareData(write.LTSID, darcID, i),
+		pqs.Share)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := newPostQuantumReply(&read, i, sh)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := protobuf.Encode(reply)
+	return buf, cothority.ErrorOrNil(err, "encoding post-quantum share")
+}
+
 func (s *Service) getKeyPair() *key.Pair {
 	return &key.Pair{
 		Public:  s.ServerIdentity().ServicePublic(ServiceName),
@@ -690,7 +946,12 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 			s.storage.DKS[id] = dks
 			s.storage.Replies[id] = reply
 			s.storage.Rosters[id] = tn.Roster()
+			err = s.addPostQuantumSeed(id)
 			s.storage.Unlock()
+			if err != nil {
+				log.Error(err)
+				return
+			}
 			err = s.save()
 			if err != nil {
 				log.Error(err)
@@ -745,7 +1006,7 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 		}
 
 		// Wait for DKG in reshare mode to end
-		go func(id byzcoin.InstanceID) {
+		go func(bcID skipchain.SkipBlockID, id byzcoin.InstanceID) {
 			// TODO: properly propagate errors during execution of DKG protocol
 			// (see dedis/cothority#2320)
 			<-setupDKG.Finished
@@ -783,9 +1044,23 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 				}
 			}
 			s.storage.Shared[id] = shared
+			s.storage.Polys[id] = &pubPoly{s.Suite().Point().Base(), dks.Commits}
 			s.storage.DKS[id] = dks
 			s.storage.Rosters[id] = roster
+			// New nodes need the reply so that GetLTSReply works on them.
+			if s.storage.Replies[id] == nil {
+				s.storage.Replies[id] = &CreateLTSReply{
+					ByzCoinID:  bcID,
+					InstanceID: id,
+					X:          shared.X,
+				}
+			}
+			err = s.addPostQuantumSeed(id)
 			s.storage.Unlock()
+			if err != nil {
+				log.Error(err)
+				return
+			}
 			err = s.save()
 			if err != nil {
 				log.Fatal(err)
@@ -793,7 +1068,7 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 			if s.afterReshare != nil {
 				s.afterReshare()
 			}
-		}(id)
+		}(cfg.Latest.SkipChainID(), id)
 		return setupDKG, nil
 	case protocol.NameOCS:
 		id := byzcoin.NewInstanceID(conf.Data)
@@ -811,6 +1086,7 @@ func (s *Service) NewProtocol(tn *onet.TreeNodeInstance, conf *onet.GenericConfi
 		ocs := pi.(*protocol.OCS)
 		ocs.Shared = shared
 		ocs.Verify = s.verifyReencryption
+		ocs.Extra = s.postQuantumShare
 		return ocs, nil
 	}
 	return nil, nil
@@ -833,23 +1109,19 @@ func (s *Service) verifyReencryption(rc *protocol.Reencrypt) bool {
 		if err != nil {
 			return xerrors.Errorf("decoding verification data: %v", err)
 		}
-		_, v0, contractID, _, err := verificationData.Proof.KeyValue()
-		if err != nil {
-			return xerrors.Errorf("proof cannot return values: %v", err)
-		}
-		if contractID != ContractReadID {
-			return xerrors.New("proof doesn't point to read instance")
+		if verificationData.Ephemeral != nil {
+			return xerrors.New("ephemeral keys are not supported")
 		}
-		var r Read
-		err = protobuf.DecodeWithConstructors(v0, &r, network.DefaultConstructors(cothority.Suite))
-		if err != nil {
-			return xerrors.Errorf("couldn't decode read data: %v", err)
+		if len(rc.Xcs) == 0 {
+			return verifyReader(&verificationData.Proof, rc.Xc)
 		}
-		if verificationData.Ephemeral != nil {
-			return xerrors.New("ephemeral keys not supported yet")
+		if len(verificationData.Proofs) != len(rc.Xcs) {
+			return xerrors.New("wrong number of read proofs in batch")
 		}
-		if !r.Xc.Equal(rc.Xc) {
-			return xerrors.New("wrong reader")
+		for i := range rc.Xcs {
+			if err := verifyReader(&verificationData.Proofs[i], rc.Xcs[i]); err != nil {
+				return xerrors.Errorf("batch entry %d: %v", i, err)
+			}
 		}
 		return nil
 	}()
@@ -860,6 +1132,27 @@ func (s *Service) verifyReencryption(rc *protocol.Reencrypt) bool {
 	return true
 }
 
+// verifyReader checks that the proof points to a read instance with the
+// public key xc.
+func verifyReader(proof *byzcoin.Proof, xc kyber.Point) error {
+	_, v0, contractID, _, err := proof.KeyValue()
+	if err != nil {
+		return xerrors.Errorf("proof cannot return values: %v", err)
+	}
+	if contractID != ContractReadID {
+		return xerrors.New("proof doesn't point to read instance")
+	}
+	var r Read
+	err = protobuf.DecodeWithConstructors(v0, &r, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return xerrors.Errorf("couldn't decode read data: %v", err)
+	}
+	if !r.Xc.Equal(xc) {
+		return xerrors.New("wrong reader")
+	}
+	return nil
+}
+
 // newService receives the context that holds information about the node it's
 // running on. Saving and loading can be done using the context. The data will
 // be stored in memory for tests and simulations, and on disk for real deployments.
@@ -869,7 +1162,8 @@ func newService(c *onet.Context) (onet.Service, error) {
 		genesisBlocks:    make(map[string]*skipchain.SkipBlock),
 	}
 	if err := s.RegisterHandlers(s.CreateLTS, s.ReshareLTS, s.DecryptKey,
-		s.GetLTSReply, s.Authorise, s.Authorize, s.updateValidPeers); err != nil {
+		s.DecryptKeys, s.GetLTSReply, s.Authorise, s.Authorize, s.updateValidPeers,
+		s.GetPostQuantumKey); err != nil {
 		return nil, xerrors.New("couldn't register messages")
 	}
 	if err := s.tryLoad(); err != nil {
@@ -323,6 +323,61 @@ func TestService_DecryptEphemeralKey(t *testing.T) {
 	require.Equal(t, key1, keyCopy1)
 }
 
+// TestService_DecryptKeyPostQuantum makes a hybrid write and makes sure that
+// the secret can only be recovered with the ML-KEM key of the reader.
+func TestService_DecryptKeyPostQuantum(t *testing.T) {
+	s := newTS(t, 5)
+	defer s.closeAll(t)
+
+	ltsID := s.ltsReply.InstanceID
+	keys := make([][]byte, len(s.ltsRoster.List))
+	for i, si := range s.ltsRoster.List {
+		reply, err := s.services[i].GetPostQuantumKey(&GetPostQuantumKey{LTSID: ltsID})
+		require.NoError(t, err)
+		require.NoError(t, verifyPostQuantumKey(ltsID, si, reply))
+		require.Error(t, verifyPostQuantumKey(ltsID, s.ltsRoster.List[(i+1)%5], reply))
+		keys[i] = reply.Key
+	}
+
+	key1 := []byte("secret key 1")
+	write, err := NewWritePostQuantum(cothority.Suite, ltsID, s.gDarc.GetBaseID(),
+		s.ltsReply.X, keys, key1)
+	require.NoError(t, err)
+	require.NoError(t, write.CheckProof(cothority.Suite, s.gDarc.GetBaseID()))
+	write.PostQuantum.Shares[1].Share[0] ^= 1
+	require.Error(t, write.CheckProof(cothority.Suite, s.gDarc.GetBaseID()))
+	write.PostQuantum.Shares[1].Share[0] ^= 1
+
+	ctr, err := s.cl.GetSignerCounters(s.signer.Identity().String())
+	require.NoError(t, err)
+	prWr := s.waitInstID(t, s.spawnWrite(t, write, ctr.Counters[0]+1))
+	var wr Write
+	require.NoError(t, prWr.VerifyAndDecode(cothority.Suite, ContractWriteID, &wr))
+
+	seed, ek, err := GeneratePostQuantumKey()
+	require.NoError(t, err)
+	prRe := s.waitInstID(t, s.spawnRead(t, prWr, &Read{
+		Write:          byzcoin.NewInstanceID(prWr.InclusionProof.Key()),
+		Xc:             s.signer.Ed25519.Point,
+		PostQuantumKey: ek,
+	}, ctr.Counters[0]+2))
+
+	dkr := DecryptKey{Read: *prRe, Write: *prWr}
+	_, err = s.services[0].DecryptKeys(&DecryptKeys{Requests: []DecryptKey{dkr}})
+	require.Error(t, err)
+	dk, err := s.services[0].DecryptKey(&dkr)
+	require.NoError(t, err)
+	_, err = dk.RecoverKey(s.signer.Ed25519.Secret)
+	require.Error(t, err)
+	otherSeed, _, err := GeneratePostQuantumKey()
+	require.NoError(t, err)
+	_, err = dk.RecoverKeyPostQuantum(s.signer.Ed25519.Secret, otherSeed, &wr)
+	require.Error(t, err)
+	keyCopy, err := dk.RecoverKeyPostQuantum(s.signer.Ed25519.Secret, seed, &wr)
+	require.NoError(t, err)
+	require.Equal(t, key1, keyCopy)
+}
+
 type ts struct {
 	local      *onet.LocalTest
 	servers    []*onet.Server
@@ -339,13 +394,14 @@ type ts struct {
 }
 
 func (s *ts) addRead(t *testing.T, write *byzcoin.Proof, Xc kyber.Point, ctr uint64) byzcoin.InstanceID {
-	var readBuf []byte
-	read := &Read{
+	return s.spawnRead(t, write, &Read{
 		Write: byzcoin.NewInstanceID(write.InclusionProof.Key()),
 		Xc:    Xc,
-	}
-	var err error
-	readBuf, err = protobuf.Encode(read)
+	}, ctr)
+}
+
+func (s *ts) spawnRead(t *testing.T, write *byzcoin.Proof, read *Read, ctr uint64) byzcoin.InstanceID {
+	readBuf, err := protobuf.Encode(read)
 	require.NoError(t, err)
 	ctx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion,
 		byzcoin.Instruction{
@@ -490,6 +546,10 @@ func (s *ts) addWriteAndWait(t *testing.T, key []byte) *byzcoin.Proof {
 
 func (s *ts) addWrite(t *testing.T, key []byte, ctr uint64) byzcoin.InstanceID {
 	write := NewWrite(cothority.Suite, s.ltsReply.InstanceID, s.gDarc.GetBaseID(), s.ltsReply.X, key)
+	return s.spawnWrite(t, write, ctr)
+}
+
+func (s *ts) spawnWrite(t *testing.T, write *Write, ctr uint64) byzcoin.InstanceID {
 	writeBuf, err := protobuf.Encode(write)
 	require.NoError(t, err)
 
@@ -15,7 +15,10 @@ import (
 func init() {
 	network.RegisterMessages(CreateLTS{}, CreateLTSReply{},
 		Authorize{}, AuthorizeReply{},
-		DecryptKey{}, DecryptKeyReply{})
+		ReshareLTS{}, ReshareLTSReply{},
+		DecryptKey{}, DecryptKeyReply{},
+		DecryptKeys{}, DecryptKeysReply{},
+		GetPostQuantumKey{}, GetPostQuantumKeyReply{})
 }
 
 type suite interface {
@@ -39,7 +42,13 @@ type suite interface {
 //   it containing the reader-darc. If it is nil then we failed to embed the
 //   key because it is too long to represent the key using a point.
 func NewWrite(suite suites.Suite, ltsid byzcoin.InstanceID, writeDarc darc.ID, X kyber.Point, key []byte) *Write {
-	wr := &Write{LTSID: ltsid}
+	return newWrite(suite, ltsid, writeDarc, X, key, nil)
+}
+
+// newWrite creates the write-request of key, whose proof also covers the
+// secret of a hybrid write if pq is non-nil.
+func newWrite(suite suites.Suite, ltsid byzcoin.InstanceID, writeDarc darc.ID, X kyber.Point, key []byte, pq *PostQuantumSecret) *Write {
+	wr := &Write{LTSID: ltsid, PostQuantum: pq}
 	r := suite.Scalar().Pick(suite.RandomStream())
 	C := suite.Point().Mul(r, X)
 	wr.U = suite.Point().Mul(r, nil)
@@ -63,6 +72,9 @@ func NewWrite(suite suites.Suite, ltsid byzcoin.InstanceID, writeDarc darc.ID, X
 	w.MarshalTo(hash)
 	wBar.MarshalTo(hash)
 	hash.Write(writeDarc)
+	if wr.PostQuantum != nil {
+		hash.Write(wr.PostQuantum.hash())
+	}
 	wr.E = suite.Scalar().SetBytes(hash.Sum(nil))
 	wr.F = suite.Scalar().Add(s, suite.Scalar().Mul(wr.E, r))
 	return wr
@@ -87,6 +99,9 @@ func (wr *Write) CheckProof(suite suite, writeID darc.ID) error {
 	w.MarshalTo(hash)
 	wBar.MarshalTo(hash)
 	hash.Write(writeID)
+	if wr.PostQuantum != nil {
+		hash.Write(wr.PostQuantum.hash())
+	}
 
 	e := suite.Scalar().SetBytes(hash.Sum(nil))
 	if e.Equal(wr.E) {
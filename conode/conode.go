@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path"
 	"reflect"
@@ -26,14 +27,17 @@ import (
 	cli "github.com/urfave/cli"
 	"go.dedis.ch/cothority/v3"
 	_ "go.dedis.ch/cothority/v3/authprox"
+	_ "go.dedis.ch/cothority/v3/blscosi/thresholdbls"
 	_ "go.dedis.ch/cothority/v3/byzcoin"
 	_ "go.dedis.ch/cothority/v3/byzcoin/contracts"
 	_ "go.dedis.ch/cothority/v3/calypso"
 	_ "go.dedis.ch/cothority/v3/eventlog"
 	_ "go.dedis.ch/cothority/v3/evoting/service"
+	"go.dedis.ch/cothority/v3/metrics"
 	_ "go.dedis.ch/cothority/v3/personhood"
 	_ "go.dedis.ch/cothority/v3/skipchain"
 	status "go.dedis.ch/cothority/v3/status/service"
+	_ "go.dedis.ch/cothority/v3/timestamp"
 	"go.dedis.ch/kyber/v3/util/encoding"
 	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/onet/v3/app"
@@ -93,6 +97,13 @@ func main() {
 			Name:   "server",
 			Usage:  "Start cothority server",
 			Action: runServer,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "metrics",
+					EnvVar: "CONODE_METRICS",
+					Usage:  "address to serve the metrics on for Prometheus, e.g. 127.0.0.1:9100",
+				},
+			},
 		},
 		{
 			Name:      "check",
@@ -156,10 +167,40 @@ func runServer(ctx *cli.Context) error {
 	if raiseFdLimit != nil {
 		raiseFdLimit()
 	}
+	if addr := ctx.String("metrics"); addr != "" {
+		if err := serveMetrics(config, addr); err != nil {
+			return err
+		}
+	}
 	app.RunServer(config)
 	return nil
 }
 
+// serveMetrics serves the metrics of the conode over plain HTTP on
+// addr/metrics, where Prometheus can scrape them. The websocket server of
+// onet doesn't let us add handlers, so it needs its own listener.
+func serveMetrics(config, addr string) error {
+	cfg, err := app.LoadCothority(config)
+	if err != nil {
+		return err
+	}
+	si, err := cfg.GetServerIdentity()
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen for metrics: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.ForServer(si))
+	go func() {
+		log.Error("metrics server stopped:", http.Serve(l, mux))
+	}()
+	log.Lvl1("Serving metrics on http://" + l.Addr().String() + "/metrics")
+	return nil
+}
+
 // checkConfig contacts all servers and verifies if it receives a valid
 // signature from each.
 func checkConfig(c *cli.Context) error {
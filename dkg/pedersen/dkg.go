@@ -29,6 +29,11 @@ type Setup struct {
 	Finished  chan bool
 	Wait      bool
 	NewDKG    func() (*dkgpedersen.DistKeyGenerator, error)
+	// OnCertified is optional and called on every node once its DKG is
+	// certified, or once its deals are sent for a node leaving the group
+	// in a resharing. If Wait is true, the root only finishes after it returned
+	// on all nodes, so it can be used to store the shares.
+	OnCertified func() error
 
 	// KeyPair must be set by the caller, if this is a new DKG, then simply
 	// generate a new KeyPair.
@@ -125,12 +130,21 @@ func (o *Setup) Dispatch() error {
 			return err
 		}
 	}
-	for !o.DKG.Certified() {
+	// A node leaving the group in a resharing doesn't receive any deal,
+	// and never gets the responses of the other nodes to their own deals,
+	// so it is done once its deals are sent.
+	leaving := o.DKG.ExpectedDeals() == 0
+	for !leaving && !o.DKG.Certified() {
 		err := o.allResponse(<-o.structResponse)
 		if err != nil && err.Error() != "vss: already existing response from same origin" {
 			return err
 		}
 	}
+	if o.OnCertified != nil {
+		if err := o.OnCertified(); err != nil {
+			return err
+		}
+	}
 
 	if o.Wait {
 		if o.IsRoot() {
@@ -142,7 +156,7 @@ func (o *Setup) Dispatch() error {
 		}
 	}
 
-	if !o.DKG.Certified() {
+	if !leaving && !o.DKG.Certified() {
 		return errors.New("not certified")
 	}
 
@@ -6,6 +6,8 @@ import (
 	"go.dedis.ch/onet/v3"
 
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/evoting/lib"
+	"go.dedis.ch/cothority/v3/skipchain"
 )
 
 // ServiceName is the identifier of the service (application name).
@@ -39,3 +41,28 @@ func (c *Client) LookupSciper(roster *onet.Roster, sciper string) (reply *Lookup
 	err = c.SendProtobuf(roster.RandomServerIdentity(), &LookupSciper{Sciper: sciper, LookupURL: c.LookupURL}, reply)
 	return
 }
+
+// LookupVoter returns information about a user in the voter directory of the
+// given master skipchain.
+func (c *Client) LookupVoter(roster *onet.Roster, master skipchain.SkipBlockID, user string) (reply *LookupSciperReply, err error) {
+	reply = &LookupSciperReply{}
+	err = c.SendProtobuf(roster.RandomServerIdentity(), &LookupSciper{Sciper: user, LookupURL: c.LookupURL, Master: master}, reply)
+	return
+}
+
+// GetEffectiveBallot asks a node of the roster which ballot of the user
+// counts in the election.
+func (c *Client) GetEffectiveBallot(roster *onet.Roster, id skipchain.SkipBlockID, user uint32) (reply *GetEffectiveBallotReply, err error) {
+	reply = &GetEffectiveBallotReply{}
+	err = c.SendProtobuf(roster.RandomServerIdentity(), &GetEffectiveBallot{ID: id, User: user}, reply)
+	return
+}
+
+// CheckReceipt asks a node of the roster to check that the ballot of the
+// receipt is the one of the voter in the box of the election. It returns an
+// error if the ballot was replaced or is missing.
+func (c *Client) CheckReceipt(roster *onet.Roster, receipt *lib.Receipt) (reply *CheckReceiptReply, err error) {
+	reply = &CheckReceiptReply{}
+	err = c.SendProtobuf(roster.RandomServerIdentity(), &CheckReceipt{Receipt: receipt}, reply)
+	return
+}
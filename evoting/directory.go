@@ -0,0 +1,264 @@
+package evoting
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DefaultDirectory is the name of the directory used by master chains that
+// don't name one. It is the LDAP directory of EPFL.
+const DefaultDirectory = "epfl"
+
+// Voter is what a directory knows about a user.
+type Voter struct {
+	ID       uint32
+	FullName string
+	Email    string
+}
+
+// VoterDirectory looks up the users of an institution. Users are identified by
+// a number, which at EPFL is their SCIPER.
+type VoterDirectory interface {
+	// Lookup returns the voter with the given identifier, or an error if
+	// the directory doesn't know it.
+	Lookup(id uint32) (*Voter, error)
+}
+
+// LDAPDirectory looks up the voters in an LDAP server.
+type LDAPDirectory struct {
+	// URL of the server, like ldaps://ldap.example.com.
+	URL string
+	// BaseDN is where the search starts.
+	BaseDN string
+	// Filter selects the entry of a voter. The identifier of the voter is
+	// put in place of the %d.
+	Filter string
+	// NameAttribute and EmailAttribute are the attributes holding the full
+	// name and the email of the voter.
+	NameAttribute  string
+	EmailAttribute string
+}
+
+// NewEPFLDirectory returns the LDAP directory of EPFL, where the voters are
+// identified by their SCIPER.
+func NewEPFLDirectory() *LDAPDirectory {
+	return &LDAPDirectory{
+		URL:            "ldaps://ldap.epfl.ch",
+		BaseDN:         "o=epfl, c=ch",
+		Filter:         "(&(objectClass=person)(uniqueIdentifier=%d))",
+		NameAttribute:  "displayName",
+		EmailAttribute: "mail",
+	}
+}
+
+// Lookup implements VoterDirectory. If more than one entry matches, the first
+// one is returned.
+func (d *LDAPDirectory) Lookup(id uint32) (*Voter, error) {
+	l, err := ldap.DialURL(d.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	searchRequest := ldap.NewSearchRequest(
+		d.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(d.Filter, id),
+		[]string{d.NameAttribute, d.EmailAttribute},
+		nil,
+	)
+	sr, err := l.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) == 0 {
+		return nil, errors.New("voter not found")
+	}
+	return &Voter{
+		ID:       id,
+		FullName: sr.Entries[0].GetAttributeValue(d.NameAttribute),
+		Email:    sr.Entries[0].GetAttributeValue(d.EmailAttribute),
+	}, nil
+}
+
+// MemoryDirectory holds its voters in memory. It is used for the rolls read
+// from files, and as a stand-in for a real directory in tests.
+type MemoryDirectory struct {
+	sync.Mutex
+	voters map[uint32]Voter
+}
+
+// NewMemoryDirectory returns a directory holding the given voters.
+func NewMemoryDirectory(voters ...Voter) *MemoryDirectory {
+	d := &MemoryDirectory{voters: make(map[uint32]Voter)}
+	for _, v := range voters {
+		d.Add(v)
+	}
+	return d
+}
+
+// Add adds a voter to the directory, replacing the one with the same ID.
+func (d *MemoryDirectory) Add(v Voter) {
+	d.Lock()
+	defer d.Unlock()
+	d.voters[v.ID] = v
+}
+
+// Lookup implements VoterDirectory.
+func (d *MemoryDirectory) Lookup(id uint32) (*Voter, error) {
+	d.Lock()
+	defer d.Unlock()
+	v, ok := d.voters[id]
+	if !ok {
+		return nil, errors.New("voter not found")
+	}
+	return &v, nil
+}
+
+// ReadRoll reads a static roll of voters. A file ending in .toml holds a list
+// of [[Voter]] tables with an ID, FullName and Email. Any other file is read
+// as CSV with one "id,full name,email" line per voter.
+func ReadRoll(file string) (*MemoryDirectory, error) {
+	if strings.HasSuffix(file, ".toml") {
+		var roll struct {
+			Voter []Voter
+		}
+		if _, err := toml.DecodeFile(file, &roll); err != nil {
+			return nil, fmt.Errorf("couldn't read roll %s: %v", file, err)
+		}
+		return NewMemoryDirectory(roll.Voter...), nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readCSVRoll(f)
+}
+
+func readCSVRoll(r io.Reader) (*MemoryDirectory, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	d := NewMemoryDirectory()
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return d, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.ParseUint(rec[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid voter id %q: %v", rec[0], err)
+		}
+		d.Add(Voter{ID: uint32(id), FullName: rec[1], Email: rec[2]})
+	}
+}
+
+var directories = struct {
+	sync.Mutex
+	m map[string]VoterDirectory
+}{m: map[string]VoterDirectory{DefaultDirectory: NewEPFLDirectory()}}
+
+// RegisterDirectory makes a directory available to the master chains under
+// the given name. A directory registered with the same name is replaced.
+func RegisterDirectory(name string, d VoterDirectory) {
+	directories.Lock()
+	defer directories.Unlock()
+	directories.m[name] = d
+}
+
+// GetDirectory returns the directory registered with the given name. An
+// empty name returns the DefaultDirectory.
+func GetDirectory(name string) (VoterDirectory, error) {
+	if name == "" {
+		name = DefaultDirectory
+	}
+	directories.Lock()
+	defer directories.Unlock()
+	d, ok := directories.m[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown voter directory %q", name)
+	}
+	return d, nil
+}
+
+// DirectoryConfig describes the directories of a conode. It is read from
+// a TOML file with LoadDirectories:
+//
+//	[[LDAP]]
+//	Name = "example"
+//	URL = "ldaps://ldap.example.com"
+//	BaseDN = "dc=example,dc=com"
+//	Filter = "(&(objectClass=person)(employeeNumber=%d))"
+//	NameAttribute = "cn"
+//	EmailAttribute = "mail"
+//
+//	[[Roll]]
+//	Name = "club"
+//	File = "club.csv"
+type DirectoryConfig struct {
+	LDAP []struct {
+		Name           string
+		URL            string
+		BaseDN         string
+		Filter         string
+		NameAttribute  string
+		EmailAttribute string
+	}
+	Roll []struct {
+		Name string
+		// File is the roll, relative to the configuration file.
+		File string
+	}
+}
+
+// LoadDirectories reads the directories described in the TOML file and
+// registers them.
+func LoadDirectories(file string) error {
+	var cfg DirectoryConfig
+	if _, err := toml.DecodeFile(file, &cfg); err != nil {
+		return fmt.Errorf("couldn't read directories from %s: %v", file, err)
+	}
+	for _, l := range cfg.LDAP {
+		if l.Name == "" {
+			return errors.New("directory without a name")
+		}
+		RegisterDirectory(l.Name, &LDAPDirectory{
+			URL:            l.URL,
+			BaseDN:         l.BaseDN,
+			Filter:         l.Filter,
+			NameAttribute:  l.NameAttribute,
+			EmailAttribute: l.EmailAttribute,
+		})
+	}
+	for _, r := range cfg.Roll {
+		if r.Name == "" {
+			return errors.New("directory without a name")
+		}
+		roll := r.File
+		if !filepath.IsAbs(roll) {
+			roll = filepath.Join(filepath.Dir(file), roll)
+		}
+		d, err := ReadRoll(roll)
+		if err != nil {
+			return err
+		}
+		RegisterDirectory(r.Name, d)
+	}
+	return nil
+}
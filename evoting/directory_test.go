@@ -0,0 +1,102 @@
+package evoting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDirectory(t *testing.T) {
+	d := NewMemoryDirectory(Voter{ID: 1, FullName: "One"})
+	v, err := d.Lookup(1)
+	require.NoError(t, err)
+	require.Equal(t, "One", v.FullName)
+	_, err = d.Lookup(2)
+	require.Error(t, err)
+
+	d.Add(Voter{ID: 2, FullName: "Two"})
+	v, err = d.Lookup(2)
+	require.NoError(t, err)
+	require.Equal(t, "Two", v.FullName)
+}
+
+func TestReadCSVRoll(t *testing.T) {
+	d, err := readCSVRoll(strings.NewReader(`# id,name,email
+123456,"Doe, Jane",jane@example.com
+7, John Doe, john@example.com
+`))
+	require.NoError(t, err)
+	v, err := d.Lookup(123456)
+	require.NoError(t, err)
+	require.Equal(t, &Voter{ID: 123456, FullName: "Doe, Jane", Email: "jane@example.com"}, v)
+	v, err = d.Lookup(7)
+	require.NoError(t, err)
+	require.Equal(t, "John Doe", v.FullName)
+
+	_, err = readCSVRoll(strings.NewReader("x,name,email\n"))
+	require.Error(t, err)
+	_, err = readCSVRoll(strings.NewReader("1,name\n"))
+	require.Error(t, err)
+}
+
+func TestLoadDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "evoting")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "club.csv"),
+		[]byte("1,Club Member,member@example.com\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "staff.toml"),
+		[]byte("[[Voter]]\nID = 2\nFullName = \"Staff Member\"\nEmail = \"staff@example.com\"\n"), 0600))
+	cfg := filepath.Join(dir, "directories.toml")
+	require.NoError(t, ioutil.WriteFile(cfg, []byte(`
+[[LDAP]]
+Name = "test-ldap"
+URL = "ldaps://ldap.example.com"
+BaseDN = "dc=example,dc=com"
+Filter = "(employeeNumber=%d)"
+NameAttribute = "cn"
+EmailAttribute = "mail"
+
+[[Roll]]
+Name = "test-club"
+File = "club.csv"
+
+[[Roll]]
+Name = "test-staff"
+File = "staff.toml"
+`), 0600))
+	require.NoError(t, LoadDirectories(cfg))
+
+	d, err := GetDirectory("test-ldap")
+	require.NoError(t, err)
+	require.Equal(t, &LDAPDirectory{
+		URL:            "ldaps://ldap.example.com",
+		BaseDN:         "dc=example,dc=com",
+		Filter:         "(employeeNumber=%d)",
+		NameAttribute:  "cn",
+		EmailAttribute: "mail",
+	}, d)
+
+	d, err = GetDirectory("test-club")
+	require.NoError(t, err)
+	v, err := d.Lookup(1)
+	require.NoError(t, err)
+	require.Equal(t, "Club Member", v.FullName)
+
+	d, err = GetDirectory("test-staff")
+	require.NoError(t, err)
+	v, err = d.Lookup(2)
+	require.NoError(t, err)
+	require.Equal(t, "staff@example.com", v.Email)
+
+	d, err = GetDirectory("")
+	require.NoError(t, err)
+	require.Equal(t, NewEPFLDirectory(), d)
+	_, err = GetDirectory("unknown")
+	require.Error(t, err)
+}
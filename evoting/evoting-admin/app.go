@@ -14,6 +14,7 @@ import (
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/evoting"
+	"go.dedis.ch/cothority/v3/evoting/lib"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/util/key"
@@ -25,6 +26,7 @@ import (
 var (
 	argRoster       = flag.String("roster", "", "path to roster toml file")
 	argAdmins       = flag.String("admins", "", "list of admin users")
+	argDirectory    = flag.String("directory", "", "name of the voter directory of the conodes to use (default: epfl)")
 	argPin          = flag.String("pin", "", "service pin")
 	argKey          = flag.String("key", "", "public key of authentication server")
 	argID           = flag.String("id", "", "ID of the master chain to modify (optional)")
@@ -35,9 +37,14 @@ var (
 	argDumpElection = flag.Bool("dumpelection", false, "Dump the current election config for the election specified with -id.")
 	argJSON         = flag.Bool("json", false, "Dump in json mode.")
 	argLoad         = flag.String("load", "", "Load the specified json file to modify the election specified with -id.")
+	argVerify       = flag.Bool("verify", false, "Verify the election specified with -id from its skipchain and print the audit report in json.")
 )
 
 func main() {
+	// "evoting-admin verify -id ..." is the same as "evoting-admin -verify -id ...".
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Args[1] = "-verify"
+	}
 	flag.Parse()
 
 	if *argRoster == "" {
@@ -63,6 +70,31 @@ func main() {
 		fmt.Printf(" Admins: %v\n", m.Admins)
 		fmt.Printf(" Roster: %v\n", m.Roster.List)
 		fmt.Printf("    Key: %v\n", m.Key)
+		fmt.Printf("    Dir: %v\n", m.Directory)
+		return
+	}
+
+	if *argVerify {
+		id, err := hex.DecodeString(*argID)
+		if err != nil {
+			log.Fatal("id decode", err)
+		}
+		blocks, err := lib.DownloadElection(roster, id)
+		if err != nil {
+			log.Fatal("cannot download election: ", err)
+		}
+		audit, err := lib.AuditElection(blocks)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := json.MarshalIndent(audit, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(b))
+		if !audit.Valid {
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -219,7 +251,7 @@ func main() {
 		pub = kp.Public
 	}
 
-	request := &evoting.Link{Pin: *argPin, Roster: roster, Key: pub, Admins: admins}
+	request := &evoting.Link{Pin: *argPin, Roster: roster, Key: pub, Admins: admins, Directory: *argDirectory}
 	if *argID != "" {
 		id, err := hex.DecodeString(*argID)
 		if err != nil {
@@ -0,0 +1,355 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+/*
+An election can be verified by anybody holding a copy of its skipchain. The
+audit doesn't trust the nodes: it checks the blocks are linked and signed by
+the roster, that the transactions were signed by the leader or the node that
+created them, and then redoes the work of the election on its own. It rebuilds
+the box, verifies the proof of every shuffle and of every partial decryption,
+reconstructs the ballots and tallies them.
+*/
+
+// Audit is the report of the verification of an election skipchain. It is
+// meant to be read by programs, so it marshals to JSON as it is.
+type Audit struct {
+	Election string // Election is the ID of the election skipchain, in hex.
+	Blocks   int    // Blocks is the number of blocks of the skipchain.
+	Ballots  int    // Ballots is the number of ballots cast, including the replaced ones.
+	Box      int    // Box is the number of ballots in the box, the last one of each voter.
+
+	Mixes    []*AuditStep // Mixes are the shuffles, in order.
+	Partials []*AuditStep // Partials are the partial decryptions, in order.
+
+	Result *Result // Result is the tally of the ballots computed by the audit.
+	Stored bool    // Stored is true if the skipchain holds a result, which must be equal to Result.
+
+	Errors []string // Errors are all the problems found.
+	Valid  bool     // Valid is true if no problem was found.
+}
+
+// AuditStep is the verification of a shuffle or a partial decryption.
+type AuditStep struct {
+	Block    int    // Block is the index of the block holding the step.
+	Node     string // Node is the address of the node that did the step.
+	Verified bool   // Verified is true if the proof of the step is valid.
+	Error    string // Error tells why the step isn't verified.
+}
+
+func (a *Audit) errorf(format string, args ...interface{}) {
+	a.Errors = append(a.Errors, fmt.Sprintf(format, args...))
+}
+
+// DownloadElection fetches all the blocks of an election skipchain from the
+// roster. The forward links of every block are verified on the way.
+func DownloadElection(roster *onet.Roster, id skipchain.SkipBlockID) ([]*skipchain.SkipBlock, error) {
+	client := skipchain.NewClient()
+	var blocks []*skipchain.SkipBlock
+	for {
+		reply, err := client.GetSingleBlockByIndex(roster, id, len(blocks))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, reply.SkipBlock)
+		if len(reply.SkipBlock.ForwardLink) == 0 {
+			return blocks, nil
+		}
+	}
+}
+
+// AuditElection verifies the blocks of an election skipchain, from the
+// genesis block on, and returns the report of the audit. Problems with the
+// election are reported in the audit, an error is only returned if there are
+// no blocks at all.
+func AuditElection(blocks []*skipchain.SkipBlock) (*Audit, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks to audit")
+	}
+	audit := &Audit{
+		Election: fmt.Sprintf("%x", []byte(blocks[0].Hash)),
+		Blocks:   len(blocks),
+	}
+	defer func() {
+		audit.Valid = len(audit.Errors) == 0
+	}()
+
+	var election *Election
+	var ballots []*Ballot
+	var indexes []int
+	var dedup *Dedup
+	var mixes []*Mix
+	var partials []*Partial
+	var stored *Result
+	for i, block := range blocks {
+		if err := auditBlock(blocks, i); err != nil {
+			audit.errorf("block %d: %v", i, err)
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		t := UnmarshalTransaction(block.Data)
+		if t == nil {
+			audit.errorf("block %d: cannot decode transaction", i)
+			continue
+		}
+
+		// The leader of the roster signs the transactions it stores, only
+		// mixes and partials are signed by the nodes creating them.
+		if t.Mix == nil && t.Partial == nil {
+			msg := make([]byte, 8)
+			binary.LittleEndian.PutUint64(msg, uint64(block.Index))
+			msg = append(msg, t.Hash()...)
+			leader := blocks[i-1].Roster.List[0].Public
+			if err := schnorr.Verify(cothority.Suite, leader, msg, t.Signature); err != nil {
+				audit.errorf("block %d: invalid signature of the leader: %v", i, err)
+				continue
+			}
+		}
+
+		switch {
+		case t.Election != nil:
+			if len(ballots) > 0 || len(mixes) > 0 {
+				audit.errorf("block %d: election changed after ballots were cast", i)
+				continue
+			}
+			if t.Election.Roster == nil || len(t.Election.Roster.List) == 0 {
+				audit.errorf("block %d: election without a roster", i)
+				continue
+			}
+			if !t.Election.ID.Equal(blocks[0].Hash) {
+				audit.errorf("block %d: election of another skipchain", i)
+				continue
+			}
+			election = t.Election
+		case t.Ballot != nil:
+			if election == nil {
+				audit.errorf("block %d: ballot before the election", i)
+				continue
+			}
+			if err := auditBallot(election, t); err != nil {
+				audit.errorf("block %d: %v", i, err)
+				continue
+			}
+			if len(mixes) > 0 || dedup != nil {
+				audit.errorf("block %d: ballot cast after the shuffle", i)
+				continue
+			}
+			if election.Revoting == NoRevoting {
+				for _, b := range ballots {
+					if b.User == t.Ballot.User {
+						audit.errorf("block %d: user %d voted twice", i, b.User)
+					}
+				}
+			}
+			ballots = append(ballots, t.Ballot)
+			indexes = append(indexes, i)
+		case t.Dedup != nil:
+			if dedup != nil || len(mixes) > 0 {
+				audit.errorf("block %d: ballots deduplicated after the shuffle", i)
+				continue
+			}
+			dedup = t.Dedup
+		case t.Mix != nil:
+			mixes = append(mixes, t.Mix)
+			audit.Mixes = append(audit.Mixes, &AuditStep{Block: i})
+		case t.Partial != nil:
+			partials = append(partials, t.Partial)
+			audit.Partials = append(audit.Partials, &AuditStep{Block: i})
+		case t.Result != nil:
+			if stored != nil {
+				audit.errorf("block %d: election tallied twice", i)
+				continue
+			}
+			stored = t.Result
+		default:
+			audit.errorf("block %d: unexpected transaction", i)
+		}
+	}
+	if election == nil {
+		audit.errorf("no election in the skipchain")
+		return audit, nil
+	}
+	audit.Ballots = len(ballots)
+	box := election.box(ballots)
+	audit.Box = len(box.Ballots)
+	if dedup != nil {
+		expected, err := election.NewDedup(ballots, indexes)
+		if err != nil || !equalDedups(dedup, expected) {
+			audit.errorf("the deduplication doesn't match the ballots")
+		}
+	}
+
+	in := box.Ballots
+	nodes := make(map[string]bool)
+	for i, mix := range mixes {
+		step := audit.Mixes[i]
+		step.Node, step.Error = auditSigner(election, mix.NodeID, mix.Signature, nodes)
+		if step.Error == "" {
+			if err := VerifyMix(mix.Proof, election.Key, in, mix.Ballots); err != nil {
+				step.Error = err.Error()
+			}
+		}
+		step.Verified = step.Error == ""
+		if !step.Verified {
+			audit.errorf("block %d: invalid shuffle: %s", step.Block, step.Error)
+		}
+		in = mix.Ballots
+	}
+
+	threshold := 2*len(election.Roster.List)/3 + 1
+	if len(mixes) < threshold {
+		if len(partials) > 0 || stored != nil {
+			audit.errorf("election decrypted before being shuffled")
+		}
+		return audit, nil
+	}
+
+	nodes = make(map[string]bool)
+	var valid []*Partial
+	for i, partial := range partials {
+		step := audit.Partials[i]
+		step.Node, step.Error = auditSigner(election, partial.NodeID, partial.Signature, nodes)
+		if step.Error == "" {
+			if len(election.Commits) == 0 {
+				step.Error = "the election has no commits to verify the partial"
+			} else {
+				index, _ := election.Roster.Search(partial.NodeID)
+				if err := VerifyPartial(election.Commits, index, in, partial); err != nil {
+					step.Error = err.Error()
+				}
+			}
+		}
+		step.Verified = step.Error == ""
+		if !step.Verified {
+			audit.errorf("block %d: invalid partial decryption: %s", step.Block, step.Error)
+			continue
+		}
+		valid = append(valid, partial)
+	}
+	if len(valid) < threshold {
+		if stored != nil {
+			audit.errorf("election tallied without enough valid partial decryptions")
+		}
+		return audit, nil
+	}
+
+	points, err := Reconstruct(election.Roster, valid)
+	if err != nil {
+		audit.errorf("cannot reconstruct the ballots: %v", err)
+		return audit, nil
+	}
+	audit.Result, err = Tally(election, points)
+	if err != nil {
+		audit.errorf("cannot tally the ballots: %v", err)
+		return audit, nil
+	}
+	if stored != nil {
+		audit.Stored = true
+		if !equalResults(stored, audit.Result) {
+			audit.errorf("the stored result doesn't match the ballots")
+		}
+	}
+	return audit, nil
+}
+
+// auditBlock checks the hash and the forward links of the i-th block, and
+// that it follows the previous one.
+func auditBlock(blocks []*skipchain.SkipBlock, i int) error {
+	block := blocks[i]
+	if block.Index != i {
+		return fmt.Errorf("block has index %d", block.Index)
+	}
+	if err := block.VerifyForwardSignatures(); err != nil {
+		return err
+	}
+	if i == 0 {
+		return nil
+	}
+	if !block.SkipChainID().Equal(blocks[0].Hash) {
+		return errors.New("block of another skipchain")
+	}
+	prev := blocks[i-1]
+	if prev.Roster == nil || len(prev.Roster.List) == 0 {
+		return errors.New("previous block has no roster")
+	}
+	if len(prev.ForwardLink) == 0 || !prev.ForwardLink[0].To.Equal(block.Hash) {
+		return errors.New("previous block doesn't link to the block")
+	}
+	if len(block.BackLinkIDs) == 0 || !block.BackLinkIDs[0].Equal(prev.Hash) {
+		return errors.New("block doesn't link back to the previous block")
+	}
+	return nil
+}
+
+// auditBallot checks the ballot transaction like the nodes do when it is
+// cast, except for the dates which the skipchain doesn't record.
+func auditBallot(e *Election, t *Transaction) error {
+	b := t.Ballot
+	if t.User != b.User {
+		return errors.New("ballot user-id differs from transaction user-id")
+	}
+	if !e.IsUser(b.User) {
+		return fmt.Errorf("user %d can't vote", b.User)
+	}
+	if b.Width() != e.ChoicePoints() || len(b.ExtraAlpha) != len(b.ExtraBeta) {
+		return fmt.Errorf("ballot of user %d doesn't have %d points", b.User, e.ChoicePoints())
+	}
+	null := cothority.Suite.Point().Null()
+	alpha, beta := b.Pairs()
+	for i := range alpha {
+		if alpha[i] == nil || beta[i] == nil || alpha[i].Equal(null) || beta[i].Equal(null) {
+			return fmt.Errorf("ballot of user %d has null points", b.User)
+		}
+	}
+	return nil
+}
+
+// auditSigner checks that the node is in the roster of the election, that
+// it signed its public key, and that it didn't appear before in nodes. It
+// returns the address of the node and an error message.
+func auditSigner(e *Election, id network.ServerIdentityID, sig []byte, nodes map[string]bool) (string, string) {
+	_, node := e.Roster.Search(id)
+	if node == nil {
+		return "", "node not in the roster"
+	}
+	address := node.Address.String()
+	data, err := node.Public.MarshalBinary()
+	if err != nil {
+		return address, err.Error()
+	}
+	if err := schnorr.Verify(cothority.Suite, node.Public, data, sig); err != nil {
+		return address, "invalid signature of the node: " + err.Error()
+	}
+	if nodes[address] {
+		return address, "node already took part"
+	}
+	nodes[address] = true
+	return address, ""
+}
+
+func equalResults(a, b *Result) bool {
+	got, err := protobuf.Encode(a)
+	if err != nil {
+		return false
+	}
+	want, err := protobuf.Encode(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(got, want)
+}
@@ -0,0 +1,200 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+// candidatesPerPoint is the number of candidates of a ballot embedded in one
+// point. Every candidate takes 3 bytes, little-endian, like the SCIPERs the
+// front-end puts in plurality ballots.
+const candidatesPerPoint = 9
+
+// emptyChunk is embedded in the points without candidates, as a point
+// embedding no data cannot be decoded. Its length is not a multiple of 3, so
+// it cannot be mistaken for candidates.
+var emptyChunk = []byte{0}
+
+// Vote is a decrypted ballot.
+type Vote struct {
+	Choices []uint32 // Choices are the chosen candidates, in order of preference for ranked ballots.
+	Weight  uint32   // Weight of the voter.
+}
+
+// Weighted returns true if the users of the election have weights.
+func (e *Election) Weighted() bool {
+	return len(e.Weights) > 0
+}
+
+// Weight returns the weight of the user, which is 1 if the election has no
+// weights, and 0 if the user can't vote.
+func (e *Election) Weight(user uint32) uint32 {
+	for i, u := range e.Users {
+		if u == user {
+			if !e.Weighted() {
+				return 1
+			}
+			return e.Weights[i]
+		}
+	}
+	return 0
+}
+
+// ChoicePoints returns the number of ElGamal pairs the voters encrypt their
+// choices in.
+func (e *Election) ChoicePoints() int {
+	if e.Type != Ranked || e.MaxChoices <= candidatesPerPoint {
+		return 1
+	}
+	return (e.MaxChoices + candidatesPerPoint - 1) / candidatesPerPoint
+}
+
+// Width returns the number of ElGamal pairs of the ballots once they are in
+// the box, including the weight of the voter for weighted elections.
+func (e *Election) Width() int {
+	if e.Weighted() {
+		return e.ChoicePoints() + 1
+	}
+	return e.ChoicePoints()
+}
+
+func (e *Election) seats() int {
+	if e.Seats < 1 {
+		return 1
+	}
+	return e.Seats
+}
+
+// EncryptBallot encrypts the choices of a user with the key of the election.
+// For ranked elections, the choices are in order of preference.
+func (e *Election) EncryptBallot(user uint32, choices []uint32) (*Ballot, error) {
+	ballot, _, err := e.EncryptBallotOpening(user, choices)
+	return ballot, err
+}
+
+// Opening holds the ephemeral secrets of the pairs of a ballot. It reveals
+// the choices of the ballot, which must then not be cast.
+type Opening struct {
+	Secrets []kyber.Scalar
+}
+
+// EncryptBallotOpening is EncryptBallot, returning also the opening of the
+// ballot, to prove what the ballot encrypts if it is challenged.
+func (e *Election) EncryptBallotOpening(user uint32, choices []uint32) (*Ballot, *Opening, error) {
+	points := e.ChoicePoints()
+	if len(choices) > points*candidatesPerPoint {
+		return nil, nil, errors.New("too many choices for the ballot")
+	}
+	alpha := make([]kyber.Point, points)
+	beta := make([]kyber.Point, points)
+	opening := &Opening{Secrets: make([]kyber.Scalar, points)}
+	for i := range alpha {
+		var data []byte
+		for j := i * candidatesPerPoint; j < len(choices) && j < (i+1)*candidatesPerPoint; j++ {
+			c := choices[j]
+			if c >= 1<<24 {
+				return nil, nil, fmt.Errorf("candidate %d doesn't fit in 3 bytes", c)
+			}
+			data = append(data, byte(c), byte(c>>8), byte(c>>16))
+		}
+		if len(data) == 0 {
+			data = emptyChunk
+		}
+		alpha[i], beta[i], opening.Secrets[i] = encrypt(e.Key, data)
+	}
+	return NewBallot(user, alpha, beta), opening, nil
+}
+
+// OpenBallot checks the opening of a ballot encrypted with the key of the
+// election and returns the choices of the ballot.
+func (e *Election) OpenBallot(ballot *Ballot, opening *Opening) ([]uint32, error) {
+	alpha, beta := ballot.Pairs()
+	if len(opening.Secrets) != len(alpha) {
+		return nil, errors.New("the opening doesn't have a secret for every pair")
+	}
+	var choices []uint32
+	for i, k := range opening.Secrets {
+		if k == nil || alpha[i] == nil || beta[i] == nil {
+			return nil, errors.New("missing secret or point")
+		}
+		if !cothority.Suite.Point().Mul(k, nil).Equal(alpha[i]) {
+			return nil, fmt.Errorf("secret %d doesn't open the ballot", i)
+		}
+		m := cothority.Suite.Point().Sub(beta[i], cothority.Suite.Point().Mul(k, e.Key))
+		c, err := decodeChoices(m)
+		if err != nil {
+			return nil, fmt.Errorf("pair %d doesn't encrypt candidates", i)
+		}
+		choices = append(choices, c...)
+	}
+	return choices, nil
+}
+
+// DecodeVotes decodes the decrypted points of the ballots, which follow
+// each other like in the partials. A ballot whose choices cannot be decoded
+// is returned as nil.
+func (e *Election) DecodeVotes(points []kyber.Point) ([]*Vote, error) {
+	width := e.Width()
+	if len(points)%width != 0 {
+		return nil, errors.New("the number of points is not a multiple of the ballot width")
+	}
+	votes := make([]*Vote, len(points)/width)
+	for i := range votes {
+		ballot := points[i*width : (i+1)*width]
+		weight := uint32(1)
+		if e.Weighted() {
+			data, err := ballot[width-1].Data()
+			if err != nil || len(data) != 4 {
+				return nil, errors.New("invalid weight in ballot")
+			}
+			weight = binary.LittleEndian.Uint32(data)
+			ballot = ballot[:width-1]
+		}
+
+		vote := &Vote{Weight: weight}
+		for _, p := range ballot {
+			c, err := decodeChoices(p)
+			if err != nil {
+				vote = nil
+				break
+			}
+			vote.Choices = append(vote.Choices, c...)
+		}
+		votes[i] = vote
+	}
+	return votes, nil
+}
+
+// decodeChoices decodes the candidates embedded in a point, 3 bytes each, or
+// none if the point embeds emptyChunk.
+func decodeChoices(p kyber.Point) ([]uint32, error) {
+	data, err := p.Data()
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, emptyChunk) {
+		return nil, nil
+	}
+	if len(data)%3 != 0 {
+		return nil, errors.New("invalid length of candidates")
+	}
+	var choices []uint32
+	for j := 0; j+2 < len(data); j += 3 {
+		choices = append(choices, uint32(data[j])|uint32(data[j+1])<<8|uint32(data[j+2])<<16)
+	}
+	return choices, nil
+}
+
+// weightPoint embeds the weight in a point without randomness, so that every
+// node computes the same point.
+func weightPoint(weight uint32) kyber.Point {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, weight)
+	return cothority.Suite.Point().Embed(data, cothority.Suite.XOF(data))
+}
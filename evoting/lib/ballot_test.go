@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenBallot(t *testing.T) {
+	_, X := RandomKeyPair()
+	e := &Election{Key: X, Type: Ranked, MaxChoices: 12}
+	choices := []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+
+	ballot, opening, err := e.EncryptBallotOpening(7, choices)
+	require.NoError(t, err)
+	require.Equal(t, 2, ballot.Width())
+	opened, err := e.OpenBallot(ballot, opening)
+	require.NoError(t, err)
+	require.Equal(t, choices, opened)
+
+	// The opening of another ballot doesn't open it.
+	_, other, err := e.EncryptBallotOpening(7, choices)
+	require.NoError(t, err)
+	_, err = e.OpenBallot(ballot, other)
+	require.Error(t, err)
+
+	_, err = e.OpenBallot(ballot, &Opening{Secrets: opening.Secrets[:1]})
+	require.Error(t, err)
+}
+
+func TestBallotHash(t *testing.T) {
+	_, X := RandomKeyPair()
+	b1 := genBox(X, 1).Ballots[0]
+	b2 := *b1
+	require.Equal(t, b1.Hash(), b2.Hash())
+	b2.User++
+	require.NotEqual(t, b1.Hash(), b2.Hash())
+	b2 = *b1
+	b2.Beta = b1.Alpha
+	require.NotEqual(t, b1.Hash(), b2.Hash())
+}
@@ -1,11 +1,15 @@
 package lib
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/proof"
+	"go.dedis.ch/kyber/v3/proof/dleq"
+	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/kyber/v3/share/dkg/rabin"
-	"go.dedis.ch/kyber/v3/shuffle"
-	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3/network"
 
 	"go.dedis.ch/cothority/v3"
@@ -18,6 +22,51 @@ type Ballot struct {
 	// ElGamal ciphertext pair.
 	Alpha kyber.Point
 	Beta  kyber.Point
+
+	// ExtraAlpha and ExtraBeta are the further ElGamal pairs of ballots
+	// that don't fit in one point, like long rankings and voter weights.
+	ExtraAlpha []kyber.Point
+	ExtraBeta  []kyber.Point
+}
+
+// Width returns the number of ElGamal pairs of the ballot.
+func (b *Ballot) Width() int {
+	return 1 + len(b.ExtraAlpha)
+}
+
+// Pairs returns all the ElGamal pairs of the ballot.
+func (b *Ballot) Pairs() (alpha, beta []kyber.Point) {
+	alpha = append([]kyber.Point{b.Alpha}, b.ExtraAlpha...)
+	beta = append([]kyber.Point{b.Beta}, b.ExtraBeta...)
+	return
+}
+
+// Hash returns the hash of the user and of the pairs of the ballot.
+func (b *Ballot) Hash() []byte {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, b.User)
+	alpha, beta := b.Pairs()
+	for i := range alpha {
+		for _, p := range []kyber.Point{alpha[i], beta[i]} {
+			if p == nil {
+				h.Write([]byte{0})
+				continue
+			}
+			p.MarshalTo(h)
+		}
+	}
+	return h.Sum(nil)
+}
+
+// NewBallot creates a ballot out of a non-empty list of ElGamal pairs.
+func NewBallot(user uint32, alpha, beta []kyber.Point) *Ballot {
+	return &Ballot{
+		User:       user,
+		Alpha:      alpha[0],
+		Beta:       beta[0],
+		ExtraAlpha: alpha[1:],
+		ExtraBeta:  beta[1:],
+	}
 }
 
 // Box is a wrapper around a list of encrypted ballots.
@@ -29,15 +78,14 @@ type Box struct {
 func (b *Box) genMix(key kyber.Point, n int) []*Mix {
 	mixes := make([]*Mix, n)
 
-	x, y := Split(b.Ballots)
+	ballots := b.Ballots
 	for i := range mixes {
-		v, w, prover := shuffle.Shuffle(cothority.Suite, nil, key, x, y, random.New())
-		proof, _ := proof.HashProve(cothority.Suite, "", prover)
+		shuffled, proof, _ := ShuffleBallots(key, ballots)
 		mixes[i] = &Mix{
-			Ballots: Combine(v, w),
+			Ballots: shuffled,
 			Proof:   proof,
 		}
-		x, y = v, w
+		ballots = shuffled
 	}
 	return mixes
 }
@@ -53,10 +101,12 @@ type Mix struct {
 
 // Partial contains the partially decrypted ballots.
 type Partial struct {
-	Points []kyber.Point // Points are the partially decrypted plaintexts.
+	Points []kyber.Point // Points are the partially decrypted plaintexts, all the pairs of a ballot one after the other.
 
 	NodeID    network.ServerIdentityID // NodeID is the node having signed the partial
 	Signature []byte                   // Signature of the public key
+
+	Proofs []*dleq.Proof // Proofs that the Points are decrypted with the share of the node, one per point.
 }
 
 // genPartials generates partial decryptions for a given list of shared secrets.
@@ -65,17 +115,66 @@ func (m *Mix) genPartials(dkgs []*dkg.DistKeyGenerator) []*Partial {
 
 	for i, gen := range dkgs {
 		secret, _ := NewSharedSecret(gen)
-		points := make([]kyber.Point, len(m.Ballots))
-		for j, ballot := range m.Ballots {
-			points[j] = Decrypt(secret.V, ballot.Alpha, ballot.Beta)
-		}
+		points, proofs, _ := DecryptBallots(secret.V, m.Ballots)
 		partials[i] = &Partial{
 			Points: points,
+			Proofs: proofs,
 		}
 	}
 	return partials
 }
 
+// DecryptBallots partially decrypts all the pairs of the ballots with a
+// share of the secret. The points of a ballot follow each other. Every point
+// comes with a proof that it was decrypted with the share whose public
+// counterpart is committed to in the election.
+func DecryptBallots(secret kyber.Scalar, ballots []*Ballot) ([]kyber.Point, []*dleq.Proof, error) {
+	var points []kyber.Point
+	var proofs []*dleq.Proof
+	for _, ballot := range ballots {
+		alpha, beta := ballot.Pairs()
+		for i := range alpha {
+			proof, _, _, err := dleq.NewDLEQProof(cothority.Suite, nil, alpha[i], secret)
+			if err != nil {
+				return nil, nil, err
+			}
+			points = append(points, Decrypt(secret, alpha[i], beta[i]))
+			proofs = append(proofs, proof)
+		}
+	}
+	return points, proofs, nil
+}
+
+// VerifyPartial verifies the proofs of the partial decryption of the ballots
+// by the node at the given index of the roster, using the commits of the
+// election.
+func VerifyPartial(commits []kyber.Point, index int, ballots []*Ballot, partial *Partial) error {
+	if len(commits) == 0 {
+		return errors.New("no commits to verify the partial against")
+	}
+	public := share.NewPubPoly(cothority.Suite, nil, commits).Eval(index).V
+
+	var alpha, beta []kyber.Point
+	for _, ballot := range ballots {
+		a, b := ballot.Pairs()
+		alpha = append(alpha, a...)
+		beta = append(beta, b...)
+	}
+	if len(partial.Points) != len(alpha) || len(partial.Proofs) != len(alpha) {
+		return errors.New("partial doesn't have a point and a proof for every pair")
+	}
+	for i := range alpha {
+		if partial.Points[i] == nil || partial.Proofs[i] == nil {
+			return fmt.Errorf("missing point or proof %d", i)
+		}
+		shared := cothority.Suite.Point().Sub(beta[i], partial.Points[i])
+		if err := partial.Proofs[i].Verify(cothority.Suite, nil, alpha[i], public, shared); err != nil {
+			return fmt.Errorf("invalid proof of point %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
 // Split separates the ElGamal pairs of a list of ballots into separate lists.
 func Split(ballots []*Ballot) (alpha, beta []kyber.Point) {
 	n := len(ballots)
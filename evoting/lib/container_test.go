@@ -6,6 +6,7 @@ import (
 	"go.dedis.ch/kyber/v3"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // genBox generates a box of encrypted ballots.
@@ -41,3 +42,25 @@ func TestCombine(t *testing.T) {
 	assert.Equal(t, X2, ballots[0].Beta)
 	assert.Equal(t, X2, ballots[1].Beta)
 }
+
+func TestVerifyPartial(t *testing.T) {
+	dkgs, err := DKGSimulate(3, 2)
+	require.NoError(t, err)
+	secret, err := NewSharedSecret(dkgs[0])
+	require.NoError(t, err)
+
+	mix := &Mix{Ballots: genBox(secret.X, 3).Ballots}
+	partials := mix.genPartials(dkgs)
+	for i, partial := range partials {
+		s, err := NewSharedSecret(dkgs[i])
+		require.NoError(t, err)
+		require.NoError(t, VerifyPartial(secret.Commits, s.Index, mix.Ballots, partial))
+		require.Error(t, VerifyPartial(secret.Commits, (s.Index+1)%3, mix.Ballots, partial))
+	}
+
+	partials[0].Points[1] = partials[1].Points[1]
+	require.Error(t, VerifyPartial(secret.Commits, 0, mix.Ballots, partials[0]))
+	partials[1].Proofs = nil
+	require.Error(t, VerifyPartial(secret.Commits, 1, mix.Ballots, partials[1]))
+	require.Error(t, VerifyPartial(nil, 2, mix.Ballots, partials[2]))
+}
@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +14,7 @@ import (
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
 
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/skipchain"
 )
 
@@ -27,8 +30,35 @@ const (
 	Decrypted
 )
 
+// ElectionType is how the ballots of an election are filled and counted.
+type ElectionType uint32
+
+const (
+	// Plurality ballots choose up to MaxChoices candidates, each of which
+	// gets the weight of the voter.
+	Plurality ElectionType = iota
+	// Ranked ballots order up to MaxChoices candidates. They are counted
+	// by instant-runoff for one seat, and by single transferable vote for
+	// more seats.
+	Ranked
+)
+
+// RevotingPolicy is what happens when a voter casts more than one ballot.
+type RevotingPolicy uint32
+
+const (
+	// LastVote counts the last ballot of each voter.
+	LastVote RevotingPolicy = iota
+	// FirstVote counts the first ballot of each voter. Later ballots are
+	// accepted but not counted. It doesn't protect against coercion: the
+	// ballots are public, so anybody sees which one was cast first.
+	FirstVote
+	// NoRevoting refuses the ballots of voters who already voted.
+	NoRevoting
+)
+
 func init() {
-	network.RegisterMessages(Election{}, Ballot{}, Box{}, Mix{}, Partial{})
+	network.RegisterMessages(Election{}, Ballot{}, Box{}, Mix{}, Partial{}, Dedup{})
 }
 
 // Election is the base object for a voting procedure. It is stored
@@ -58,6 +88,23 @@ type Election struct {
 
 	Voted        skipchain.SkipBlockID // Voted denotes if a user has already cast a ballot for this election.
 	MoreInfoLang map[string]string     // MoreInfoLang, is MoreInfo, but as a lang-code/value map. MoreInfoLang should be used in preference to MoreInfo.
+
+	Type    ElectionType // Type is how the ballots are filled and counted.
+	Seats   int          // Seats is the number of candidates elected, 1 if not set.
+	Weights []uint32     // Weights are the weights of the Users, in the same order; all 1 if not set.
+
+	Commits []kyber.Point // Commits are the coefficients of the DKG public polynomial, to verify the partials.
+
+	Revoting RevotingPolicy // Revoting is which ballot counts when a voter casts more than one.
+}
+
+// Dedup records which ballots are put in the box, following the revoting
+// policy of the election. It is stored before the first shuffle, and every
+// node checks it against the ballots cast.
+type Dedup struct {
+	Policy RevotingPolicy // Policy applied.
+	Blocks []int          // Blocks are the indexes of the blocks holding the ballots of the box, in order.
+	Box    []byte         // Box is the hash of the ballots of the box.
 }
 
 // Footer denotes the fields for the election footer
@@ -118,33 +165,14 @@ func GetElection(s *skipchain.Service, id skipchain.SkipBlockID,
 }
 
 // setVoted sets the Voted field of the election to the skipblock id
-// of the last ballot cast by the user
+// of the ballot of the user that counts.
 func (e *Election) setVoted(s *skipchain.Service, user uint32) error {
-	db := s.GetDB()
-	block := db.GetByID(e.ID)
-	if block == nil {
-		return errors.New("Election skipchain empty")
+	block, _, err := e.Effective(s, user)
+	if err != nil {
+		return err
 	}
-
-	for {
-		transaction := UnmarshalTransaction(block.Data)
-		if transaction == nil {
-			if len(block.ForwardLink) == 0 {
-				break
-			}
-			block = db.GetByID(block.ForwardLink[0].To)
-			continue
-		}
-		if transaction.Ballot != nil && transaction.Ballot.User == user {
-			e.Voted = block.Hash
-		}
-		if transaction.Mix != nil || transaction.Partial != nil {
-			break
-		}
-		if len(block.ForwardLink) == 0 {
-			break
-		}
-		block = db.GetByID(block.ForwardLink[0].To)
+	if block != nil {
+		e.Voted = block.Hash
 	}
 	return nil
 }
@@ -175,55 +203,169 @@ func (e *Election) setStage(s *skipchain.Service) error {
 	return nil
 }
 
-// Box accumulates all the ballots while only keeping the last ballot for each user.
-func (e *Election) Box(s *skipchain.Service) (*Box, error) {
+// Ballots returns all the ballots cast, in order, with the blocks holding them.
+func (e *Election) Ballots(s *skipchain.Service) ([]*Ballot, []*skipchain.SkipBlock, error) {
 	search, err := s.GetSingleBlockByIndex(
 		&skipchain.GetSingleBlockByIndex{
 			Genesis: e.ID,
 			Index:   0,
 		})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	block := search.SkipBlock
 
-	// Use map to only included a user's last ballot.
-	ballots := make([]*Ballot, 0)
+	var ballots []*Ballot
+	var blocks []*skipchain.SkipBlock
 	for {
 		transaction := UnmarshalTransaction(block.Data)
 		if transaction != nil && transaction.Ballot != nil {
 			ballots = append(ballots, transaction.Ballot)
+			blocks = append(blocks, block)
+		}
+		if transaction != nil && (transaction.Dedup != nil || transaction.Mix != nil) {
+			break
 		}
 
 		if len(block.ForwardLink) <= 0 {
 			break
 		}
-		block, _ = s.GetSingleBlock(
+		block, err = s.GetSingleBlock(
 			&skipchain.GetSingleBlock{
 				ID: block.ForwardLink[0].To,
 			})
+		if err != nil {
+			return nil, nil, err
+		}
 	}
+	return ballots, blocks, nil
+}
 
-	// Reverse ballot list
-	for i, j := 0, len(ballots)-1; i < j; i, j = i+1, j-1 {
-		ballots[i], ballots[j] = ballots[j], ballots[i]
+// Box accumulates the ballots that count, following the revoting policy.
+func (e *Election) Box(s *skipchain.Service) (*Box, error) {
+	ballots, _, err := e.Ballots(s)
+	if err != nil {
+		return nil, err
 	}
+	return e.box(ballots), nil
+}
 
-	// Only keep last casted ballot per user
-	mapping := make(map[uint32]bool)
-	unique := make([]*Ballot, 0)
+// Effective returns the block holding the ballot of the user that counts,
+// and the number of ballots the user cast. The block is nil if the user
+// didn't vote.
+func (e *Election) Effective(s *skipchain.Service, user uint32) (*skipchain.SkipBlock, int, error) {
+	ballots, blocks, err := e.Ballots(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	var effective *skipchain.SkipBlock
+	for _, i := range e.selectBallots(ballots) {
+		if ballots[i].User == user {
+			effective = blocks[i]
+		}
+	}
+	cast := 0
 	for _, ballot := range ballots {
-		if _, found := mapping[ballot.User]; !found {
-			unique = append(unique, ballot)
-			mapping[ballot.User] = true
+		if ballot.User == user {
+			cast++
+		}
+	}
+	return effective, cast, nil
+}
+
+// NewDedup records the ballots of the box, out of all the ballots cast and
+// the indexes of their blocks.
+func (e *Election) NewDedup(ballots []*Ballot, blocks []int) (*Dedup, error) {
+	if len(ballots) != len(blocks) {
+		return nil, errors.New("not one block per ballot")
+	}
+	dedup := &Dedup{Policy: e.Revoting}
+	for _, i := range e.selectBallots(ballots) {
+		dedup.Blocks = append(dedup.Blocks, blocks[i])
+	}
+	h := sha256.New()
+	for _, ballot := range e.box(ballots).Ballots {
+		h.Write(ballot.Hash())
+	}
+	dedup.Box = h.Sum(nil)
+	return dedup, nil
+}
+
+// selectBallots returns the positions of the ballots that count, in the
+// order they were cast.
+func (e *Election) selectBallots(ballots []*Ballot) []int {
+	counted := make(map[uint32]int)
+	for i, ballot := range ballots {
+		if _, found := counted[ballot.User]; found && e.Revoting != LastVote {
+			continue
+		}
+		counted[ballot.User] = i
+	}
+	positions := make([]int, 0, len(counted))
+	for i, ballot := range ballots {
+		if counted[ballot.User] == i {
+			positions = append(positions, i)
 		}
 	}
+	return positions
+}
+
+// box puts the ballots that count, out of all the ballots cast, in the box.
+func (e *Election) box(ballots []*Ballot) *Box {
+	unique := make([]*Ballot, 0)
+	for _, i := range e.selectBallots(ballots) {
+		unique = append(unique, ballots[i])
+	}
 
-	// Reverse back list of unique ballots
-	for i, j := 0, len(unique)-1; i < j; i, j = i+1, j-1 {
-		unique[i], unique[j] = unique[j], unique[i]
+	// The weights are public, so they are added as an encryption without
+	// randomness that everybody can check. The first shuffle hides them.
+	if e.Weighted() {
+		for i, ballot := range unique {
+			weighted := *ballot
+			weighted.ExtraAlpha = append(append([]kyber.Point{}, ballot.ExtraAlpha...),
+				cothority.Suite.Point().Null())
+			weighted.ExtraBeta = append(append([]kyber.Point{}, ballot.ExtraBeta...),
+				weightPoint(e.Weight(ballot.User)))
+			unique[i] = &weighted
+		}
 	}
-	return &Box{Ballots: unique}, nil
+	return &Box{Ballots: unique}
+}
+
+// Dedup returns the record of the ballots put in the box, or nil if the
+// ballots haven't been deduplicated.
+func (e *Election) Dedup(s *skipchain.Service) (*Dedup, error) {
+	block, err := s.GetDB().GetLatest(s.GetDB().GetByID(e.ID))
+	if err != nil {
+		return nil, err
+	}
+	for block != nil {
+		transaction := UnmarshalTransaction(block.Data)
+		if transaction == nil || (transaction.Mix == nil && transaction.Partial == nil &&
+			transaction.Result == nil && transaction.Dedup == nil) {
+			break
+		}
+		if transaction.Dedup != nil {
+			return transaction.Dedup, nil
+		}
+		if len(block.BackLinkIDs) == 0 {
+			break
+		}
+		block = s.GetDB().GetByID(block.BackLinkIDs[0])
+	}
+	return nil, nil
+}
+
+func equalDedups(a, b *Dedup) bool {
+	if a.Policy != b.Policy || len(a.Blocks) != len(b.Blocks) || !bytes.Equal(a.Box, b.Box) {
+		return false
+	}
+	for i := range a.Blocks {
+		if a.Blocks[i] != b.Blocks[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // Mixes returns all mixes created by the roster conodes.
@@ -247,7 +389,7 @@ func (e *Election) Mixes(s *skipchain.Service) ([]*Mix, error) {
 			block = s.GetDB().GetByID(block.BackLinkIDs[0])
 			continue
 		}
-		if transaction.Mix == nil && transaction.Partial == nil {
+		if transaction.Mix == nil && transaction.Partial == nil && transaction.Result == nil {
 			// we're done
 			break
 		}
@@ -293,7 +435,7 @@ func (e *Election) Partials(s *skipchain.Service) ([]*Partial, error) {
 			block = s.GetDB().GetByID(block.BackLinkIDs[0])
 			continue
 		}
-		if transaction.Partial == nil {
+		if transaction.Partial == nil && transaction.Result == nil {
 			// we're done
 			break
 		}
@@ -327,6 +469,29 @@ func (e *Election) IsUser(user uint32) bool {
 	return false
 }
 
+// Result returns the result of the tally stored on the election skipchain,
+// or nil if the election hasn't been tallied.
+func (e *Election) Result(s *skipchain.Service) (*Result, error) {
+	block, err := s.GetDB().GetLatest(s.GetDB().GetByID(e.ID))
+	if err != nil {
+		return nil, err
+	}
+	for block != nil {
+		transaction := UnmarshalTransaction(block.Data)
+		if transaction == nil || (transaction.Partial == nil && transaction.Result == nil) {
+			break
+		}
+		if transaction.Result != nil {
+			return transaction.Result, nil
+		}
+		if len(block.BackLinkIDs) == 0 {
+			break
+		}
+		block = s.GetDB().GetByID(block.BackLinkIDs[0])
+	}
+	return nil, nil
+}
+
 // IsCreator checks if a given user is the creator of the election.
 func (e *Election) IsCreator(user uint32) bool {
 	return user == e.Creator
@@ -343,6 +508,10 @@ func (e *Election) String() string {
 	printLang(str, e.Subtitle)
 	fmt.Fprintf(str, "Candidates: %v\n", e.Candidates)
 	fmt.Fprintf(str, "MaxChoices: %v\n", e.MaxChoices)
+	fmt.Fprintf(str, "Type: %v\n", e.Type)
+	fmt.Fprintf(str, "Seats: %v\n", e.seats())
+	fmt.Fprintf(str, "Weights: %v\n", e.Weights)
+	fmt.Fprintf(str, "Revoting: %v\n", e.Revoting)
 	fmt.Fprintf(str, "MoreInfo: %v\n", e.MoreInfo)
 	fmt.Fprintf(str, "MoreInfoLang:\n")
 	printLang(str, e.MoreInfoLang)
@@ -17,3 +17,31 @@ func TestIsCreator(t *testing.T) {
 	assert.True(t, e.IsCreator(0))
 	assert.False(t, e.IsCreator(1))
 }
+
+func TestBoxRevoting(t *testing.T) {
+	_, X := RandomKeyPair()
+	cast := genBox(X, 3).Ballots
+	// User 0 votes again after user 1, and user 2 after that.
+	revote := *cast[0]
+	revote.Alpha, revote.Beta = Encrypt(X, []byte{9})
+	ballots := []*Ballot{cast[0], cast[1], &revote, cast[2]}
+	blocks := []int{2, 3, 4, 5}
+
+	e := &Election{}
+	assert.Equal(t, []*Ballot{cast[1], &revote, cast[2]}, e.box(ballots).Ballots)
+	dedup, err := e.NewDedup(ballots, blocks)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 4, 5}, dedup.Blocks)
+
+	for _, policy := range []RevotingPolicy{FirstVote, NoRevoting} {
+		e.Revoting = policy
+		assert.Equal(t, []*Ballot{cast[0], cast[1], cast[2]}, e.box(ballots).Ballots)
+		other, err := e.NewDedup(ballots, blocks)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 3, 5}, other.Blocks)
+		assert.False(t, equalDedups(dedup, other))
+	}
+
+	_, err = e.NewDedup(ballots, blocks[1:])
+	assert.Error(t, err)
+}
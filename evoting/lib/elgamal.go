@@ -13,13 +13,20 @@ import (
 
 // Encrypt performs the ElGamal encryption algorithm.
 func Encrypt(public kyber.Point, message []byte) (K, C kyber.Point) {
+	K, C, _ = encrypt(public, message)
+	return
+}
+
+// encrypt is Encrypt, returning also the ephemeral private key, which
+// reveals the message to anybody knowing the public key.
+func encrypt(public kyber.Point, message []byte) (K, C kyber.Point, k kyber.Scalar) {
 	M := cothority.Suite.Point().Embed(message, random.New())
 
 	// ElGamal-encrypt the point to produce ciphertext (K,C).
-	k := cothority.Suite.Scalar().Pick(random.New()) // ephemeral private key
-	K = cothority.Suite.Point().Mul(k, nil)          // ephemeral DH public key
-	S := cothority.Suite.Point().Mul(k, public)      // ephemeral DH shared secret
-	C = S.Add(S, M)                                  // message blinded with secret
+	k = cothority.Suite.Scalar().Pick(random.New()) // ephemeral private key
+	K = cothority.Suite.Point().Mul(k, nil)         // ephemeral DH public key
+	S := cothority.Suite.Point().Mul(k, public)     // ephemeral DH shared secret
+	C = S.Add(S, M)                                 // message blinded with secret
 	return
 }
 
@@ -24,6 +24,10 @@ type Master struct {
 	Admins []uint32 // Admins is the list of administrators.
 
 	Key kyber.Point // Key is the front-end public key.
+
+	// Directory is the name of the voter directory used to look up the
+	// users. If empty, evoting.DefaultDirectory is used.
+	Directory string
 }
 
 // Link is a wrapper around the genesis Skipblock identifier of an
@@ -0,0 +1,204 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/proof"
+	"go.dedis.ch/kyber/v3/shuffle"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+/*
+Ballots of more than one ElGamal pair are shuffled as sequences: all the pairs
+of a ballot are moved with the same permutation and re-encrypted each with
+their own blinding factor. The proof follows section 5 of Neff's paper: the
+pairs of each ballot are combined with random factors derived from all the
+inputs and outputs, and a single pair shuffle of the combined ballots is
+proven. Ballots of a single pair are shuffled and proven like they always
+were.
+*/
+
+// ShuffleBallots permutes and re-encrypts the ballots, which must all have
+// the same width, and returns a proof of the shuffle.
+func ShuffleBallots(key kyber.Point, ballots []*Ballot) ([]*Ballot, []byte, error) {
+	x, y, err := columns(ballots)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(x) == 1 {
+		v, w, prover := shuffle.Shuffle(cothority.Suite, nil, key, x[0], y[0], random.New())
+		prf, err := proof.HashProve(cothority.Suite, "", prover)
+		if err != nil {
+			return nil, nil, err
+		}
+		return Combine(v, w), prf, nil
+	}
+
+	k := len(ballots)
+	pi := make([]int, k)
+	for i := range pi {
+		pi[i] = i
+	}
+	for i := k - 1; i > 0; i-- {
+		j := int(random.Int(big.NewInt(int64(i+1)), random.New()).Int64())
+		pi[i], pi[j] = pi[j], pi[i]
+	}
+
+	v := make([][]kyber.Point, len(x))
+	w := make([][]kyber.Point, len(x))
+	beta := make([][]kyber.Scalar, len(x))
+	for c := range x {
+		v[c] = make([]kyber.Point, k)
+		w[c] = make([]kyber.Point, k)
+		beta[c] = make([]kyber.Scalar, k)
+		for i := range beta[c] {
+			beta[c][i] = cothority.Suite.Scalar().Pick(random.New())
+		}
+		for i := 0; i < k; i++ {
+			v[c][i] = cothority.Suite.Point().Mul(beta[c][pi[i]], nil)
+			v[c][i].Add(v[c][i], x[c][pi[i]])
+			w[c][i] = cothority.Suite.Point().Mul(beta[c][pi[i]], key)
+			w[c][i].Add(w[c][i], y[c][pi[i]])
+		}
+	}
+
+	e, err := challenges(key, x, y, v, w)
+	if err != nil {
+		return nil, nil, err
+	}
+	X, Y := linearCombination(e, x, y)
+	B := make([]kyber.Scalar, k)
+	for i := range B {
+		B[i] = cothority.Suite.Scalar().Zero()
+		for c := range beta {
+			B[i].Add(B[i], cothority.Suite.Scalar().Mul(e[c], beta[c][i]))
+		}
+	}
+	ps := shuffle.PairShuffle{}
+	ps.Init(cothority.Suite, k)
+	prover := func(ctx proof.ProverContext) error {
+		return ps.Prove(pi, nil, key, B, X, Y, random.New(), ctx)
+	}
+	prf, err := proof.HashProve(cothority.Suite, "", prover)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shuffled := make([]*Ballot, k)
+	for i := range shuffled {
+		alphas := make([]kyber.Point, len(v))
+		betas := make([]kyber.Point, len(w))
+		for c := range v {
+			alphas[c], betas[c] = v[c][i], w[c][i]
+		}
+		shuffled[i] = NewBallot(0, alphas, betas)
+	}
+	return shuffled, prf, nil
+}
+
+// VerifyMix verifies the proof that the output ballots are a shuffle of the
+// input ballots.
+func VerifyMix(prf []byte, key kyber.Point, in, out []*Ballot) error {
+	x, y, err := columns(in)
+	if err != nil {
+		return err
+	}
+	v, w, err := columns(out)
+	if err != nil {
+		return err
+	}
+	if len(in) != len(out) || len(x) != len(v) {
+		return errors.New("the mix doesn't have the shape of its input")
+	}
+
+	if len(x) == 1 {
+		return Verify(prf, key, x[0], y[0], v[0], w[0])
+	}
+	if len(in) < 2 {
+		return errors.New("cannot verify less than 2 points")
+	}
+	e, err := challenges(key, x, y, v, w)
+	if err != nil {
+		return err
+	}
+	X, Y := linearCombination(e, x, y)
+	V, W := linearCombination(e, v, w)
+	return Verify(prf, key, X, Y, V, W)
+}
+
+// columns splits the ballots into their pairs: x[c][i] is the alpha of the
+// c-th pair of the i-th ballot. Missing points are replaced by the null point.
+func columns(ballots []*Ballot) (x, y [][]kyber.Point, err error) {
+	if len(ballots) == 0 {
+		return nil, nil, errors.New("no ballots")
+	}
+	width := ballots[0].Width()
+	x = make([][]kyber.Point, width)
+	y = make([][]kyber.Point, width)
+	for c := range x {
+		x[c] = make([]kyber.Point, len(ballots))
+		y[c] = make([]kyber.Point, len(ballots))
+	}
+	for i, b := range ballots {
+		alpha, beta := b.Pairs()
+		if len(alpha) != width || len(beta) != width {
+			return nil, nil, errors.New("ballots of different widths")
+		}
+		for c := range alpha {
+			x[c][i], y[c][i] = alpha[c], beta[c]
+			if x[c][i] == nil {
+				x[c][i] = cothority.Suite.Point().Null()
+			}
+			if y[c][i] == nil {
+				y[c][i] = cothority.Suite.Point().Null()
+			}
+		}
+	}
+	return
+}
+
+// challenges derives the factors combining the pairs of the ballots from all
+// the inputs and outputs of the shuffle.
+func challenges(key kyber.Point, x, y, v, w [][]kyber.Point) ([]kyber.Scalar, error) {
+	h := sha256.New()
+	h.Write([]byte("evoting sequence shuffle"))
+	if _, err := key.MarshalTo(h); err != nil {
+		return nil, err
+	}
+	for _, points := range [][][]kyber.Point{x, y, v, w} {
+		for _, column := range points {
+			for _, p := range column {
+				if _, err := p.MarshalTo(h); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	xof := cothority.Suite.XOF(h.Sum(nil))
+	e := make([]kyber.Scalar, len(x))
+	for c := range e {
+		e[c] = cothority.Suite.Scalar().Pick(xof)
+	}
+	return e, nil
+}
+
+// linearCombination combines the pairs of each ballot into a single pair.
+func linearCombination(e []kyber.Scalar, x, y [][]kyber.Point) (X, Y []kyber.Point) {
+	X = make([]kyber.Point, len(x[0]))
+	Y = make([]kyber.Point, len(y[0]))
+	for i := range X {
+		X[i] = cothority.Suite.Point().Null()
+		Y[i] = cothority.Suite.Point().Null()
+		for c := range e {
+			X[i].Add(X[i], cothority.Suite.Point().Mul(e[c], x[c][i]))
+			Y[i].Add(Y[i], cothority.Suite.Point().Mul(e[c], y[c][i]))
+		}
+	}
+	return
+}
@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+)
+
+func TestShuffleBallots(t *testing.T) {
+	x, X := RandomKeyPair()
+	for _, width := range []int{1, 3} {
+		var ballots []*Ballot
+		for i := 0; i < 5; i++ {
+			alpha := make([]kyber.Point, width)
+			beta := make([]kyber.Point, width)
+			for c := range alpha {
+				alpha[c], beta[c] = Encrypt(X, []byte{byte(i), byte(c)})
+			}
+			ballots = append(ballots, NewBallot(uint32(i), alpha, beta))
+		}
+
+		shuffled, prf, err := ShuffleBallots(X, ballots)
+		require.NoError(t, err)
+		require.NoError(t, VerifyMix(prf, X, ballots, shuffled))
+
+		// The pairs of a ballot stay together.
+		seen := make(map[byte]bool)
+		for _, b := range shuffled {
+			alpha, beta := b.Pairs()
+			require.Equal(t, width, len(alpha))
+			var first byte
+			for c := range alpha {
+				data, err := Decrypt(x, alpha[c], beta[c]).Data()
+				require.NoError(t, err)
+				if c == 0 {
+					first = data[0]
+				}
+				require.Equal(t, []byte{first, byte(c)}, data)
+			}
+			seen[first] = true
+		}
+		require.Equal(t, 5, len(seen))
+
+		// Moving a single pair to another ballot is detected.
+		if width > 1 {
+			shuffled[0].ExtraAlpha[0], shuffled[1].ExtraAlpha[0] = shuffled[1].ExtraAlpha[0], shuffled[0].ExtraAlpha[0]
+			shuffled[0].ExtraBeta[0], shuffled[1].ExtraBeta[0] = shuffled[1].ExtraBeta[0], shuffled[0].ExtraBeta[0]
+			require.Error(t, VerifyMix(prf, X, ballots, shuffled))
+		}
+		require.Error(t, VerifyMix(prf, X, ballots, shuffled[1:]))
+	}
+
+	_, _, err := ShuffleBallots(X, []*Ballot{
+		NewBallot(0, []kyber.Point{X}, []kyber.Point{X}),
+		NewBallot(1, []kyber.Point{X, X}, []kyber.Point{X, X}),
+	})
+	require.Error(t, err)
+}
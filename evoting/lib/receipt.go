@@ -0,0 +1,101 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+
+	"go.dedis.ch/onet/v3/network"
+
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+func init() {
+	network.RegisterMessages(Receipt{}, Opening{})
+}
+
+// Receipt is given to a voter when their ballot is stored. It proves that
+// the ballot is in the election skipchain, and lets the voter check later
+// that it is the ballot counted for them.
+type Receipt struct {
+	Election skipchain.SkipBlockID  // Election is the ID of the election skipchain.
+	User     uint32                 // User who cast the ballot.
+	Ballot   []byte                 // Ballot is the hash of the ballot.
+	Proof    skipchain.CompactProof // Proof links the genesis block to the block holding the ballot.
+}
+
+// NewReceipt creates the receipt of the ballot stored in the given block of
+// the election skipchain.
+func NewReceipt(s *skipchain.Service, election, block skipchain.SkipBlockID) (*Receipt, error) {
+	proof, err := s.GetDB().GetCompactProof(election, block)
+	if err != nil {
+		return nil, err
+	}
+	receipt := &Receipt{Election: election, Proof: proof}
+	ballot, err := receipt.ballot()
+	if err != nil {
+		return nil, err
+	}
+	receipt.User = ballot.User
+	receipt.Ballot = ballot.Hash()
+	return receipt, nil
+}
+
+// Verify checks that the proof of the receipt starts at the election genesis
+// block and ends at a block holding the ballot of the receipt.
+func (r *Receipt) Verify() error {
+	if err := r.Proof.VerifyFromID(r.Election); err != nil {
+		return err
+	}
+	ballot, err := r.ballot()
+	if err != nil {
+		return err
+	}
+	if ballot.User != r.User || !bytes.Equal(ballot.Hash(), r.Ballot) {
+		return errors.New("the block holds another ballot")
+	}
+	return nil
+}
+
+// Check verifies the receipt, and checks that its ballot is the one of the
+// user in the box of the election.
+func (r *Receipt) Check(e *Election, box *Box) error {
+	if !e.ID.Equal(r.Election) {
+		return errors.New("receipt of another election")
+	}
+	if err := r.Verify(); err != nil {
+		return err
+	}
+	for _, ballot := range box.Ballots {
+		if ballot.User != r.User {
+			continue
+		}
+		// The weight is added to the ballots once they are in the box.
+		if e.Weighted() && len(ballot.ExtraAlpha) > 0 {
+			cast := *ballot
+			cast.ExtraAlpha = ballot.ExtraAlpha[:len(ballot.ExtraAlpha)-1]
+			cast.ExtraBeta = ballot.ExtraBeta[:len(ballot.ExtraBeta)-1]
+			ballot = &cast
+		}
+		if !bytes.Equal(ballot.Hash(), r.Ballot) {
+			return errors.New("another ballot of the user counts")
+		}
+		return nil
+	}
+	return errors.New("the ballot is not in the box")
+}
+
+// ballot returns the ballot in the last block of the proof.
+func (r *Receipt) ballot() (*Ballot, error) {
+	if len(r.Proof) == 0 {
+		return nil, errors.New("empty proof")
+	}
+	last := r.Proof[len(r.Proof)-1]
+	if last == nil || last.SkipBlockFix == nil {
+		return nil, errors.New("missing block")
+	}
+	transaction := UnmarshalTransaction(last.Data)
+	if transaction == nil || transaction.Ballot == nil {
+		return nil, errors.New("no ballot in the block")
+	}
+	return transaction.Ballot, nil
+}
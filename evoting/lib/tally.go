@@ -0,0 +1,282 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+
+	"go.dedis.ch/cothority/v3"
+)
+
+func init() {
+	network.RegisterMessages(Result{}, Round{})
+}
+
+// VoteUnit is the value of a vote of weight 1 in the tallies. Votes are
+// counted in millionths so that the transfers of the surpluses in a single
+// transferable vote stay exact and deterministic.
+const VoteUnit = 1000000
+
+// Result is the outcome of the tally of an election. It is stored on the
+// election skipchain by the leader, and every node verifies it by tallying
+// the ballots again.
+type Result struct {
+	Ballots []byte // Ballots is the hash of the decrypted points that were tallied.
+	Valid   int    // Valid is the number of ballots counted, blank ones included.
+	Invalid int    // Invalid is the number of ballots that couldn't be counted.
+
+	Rounds  []*Round // Rounds of the count, a single one for plurality elections.
+	Elected []uint32 // Elected candidates, in the order of their election.
+}
+
+// Round is one round of counting.
+type Round struct {
+	Candidates []uint32 // Candidates still in the count.
+	Votes      []uint64 // Votes of the Candidates, in VoteUnits.
+	Exhausted  uint64   // Exhausted are the votes without a preference left, in VoteUnits.
+	Quota      uint64   // Quota of votes to be elected, in VoteUnits; 0 for plurality.
+	Elected    []uint32 // Elected candidates in this round.
+	Eliminated []uint32 // Eliminated candidate in this round.
+}
+
+// Reconstruct recovers the decrypted points out of the partial decryptions
+// of the nodes of the roster, using Lagrange interpolation.
+func Reconstruct(roster *onet.Roster, partials []*Partial) ([]kyber.Point, error) {
+	if len(partials) == 0 {
+		return nil, errors.New("no partials")
+	}
+	points := make([]kyber.Point, 0)
+	n := len(roster.List)
+	for i := 0; i < len(partials[0].Points); i++ {
+		shares := make([]*share.PubShare, n)
+		for _, partial := range partials {
+			j, _ := roster.Search(partial.NodeID)
+			if j < 0 || len(partial.Points) != len(partials[0].Points) {
+				return nil, errors.New("invalid partial")
+			}
+			shares[j] = &share.PubShare{I: j, V: partial.Points[i]}
+		}
+
+		message, err := share.RecoverCommit(cothority.Suite, shares, 2*n/3+1, n)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, message)
+	}
+	return points, nil
+}
+
+// Tally counts the decrypted points of the ballots of the election. Invalid
+// ballots, with unknown or repeated candidates or too many choices, are not
+// counted. Ties are broken by the order of the candidates in the election:
+// the first one wins and the last one is eliminated.
+func Tally(e *Election, points []kyber.Point) (*Result, error) {
+	votes, err := e.DecodeVotes(points)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	for _, p := range points {
+		if _, err := p.MarshalTo(h); err != nil {
+			return nil, err
+		}
+	}
+	result := &Result{Ballots: h.Sum(nil)}
+
+	var papers []*paper
+	for _, vote := range votes {
+		if vote == nil || !e.validVote(vote) {
+			result.Invalid++
+			continue
+		}
+		result.Valid++
+		papers = append(papers, &paper{
+			choices: vote.Choices,
+			value:   new(big.Int).Mul(big.NewInt(int64(vote.Weight)), big.NewInt(VoteUnit)),
+		})
+	}
+
+	if e.Type == Ranked {
+		e.countRanked(result, papers)
+	} else {
+		e.countPlurality(result, papers)
+	}
+	return result, nil
+}
+
+// paper is a valid ballot during the count.
+type paper struct {
+	choices []uint32
+	value   *big.Int
+}
+
+func (e *Election) validVote(vote *Vote) bool {
+	if e.MaxChoices > 0 && len(vote.Choices) > e.MaxChoices {
+		return false
+	}
+	seen := make(map[uint32]bool)
+	for _, c := range vote.Choices {
+		if seen[c] || e.candidateIndex(c) < 0 {
+			return false
+		}
+		seen[c] = true
+	}
+	return true
+}
+
+func (e *Election) candidateIndex(candidate uint32) int {
+	for i, c := range e.Candidates {
+		if c == candidate {
+			return i
+		}
+	}
+	return -1
+}
+
+// countPlurality gives the value of each paper to all its choices, and
+// elects the candidates with the most votes.
+func (e *Election) countPlurality(result *Result, papers []*paper) {
+	votes := make([]*big.Int, len(e.Candidates))
+	for i := range votes {
+		votes[i] = new(big.Int)
+	}
+	exhausted := new(big.Int)
+	for _, p := range papers {
+		if len(p.choices) == 0 {
+			exhausted.Add(exhausted, p.value)
+		}
+		for _, c := range p.choices {
+			i := e.candidateIndex(c)
+			votes[i].Add(votes[i], p.value)
+		}
+	}
+
+	round := &Round{
+		Candidates: append([]uint32{}, e.Candidates...),
+		Exhausted:  exhausted.Uint64(),
+	}
+	for _, v := range votes {
+		round.Votes = append(round.Votes, v.Uint64())
+	}
+	elected := make([]bool, len(e.Candidates))
+	for len(round.Elected) < e.seats() && len(round.Elected) < len(e.Candidates) {
+		best := -1
+		for i := range votes {
+			if !elected[i] && (best < 0 || votes[i].Cmp(votes[best]) > 0) {
+				best = i
+			}
+		}
+		elected[best] = true
+		round.Elected = append(round.Elected, e.Candidates[best])
+	}
+	result.Rounds = []*Round{round}
+	result.Elected = round.Elected
+}
+
+// countRanked runs a single transferable vote with a Droop quota, which is
+// an instant-runoff for a single seat. In each round, the candidate with the
+// most votes is elected if they reach the quota, and the surplus is
+// transferred to the next preferences at a reduced value. Else the candidate
+// with the fewest votes is eliminated. When there are no more candidates
+// left than seats to fill, they are all elected.
+func (e *Election) countRanked(result *Result, papers []*paper) {
+	continuing := append([]uint32{}, e.Candidates...)
+	isContinuing := func(c uint32) bool {
+		for _, cc := range continuing {
+			if cc == c {
+				return true
+			}
+		}
+		return false
+	}
+	remove := func(c uint32) {
+		for i, cc := range continuing {
+			if cc == c {
+				continuing = append(continuing[:i], continuing[i+1:]...)
+				return
+			}
+		}
+	}
+
+	total := new(big.Int)
+	for _, p := range papers {
+		if len(p.choices) > 0 {
+			total.Add(total, p.value)
+		}
+	}
+	quota := new(big.Int).Div(total, big.NewInt(int64(e.seats()+1)))
+	quota.Add(quota, big.NewInt(1))
+
+	for len(result.Elected) < e.seats() && len(continuing) > 0 {
+		// Every paper goes to its first continuing choice.
+		votes := make([]*big.Int, len(continuing))
+		for i := range votes {
+			votes[i] = new(big.Int)
+		}
+		holders := make([][]*paper, len(continuing))
+		exhausted := new(big.Int)
+		for _, p := range papers {
+			for len(p.choices) > 0 && !isContinuing(p.choices[0]) {
+				p.choices = p.choices[1:]
+			}
+			if len(p.choices) == 0 {
+				exhausted.Add(exhausted, p.value)
+				continue
+			}
+			for i, c := range continuing {
+				if c == p.choices[0] {
+					votes[i].Add(votes[i], p.value)
+					holders[i] = append(holders[i], p)
+				}
+			}
+		}
+
+		round := &Round{
+			Candidates: append([]uint32{}, continuing...),
+			Exhausted:  exhausted.Uint64(),
+			Quota:      quota.Uint64(),
+		}
+		for _, v := range votes {
+			round.Votes = append(round.Votes, v.Uint64())
+		}
+		result.Rounds = append(result.Rounds, round)
+
+		if len(continuing) <= e.seats()-len(result.Elected) {
+			round.Elected = append(round.Elected, continuing...)
+			result.Elected = append(result.Elected, continuing...)
+			break
+		}
+
+		best, worst := 0, 0
+		for i := range votes {
+			if votes[i].Cmp(votes[best]) > 0 {
+				best = i
+			}
+			if votes[i].Cmp(votes[worst]) <= 0 {
+				worst = i
+			}
+		}
+
+		if votes[best].Cmp(quota) >= 0 {
+			elected := continuing[best]
+			surplus := new(big.Int).Sub(votes[best], quota)
+			for _, p := range holders[best] {
+				p.value.Mul(p.value, surplus)
+				p.value.Div(p.value, votes[best])
+			}
+			round.Elected = []uint32{elected}
+			result.Elected = append(result.Elected, elected)
+			remove(elected)
+		} else {
+			eliminated := continuing[worst]
+			round.Eliminated = []uint32{eliminated}
+			remove(eliminated)
+		}
+	}
+}
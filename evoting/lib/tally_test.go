@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+)
+
+// decryptBox encrypts the votes like voters and the box do, and decrypts them
+// like the reconstruction does.
+func decryptBox(t *testing.T, e *Election, votes map[uint32][]uint32) []kyber.Point {
+	x, X := RandomKeyPair()
+	e.Key = X
+	var points []kyber.Point
+	for _, user := range e.Users {
+		choices, ok := votes[user]
+		if !ok {
+			continue
+		}
+		ballot, err := e.EncryptBallot(user, choices)
+		require.NoError(t, err)
+		alpha, beta := ballot.Pairs()
+		if e.Weighted() {
+			alpha = append(alpha, X.Clone().Null())
+			beta = append(beta, weightPoint(e.Weight(user)))
+		}
+		for i := range alpha {
+			points = append(points, Decrypt(x, alpha[i], beta[i]))
+		}
+	}
+	return points
+}
+
+func TestTally_Plurality(t *testing.T) {
+	e := &Election{
+		Users:      []uint32{1, 2, 3, 4},
+		Weights:    []uint32{1, 1, 5, 1},
+		Candidates: []uint32{10, 11, 12},
+		MaxChoices: 2,
+		Seats:      2,
+	}
+	points := decryptBox(t, e, map[uint32][]uint32{
+		1: {10, 11},
+		2: {10, 10},
+		3: {12},
+		4: {},
+	})
+	result, err := Tally(e, points)
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Valid)
+	require.Equal(t, 1, result.Invalid)
+	require.Equal(t, []uint64{1 * VoteUnit, 1 * VoteUnit, 5 * VoteUnit}, result.Rounds[0].Votes)
+	require.Equal(t, uint64(VoteUnit), result.Rounds[0].Exhausted)
+	// 10 and 11 are tied, the first candidate wins.
+	require.Equal(t, []uint32{12, 10}, result.Elected)
+}
+
+func TestTally_InstantRunoff(t *testing.T) {
+	e := &Election{
+		Users:      []uint32{1, 2, 3, 4, 5},
+		Candidates: []uint32{10, 11, 12},
+		Type:       Ranked,
+	}
+	points := decryptBox(t, e, map[uint32][]uint32{
+		1: {10},
+		2: {10},
+		3: {11, 12},
+		4: {12, 11},
+		5: {12, 13},
+	})
+	result, err := Tally(e, points)
+	require.NoError(t, err)
+	require.Equal(t, 4, result.Valid)
+	require.Equal(t, 1, result.Invalid)
+	// 11 and 12 are tied, the last one is eliminated and its vote goes to
+	// 11. Then 10 and 11 are tied below the quota, and 11 is eliminated.
+	require.Equal(t, 3, len(result.Rounds))
+	require.Equal(t, uint64(2*VoteUnit+1), result.Rounds[0].Quota)
+	require.Equal(t, []uint32{12}, result.Rounds[0].Eliminated)
+	require.Equal(t, []uint64{2 * VoteUnit, 2 * VoteUnit}, result.Rounds[1].Votes)
+	require.Equal(t, []uint32{11}, result.Rounds[1].Eliminated)
+	require.Equal(t, []uint32{10}, result.Elected)
+
+	// The tally is deterministic.
+	again, err := Tally(e, points)
+	require.NoError(t, err)
+	require.Equal(t, result, again)
+}
+
+func TestTally_SingleTransferableVote(t *testing.T) {
+	e := &Election{
+		Users:      []uint32{1, 2, 3, 4, 5, 6},
+		Weights:    []uint32{2, 2, 2, 1, 1, 1},
+		Candidates: []uint32{10, 11, 12, 13},
+		MaxChoices: 10,
+		Seats:      2,
+		Type:       Ranked,
+	}
+	points := decryptBox(t, e, map[uint32][]uint32{
+		1: {10, 11},
+		2: {10, 11},
+		3: {10, 12},
+		4: {12, 13},
+		5: {13, 12},
+		6: {11},
+	})
+	// With 10 choices, the ballots take two points, and the weight a third
+	// one.
+	require.Equal(t, 6*3, len(points))
+	result, err := Tally(e, points)
+	require.NoError(t, err)
+
+	// 9 votes for 2 seats, the quota is 9/3 votes and the smallest unit.
+	// 10 has 6 votes and is elected, and its surplus is shared among the
+	// next preferences of its ballots.
+	require.Equal(t, uint64(3*VoteUnit+1), result.Rounds[0].Quota)
+	require.Equal(t, []uint32{10}, result.Rounds[0].Elected)
+	require.Equal(t, []uint32{11, 12, 13}, result.Rounds[1].Candidates)
+	require.Equal(t, []uint64{2999998, 1999999, 1000000}, result.Rounds[1].Votes)
+	require.Equal(t, []uint32{13}, result.Rounds[1].Eliminated)
+	require.Equal(t, []uint64{2999998, 2999999}, result.Rounds[2].Votes)
+	require.Equal(t, []uint32{11}, result.Rounds[2].Eliminated)
+	require.Equal(t, []uint32{10, 12}, result.Elected)
+}
@@ -9,7 +9,6 @@ import (
 	"sort"
 	"time"
 
-	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
@@ -41,6 +40,9 @@ type Transaction struct {
 
 	User      uint32
 	Signature []byte
+
+	Result *Result
+	Dedup  *Dedup
 }
 
 // UnmarshalTransaction decodes a data blob to a transaction structure.
@@ -73,6 +75,10 @@ func NewTransaction(data interface{}, user uint32) *Transaction {
 		transaction.Mix = data.(*Mix)
 	case *Partial:
 		transaction.Partial = data.(*Partial)
+	case *Result:
+		transaction.Result = data.(*Result)
+	case *Dedup:
+		transaction.Dedup = data.(*Dedup)
 	default:
 		return nil
 	}
@@ -178,6 +184,16 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 			return errors.New("open error: invalid end date")
 		}
 
+		if election.Weighted() && len(election.Weights) != len(election.Users) {
+			return errors.New("open error: not one weight per user")
+		}
+		if election.Type != Plurality && election.Type != Ranked {
+			return errors.New("open error: unknown election type")
+		}
+		if election.Revoting > NoRevoting {
+			return errors.New("open error: unknown revoting policy")
+		}
+
 		master, err := GetMaster(s, election.Master)
 		if err != nil {
 			return err
@@ -194,6 +210,15 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		if t.Ballot.Alpha.Equal(null) || t.Ballot.Beta.Equal(null) {
 			return errors.New("alpha and beta must be null points")
 		}
+		if len(t.Ballot.ExtraAlpha) != len(t.Ballot.ExtraBeta) {
+			return errors.New("ballot has different numbers of alphas and betas")
+		}
+		for i := range t.Ballot.ExtraAlpha {
+			if t.Ballot.ExtraAlpha[i] == nil || t.Ballot.ExtraBeta[i] == nil ||
+				t.Ballot.ExtraAlpha[i].Equal(null) || t.Ballot.ExtraBeta[i].Equal(null) {
+				return errors.New("all alphas and betas must be non-null points")
+			}
+		}
 
 		// t.User is trusted at this point, so make sure that they did not try to sneak
 		// through a different user-id in the ballot.
@@ -215,17 +240,30 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		if now.After(end) {
 			return errors.New("election is already closed")
 		}
+		if t.Ballot.Width() != election.ChoicePoints() {
+			return fmt.Errorf("ballot must have %d points", election.ChoicePoints())
+		}
 
 		latest, err := s.GetDB().GetLatest(s.GetDB().GetByID(election.ID))
 		transaction := UnmarshalTransaction(latest.Data)
 		if err != nil {
 			return err
 		}
-		if transaction.Mix != nil || transaction.Partial != nil {
+		if transaction.Mix != nil || transaction.Partial != nil || transaction.Result != nil ||
+			transaction.Dedup != nil {
 			return errors.New("cast error: election not in running stage")
 		} else if !election.IsUser(t.User) {
 			return errors.New("cast error: user not part")
 		}
+		if election.Revoting == NoRevoting {
+			_, cast, err := election.Effective(s, t.User)
+			if err != nil {
+				return err
+			}
+			if cast > 0 {
+				return errors.New("cast error: user already voted")
+			}
+		}
 		return nil
 	} else if t.Mix != nil {
 		election, err := GetElection(s, genesis, false, t.User)
@@ -271,25 +309,19 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		}
 
 		// check if Mix is valid
-		var x, y []kyber.Point
+		var in []*Ballot
 		if len(mixes) == 0 {
 			// verify against Boxes
 			boxes, err := election.Box(s)
 			if err != nil {
 				return err
 			}
-			x, y = Split(boxes.Ballots)
+			in = boxes.Ballots
 		} else {
 			// verify against the last mix
-			x, y = Split(mixes[len(mixes)-1].Ballots)
+			in = mixes[len(mixes)-1].Ballots
 		}
-		v, w := Split(t.Mix.Ballots)
-		err = Verify(t.Mix.Proof, election.Key, x, y, v, w)
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return VerifyMix(t.Mix.Proof, election.Key, in, t.Mix.Ballots)
 	} else if t.Partial != nil {
 		election, err := GetElection(s, genesis, false, t.User)
 		if err != nil {
@@ -319,7 +351,7 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		}
 
 		// verify proposer
-		_, proposer := election.Roster.Search(t.Partial.NodeID)
+		index, proposer := election.Roster.Search(t.Partial.NodeID)
 		if proposer == nil {
 			return errors.New("didn't find node who created the partial")
 		}
@@ -331,6 +363,85 @@ func (t *Transaction) Verify(genesis skipchain.SkipBlockID, s *skipchain.Service
 		if err != nil {
 			return err
 		}
+
+		// Elections opened before the commits were kept can't be checked.
+		if len(election.Commits) == 0 {
+			return nil
+		}
+		return VerifyPartial(election.Commits, index, mixes[len(mixes)-1].Ballots, t.Partial)
+	} else if t.Dedup != nil {
+		election, err := GetElection(s, genesis, false, t.User)
+		if err != nil {
+			return err
+		}
+		if !election.IsCreator(t.User) {
+			return errors.New("dedup error: user is not election creator")
+		}
+		prev, err := election.Dedup(s)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			return errors.New("dedup error: ballots already deduplicated")
+		}
+		mixes, err := election.Mixes(s)
+		if err != nil {
+			return err
+		}
+		if len(mixes) > 0 {
+			return errors.New("dedup error: election already shuffled")
+		}
+
+		ballots, blocks, err := election.Ballots(s)
+		if err != nil {
+			return err
+		}
+		indexes := make([]int, len(blocks))
+		for i, block := range blocks {
+			indexes[i] = block.Index
+		}
+		dedup, err := election.NewDedup(ballots, indexes)
+		if err != nil {
+			return err
+		}
+		if !equalDedups(t.Dedup, dedup) {
+			return errors.New("dedup error: dedup doesn't match the ballots")
+		}
+		return nil
+	} else if t.Result != nil {
+		election, err := GetElection(s, genesis, false, t.User)
+		if err != nil {
+			return err
+		}
+		if !election.IsCreator(t.User) {
+			return errors.New("tally error: user is not election creator")
+		}
+		prev, err := election.Result(s)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			return errors.New("tally error: election already tallied")
+		}
+
+		partials, err := election.Partials(s)
+		if err != nil {
+			return err
+		}
+		if len(partials) <= 2*len(election.Roster.List)/3 {
+			return errors.New("tally error: election not decrypted yet")
+		}
+		points, err := Reconstruct(election.Roster, partials)
+		if err != nil {
+			return err
+		}
+		result, err := Tally(election, points)
+		if err != nil {
+			return err
+		}
+		if !equalResults(t.Result, result) {
+			return errors.New("tally error: result doesn't match the ballots")
+		}
 		return nil
 	}
 	return errors.New("transaction error: empty transaction")
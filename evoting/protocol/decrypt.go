@@ -5,7 +5,6 @@ import (
 	"sync"
 
 	"go.dedis.ch/cothority/v3"
-	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
@@ -101,9 +100,9 @@ func (d *Decrypt) HandlePrompt(prompt MessagePromptDecrypt) error {
 	if !d.IsRoot() || d.LeaderParticipates {
 		err := func() error {
 			mix := mixes[len(mixes)-1]
-			points := make([]kyber.Point, len(mix.Ballots))
-			for i := range points {
-				points[i] = lib.Decrypt(d.Secret.V, mix.Ballots[i].Alpha, mix.Ballots[i].Beta)
+			points, proofs, err := lib.DecryptBallots(d.Secret.V, mix.Ballots)
+			if err != nil {
+				return d.SendTo(d.Root(), &TerminateDecrypt{Error: err.Error()})
 			}
 			index := -1
 			for i, node := range d.Election.Roster.List {
@@ -118,6 +117,7 @@ func (d *Decrypt) HandlePrompt(prompt MessagePromptDecrypt) error {
 
 			partial = &lib.Partial{
 				Points: points,
+				Proofs: proofs,
 				NodeID: d.ServerIdentity().ID,
 			}
 			data, err := d.ServerIdentity().Public.MarshalBinary()
@@ -5,7 +5,6 @@ import (
 	"testing"
 	"time"
 
-	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/proof"
 	"go.dedis.ch/kyber/v3/shuffle"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
@@ -215,14 +214,13 @@ func TestDecryptNodeFailure(t *testing.T) {
 	// we're trying to simulate a decryption that failed previously
 	for i := 0; i < 2; i++ {
 		mix := mixes[len(mixes)-1]
-		points := make([]kyber.Point, len(mix.Ballots))
-		for i := range points {
-			secret, _ := lib.NewSharedSecret(dkgs[i])
-			points[i] = lib.Decrypt(secret.V, mix.Ballots[i].Alpha, mix.Ballots[i].Beta)
-		}
+		secret, _ := lib.NewSharedSecret(dkgs[i])
+		points, proofs, err := lib.DecryptBallots(secret.V, mix.Ballots)
+		require.NoError(t, err)
 
 		partial := &lib.Partial{
 			Points: points,
+			Proofs: proofs,
 			NodeID: nodes[i].ServerIdentity.ID,
 		}
 		data, _ := nodes[i].ServerIdentity.Public.MarshalBinary()
@@ -234,7 +232,9 @@ func TestDecryptNodeFailure(t *testing.T) {
 		lib.StoreUsingWebsocket(election.ID, election.Roster, transaction)
 	}
 
-	rooted := onet.NewRoster(append([]*network.ServerIdentity{tree.Roster.List[0]}, tree.Roster.List[2:]...))
+	// Nodes 1, 5 and 6 are down, so the three remaining nodes have to store
+	// their partials before the protocol finishes.
+	rooted := onet.NewRoster(append([]*network.ServerIdentity{tree.Roster.List[0]}, tree.Roster.List[2:5]...))
 	protocolTree := rooted.GenerateNaryTree(1)
 	instance, _ := services[0].(*decryptService).CreateProtocol(NameDecrypt, protocolTree)
 	decrypt := instance.(*Decrypt)
@@ -4,10 +4,7 @@ import (
 	"errors"
 
 	"go.dedis.ch/cothority/v3"
-	"go.dedis.ch/kyber/v3/proof"
-	"go.dedis.ch/kyber/v3/shuffle"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
-	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
@@ -129,25 +126,13 @@ func (s *Shuffle) HandlePrompt(prompt MessagePrompt) error {
 			return nil
 		}
 
-		a, b := lib.Split(ballots)
-		// Protect from missing input.
-		for i := range a {
-			if a[i] == nil {
-				a[i] = cothority.Suite.Point().Null()
-			}
-		}
-		for i := range b {
-			if b[i] == nil {
-				b[i] = cothority.Suite.Point().Null()
-			}
-		}
-		g, d, prov := shuffle.Shuffle(cothority.Suite, nil, s.Election.Key, a, b, random.New())
-		proof, err := proof.HashProve(cothority.Suite, "", prov)
+		// Missing points are replaced by null points.
+		shuffled, proof, err := lib.ShuffleBallots(s.Election.Key, ballots)
 		if err != nil {
 			return err
 		}
 		mix = &lib.Mix{
-			Ballots: lib.Combine(g, d),
+			Ballots: shuffled,
 			Proof:   proof,
 			NodeID:  s.ServerIdentity().ID,
 		}
@@ -1,4 +1,5 @@
-// Package service is the evoting service designed for use at EPFL.
+// Package service is the evoting service designed for use at EPFL. Other
+// institutions can use it with their own voter directory.
 package service
 
 import (
@@ -8,19 +9,18 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"os"
 	"strconv"
 	"sync"
 	"time"
 
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/kyber/v3/util/random"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
 
-	"github.com/go-ldap/ldap/v3"
 	"go.dedis.ch/cothority/v3"
 	dkgprotocol "go.dedis.ch/cothority/v3/dkg/rabin"
 	"go.dedis.ch/cothority/v3/evoting"
@@ -39,6 +39,12 @@ func init() {
 // timeout for protocol termination.
 var timeout = 120 * time.Second
 
+// directoriesEnv is the environment variable pointing to the TOML file
+// describing the voter directories of the conode.
+const directoriesEnv = "COTHORITY_EVOTING_DIRECTORIES"
+
+var loadDirectories sync.Once
+
 // serviceID is the onet identifier.
 var serviceID onet.ServiceID
 
@@ -59,6 +65,12 @@ type Service struct {
 	sciperMu    sync.Mutex
 	sciperCache []cacheEntry
 
+	// prepared holds the last ballot encrypted by the leader for a user,
+	// by election and user, until it is cast or challenged. The challenged
+	// ballots are in storage.Revealed.
+	ballotsMu sync.Mutex
+	prepared  map[string]*preparedBallot
+
 	pin string // pin is the current service number.
 }
 
@@ -67,6 +79,21 @@ type storage struct {
 	Roster  *onet.Roster
 	Master  skipchain.SkipBlockID
 	Secrets map[string]*lib.SharedSecret
+	// Revealed holds the hashes of the challenged ballots, in hex, which
+	// can't be cast, with the end of their election, after which they are
+	// dropped.
+	Revealed map[string]int64
+}
+
+// maxPrepared is the maximum number of ballots prepared by the leader that
+// are not cast or challenged yet.
+const maxPrepared = 100000
+
+// preparedBallot is a ballot encrypted by the leader, with its opening.
+type preparedBallot struct {
+	ballot  *lib.Ballot
+	opening *lib.Opening
+	end     int64 // end of the election, after which it is dropped
 }
 
 // synchronizer is broadcasted to all roster nodes before every protocol.
@@ -85,6 +112,9 @@ func (s *Service) Link(req *evoting.Link) (*evoting.LinkReply, error) {
 	if req.Pin != s.pin {
 		return nil, errors.New("link error: invalid pin")
 	}
+	if _, err := evoting.GetDirectory(req.Directory); err != nil {
+		return nil, err
+	}
 
 	var id skipchain.SkipBlockID
 	var user uint32
@@ -116,10 +146,11 @@ func (s *Service) Link(req *evoting.Link) (*evoting.LinkReply, error) {
 	}
 
 	master := &lib.Master{
-		ID:     id,
-		Roster: req.Roster,
-		Admins: req.Admins,
-		Key:    req.Key,
+		ID:        id,
+		Roster:    req.Roster,
+		Admins:    req.Admins,
+		Key:       req.Key,
+		Directory: req.Directory,
 	}
 	transaction := lib.NewTransaction(master, user)
 	if _, err := lib.Store(s.skipchain, master.ID, transaction, s.ServerIdentity().GetPrivate()); err != nil {
@@ -130,7 +161,9 @@ func (s *Service) Link(req *evoting.Link) (*evoting.LinkReply, error) {
 	s.storage.Master = id
 	s.storage.Roster = req.Roster
 	s.mutex.Unlock()
-	s.save()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
 
 	return &evoting.LinkReply{ID: id}, nil
 }
@@ -181,6 +214,10 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 		cur.End = req.Election.End
 		cur.Theme = req.Election.Theme
 		cur.Footer = req.Election.Footer
+		cur.Type = req.Election.Type
+		cur.Seats = req.Election.Seats
+		cur.Weights = req.Election.Weights
+		cur.Revoting = req.Election.Revoting
 
 		transaction := lib.NewTransaction(cur, req.User)
 		if _, err := lib.Store(s.skipchain, req.Election.ID, transaction, s.ServerIdentity().GetPrivate()); err != nil {
@@ -218,6 +255,7 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 		req.Election.Master = req.ID
 		req.Election.Roster = master.Roster
 		req.Election.Key = secret.X
+		req.Election.Commits = secret.Commits
 		req.Election.MasterKey = master.Key
 		req.Election.Creator = req.User
 
@@ -235,7 +273,9 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 		s.mutex.Lock()
 		s.storage.Secrets[genesis.Short()] = secret
 		s.mutex.Unlock()
-		s.save()
+		if err := s.save(); err != nil {
+			return nil, err
+		}
 
 		// Autovote mode: cast 1 empty ballot for the first user; useful to test shuffles in the UI
 		// without having to login as two users. Will cause unit tests to fail.
@@ -263,16 +303,17 @@ func (s *Service) Open(req *evoting.Open) (*evoting.OpenReply, error) {
 }
 
 type cacheEntry struct {
-	id      int
-	reply   *evoting.LookupSciperReply
-	expires time.Time
+	directory string
+	id        uint32
+	reply     *evoting.LookupSciperReply
+	expires   time.Time
 }
 
 const sciperCacheLen = 100
 
-func (s *Service) sciperGetNoLock(sciper int) *evoting.LookupSciperReply {
+func (s *Service) sciperGetNoLock(directory string, id uint32) *evoting.LookupSciperReply {
 	for _, r := range s.sciperCache {
-		if r.id == sciper && r.expires.After(time.Now()) {
+		if r.directory == directory && r.id == id && r.expires.After(time.Now()) {
 			return r.reply
 		}
 	}
@@ -281,24 +322,25 @@ func (s *Service) sciperGetNoLock(sciper int) *evoting.LookupSciperReply {
 
 // sciperGet runs through the cache looking for a match. The search is linear
 // because the cache is small, and the whole thing will fit in a couple of cache lines.
-func (s *Service) sciperGet(sciper int) (reply *evoting.LookupSciperReply) {
+func (s *Service) sciperGet(directory string, id uint32) (reply *evoting.LookupSciperReply) {
 	s.sciperMu.Lock()
-	reply = s.sciperGetNoLock(sciper)
+	reply = s.sciperGetNoLock(directory, id)
 	s.sciperMu.Unlock()
 	return
 }
 
 // sciperPut puts an entry into the cache, if it is not present
-func (s *Service) sciperPut(sciper int, reply *evoting.LookupSciperReply) {
+func (s *Service) sciperPut(directory string, id uint32, reply *evoting.LookupSciperReply) {
 	s.sciperMu.Lock()
 	defer s.sciperMu.Unlock()
 
 	// check that no one raced us to put their own copy in.
-	if s.sciperGetNoLock(sciper) == nil {
+	if s.sciperGetNoLock(directory, id) == nil {
 		s.sciperCache = append(s.sciperCache, cacheEntry{
-			id:      sciper,
-			reply:   reply,
-			expires: time.Now().Add(1 * time.Hour),
+			directory: directory,
+			id:        id,
+			reply:     reply,
+			expires:   time.Now().Add(1 * time.Hour),
 		})
 		if len(s.sciperCache) > sciperCacheLen {
 			from := len(s.sciperCache) - sciperCacheLen
@@ -309,61 +351,59 @@ func (s *Service) sciperPut(sciper int, reply *evoting.LookupSciperReply) {
 	return
 }
 
-// LookupSciper calls https://people.epfl.ch/cgi-bin/people/vCard?id=sciper
-// to convert Sciper numbers to names.
+// LookupSciper looks up a user in the voter directory of the master skipchain
+// given in the request. Without a master, the default directory of EPFL is
+// used.
 func (s *Service) LookupSciper(req *evoting.LookupSciper) (*evoting.LookupSciperReply, error) {
-	if len(req.Sciper) != 6 {
+	directory := evoting.DefaultDirectory
+	if len(req.Master) > 0 {
+		master, err := lib.GetMaster(s.skipchain, req.Master)
+		if err != nil {
+			return nil, err
+		}
+		if master.Directory != "" {
+			directory = master.Directory
+		}
+	}
+
+	if directory == evoting.DefaultDirectory && len(req.Sciper) != 6 {
 		return nil, errors.New("sciper should be 6 digits only")
 	}
-	sciper, err := strconv.Atoi(req.Sciper)
+	id, err := strconv.ParseUint(req.Sciper, 10, 32)
 	if err != nil {
 		return nil, errors.New("couldn't convert Sciper to integer")
 	}
+	user := uint32(id)
 
 	// Try to find it in cache first
-	if res := s.sciperGet(sciper); res != nil {
-		log.Lvl3("Got vcard (cache hit)", res)
+	if res := s.sciperGet(directory, user); res != nil {
+		log.Lvl3("Got voter (cache hit)", res)
 		return res, nil
 	}
 
-	url := "ldaps://ldap.epfl.ch"
-	if req.LookupURL != "" {
-		url = req.LookupURL
-	}
-	l, err := ldap.DialURL(url)
+	dir, err := evoting.GetDirectory(directory)
 	if err != nil {
 		return nil, err
 	}
-	defer l.Close()
-
-	// Search for the given username
-	searchRequest := ldap.NewSearchRequest(
-		"o=epfl, c=ch",
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		fmt.Sprintf("(&(objectClass=person)(uniqueIdentifier=%d))", sciper),
-		[]string{"displayName", "mail"},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
+	if ldap, ok := dir.(*evoting.LDAPDirectory); ok && req.LookupURL != "" {
+		test := *ldap
+		test.URL = req.LookupURL
+		dir = &test
+	}
+	voter, err := dir.Lookup(user)
 	if err != nil {
 		return nil, err
 	}
 
-	// If no results, err.
-	// If more than one are returned, we look only at the first one.
-	if len(sr.Entries) == 0 {
-		return nil, errors.New("SCIPER not found")
+	reply := &evoting.LookupSciperReply{
+		FullName: voter.FullName,
+		Email:    voter.Email,
 	}
 
-	reply := &evoting.LookupSciperReply{}
-	reply.FullName = sr.Entries[0].GetAttributeValue("displayName")
-	reply.Email = sr.Entries[0].GetAttributeValue("mail")
-
 	// Put it into the cache
-	s.sciperPut(sciper, reply)
+	s.sciperPut(directory, user, reply)
 
-	log.Lvl3("Got vcard (cache miss): ", reply)
+	log.Lvl3("Got voter (cache miss): ", reply)
 	return reply, nil
 }
 
@@ -394,12 +434,208 @@ func (s *Service) Cast(req *evoting.Cast) (*evoting.CastReply, error) {
 		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: %v", req.ID, req.User, err)
 	}
 
+	if req.Ballot == nil {
+		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: no ballot", req.ID, req.User)
+	}
+	if election.Revoting == lib.NoRevoting {
+		_, cast, err := election.Effective(s.skipchain, req.User)
+		if err != nil {
+			return nil, fmt.Errorf("could not cast ballot on election %x for user %v: %v", req.ID, req.User, err)
+		}
+		if cast > 0 {
+			return nil, fmt.Errorf("could not cast ballot on election %x for user %v: user already voted", req.ID, req.User)
+		}
+	}
+	s.mutex.Lock()
+	_, revealed := s.storage.Revealed[hex.EncodeToString(req.Ballot.Hash())]
+	s.mutex.Unlock()
+	if revealed {
+		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: the ballot was challenged", req.ID, req.User)
+	}
+
 	transaction := lib.NewTransaction(req.Ballot, req.User)
 	skipblockID, err := lib.Store(s.skipchain, req.ID, transaction, s.ServerIdentity().GetPrivate())
 	if err != nil {
 		return nil, fmt.Errorf("could not cast ballot on election %x for user %v: %v", req.ID, req.User, err)
 	}
-	return &evoting.CastReply{ID: skipblockID}, nil
+
+	s.ballotsMu.Lock()
+	delete(s.prepared, preparedKey(req.ID, req.User))
+	s.ballotsMu.Unlock()
+
+	receipt, err := lib.NewReceipt(s.skipchain, req.ID, skipblockID)
+	if err != nil {
+		log.Error(s.ServerIdentity(), "couldn't create the receipt of the ballot:", err)
+	}
+	return &evoting.CastReply{ID: skipblockID, Receipt: receipt}, nil
+}
+
+// Prepare message handler. It encrypts the choices of the user, so that the
+// user can check the encryption by challenging the ballot before casting
+// another one. The leader sees the choices in clear: the user trusts it to
+// keep them secret, and the challenges only check that it encrypts them
+// correctly. Users who don't trust the leader encrypt their ballots
+// themselves and cast them directly.
+func (s *Service) Prepare(req *evoting.Prepare) (*evoting.PrepareReply, error) {
+	if !s.leader() {
+		return nil, errOnlyLeader
+	}
+
+	election, err := lib.GetElection(s.skipchain, req.ID, false, req.User)
+	if err != nil {
+		return nil, err
+	}
+	if err = auth(req.User, req.Signature, election.Master, election.MasterKey); err != nil {
+		return nil, err
+	}
+	if !election.IsUser(req.User) {
+		return nil, errors.New("prepare error: user not part")
+	}
+	if time.Now().After(time.Unix(election.End, 0)) {
+		return nil, errors.New("prepare error: election is already closed")
+	}
+
+	ballot, opening, err := election.EncryptBallotOpening(req.User, req.Choices)
+	if err != nil {
+		return nil, err
+	}
+	s.ballotsMu.Lock()
+	defer s.ballotsMu.Unlock()
+	if err := s.dropClosed(); err != nil {
+		return nil, err
+	}
+	key := preparedKey(req.ID, req.User)
+	if _, ok := s.prepared[key]; !ok && len(s.prepared) >= maxPrepared {
+		return nil, errors.New("prepare error: too many ballots prepared")
+	}
+	s.prepared[key] = &preparedBallot{ballot: ballot, opening: opening,
+		end: election.End}
+	return &evoting.PrepareReply{Ballot: ballot}, nil
+}
+
+// Challenge message handler. It reveals the opening of the last ballot
+// prepared for the user, which can't be cast afterwards.
+func (s *Service) Challenge(req *evoting.Challenge) (*evoting.ChallengeReply, error) {
+	if !s.leader() {
+		return nil, errOnlyLeader
+	}
+
+	election, err := lib.GetElection(s.skipchain, req.ID, false, req.User)
+	if err != nil {
+		return nil, err
+	}
+	if err = auth(req.User, req.Signature, election.Master, election.MasterKey); err != nil {
+		return nil, err
+	}
+
+	s.ballotsMu.Lock()
+	defer s.ballotsMu.Unlock()
+	key := preparedKey(req.ID, req.User)
+	prepared, ok := s.prepared[key]
+	if !ok {
+		return nil, errors.New("challenge error: no ballot prepared")
+	}
+	delete(s.prepared, key)
+	s.mutex.Lock()
+	s.storage.Revealed[hex.EncodeToString(prepared.ballot.Hash())] = prepared.end
+	s.mutex.Unlock()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &evoting.ChallengeReply{Ballot: prepared.ballot, Opening: prepared.opening}, nil
+}
+
+// dropClosed removes the prepared and the revealed ballots of the elections
+// that are closed, as they can't be cast anymore. It must be called with
+// ballotsMu held.
+func (s *Service) dropClosed() error {
+	now := time.Now().Unix()
+	for key, prepared := range s.prepared {
+		if prepared.end < now {
+			delete(s.prepared, key)
+		}
+	}
+	s.mutex.Lock()
+	dropped := false
+	for hash, end := range s.storage.Revealed {
+		if end < now {
+			delete(s.storage.Revealed, hash)
+			dropped = true
+		}
+	}
+	s.mutex.Unlock()
+	if dropped {
+		return s.save()
+	}
+	return nil
+}
+
+// GetEffectiveBallot message handler. It returns the ballot of the user that
+// counts, following the revoting policy of the election. Anybody can ask, as
+// the ballots are public in the election skipchain.
+func (s *Service) GetEffectiveBallot(req *evoting.GetEffectiveBallot) (*evoting.GetEffectiveBallotReply, error) {
+	election, err := lib.GetElection(s.skipchain, req.ID, false, req.User)
+	if err != nil {
+		return nil, err
+	}
+	block, cast, err := election.Effective(s.skipchain, req.User)
+	if err != nil {
+		return nil, err
+	}
+	reply := &evoting.GetEffectiveBallotReply{Cast: cast, Policy: election.Revoting}
+	if block != nil {
+		reply.Block = block.Hash
+		reply.Ballot = lib.UnmarshalTransaction(block.Data).Ballot
+	}
+	return reply, nil
+}
+
+// CheckReceipt message handler. It returns an error if the ballot of the
+// receipt is not the one of the user in the box of the election.
+func (s *Service) CheckReceipt(req *evoting.CheckReceipt) (*evoting.CheckReceiptReply, error) {
+	if req.Receipt == nil {
+		return nil, errors.New("no receipt")
+	}
+	election, err := lib.GetElection(s.skipchain, req.Receipt.Election, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	box, err := election.Box(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.Receipt.Check(election, box); err != nil {
+		return nil, err
+	}
+	return &evoting.CheckReceiptReply{Stage: election.Stage}, nil
+}
+
+// dedup stores the record of the ballots put in the box, if it isn't stored
+// yet.
+func (s *Service) dedup(election *lib.Election, user uint32) error {
+	prev, err := election.Dedup(s.skipchain)
+	if err != nil || prev != nil {
+		return err
+	}
+	ballots, blocks, err := election.Ballots(s.skipchain)
+	if err != nil {
+		return err
+	}
+	indexes := make([]int, len(blocks))
+	for i, block := range blocks {
+		indexes[i] = block.Index
+	}
+	dedup, err := election.NewDedup(ballots, indexes)
+	if err != nil {
+		return err
+	}
+	transaction := lib.NewTransaction(dedup, user)
+	_, err = lib.Store(s.skipchain, election.ID, transaction, s.ServerIdentity().GetPrivate())
+	return err
+}
+
+func preparedKey(election skipchain.SkipBlockID, user uint32) string {
+	return fmt.Sprintf("%x/%d", []byte(election), user)
 }
 
 // GetElections message handler. Return all elections in which the given user participates.
@@ -513,6 +749,13 @@ func (s *Service) Shuffle(req *evoting.Shuffle) (*evoting.ShuffleReply, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	// Record which ballots are shuffled before the first shuffle.
+	if len(mixes) == 0 {
+		if err = s.dedup(election, req.User); err != nil {
+			return nil, err
+		}
+	}
 	participated := make(map[string]bool)
 	for _, mix := range mixes {
 		participated[mix.NodeID.String()] = true
@@ -664,25 +907,59 @@ func (s *Service) Reconstruct(req *evoting.Reconstruct) (*evoting.ReconstructRep
 		return nil, errors.New("reconstruct error, election not closed yet")
 	}
 
-	points := make([]kyber.Point, 0)
+	points, err := lib.Reconstruct(election.Roster, partials)
+	if err != nil {
+		return nil, err
+	}
+	return &evoting.ReconstructReply{Points: points}, nil
+}
+
+// Tally message handler. Counts the decrypted ballots and stores the result
+// on the election skipchain, where every node verifies it.
+func (s *Service) Tally(req *evoting.Tally) (*evoting.TallyReply, error) {
+	s.finalizeMutex.Lock()
+	defer s.finalizeMutex.Unlock()
+	if !s.leader() {
+		return nil, errOnlyLeader
+	}
+
+	election, err := lib.GetElection(s.skipchain, req.ID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	err = auth(req.User, req.Signature, election.Master, election.MasterKey)
+	if err != nil {
+		return nil, err
+	}
 
-	n := len(election.Roster.List)
-	for i := 0; i < len(partials[0].Points); i++ {
-		shares := make([]*share.PubShare, n)
-		for _, partial := range partials {
-			j, _ := election.Roster.Search(partial.NodeID)
-			shares[j] = &share.PubShare{I: j, V: partial.Points[i]}
-		}
+	result, err := election.Result(s.skipchain)
+	if err != nil {
+		return nil, err
+	}
+	if result != nil {
+		return &evoting.TallyReply{Result: result}, nil
+	}
 
-		log.Lvl3("Recovering commits", i)
-		message, err := share.RecoverCommit(cothority.Suite, shares, 2*n/3+1, n)
-		if err != nil {
-			return nil, err
-		}
-		points = append(points, message)
+	partials, err := election.Partials(s.skipchain)
+	if err != nil {
+		return nil, err
+	} else if len(partials) <= 2*len(election.Roster.List)/3 {
+		return nil, errors.New("tally error, election not decrypted yet")
+	}
+	points, err := lib.Reconstruct(election.Roster, partials)
+	if err != nil {
+		return nil, err
+	}
+	result, err = lib.Tally(election, points)
+	if err != nil {
+		return nil, err
 	}
 
-	return &evoting.ReconstructReply{Points: points}, nil
+	transaction := lib.NewTransaction(result, req.User)
+	if _, err := lib.Store(s.skipchain, election.ID, transaction, s.ServerIdentity().GetPrivate()); err != nil {
+		return nil, err
+	}
+	return &evoting.TallyReply{Result: result}, nil
 }
 
 // NewProtocol hooks non-root nodes into created protocols.
@@ -706,7 +983,9 @@ func (s *Service) NewProtocol(node *onet.TreeNodeInstance, conf *onet.GenericCon
 			s.mutex.Lock()
 			s.storage.Secrets[sync.ID.Short()] = secret
 			s.mutex.Unlock()
-			s.save()
+			if err := s.save(); err != nil {
+				log.Error(err)
+			}
 		}()
 		return protocol, nil
 	case protocol.NameShuffle:
@@ -824,15 +1103,16 @@ func (s *Service) secret(id skipchain.SkipBlockID) *lib.SharedSecret {
 }
 
 // save saves the storage onto the disk.
-func (s *Service) save() {
+func (s *Service) save() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	if err := s.Save(storageKey, s.storage); err != nil {
-		log.Error(err)
+		return fmt.Errorf("saving data: %v", err)
 	}
 	if err := s.SaveVersion(dbVersion); err != nil {
-		log.Error(err)
+		return fmt.Errorf("saving version: %v", err)
 	}
+	return nil
 }
 
 // load fetches the storage from disk.
@@ -859,6 +1139,9 @@ func (s *Service) load() error {
 	if s.storage.Secrets == nil {
 		s.storage.Secrets = make(map[string]*lib.SharedSecret)
 	}
+	if s.storage.Revealed == nil {
+		s.storage.Revealed = make(map[string]int64)
+	}
 	return nil
 }
 
@@ -871,9 +1154,21 @@ func new(context *onet.Context) (onet.Service, error) {
 	service := &Service{
 		ServiceProcessor: onet.NewServiceProcessor(context),
 		storage: &storage{
-			Secrets: make(map[string]*lib.SharedSecret),
+			Secrets:  make(map[string]*lib.SharedSecret),
+			Revealed: make(map[string]int64),
 		},
 		skipchain: context.Service(skipchain.ServiceName).(*skipchain.Service),
+		prepared:  make(map[string]*preparedBallot),
+	}
+
+	var err error
+	loadDirectories.Do(func() {
+		if file := os.Getenv(directoriesEnv); file != "" {
+			err = evoting.LoadDirectories(file)
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	service.RegisterHandlers(
@@ -888,6 +1183,11 @@ func new(context *onet.Context) (onet.Service, error) {
 		service.GetPartials,
 		service.Decrypt,
 		service.Reconstruct,
+		service.Tally,
+		service.Prepare,
+		service.Challenge,
+		service.CheckReceipt,
+		service.GetEffectiveBallot,
 		service.LookupSciper,
 	)
 	skipchain.RegisterVerification(context, lib.TransactionVerifierID, service.verify)
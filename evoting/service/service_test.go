@@ -893,7 +893,19 @@ func TestShuffleCatastrophicNodeFailure(t *testing.T) {
 		User:      idAdmin,
 		Signature: adminSig,
 	})
+	if err != nil {
+		// Unpausing closes the connections that got a message during the
+		// pause, and a prompt sent over them in the meantime is lost. Once
+		// they are closed, the shuffle reaches the nodes.
+		log.Lvl2("Shuffle got lost on a paused connection, retrying")
+		_, err = s0.Shuffle(&evoting.Shuffle{
+			ID:        electionID,
+			User:      idAdmin,
+			Signature: adminSig,
+		})
+	}
 	require.NoError(t, err)
+	require.Nil(t, local.WaitDone(timeout))
 }
 
 func TestDecryptBenignNodeFailure(t *testing.T) {
@@ -1015,6 +1027,11 @@ func TestDecryptCatastrophicNodeFailure(t *testing.T) {
 		Signature: adminSig,
 	})
 	require.NoError(t, err)
+
+	// Messages lost on the paused connections leave the signing protocols
+	// of the blocks waiting for their timeout.
+	log.Lvl2("Waiting for protocols to finish")
+	require.Nil(t, local.WaitDone(timeout))
 }
 
 func TestCastNodeFailureShuffleAllOk(t *testing.T) {
@@ -1118,3 +1135,497 @@ func TestLookupSciper(t *testing.T) {
 	require.Equal(t, reply.FullName, "Bryan Alexander Ford")
 	require.Equal(t, reply.Email, "bryan.ford@epfl.ch")
 }
+
+func TestLookupVoterDirectory(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	sc0.SetPropTimeout(defaultTimeout)
+
+	evoting.RegisterDirectory("test", evoting.NewMemoryDirectory(
+		evoting.Voter{ID: 42, FullName: "Jane Doe", Email: "jane@example.com"}))
+
+	// Unknown directories are refused.
+	_, err := s0.Link(&evoting.Link{
+		Pin:       s0.pin,
+		Roster:    roster,
+		Key:       key.NewKeyPair(cothority.Suite).Public,
+		Admins:    []uint32{42},
+		Directory: "unknown",
+	})
+	require.Error(t, err)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:       s0.pin,
+		Roster:    roster,
+		Key:       key.NewKeyPair(cothority.Suite).Public,
+		Admins:    []uint32{42},
+		Directory: "test",
+	})
+	require.NoError(t, err)
+
+	// The directory of the master is used, without the SCIPER format.
+	reply, err := s0.LookupSciper(&evoting.LookupSciper{Sciper: "42", Master: replyLink.ID})
+	require.NoError(t, err)
+	require.Equal(t, "Jane Doe", reply.FullName)
+	require.Equal(t, "jane@example.com", reply.Email)
+
+	_, err = s0.LookupSciper(&evoting.LookupSciper{Sciper: "43", Master: replyLink.ID})
+	require.Error(t, err)
+
+	// Without a master, it is still a SCIPER.
+	_, err = s0.LookupSciper(&evoting.LookupSciper{Sciper: "42"})
+	require.Error(t, err)
+}
+
+// Runs a weighted ranked-choice election with ballots of two points until the
+// result is stored on the election skipchain.
+func TestRankedWeightedTally(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodeKP := key.NewKeyPair(cothority.Suite)
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	sc0.SetPropTimeout(defaultTimeout)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:    s0.pin,
+		Roster: roster,
+		Key:    nodeKP.Public,
+		Admins: []uint32{idAdmin},
+	})
+	require.NoError(t, err)
+	idAdminSig := generateSignature(nodeKP.Private, replyLink.ID, idAdmin)
+
+	// Ten candidates don't fit in a single point.
+	var candidates []uint32
+	for i := uint32(0); i < 10; i++ {
+		candidates = append(candidates, idCand1+i)
+	}
+	replyOpen, err := s0.Open(&evoting.Open{
+		ID: replyLink.ID,
+		Election: &lib.Election{
+			Name:       map[string]string{"en": "ranked"},
+			Creator:    idAdmin,
+			Users:      []uint32{idUser1, idUser2, idUser3},
+			Weights:    []uint32{1, 1, 3},
+			Candidates: candidates,
+			MaxChoices: len(candidates),
+			Type:       lib.Ranked,
+			Roster:     roster,
+			Start:      yesterday.Unix(),
+			End:        tomorrow.Unix(),
+		},
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+
+	box, err := s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+	require.NoError(t, err)
+	election := box.Election
+	require.Equal(t, 2, election.ChoicePoints())
+
+	vote := func(user uint32, ranking ...uint32) error {
+		ballot, err := election.EncryptBallot(user, ranking)
+		require.NoError(t, err)
+		_, err = s0.Cast(&evoting.Cast{
+			ID:        replyOpen.ID,
+			Ballot:    ballot,
+			User:      user,
+			Signature: generateSignature(nodeKP.Private, replyLink.ID, user),
+		})
+		return err
+	}
+
+	// A ballot of a single point is refused.
+	k, c := lib.Encrypt(replyOpen.Key, bufCand1)
+	_, err = s0.Cast(&evoting.Cast{
+		ID:        replyOpen.ID,
+		Ballot:    &lib.Ballot{User: idUser1, Alpha: k, Beta: c},
+		User:      idUser1,
+		Signature: generateSignature(nodeKP.Private, replyLink.ID, idUser1),
+	})
+	require.Error(t, err)
+
+	// Two voters prefer the first candidate, but the third one weighs more.
+	require.NoError(t, vote(idUser1, candidates[0], candidates[1]))
+	require.NoError(t, vote(idUser2, candidates[0], candidates[9]))
+	require.NoError(t, vote(idUser3, candidates[9], candidates[0]))
+
+	_, err = s0.Shuffle(&evoting.Shuffle{
+		ID:        replyOpen.ID,
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+
+	// Tallying needs the decryption.
+	_, err = s0.Tally(&evoting.Tally{
+		ID:        replyOpen.ID,
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.Error(t, err)
+
+	_, err = s0.Decrypt(&evoting.Decrypt{
+		ID:        replyOpen.ID,
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+	require.Nil(t, local.WaitDone(time.Second))
+
+	reconstructReply, err := s0.Reconstruct(&evoting.Reconstruct{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.Equal(t, 3*election.Width(), len(reconstructReply.Points))
+
+	tallyReply, err := s0.Tally(&evoting.Tally{
+		ID:        replyOpen.ID,
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, tallyReply.Result.Valid)
+	require.Equal(t, []uint32{candidates[9]}, tallyReply.Result.Elected)
+
+	// The result is on the chain, and a second tally returns it.
+	stored, err := election.Result(sc0)
+	require.NoError(t, err)
+	require.Equal(t, tallyReply.Result.Ballots, stored.Ballots)
+	tallyReply2, err := s0.Tally(&evoting.Tally{
+		ID:        replyOpen.ID,
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+	require.Equal(t, tallyReply.Result.Ballots, tallyReply2.Result.Ballots)
+}
+
+func TestAuditElection(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodeKP := key.NewKeyPair(cothority.Suite)
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	sc0.SetPropTimeout(defaultTimeout)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:    s0.pin,
+		Roster: roster,
+		Key:    nodeKP.Public,
+		Admins: []uint32{idAdmin},
+	})
+	require.NoError(t, err)
+	idAdminSig := generateSignature(nodeKP.Private, replyLink.ID, idAdmin)
+
+	replyOpen, err := s0.Open(&evoting.Open{
+		ID: replyLink.ID,
+		Election: &lib.Election{
+			Name:       map[string]string{"en": "audit"},
+			Creator:    idAdmin,
+			Users:      []uint32{idUser1, idUser2, idUser3},
+			Candidates: []uint32{idCand1, idCand2},
+			MaxChoices: 1,
+			Roster:     roster,
+			Start:      yesterday.Unix(),
+			End:        tomorrow.Unix(),
+		},
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+
+	box, err := s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+	require.NoError(t, err)
+	election := box.Election
+	require.Equal(t, 3, len(election.Commits))
+
+	vote := func(user uint32, candidate uint32) {
+		ballot, err := election.EncryptBallot(user, []uint32{candidate})
+		require.NoError(t, err)
+		_, err = s0.Cast(&evoting.Cast{
+			ID:        replyOpen.ID,
+			Ballot:    ballot,
+			User:      user,
+			Signature: generateSignature(nodeKP.Private, replyLink.ID, user),
+		})
+		require.NoError(t, err)
+	}
+	// The first voter changes their mind, only the second ballot counts.
+	vote(idUser1, idCand1)
+	vote(idUser2, idCand2)
+	vote(idUser3, idCand2)
+	vote(idUser1, idCand2)
+
+	// Before the shuffle, the audit only checks the ballots.
+	blocks, err := lib.DownloadElection(roster, replyOpen.ID)
+	require.NoError(t, err)
+	audit, err := lib.AuditElection(blocks)
+	require.NoError(t, err)
+	require.True(t, audit.Valid, "%v", audit.Errors)
+	require.Equal(t, 4, audit.Ballots)
+	require.Equal(t, 3, audit.Box)
+	require.Nil(t, audit.Result)
+
+	_, err = s0.Shuffle(&evoting.Shuffle{ID: replyOpen.ID, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+	_, err = s0.Decrypt(&evoting.Decrypt{ID: replyOpen.ID, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+	require.Nil(t, local.WaitDone(time.Second))
+	tallyReply, err := s0.Tally(&evoting.Tally{ID: replyOpen.ID, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+
+	blocks, err = lib.DownloadElection(roster, replyOpen.ID)
+	require.NoError(t, err)
+	audit, err = lib.AuditElection(blocks)
+	require.NoError(t, err)
+	require.True(t, audit.Valid, "%v", audit.Errors)
+	require.Equal(t, 3, len(audit.Mixes))
+	for _, step := range append(audit.Mixes, audit.Partials...) {
+		require.True(t, step.Verified)
+	}
+	require.True(t, len(audit.Partials) >= 3)
+	require.True(t, audit.Stored)
+	require.Equal(t, 3, audit.Result.Valid)
+	require.Equal(t, []uint32{idCand2}, audit.Result.Elected)
+	require.Equal(t, tallyReply.Result.Ballots, audit.Result.Ballots)
+
+	// A ballot changed on the way is caught.
+	blocks[2] = blocks[2].Copy()
+	blocks[2].Data = append([]byte{}, blocks[2].Data...)
+	blocks[2].Data[len(blocks[2].Data)-1]++
+	audit, err = lib.AuditElection(blocks)
+	require.NoError(t, err)
+	require.False(t, audit.Valid)
+}
+
+func TestReceiptAndChallenge(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodeKP := key.NewKeyPair(cothority.Suite)
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	s1 := local.GetServices(nodes, serviceID)[1].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	sc0.SetPropTimeout(defaultTimeout)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:    s0.pin,
+		Roster: roster,
+		Key:    nodeKP.Public,
+		Admins: []uint32{idAdmin},
+	})
+	require.NoError(t, err)
+	idAdminSig := generateSignature(nodeKP.Private, replyLink.ID, idAdmin)
+	idUser1Sig := generateSignature(nodeKP.Private, replyLink.ID, idUser1)
+
+	replyOpen, err := s0.Open(&evoting.Open{
+		ID: replyLink.ID,
+		Election: &lib.Election{
+			Name:       map[string]string{"en": "receipts"},
+			Creator:    idAdmin,
+			Users:      []uint32{idUser1, idUser2},
+			Weights:    []uint32{2, 1},
+			Candidates: []uint32{idCand1, idCand2},
+			MaxChoices: 1,
+			Roster:     roster,
+			Start:      yesterday.Unix(),
+			End:        tomorrow.Unix(),
+		},
+		User:      idAdmin,
+		Signature: idAdminSig,
+	})
+	require.NoError(t, err)
+	box, err := s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+	require.NoError(t, err)
+	election := box.Election
+
+	// The voter challenges the first ballot the leader encrypts for them.
+	_, err = s0.Prepare(&evoting.Prepare{ID: replyOpen.ID, Choices: []uint32{idCand1}, User: idUser1})
+	require.Error(t, err)
+	prepared, err := s0.Prepare(&evoting.Prepare{
+		ID:        replyOpen.ID,
+		Choices:   []uint32{idCand1},
+		User:      idUser1,
+		Signature: idUser1Sig,
+	})
+	require.NoError(t, err)
+	challenged, err := s0.Challenge(&evoting.Challenge{ID: replyOpen.ID, User: idUser1, Signature: idUser1Sig})
+	require.NoError(t, err)
+	require.Equal(t, prepared.Ballot.Hash(), challenged.Ballot.Hash())
+	choices, err := election.OpenBallot(challenged.Ballot, challenged.Opening)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{idCand1}, choices)
+
+	// The challenged ballot can't be cast, nor challenged again.
+	_, err = s0.Cast(&evoting.Cast{ID: replyOpen.ID, Ballot: prepared.Ballot, User: idUser1, Signature: idUser1Sig})
+	require.Error(t, err)
+	_, err = s0.Challenge(&evoting.Challenge{ID: replyOpen.ID, User: idUser1, Signature: idUser1Sig})
+	require.Error(t, err)
+
+	// The challenged ballots are kept on disk.
+	s0.storage = &storage{}
+	require.NoError(t, s0.load())
+	require.Len(t, s0.storage.Revealed, 1)
+	_, err = s0.Cast(&evoting.Cast{ID: replyOpen.ID, Ballot: prepared.Ballot, User: idUser1, Signature: idUser1Sig})
+	require.Error(t, err)
+
+	// The second one is cast.
+	prepared, err = s0.Prepare(&evoting.Prepare{
+		ID:        replyOpen.ID,
+		Choices:   []uint32{idCand1},
+		User:      idUser1,
+		Signature: idUser1Sig,
+	})
+	require.NoError(t, err)
+	cast, err := s0.Cast(&evoting.Cast{ID: replyOpen.ID, Ballot: prepared.Ballot, User: idUser1, Signature: idUser1Sig})
+	require.NoError(t, err)
+	receipt := cast.Receipt
+	require.NotNil(t, receipt)
+	require.NoError(t, receipt.Verify())
+	require.Equal(t, prepared.Ballot.Hash(), receipt.Ballot)
+
+	// Any node checks the receipt, and so can the voter with the box.
+	reply, err := s1.CheckReceipt(&evoting.CheckReceipt{Receipt: receipt})
+	require.NoError(t, err)
+	require.Equal(t, lib.Running, reply.Stage)
+	box, err = s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+	require.NoError(t, err)
+	require.NoError(t, receipt.Check(box.Election, box.Box))
+
+	// A receipt for another ballot is refused.
+	forged := *receipt
+	forged.Ballot = challenged.Ballot.Hash()
+	_, err = s1.CheckReceipt(&evoting.CheckReceipt{Receipt: &forged})
+	require.Error(t, err)
+
+	// Once the voter casts again, the first receipt isn't counted anymore.
+	ballot, err := election.EncryptBallot(idUser1, []uint32{idCand2})
+	require.NoError(t, err)
+	_, err = s0.Cast(&evoting.Cast{ID: replyOpen.ID, Ballot: ballot, User: idUser1, Signature: idUser1Sig})
+	require.NoError(t, err)
+	_, err = s1.CheckReceipt(&evoting.CheckReceipt{Receipt: receipt})
+	require.Error(t, err)
+}
+
+func TestRevotingPolicy(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+
+	nodeKP := key.NewKeyPair(cothority.Suite)
+
+	nodes, roster, _ := local.GenBigTree(3, 3, 1, true)
+	s0 := local.GetServices(nodes, serviceID)[0].(*Service)
+	sc0 := local.GetServices(nodes, onet.ServiceFactory.ServiceID(skipchain.ServiceName))[0].(*skipchain.Service)
+	sc0.SetPropTimeout(defaultTimeout)
+
+	replyLink, err := s0.Link(&evoting.Link{
+		Pin:    s0.pin,
+		Roster: roster,
+		Key:    nodeKP.Public,
+		Admins: []uint32{idAdmin},
+	})
+	require.NoError(t, err)
+	idAdminSig := generateSignature(nodeKP.Private, replyLink.ID, idAdmin)
+
+	open := func(policy lib.RevotingPolicy) *lib.Election {
+		replyOpen, err := s0.Open(&evoting.Open{
+			ID: replyLink.ID,
+			Election: &lib.Election{
+				Name:       map[string]string{"en": "revoting"},
+				Creator:    idAdmin,
+				Users:      []uint32{idUser1, idUser2},
+				Candidates: []uint32{idCand1, idCand2},
+				MaxChoices: 1,
+				Roster:     roster,
+				Start:      yesterday.Unix(),
+				End:        tomorrow.Unix(),
+				Revoting:   policy,
+			},
+			User:      idAdmin,
+			Signature: idAdminSig,
+		})
+		require.NoError(t, err)
+		box, err := s0.GetBox(&evoting.GetBox{ID: replyOpen.ID})
+		require.NoError(t, err)
+		return box.Election
+	}
+	vote := func(e *lib.Election, user uint32, candidate uint32) (*evoting.CastReply, error) {
+		ballot, err := e.EncryptBallot(user, []uint32{candidate})
+		require.NoError(t, err)
+		return s0.Cast(&evoting.Cast{
+			ID:        e.ID,
+			Ballot:    ballot,
+			User:      user,
+			Signature: generateSignature(nodeKP.Private, replyLink.ID, user),
+		})
+	}
+
+	// Without revoting, the second ballot is refused.
+	e := open(lib.NoRevoting)
+	first, err := vote(e, idUser1, idCand1)
+	require.NoError(t, err)
+	_, err = vote(e, idUser1, idCand2)
+	require.Error(t, err)
+	effective, err := s0.GetEffectiveBallot(&evoting.GetEffectiveBallot{ID: e.ID, User: idUser1})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, effective.Block)
+	require.Equal(t, 1, effective.Cast)
+
+	// When the first vote counts, the second ballot is accepted but ignored.
+	e = open(lib.FirstVote)
+	first, err = vote(e, idUser1, idCand1)
+	require.NoError(t, err)
+	second, err := vote(e, idUser1, idCand2)
+	require.NoError(t, err)
+	_, err = vote(e, idUser2, idCand2)
+	require.NoError(t, err)
+	effective, err = s0.GetEffectiveBallot(&evoting.GetEffectiveBallot{ID: e.ID, User: idUser1})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, effective.Block)
+	require.Equal(t, 2, effective.Cast)
+	require.Equal(t, lib.FirstVote, effective.Policy)
+	require.NoError(t, first.Receipt.Check(e, mustBox(t, s0, e.ID)))
+	require.Error(t, second.Receipt.Check(e, mustBox(t, s0, e.ID)))
+	effective, err = s0.GetEffectiveBallot(&evoting.GetEffectiveBallot{ID: e.ID, User: idUser3})
+	require.NoError(t, err)
+	require.Nil(t, effective.Block)
+
+	// The ballots that count are recorded before the shuffle, after which
+	// nobody can vote anymore.
+	_, err = s0.Shuffle(&evoting.Shuffle{ID: e.ID, User: idAdmin, Signature: idAdminSig})
+	require.NoError(t, err)
+	dedup, err := e.Dedup(sc0)
+	require.NoError(t, err)
+	require.NotNil(t, dedup)
+	require.Equal(t, lib.FirstVote, dedup.Policy)
+	require.Equal(t, 2, len(dedup.Blocks))
+	_, err = vote(e, idUser2, idCand1)
+	require.Error(t, err)
+
+	blocks, err := lib.DownloadElection(roster, e.ID)
+	require.NoError(t, err)
+	audit, err := lib.AuditElection(blocks)
+	require.NoError(t, err)
+	require.True(t, audit.Valid, "%v", audit.Errors)
+	require.Equal(t, 3, audit.Ballots)
+	require.Equal(t, 2, audit.Box)
+}
+
+func mustBox(t *testing.T, s *Service, id skipchain.SkipBlockID) *lib.Box {
+	reply, err := s.GetBox(&evoting.GetBox{ID: id})
+	require.NoError(t, err)
+	return reply.Box
+}
@@ -22,6 +22,11 @@ func init() {
 	network.RegisterMessages(GetMixes{}, GetMixesReply{})
 	network.RegisterMessages(GetPartials{}, GetPartialsReply{})
 	network.RegisterMessages(Reconstruct{}, ReconstructReply{})
+	network.RegisterMessages(Tally{}, TallyReply{})
+	network.RegisterMessages(Prepare{}, PrepareReply{})
+	network.RegisterMessages(Challenge{}, ChallengeReply{})
+	network.RegisterMessages(CheckReceipt{}, CheckReceiptReply{})
+	network.RegisterMessages(GetEffectiveBallot{}, GetEffectiveBallotReply{})
 }
 
 // LookupSciper takes a SCIPER number and looks up the full name.
@@ -29,9 +34,12 @@ type LookupSciper struct {
 	Sciper string
 	// If LookupURL is set, use it instead of the default (for testing).
 	LookupURL string
+	// Master is the ID of the master skipchain whose voter directory is
+	// used; optional, the default directory is used if not given.
+	Master skipchain.SkipBlockID
 }
 
-// LookupSciperReply returns user info, as looked up via LDAP.
+// LookupSciperReply returns user info, as looked up in the voter directory.
 type LookupSciperReply struct {
 	FullName string
 	Email    string
@@ -48,6 +56,7 @@ type Link struct {
 	ID        *skipchain.SkipBlockID // ID of the master skipchain to update; optional.
 	User      *uint32                // User identifier; optional (required with ID).
 	Signature *[]byte                // Signature authenticating the message; optional (required with ID).
+	Directory string                 // Directory is the name of the voter directory; optional.
 }
 
 // LinkReply message.
@@ -81,7 +90,63 @@ type Cast struct {
 
 // CastReply message.
 type CastReply struct {
-	ID skipchain.SkipBlockID // Hash of the block storing the transaction
+	ID      skipchain.SkipBlockID // Hash of the block storing the transaction
+	Receipt *lib.Receipt          // Receipt proving that the ballot is stored.
+}
+
+// Prepare message. The leader encrypts the choices of the user, and keeps
+// the opening of the ballot until it is cast or challenged.
+type Prepare struct {
+	ID      skipchain.SkipBlockID // ID of the election skipchain.
+	Choices []uint32              // Choices of the user, in order of preference for ranked elections.
+
+	User      uint32 // User identifier.
+	Signature []byte // Signature authenticating the message.
+}
+
+// PrepareReply message.
+type PrepareReply struct {
+	Ballot *lib.Ballot // Ballot encrypting the choices, to be cast or challenged.
+}
+
+// Challenge message. It asks for the opening of the last ballot prepared for
+// the user, which can't be cast anymore.
+type Challenge struct {
+	ID skipchain.SkipBlockID // ID of the election skipchain.
+
+	User      uint32 // User identifier.
+	Signature []byte // Signature authenticating the message.
+}
+
+// ChallengeReply message.
+type ChallengeReply struct {
+	Ballot  *lib.Ballot  // Ballot that was challenged.
+	Opening *lib.Opening // Opening of the ballot, to check with Election.OpenBallot.
+}
+
+// CheckReceipt message.
+type CheckReceipt struct {
+	Receipt *lib.Receipt // Receipt given when the ballot was cast.
+}
+
+// CheckReceiptReply message. It is only returned if the ballot of the
+// receipt is the one of the user in the box.
+type CheckReceiptReply struct {
+	Stage lib.ElectionState // Stage of the election.
+}
+
+// GetEffectiveBallot message.
+type GetEffectiveBallot struct {
+	ID   skipchain.SkipBlockID // ID of the election skipchain.
+	User uint32                // User identifier.
+}
+
+// GetEffectiveBallotReply message.
+type GetEffectiveBallotReply struct {
+	Block  skipchain.SkipBlockID // Block holding the ballot that counts; nil if the user didn't vote.
+	Ballot *lib.Ballot           // Ballot that counts.
+	Cast   int                   // Cast is the number of ballots the user cast.
+	Policy lib.RevotingPolicy    // Policy deciding which ballot counts.
 }
 
 // Shuffle message.
@@ -160,7 +225,20 @@ type Reconstruct struct {
 
 // ReconstructReply message.
 type ReconstructReply struct {
-	Points []kyber.Point // Points are the decrypted plaintexts.
+	Points []kyber.Point // Points are the decrypted plaintexts, Election.Width() per ballot.
+}
+
+// Tally message.
+type Tally struct {
+	ID skipchain.SkipBlockID // ID of the election skipchain.
+
+	User      uint32 // User identifier.
+	Signature []byte // Signature authenticating the message.
+}
+
+// TallyReply message.
+type TallyReply struct {
+	Result *lib.Result // Result of the election, as stored on its skipchain.
 }
 
 // Ping message.
@@ -0,0 +1,408 @@
+// Package metrics collects counters, gauges and histograms about a conode and
+// writes them in the text format of Prometheus.
+//
+// Every conode has its own Registry, returned by ForServer, so that the
+// conodes of a test running in the same process don't mix their metrics.
+// Services and protocols get it from their ServerIdentity:
+//
+//	blocks := metrics.ForServer(s.ServerIdentity()).Counter(
+//	    "byzcoin_blocks_total", "Blocks added to the chains")
+//	blocks.Inc()
+//
+// Asking twice for a metric with the same name returns the same metric.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// The types of the metrics.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are the upper bounds of the buckets of the histograms of
+// durations, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+var registries = struct {
+	sync.Mutex
+	m map[network.ServerIdentityID]*Registry
+}{m: make(map[network.ServerIdentityID]*Registry)}
+
+// ForServer returns the registry of the conode with the given identity. It
+// is created on the first call.
+func ForServer(si *network.ServerIdentity) *Registry {
+	registries.Lock()
+	defer registries.Unlock()
+	r, ok := registries.m[si.ID]
+	if !ok {
+		r = NewRegistry()
+		registries.m[si.ID] = r
+	}
+	return r
+}
+
+// Registry holds the metrics of a conode.
+type Registry struct {
+	sync.Mutex
+	families   map[string]*family
+	collectors []func()
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Counter returns the counter with the given name. The label names must be
+// the same for all the calls with this name.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.family(name, help, TypeCounter, nil, labels)}
+}
+
+// Gauge returns the gauge with the given name.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.family(name, help, TypeGauge, nil, labels)}
+}
+
+// Histogram returns the histogram with the given name. The buckets are the
+// sorted upper bounds of the buckets, DefaultBuckets if nil.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{r.family(name, help, TypeHistogram, buckets, labels)}
+}
+
+// OnCollect registers a function called before every snapshot of the
+// registry. It is used to update the gauges that are expensive to keep
+// current, like the size of a database.
+func (r *Registry) OnCollect(fn func()) {
+	r.Lock()
+	defer r.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+func (r *Registry) family(name, help, typ string, buckets []float64, labels []string) *family {
+	r.Lock()
+	defer r.Unlock()
+	f, ok := r.families[name]
+	if ok {
+		if f.typ != typ || len(f.labels) != len(labels) {
+			log.Errorf("metric %s registered twice with different types or labels", name)
+		}
+		return f
+	}
+	f = &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// Snapshot returns the current value of all the metrics, sorted by name.
+func (r *Registry) Snapshot() []Family {
+	r.Lock()
+	collectors := append([]func(){}, r.collectors...)
+	r.Unlock()
+	for _, fn := range collectors {
+		fn()
+	}
+
+	r.Lock()
+	var names []string
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make([]*family, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		families[i] = r.families[name]
+	}
+	r.Unlock()
+
+	out := make([]Family, len(families))
+	for i, f := range families {
+		out[i] = f.snapshot()
+	}
+	return out
+}
+
+// WriteText writes all the metrics in the text format of Prometheus.
+func (r *Registry) WriteText(w io.Writer) error {
+	return WriteText(w, r.Snapshot())
+}
+
+// ServeHTTP serves the metrics in the text format of Prometheus.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := r.WriteText(w); err != nil {
+		log.Error("couldn't write metrics:", err)
+	}
+}
+
+// Family is a snapshot of a metric with all its label values.
+type Family struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels []string
+	Series []Series
+}
+
+// Series is a snapshot of a metric for a set of label values. Counters and
+// gauges only have a Value. Histograms have the upper bounds of their
+// Buckets, the cumulative Counts of the observations in each bucket, and
+// the Sum and Count of all observations.
+type Series struct {
+	LabelValues []string
+	Value       float64
+	Buckets     []float64
+	Counts      []uint64
+	Sum         float64
+	Count       uint64
+}
+
+// Counter is a value that only goes up. The methods of the metrics do
+// nothing on a nil metric, so that structures created without their metrics
+// still work.
+type Counter struct {
+	f *family
+}
+
+// Inc adds one to the counter of the label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the counter of the label
+// values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if c == nil {
+		return
+	}
+	if v < 0 {
+		log.Error("cannot decrease counter", c.f.name)
+		return
+	}
+	c.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	f *family
+}
+
+// Set sets the gauge of the label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.f.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Add adds v, which can be negative, to the gauge of the label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	f *family
+}
+
+// Observe adds an observation to the histogram of the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+	h.f.update(labelValues, func(s *series) {
+		for i, bound := range h.f.buckets {
+			if v <= bound {
+				s.counts[i]++
+			}
+		}
+		s.sum += v
+		s.count++
+	})
+}
+
+// Since observes the time elapsed since start, in seconds.
+func (h *Histogram) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+type family struct {
+	sync.Mutex
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func (f *family) update(labelValues []string, fn func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		log.Errorf("metric %s needs %d label values, got %d", f.name,
+			len(f.labels), len(labelValues))
+		return
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.Lock()
+	defer f.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labelValues: append([]string{}, labelValues...),
+			counts:      make([]uint64, len(f.buckets)),
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+func (f *family) snapshot() Family {
+	f.Lock()
+	defer f.Unlock()
+	var keys []string
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := Family{
+		Name:   f.name,
+		Help:   f.help,
+		Type:   f.typ,
+		Labels: f.labels,
+	}
+	for _, key := range keys {
+		s := f.series[key]
+		snap := Series{LabelValues: s.labelValues, Value: s.value}
+		if f.typ == TypeHistogram {
+			snap.Buckets = f.buckets
+			snap.Counts = append([]uint64{}, s.counts...)
+			snap.Sum = s.sum
+			snap.Count = s.count
+		}
+		out.Series = append(out.Series, snap)
+	}
+	return out
+}
+
+// WriteText writes the families in the text format of Prometheus.
+func WriteText(w io.Writer, families []Family) error {
+	for _, f := range families {
+		if len(f.Series) == 0 {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.Name,
+			escape(f.Help, false), f.Name, f.Type)
+		if err != nil {
+			return err
+		}
+		for _, s := range f.Series {
+			if len(s.LabelValues) != len(f.Labels) {
+				return errors.New("wrong number of label values in " + f.Name)
+			}
+			if f.Type != TypeHistogram {
+				err = writeSample(w, f.Name, f.Labels, s.LabelValues, s.Value)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			labels := append(append([]string{}, f.Labels...), "le")
+			for i, bound := range s.Buckets {
+				values := append(append([]string{}, s.LabelValues...), formatFloat(bound))
+				err = writeSample(w, f.Name+"_bucket", labels, values, float64(s.Counts[i]))
+				if err != nil {
+					return err
+				}
+			}
+			values := append(append([]string{}, s.LabelValues...), "+Inf")
+			err = writeSample(w, f.Name+"_bucket", labels, values, float64(s.Count))
+			if err != nil {
+				return err
+			}
+			err = writeSample(w, f.Name+"_sum", f.Labels, s.LabelValues, s.Sum)
+			if err != nil {
+				return err
+			}
+			err = writeSample(w, f.Name+"_count", f.Labels, s.LabelValues, float64(s.Count))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeSample(w io.Writer, name string, labels, values []string, v float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteString("{")
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", label, escape(values[i], true))
+		}
+		b.WriteString("}")
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", b.String(), formatFloat(v))
+	return err
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escape escapes the backslashes and new lines of a help text, and also the
+// double quotes of a label value.
+func escape(s string, quotes bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quotes {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
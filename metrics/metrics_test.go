@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	txs := r.Counter("txs_total", "Transactions", "contract", "result")
+	txs.Inc("value", "accepted")
+	txs.Add(2, "value", "accepted")
+	txs.Inc("coin", "rejected")
+	txs.Add(-1, "coin", "rejected")
+	txs.Inc("too few labels")
+	// The same name gives the same metric.
+	r.Counter("txs_total", "Transactions", "contract", "result").Inc("coin", "rejected")
+
+	size := r.Gauge("db_size_bytes", "Size of the \"db\"")
+	r.OnCollect(func() { size.Set(1024) })
+	r.Gauge("unused", "No series")
+
+	rounds := r.Histogram("round_seconds", "Round times", []float64{0.1, 1}, "protocol")
+	rounds.Observe(0.05, "blscosi")
+	rounds.Observe(0.5, "blscosi")
+	rounds.Observe(5, "blscosi")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteText(&buf))
+	require.Equal(t, `# HELP db_size_bytes Size of the "db"
+# TYPE db_size_bytes gauge
+db_size_bytes 1024
+# HELP round_seconds Round times
+# TYPE round_seconds histogram
+round_seconds_bucket{protocol="blscosi",le="0.1"} 1
+round_seconds_bucket{protocol="blscosi",le="1"} 2
+round_seconds_bucket{protocol="blscosi",le="+Inf"} 3
+round_seconds_sum{protocol="blscosi"} 5.55
+round_seconds_count{protocol="blscosi"} 3
+# HELP txs_total Transactions
+# TYPE txs_total counter
+txs_total{contract="coin",result="rejected"} 2
+txs_total{contract="value",result="accepted"} 3
+`, buf.String())
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, buf.String(), rec.Body.String())
+}
+
+func TestForServer(t *testing.T) {
+	si1 := network.NewServerIdentity(key.NewKeyPair(cothority.Suite).Public, "tls://127.0.0.1:7770")
+	si2 := network.NewServerIdentity(key.NewKeyPair(cothority.Suite).Public, "tls://127.0.0.1:7772")
+	require.True(t, ForServer(si1) == ForServer(si1))
+	require.True(t, ForServer(si1) != ForServer(si2))
+}
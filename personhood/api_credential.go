@@ -0,0 +1,397 @@
+package personhood
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// VCContext is the JSON-LD context of the W3C verifiable credentials.
+const VCContext = "https://www.w3.org/2018/credentials/v1"
+
+// VCProofType is the type of the proof of the verifiable credentials issued
+// for credential instances.
+const VCProofType = "ByzCoinDarcSignature"
+
+// VerifiableCredential is a W3C verifiable credential holding some
+// attributes of a credential instance. It is signed by an identity of the
+// darc of the credential instance, and holds the proofs that the instance
+// and its darc are stored on ByzCoin, so it can be verified by anybody
+// trusting the ID of the ByzCoin chain, without contacting the chain.
+type VerifiableCredential struct {
+	Context           []string   `json:"@context"`
+	Type              []string   `json:"type"`
+	Issuer            string     `json:"issuer"`
+	IssuanceDate      string     `json:"issuanceDate"`
+	CredentialSubject VCSubject  `json:"credentialSubject"`
+	Evidence          VCEvidence `json:"evidence"`
+	Proof             VCProof    `json:"proof"`
+}
+
+// VCSubject is the credential instance and the attributes exported.
+type VCSubject struct {
+	// ID is the instance ID of the credential, in hex.
+	ID string `json:"id"`
+	// ByzCoinID is the ID of the ByzCoin chain holding the instance, in hex.
+	ByzCoinID  string        `json:"byzCoinID"`
+	Attributes []VCAttribute `json:"attributes"`
+}
+
+// VCAttribute is one attribute of a credential.
+type VCAttribute struct {
+	Credential string `json:"credential"`
+	Name       string `json:"name"`
+	// Value is the value of the attribute, in hex.
+	Value string `json:"value"`
+}
+
+// VCEvidence holds the protobuf-encoded data needed to verify the
+// credential, in hex.
+type VCEvidence struct {
+	// Genesis is the genesis block of the ByzCoin chain.
+	Genesis string `json:"genesis"`
+	// Credential is the ByzCoin proof of the credential instance.
+	Credential string `json:"credential"`
+	// Darc is the ByzCoin proof of the darc of the credential instance.
+	Darc string `json:"darc"`
+	// Darcs are the ByzCoin proofs of the darcs the issuer signs through,
+	// if the darc of the credential delegates to other darcs.
+	Darcs []string `json:"darcs,omitempty"`
+}
+
+// VCProof is the signature of the issuer.
+type VCProof struct {
+	Type               string `json:"type"`
+	Created            string `json:"created"`
+	VerificationMethod string `json:"verificationMethod"`
+	ProofPurpose       string `json:"proofPurpose"`
+	// ProofValue is the signature in hex.
+	ProofValue string `json:"proofValue"`
+}
+
+// CredentialExport returns a verifiable credential holding the attributes of
+// the credential instance credIID. The attributes are given as
+// 'credential/attribute'. The signer must be allowed to sign by the darc of
+// the credential instance.
+func CredentialExport(cl *byzcoin.Client, credIID byzcoin.InstanceID,
+	attributes []string, signer darc.Signer) (*VerifiableCredential, error) {
+	if len(attributes) == 0 {
+		return nil, xerrors.New("no attributes to export")
+	}
+	credProof, err := cl.GetProof(credIID.Slice())
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get proof of credential: %v", err)
+	}
+	cs, darcID, err := vcCredential(&credProof.Proof, credIID)
+	if err != nil {
+		return nil, err
+	}
+	darcProof, err := cl.GetProof(darcID)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get proof of darc: %v", err)
+	}
+	d, err := vcDarc(&darcProof.Proof, darcID)
+	if err != nil {
+		return nil, err
+	}
+	var delegated []*byzcoin.Proof
+	err = vcCheckSigner(d, signer.Identity(), func(id darc.ID) *darc.Darc {
+		p, err := cl.GetProof(id)
+		if err != nil {
+			return nil
+		}
+		d, err := vcDarc(&p.Proof, id)
+		if err != nil {
+			return nil
+		}
+		delegated = append(delegated, &p.Proof)
+		return d
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	vc := &VerifiableCredential{
+		Context:      []string{VCContext},
+		Type:         []string{"VerifiableCredential", "PersonhoodCredential"},
+		Issuer:       signer.Identity().String(),
+		IssuanceDate: now,
+		CredentialSubject: VCSubject{
+			ID:        hex.EncodeToString(credIID.Slice()),
+			ByzCoinID: hex.EncodeToString(cl.ID),
+		},
+	}
+	for _, attr := range attributes {
+		names := strings.SplitN(attr, "/", 2)
+		if len(names) != 2 {
+			return nil, xerrors.Errorf("attribute '%s' is not 'credential/attribute'", attr)
+		}
+		value, err := vcAttribute(cs, names[0], names[1])
+		if err != nil {
+			return nil, err
+		}
+		vc.CredentialSubject.Attributes = append(vc.CredentialSubject.Attributes,
+			VCAttribute{Credential: names[0], Name: names[1],
+				Value: hex.EncodeToString(value)})
+	}
+
+	for _, e := range []struct {
+		dst *string
+		msg interface{}
+	}{
+		{&vc.Evidence.Genesis, cl.Genesis},
+		{&vc.Evidence.Credential, &credProof.Proof},
+		{&vc.Evidence.Darc, &darcProof.Proof},
+	} {
+		buf, err := protobuf.Encode(e.msg)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't encode evidence: %v", err)
+		}
+		*e.dst = hex.EncodeToString(buf)
+	}
+	for _, p := range delegated {
+		buf, err := protobuf.Encode(p)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't encode evidence: %v", err)
+		}
+		vc.Evidence.Darcs = append(vc.Evidence.Darcs, hex.EncodeToString(buf))
+	}
+
+	sig, err := signer.Sign(vc.hash())
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't sign credential: %v", err)
+	}
+	vc.Proof = VCProof{
+		Type:               VCProofType,
+		Created:            now,
+		VerificationMethod: vc.Issuer,
+		ProofPurpose:       "assertionMethod",
+		ProofValue:         hex.EncodeToString(sig),
+	}
+	return vc, nil
+}
+
+// Verify checks that the attributes of the verifiable credential are stored
+// in the credential instance on the ByzCoin chain bcID, and that the issuer
+// can sign for the darc of the instance. The chain is not contacted, so the
+// attributes are verified as they were when the credential was issued.
+func (vc *VerifiableCredential) Verify(bcID skipchain.SkipBlockID) error {
+	if len(vc.Context) == 0 || vc.Context[0] != VCContext {
+		return xerrors.New("not a verifiable credential")
+	}
+	if vc.Proof.Type != VCProofType || vc.Proof.VerificationMethod != vc.Issuer {
+		return xerrors.New("unknown proof of the credential")
+	}
+	if vc.CredentialSubject.ByzCoinID != hex.EncodeToString(bcID) {
+		return xerrors.New("credential of another ByzCoin chain")
+	}
+	credBuf, err := hex.DecodeString(vc.CredentialSubject.ID)
+	if err != nil || len(credBuf) != len(byzcoin.InstanceID{}) {
+		return xerrors.New("invalid credential ID")
+	}
+	credIID := byzcoin.NewInstanceID(credBuf)
+
+	var genesis skipchain.SkipBlock
+	var credProof, darcProof byzcoin.Proof
+	for _, e := range []struct {
+		src string
+		msg interface{}
+	}{
+		{vc.Evidence.Genesis, &genesis},
+		{vc.Evidence.Credential, &credProof},
+		{vc.Evidence.Darc, &darcProof},
+	} {
+		buf, err := hex.DecodeString(e.src)
+		if err != nil {
+			return xerrors.Errorf("couldn't decode evidence: %v", err)
+		}
+		err = protobuf.DecodeWithConstructors(buf, e.msg,
+			network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return xerrors.Errorf("couldn't decode evidence: %v", err)
+		}
+	}
+	if genesis.Index != 0 || !genesis.CalculateHash().Equal(bcID) {
+		return xerrors.New("genesis block is not the one of the chain")
+	}
+	genesis.Hash = genesis.CalculateHash()
+	if err = credProof.VerifyFromBlock(&genesis); err != nil {
+		return xerrors.Errorf("invalid proof of credential: %v", err)
+	}
+	if err = darcProof.VerifyFromBlock(&genesis); err != nil {
+		return xerrors.Errorf("invalid proof of darc: %v", err)
+	}
+	delegated := make(map[string]*darc.Darc)
+	for _, src := range vc.Evidence.Darcs {
+		buf, err := hex.DecodeString(src)
+		if err != nil {
+			return xerrors.Errorf("couldn't decode evidence: %v", err)
+		}
+		var p byzcoin.Proof
+		err = protobuf.DecodeWithConstructors(buf, &p,
+			network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return xerrors.Errorf("couldn't decode evidence: %v", err)
+		}
+		if err = p.VerifyFromBlock(&genesis); err != nil {
+			return xerrors.Errorf("invalid proof of delegated darc: %v", err)
+		}
+		id := darc.ID(p.InclusionProof.Key())
+		d, err := vcDarc(&p, id)
+		if err != nil {
+			return err
+		}
+		delegated[string(id)] = d
+	}
+
+	cs, darcID, err := vcCredential(&credProof, credIID)
+	if err != nil {
+		return err
+	}
+	for _, a := range vc.CredentialSubject.Attributes {
+		value, err := vcAttribute(cs, a.Credential, a.Name)
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(value) != a.Value {
+			return xerrors.Errorf("wrong value of attribute %s/%s",
+				a.Credential, a.Name)
+		}
+	}
+	d, err := vcDarc(&darcProof, darcID)
+	if err != nil {
+		return err
+	}
+	id, err := darc.ParseIdentity(vc.Issuer)
+	if err != nil {
+		return xerrors.Errorf("invalid issuer: %v", err)
+	}
+	err = vcCheckSigner(d, id, func(id darc.ID) *darc.Darc {
+		return delegated[string(id)]
+	})
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(vc.Proof.ProofValue)
+	if err != nil {
+		return xerrors.Errorf("invalid signature: %v", err)
+	}
+	if err = id.Verify(vc.hash(), sig); err != nil {
+		return xerrors.Errorf("invalid signature: %v", err)
+	}
+	return nil
+}
+
+// hash returns the hash of the fields signed by the issuer.
+func (vc *VerifiableCredential) hash() []byte {
+	h := sha256.New()
+	write := func(s string) {
+		l := make([]byte, 8)
+		binary.LittleEndian.PutUint64(l, uint64(len(s)))
+		h.Write(l)
+		h.Write([]byte(s))
+	}
+	write(VCContext)
+	write(vc.Issuer)
+	write(vc.IssuanceDate)
+	write(vc.CredentialSubject.ByzCoinID)
+	write(vc.CredentialSubject.ID)
+	for _, a := range vc.CredentialSubject.Attributes {
+		write(a.Credential)
+		write(a.Name)
+		write(a.Value)
+	}
+	return h.Sum(nil)
+}
+
+// vcCredential returns the credential stored in the proof and the ID of its
+// darc.
+func vcCredential(p *byzcoin.Proof, credIID byzcoin.InstanceID) (*contracts.CredentialStruct, darc.ID, error) {
+	if !p.InclusionProof.Match(credIID.Slice()) {
+		return nil, nil, xerrors.New("credential instance doesn't exist")
+	}
+	val, cid, darcID, err := p.Get(credIID.Slice())
+	if err != nil {
+		return nil, nil, err
+	}
+	if cid != contracts.ContractCredentialID {
+		return nil, nil, xerrors.New("the instance is not a credential, but: " + cid)
+	}
+	var cs contracts.CredentialStruct
+	if err = protobuf.Decode(val, &cs); err != nil {
+		return nil, nil, xerrors.Errorf("couldn't decode credential: %v", err)
+	}
+	return &cs, darcID, nil
+}
+
+// vcDarc returns the darc stored in the proof.
+func vcDarc(p *byzcoin.Proof, darcID darc.ID) (*darc.Darc, error) {
+	if !p.InclusionProof.Match(darcID) {
+		return nil, xerrors.Errorf("darc %x doesn't exist", darcID)
+	}
+	val, cid, _, err := p.Get(darcID)
+	if err != nil {
+		return nil, err
+	}
+	if cid != byzcoin.ContractDarcID {
+		return nil, xerrors.Errorf("instance %x is not a darc", darcID)
+	}
+	d, err := darc.NewFromProtobuf(val)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't decode darc: %v", err)
+	}
+	if !bytes.Equal(d.GetBaseID(), darcID) {
+		return nil, xerrors.Errorf("wrong darc in the proof of %x", darcID)
+	}
+	return d, nil
+}
+
+// vcCheckSigner returns an error if the identity cannot sign for the darc.
+// The 'darc:' identities of the sign rule are resolved with getDarc, which
+// returns nil for unknown darcs.
+func vcCheckSigner(d *darc.Darc, id darc.Identity,
+	getDarc func(darc.ID) *darc.Darc) error {
+	err := darc.EvalExpr(d.Rules.GetSignExpr(), func(str string, latest bool) *darc.Darc {
+		if !strings.HasPrefix(str, "darc:") {
+			return nil
+		}
+		darcID, err := hex.DecodeString(str[5:])
+		if err != nil {
+			return nil
+		}
+		return getDarc(darcID)
+	}, id.String())
+	if err != nil {
+		return xerrors.Errorf("%s cannot sign for the credential: %v", id, err)
+	}
+	return nil
+}
+
+// vcAttribute returns the value of the attribute attr of the credential
+// cred.
+func vcAttribute(cs *contracts.CredentialStruct, cred, attr string) ([]byte, error) {
+	for _, c := range cs.Credentials {
+		if c.Name != cred {
+			continue
+		}
+		for _, a := range c.Attributes {
+			if a.Name == attr {
+				return a.Value, nil
+			}
+		}
+	}
+	return nil, xerrors.New("didn't find attribute " + cred + "/" + attr)
+}
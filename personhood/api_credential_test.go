@@ -0,0 +1,204 @@
+package personhood
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestVerifiableCredential_Verify(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	_, roster, _ := local.GenTree(3, true)
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	cl, credIID := newCredentialLedger(t, roster, signer)
+	vc, err := CredentialExport(cl, credIID, []string{"public/alias"}, signer)
+	require.NoError(t, err)
+	require.NoError(t, vc.Verify(cl.ID))
+
+	_, err = CredentialExport(cl, credIID, nil, signer)
+	require.Error(t, err)
+	_, err = CredentialExport(cl, credIID, []string{"public/email"}, signer)
+	require.Error(t, err)
+
+	// The credential survives a round-trip through JSON.
+	require.NoError(t, copyVC(t, vc).Verify(cl.ID))
+
+	// Tampered attributes are refused.
+	tampered := copyVC(t, vc)
+	tampered.CredentialSubject.Attributes[0].Value = hex.EncodeToString([]byte("eve"))
+	require.Error(t, tampered.Verify(cl.ID))
+	tampered = copyVC(t, vc)
+	tampered.CredentialSubject.Attributes[0].Name = "email"
+	require.Error(t, tampered.Verify(cl.ID))
+
+	// The issuer must be the signer of the credential.
+	outsider := darc.NewSignerEd25519(nil, nil)
+	wrongIssuer := copyVC(t, vc)
+	wrongIssuer.Issuer = outsider.Identity().String()
+	wrongIssuer.Proof.VerificationMethod = wrongIssuer.Issuer
+	require.Error(t, wrongIssuer.Verify(cl.ID))
+	wrongIssuer.Proof.VerificationMethod = vc.Issuer
+	require.Error(t, wrongIssuer.Verify(cl.ID))
+
+	// Signers outside of the darc of the credential cannot issue it.
+	_, err = CredentialExport(cl, credIID, []string{"public/alias"}, outsider)
+	require.Error(t, err)
+	forged := copyVC(t, wrongIssuer)
+	forged.Proof.VerificationMethod = forged.Issuer
+	sig, err := outsider.Sign(forged.hash())
+	require.NoError(t, err)
+	forged.Proof.ProofValue = hex.EncodeToString(sig)
+	require.Error(t, forged.Verify(cl.ID))
+
+	// Proofs of another chain are refused.
+	cl2, credIID2 := newCredentialLedger(t, roster, signer)
+	vc2, err := CredentialExport(cl2, credIID2, []string{"public/alias"}, signer)
+	require.NoError(t, err)
+	require.NoError(t, vc2.Verify(cl2.ID))
+	require.Error(t, vc2.Verify(cl.ID))
+	other := copyVC(t, vc2)
+	other.CredentialSubject.ByzCoinID = vc.CredentialSubject.ByzCoinID
+	require.Error(t, other.Verify(cl.ID))
+	other = copyVC(t, vc)
+	other.Evidence.Credential = vc2.Evidence.Credential
+	other.CredentialSubject.ID = vc2.CredentialSubject.ID
+	sig, err = signer.Sign(other.hash())
+	require.NoError(t, err)
+	other.Proof.ProofValue = hex.EncodeToString(sig)
+	require.Error(t, other.Verify(cl.ID))
+}
+
+func TestVerifiableCredential_DelegatedDarc(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	_, roster, _ := local.GenTree(3, true)
+
+	admin := darc.NewSignerEd25519(nil, nil)
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:" + contracts.ContractCredentialID}, admin.Identity())
+	require.NoError(t, err)
+	genesisMsg.BlockInterval = 500 * time.Millisecond
+	gDarc := &genesisMsg.GenesisDarc
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	// The darc of the credential delegates signing to the darc of the user.
+	user := darc.NewSignerEd25519(nil, nil)
+	userDarc := darc.NewDarc(darc.InitRules([]darc.Identity{user.Identity()},
+		[]darc.Identity{user.Identity()}), []byte("user"))
+	credDarc := darc.NewDarc(darc.InitRules(
+		[]darc.Identity{darc.NewIdentityDarc(userDarc.GetBaseID())},
+		[]darc.Identity{darc.NewIdentityDarc(userDarc.GetBaseID())}),
+		[]byte("credential"))
+	var insts byzcoin.Instructions
+	for _, d := range []*darc.Darc{userDarc, credDarc} {
+		buf, err := d.ToProto()
+		require.NoError(t, err)
+		insts = append(insts, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(gDarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: byzcoin.ContractDarcID,
+				Args:       byzcoin.Arguments{{Name: "darc", Value: buf}},
+			},
+		})
+	}
+	cred := contracts.CredentialStruct{Credentials: []contracts.Credential{{
+		Name:       "public",
+		Attributes: []contracts.Attribute{{Name: "alias", Value: []byte("bob")}},
+	}}}
+	credID := byzcoin.NewInstanceID(random.Bits(256, true, random.New()))
+	inst, err := contracts.NewInstructionCredentialSpawn(
+		byzcoin.NewInstanceID(gDarc.GetBaseID()), credDarc.GetBaseID(), credID,
+		cred)
+	require.NoError(t, err)
+	insts = append(insts, inst)
+	for i := range insts {
+		insts[i].SignerCounter = []uint64{uint64(i + 1)}
+	}
+	ctx, err := cl.CreateTransaction(insts...)
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(admin))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	h := sha256.New()
+	h.Write([]byte(contracts.ContractCredentialID))
+	h.Write(credID[:])
+	credIID := byzcoin.NewInstanceID(h.Sum(nil))
+
+	vc, err := CredentialExport(cl, credIID, []string{"public/alias"}, user)
+	require.NoError(t, err)
+	require.Len(t, vc.Evidence.Darcs, 1)
+	require.NoError(t, vc.Verify(cl.ID))
+	require.NoError(t, copyVC(t, vc).Verify(cl.ID))
+
+	// Without the proof of the delegated darc, the issuer is refused.
+	missing := copyVC(t, vc)
+	missing.Evidence.Darcs = nil
+	require.Error(t, missing.Verify(cl.ID))
+	// A proof of another darc doesn't help.
+	other := copyVC(t, vc)
+	other.Evidence.Darcs = []string{vc.Evidence.Darc}
+	require.Error(t, other.Verify(cl.ID))
+
+	// Only the signers of the delegated darc can issue the credential.
+	_, err = CredentialExport(cl, credIID, []string{"public/alias"}, admin)
+	require.Error(t, err)
+}
+
+// newCredentialLedger starts a new ByzCoin chain and stores a credential
+// with the attribute public/alias, signed by the genesis darc.
+func newCredentialLedger(t *testing.T, roster *onet.Roster,
+	signer darc.Signer) (*byzcoin.Client, byzcoin.InstanceID) {
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:" + contracts.ContractCredentialID}, signer.Identity())
+	require.NoError(t, err)
+	genesisMsg.BlockInterval = 500 * time.Millisecond
+	gDarc := &genesisMsg.GenesisDarc
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	cred := contracts.CredentialStruct{Credentials: []contracts.Credential{{
+		Name: "public",
+		Attributes: []contracts.Attribute{{
+			Name:  "alias",
+			Value: []byte("alice"),
+		}},
+	}}}
+	credID := byzcoin.NewInstanceID(random.Bits(256, true, random.New()))
+	inst, err := contracts.NewInstructionCredentialSpawn(
+		byzcoin.NewInstanceID(gDarc.GetBaseID()), gDarc.GetBaseID(), credID,
+		cred)
+	require.NoError(t, err)
+	inst.SignerCounter = []uint64{1}
+	ctx, err := cl.CreateTransaction(inst)
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+
+	h := sha256.New()
+	h.Write([]byte(contracts.ContractCredentialID))
+	h.Write(credID[:])
+	return cl, byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+// copyVC returns a deep copy of the credential, going through JSON.
+func copyVC(t *testing.T, vc *VerifiableCredential) *VerifiableCredential {
+	buf, err := json.Marshal(vc)
+	require.NoError(t, err)
+	cp := &VerifiableCredential{}
+	require.NoError(t, json.Unmarshal(buf, cp))
+	return cp
+}
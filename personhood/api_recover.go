@@ -0,0 +1,135 @@
+package personhood
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/kyber/v3"
+	"golang.org/x/xerrors"
+)
+
+// A social recovery gives the darc of a credential to a new key, once enough
+// trustees approved it. The steps are:
+//  1. the owner sets the trustees and the threshold with CredentialRecoverSetup
+//  2. the new key asks for a recovery with CredentialRecoverRequest
+//  3. every trustee approves the request with CredentialRecoverApprove, which
+//     doesn't need to contact ByzCoin
+//  4. anybody sends the request with the approvals to CredentialRecoverSubmit
+
+// recoverRequestLength is the length of a request: the credential instance
+// ID, the new public key, and the version of the darc.
+var recoverRequestLength = len(byzcoin.InstanceID{}) + cothority.Suite.PointLen() + 8
+
+// CredentialRecoverSetup sets the trustees and the threshold of approvals
+// needed to recover the darc of the credential credIID. The signers must be
+// allowed to update the credential.
+func CredentialRecoverSetup(cl *byzcoin.Client, credIID byzcoin.InstanceID,
+	threshold uint32, trustees []byzcoin.InstanceID, signers ...darc.Signer) error {
+	cs, _, err := getCredential(cl, credIID)
+	if err != nil {
+		return err
+	}
+	if err = cs.SetRecover(threshold, trustees); err != nil {
+		return err
+	}
+	inst, err := contracts.NewInstructionCredentialUpdate(credIID, *cs)
+	if err != nil {
+		return xerrors.Errorf("couldn't create instruction: %v", err)
+	}
+
+	var sigStrs []string
+	for _, sig := range signers {
+		sigStrs = append(sigStrs, sig.Identity().String())
+	}
+	signerCtrs, err := cl.GetSignerCounters(sigStrs...)
+	if err != nil {
+		return err
+	}
+	for _, ctr := range signerCtrs.Counters {
+		inst.SignerCounter = append(inst.SignerCounter, ctr+1)
+	}
+	ctx, err := cl.CreateTransaction(inst)
+	if err != nil {
+		return err
+	}
+	if err = ctx.FillSignersAndSignWith(signers...); err != nil {
+		return err
+	}
+	_, err = cl.AddTransactionAndWait(ctx, 5)
+	return err
+}
+
+// CredentialRecoverRequest returns the request to give the darc of the
+// credential credIID to the public key. The request is only valid for the
+// current version of the darc.
+func CredentialRecoverRequest(cl *byzcoin.Client, credIID byzcoin.InstanceID,
+	public kyber.Point) ([]byte, error) {
+	_, darcID, err := getCredential(cl, credIID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cl.GetProofFromLatest(darcID)
+	if err != nil {
+		return nil, err
+	}
+	var d darc.Darc
+	if err = p.Proof.VerifyAndDecode(cothority.Suite, byzcoin.ContractDarcID, &d); err != nil {
+		return nil, xerrors.Errorf("couldn't get darc of the credential: %v", err)
+	}
+	pubBuf, err := public.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return contracts.RecoverMessage(credIID, pubBuf, d.Version), nil
+}
+
+// CredentialRecoverApprove returns the approval of the request by a
+// trustee. The signer must be an ed25519 key in the sign rule of the darc of
+// the trustee's credential.
+func CredentialRecoverApprove(request []byte, signer darc.Signer) ([]byte, error) {
+	if len(request) != recoverRequestLength {
+		return nil, xerrors.New("wrong length of request")
+	}
+	if signer.Ed25519 == nil {
+		return nil, xerrors.New("only ed25519 signers can approve a recovery")
+	}
+	return contracts.SignRecover(request, signer.Ed25519.Secret)
+}
+
+// CredentialRecoverSubmit sends the request with the approvals of the
+// trustees to ByzCoin. The transaction doesn't need to be signed.
+func CredentialRecoverSubmit(cl *byzcoin.Client, request []byte,
+	approvals [][]byte) error {
+	if len(request) != recoverRequestLength {
+		return xerrors.New("wrong length of request")
+	}
+	iidLength := len(byzcoin.InstanceID{})
+	credIID := byzcoin.NewInstanceID(request[:iidLength])
+	public := cothority.Suite.Point()
+	err := public.UnmarshalBinary(request[iidLength : iidLength+cothority.Suite.PointLen()])
+	if err != nil {
+		return xerrors.Errorf("couldn't get public key of request: %v", err)
+	}
+	inst, err := contracts.NewInstructionCredentialRecover(credIID, public, approvals)
+	if err != nil {
+		return err
+	}
+	ctx, err := cl.CreateTransaction(inst)
+	if err != nil {
+		return err
+	}
+	_, err = cl.AddTransactionAndWait(ctx, 5)
+	return err
+}
+
+// getCredential returns the credential stored in credIID and the ID of its
+// darc.
+func getCredential(cl *byzcoin.Client, credIID byzcoin.InstanceID) (
+	*contracts.CredentialStruct, darc.ID, error) {
+	p, err := cl.GetProofFromLatest(credIID.Slice())
+	if err != nil {
+		return nil, nil, err
+	}
+	return vcCredential(&p.Proof, credIID)
+}
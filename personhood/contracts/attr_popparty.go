@@ -0,0 +1,218 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/url"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+// AttrPopPartyID is the name of the attribute that lets the anonymous
+// attendees of a finalized pop-party sign an instruction. It can be used in
+// the rules of the darcs of all contracts using the MakeAttrInterpreters of
+// the BasicContract. The attribute is written as:
+//   attr:popparty:party=<hex of the party instance ID>
+//   attr:popparty:party=<hex of the party instance ID>&scope=<scope>
+// The instruction must be a spawn or an invoke, and its PopPartyArgument
+// must hold a linkable ring signature of PopPartyMessage over the attendees
+// of the party.
+//
+// Without scope, the signature is not linkable, so the attendees can use it
+// as often as they want. With a scope, every attendee can only use it once:
+// the same transaction must first invoke 'usetag' on the party with the same
+// signature, which stores the tag in a popPartyTag instance and refuses tags
+// already used in this scope.
+//
+// As byzcoin needs at least one signature for every instruction, the
+// instruction still has to be signed, for example by a new key.
+const AttrPopPartyID = "popparty"
+
+// ContractPopPartyTagID is the contract of the instances holding the tags
+// used by the popparty attribute. They are created by the 'usetag' command
+// of the party, and cannot be changed.
+var ContractPopPartyTagID = "popPartyTag"
+
+// ContractPopPartyTag holds a PopPartyTag. It only has the methods of the
+// BasicContract, so it cannot be spawned, invoked or deleted.
+type ContractPopPartyTag struct {
+	byzcoin.BasicContract
+	PopPartyTag
+}
+
+// ContractPopPartyTagFromBytes returns a ContractPopPartyTag given a slice
+// of bytes.
+func ContractPopPartyTagFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractPopPartyTag{}
+	err := protobuf.Decode(in, &c.PopPartyTag)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+// PopPartyArgument is the name of the argument holding the linkable ring
+// signature for the popparty attribute.
+const PopPartyArgument = "popparty_lrs"
+
+// PopPartyAttrInterpreter returns the interpreter of the popparty attribute
+// for the instruction.
+func PopPartyAttrInterpreter(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction) func(string) error {
+	return func(attr string) error {
+		vals, err := url.ParseQuery(attr)
+		if err != nil {
+			return errors.New("couldn't parse attribute: " + err.Error())
+		}
+		party, err := hex.DecodeString(vals.Get("party"))
+		if err != nil || len(party) != len(byzcoin.InstanceID{}) {
+			return errors.New("need the instance ID of a party")
+		}
+		pop, err := getFinalizedParty(rst, party)
+		if err != nil {
+			return err
+		}
+
+		var lrs []byte
+		switch inst.GetType() {
+		case byzcoin.SpawnType:
+			lrs = inst.Spawn.Args.Search(PopPartyArgument)
+		case byzcoin.InvokeType:
+			lrs = inst.Invoke.Args.Search(PopPartyArgument)
+		default:
+			return errors.New("only spawn and invoke can be signed by attendees")
+		}
+		if lrs == nil {
+			return errors.New("need " + PopPartyArgument + " argument")
+		}
+
+		scope := vals.Get("scope")
+		var linkScope []byte
+		if scope != "" {
+			linkScope = PopPartyScope(byzcoin.NewInstanceID(party), scope)
+		}
+		msg := PopPartyMessage(inst)
+		tag, err := anon.Verify(&SuiteBlake2s{}, msg, pop.Attendees.Keys,
+			linkScope, lrs)
+		if err != nil {
+			return errors.New("error while verifying signature: " + err.Error())
+		}
+		if scope == "" {
+			return nil
+		}
+		tagBuf, _, cid, _, err := rst.GetValues(
+			PopPartyTagID(byzcoin.NewInstanceID(party), scope, tag).Slice())
+		if err != nil || cid != ContractPopPartyTagID {
+			return errors.New("the tag has not been used on the party")
+		}
+		var t PopPartyTag
+		if err = protobuf.Decode(tagBuf, &t); err != nil {
+			return errors.New("couldn't decode tag: " + err.Error())
+		}
+		if !bytes.Equal(t.Instruction, msg) {
+			return errors.New("this attendee already used this scope")
+		}
+		return nil
+	}
+}
+
+// PopPartyScope returns the scope of the linkable ring signatures of the
+// party for the scope of the popparty attribute.
+func PopPartyScope(party byzcoin.InstanceID, scope string) []byte {
+	h := sha256.New()
+	h.Write([]byte(AttrPopPartyID))
+	h.Write(party[:])
+	h.Write([]byte(scope))
+	return h.Sum(nil)
+}
+
+// PopPartyTagID returns the ID of the instance holding the tag once it has
+// been used in the scope of the party.
+func PopPartyTagID(party byzcoin.InstanceID, scope string, tag []byte) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write([]byte(ContractPopPartyTagID))
+	h.Write(party[:])
+	l := make([]byte, 8)
+	binary.LittleEndian.PutUint64(l, uint64(len(scope)))
+	h.Write(l)
+	h.Write([]byte(scope))
+	h.Write(tag)
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+// PopPartyMessage returns the message signed by the attendees for the
+// popparty attribute. It is the hash of the instruction without the
+// PopPartyArgument, so the signer identities and counters must be set
+// before.
+func PopPartyMessage(inst byzcoin.Instruction) []byte {
+	strip := func(args byzcoin.Arguments) (res byzcoin.Arguments) {
+		for _, arg := range args {
+			if arg.Name != PopPartyArgument {
+				res = append(res, arg)
+			}
+		}
+		return
+	}
+	switch inst.GetType() {
+	case byzcoin.SpawnType:
+		spawn := *inst.Spawn
+		spawn.Args = strip(spawn.Args)
+		inst.Spawn = &spawn
+	case byzcoin.InvokeType:
+		invoke := *inst.Invoke
+		invoke.Args = strip(invoke.Args)
+		inst.Invoke = &invoke
+	}
+	return inst.Hash()
+}
+
+// SignPopParty adds the linkable ring signature of the attendee at index
+// mine in atts to the instruction, for the popparty attribute with the given
+// scope, which can be empty. The signature is returned so that it can be
+// given to NewInstructionPopPartyUseTag.
+func SignPopParty(inst *byzcoin.Instruction, party byzcoin.InstanceID,
+	scope string, atts Attendees, mine int, priv kyber.Scalar) ([]byte, error) {
+	var args *byzcoin.Arguments
+	switch inst.GetType() {
+	case byzcoin.SpawnType:
+		args = &inst.Spawn.Args
+	case byzcoin.InvokeType:
+		args = &inst.Invoke.Args
+	default:
+		return nil, errors.New("only spawn and invoke can be signed by attendees")
+	}
+	var linkScope []byte
+	if scope != "" {
+		linkScope = PopPartyScope(party, scope)
+	}
+	lrs := anon.Sign(&SuiteBlake2s{}, PopPartyMessage(*inst), atts.Keys,
+		linkScope, mine, priv)
+	*args = append(*args, newArg(PopPartyArgument, lrs))
+	return lrs, nil
+}
+
+// NewInstructionPopPartyUseTag returns an instruction using the tag of the
+// linkable ring signature lrs once in the scope of the party. It must come
+// before the instruction signed by lrs in the same transaction, and doesn't
+// need to be signed.
+func NewInstructionPopPartyUseTag(party byzcoin.InstanceID, scope string,
+	inst byzcoin.Instruction, lrs []byte) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: party,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractPopPartyID,
+			Command:    "usetag",
+			Args: byzcoin.Arguments{
+				newArg("scope", []byte(scope)),
+				newArg("instruction", PopPartyMessage(inst)),
+				newArg("lrs", lrs),
+			},
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package contracts
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/kyber/v3/util/key"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+// Signs instructions as anonymous attendees of a party, with and without
+// scope, and makes sure strangers and reused tags are refused.
+func TestAttrPopParty(t *testing.T) {
+	rost := byzcoin.NewROSTSimul()
+	d, err := rost.CreateBasicDarc(nil, "popparty")
+	require.NoError(t, err)
+
+	var kps []*key.Pair
+	var atts Attendees
+	for i := 0; i < 3; i++ {
+		kp := key.NewKeyPair(cothority.Suite)
+		kps = append(kps, kp)
+		atts.Keys = append(atts.Keys, kp.Public)
+	}
+	party, err := rost.CreateRandomInstance(ContractPopPartyID,
+		&PopPartyStruct{State: FinalizedState, Attendees: atts}, d.GetBaseID())
+	require.NoError(t, err)
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	newInst := func(value string) byzcoin.Instruction {
+		return byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(d.GetBaseID()),
+			Invoke: &byzcoin.Invoke{
+				ContractID: "value",
+				Command:    "update",
+				Args:       byzcoin.Arguments{newArg("value", []byte(value))},
+			},
+			SignerIdentities: []darc.Identity{signer.Identity()},
+			SignerCounter:    []uint64{1},
+		}
+	}
+	verify := func(inst byzcoin.Instruction, scope string) error {
+		attr := "party=" + hex.EncodeToString(party[:])
+		if scope != "" {
+			attr += "&scope=" + scope
+		}
+		attrs := byzcoin.BasicContract{}.MakeAttrInterpreters(rost, inst)
+		require.NotNil(t, attrs[AttrPopPartyID])
+		return attrs[AttrPopPartyID](attr)
+	}
+	useTag := func(scope string, inst byzcoin.Instruction, lrs []byte) error {
+		c, err := ContractPopPartyFromBytes(rost.Values[string(party[:])].Value)
+		require.NoError(t, err)
+		ut := NewInstructionPopPartyUseTag(party, scope, inst, lrs)
+		scs, _, err := c.(*ContractPopParty).Invoke(rost, ut, nil)
+		if err != nil {
+			return err
+		}
+		_, err = rost.StoreAllToReplica(scs)
+		return err
+	}
+
+	// Without scope, the attendees can sign as often as they want.
+	inst := newInst("one")
+	_, err = SignPopParty(&inst, party, "", atts, 0, kps[0].Private)
+	require.NoError(t, err)
+	require.NoError(t, verify(inst, ""))
+	require.Error(t, verify(inst, "vote"))
+	inst.Invoke.Args[0].Value = []byte("two")
+	require.Error(t, verify(inst, ""))
+
+	stranger := key.NewKeyPair(cothority.Suite)
+	ring := Attendees{Keys: []kyber.Point{atts.Keys[0], atts.Keys[1], stranger.Public}}
+	inst = newInst("one")
+	_, err = SignPopParty(&inst, party, "", ring, 2, stranger.Private)
+	require.NoError(t, err)
+	require.Error(t, verify(inst, ""))
+
+	// With a scope, the tag must be used once on the party.
+	inst = newInst("one")
+	lrs, err := SignPopParty(&inst, party, "vote", atts, 1, kps[1].Private)
+	require.NoError(t, err)
+	require.Error(t, verify(inst, "vote"))
+	partyBuf := rost.Values[string(party[:])].Value
+	require.NoError(t, useTag("vote", inst, lrs))
+	require.NoError(t, verify(inst, "vote"))
+	// The tag is stored in its own instance, the party doesn't change.
+	require.Equal(t, partyBuf, rost.Values[string(party[:])].Value)
+	tag, err := anon.Verify(&SuiteBlake2s{}, PopPartyMessage(inst), atts.Keys,
+		PopPartyScope(party, "vote"), lrs)
+	require.NoError(t, err)
+	tagID := PopPartyTagID(party, "vote", tag)
+	require.Equal(t, ContractPopPartyTagID,
+		rost.Values[string(tagID[:])].ContractID)
+
+	inst2 := newInst("two")
+	lrs, err = SignPopParty(&inst2, party, "vote", atts, 1, kps[1].Private)
+	require.NoError(t, err)
+	require.Error(t, useTag("vote", inst2, lrs))
+	require.Error(t, verify(inst2, "vote"))
+
+	// Other scopes and attendees are independent.
+	inst2 = newInst("two")
+	lrs, err = SignPopParty(&inst2, party, "poll", atts, 1, kps[1].Private)
+	require.NoError(t, err)
+	require.NoError(t, useTag("poll", inst2, lrs))
+	require.NoError(t, verify(inst2, "poll"))
+	inst2 = newInst("two")
+	lrs, err = SignPopParty(&inst2, party, "vote", atts, 2, kps[2].Private)
+	require.NoError(t, err)
+	require.NoError(t, useTag("vote", inst2, lrs))
+	require.NoError(t, verify(inst2, "vote"))
+
+	// Chains running an older version don't have the attribute.
+	rost.Version = byzcoin.VersionPopPartyAttr - 1
+	attrs := byzcoin.BasicContract{}.MakeAttrInterpreters(rost, inst2)
+	require.Nil(t, attrs[AttrPopPartyID])
+}
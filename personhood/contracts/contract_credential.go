@@ -11,6 +11,7 @@ import (
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 
 	"go.dedis.ch/cothority/v3/byzcoin"
@@ -177,13 +178,18 @@ func (c *ContractCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.
 			return nil, nil, errors.New("no threshold or no trustee found")
 		}
 		var valid uint32
-		msg := append(inst.InstanceID.Slice(), pubBuf...)
-		darcVersion := make([]byte, 8)
-		binary.LittleEndian.PutUint64(darcVersion, d.Version)
-		msg = append(msg, darcVersion...)
-		for signer := 0; signer < len(recBuf); signer += sigLength {
+		msg := RecoverMessage(inst.InstanceID, pubBuf, d.Version)
+		// Before VersionCredentialRecover, the signatures were read at the
+		// wrong offsets, and a trustee could approve more than once.
+		fixed := rst.GetVersion() >= byzcoin.VersionCredentialRecover
+		step, sigEnd := sigLength, sigLength
+		if fixed {
+			step, sigEnd = recoverLength, recoverLength
+		}
+		approved := make(map[int]bool)
+		for signer := 0; signer < len(recBuf); signer += step {
 			pubBuf := recBuf[signer : signer+pointLength]
-			sig := recBuf[signer+pointLength : signer+sigLength]
+			sig := recBuf[signer+pointLength : signer+sigEnd]
 			pub := cothority.Suite.Point()
 			err = pub.UnmarshalBinary(pubBuf)
 			if err != nil {
@@ -191,8 +197,12 @@ func (c *ContractCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.
 			}
 			pubStr := darc.NewIdentityEd25519(pub).String()
 			if err = schnorr.Verify(cothority.Suite, pub, msg, sig); err == nil {
-				for _, trusteeDarc := range trusteesDarc {
+				for i, trusteeDarc := range trusteesDarc {
+					if fixed && approved[i] {
+						continue
+					}
 					if err := checkDarcRule(rst, trusteeDarc, pubStr); err == nil {
+						approved[i] = true
 						valid++
 						break
 					}
@@ -206,9 +216,26 @@ func (c *ContractCredential) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.
 		}
 		publicStr := darc.NewIdentityEd25519(public).String()
 		newDarc := d.Copy()
-		err = newDarc.Rules.UpdateRule("invoke:evolve", expression.InitAndExpr(publicStr))
-		if err != nil {
-			return
+		if fixed {
+			// Darcs of newer chains use the prefixed rule.
+			evolved := false
+			for _, a := range []darc.Action{"invoke:evolve", "invoke:darc.evolve"} {
+				if newDarc.Rules.Contains(a) {
+					err = newDarc.Rules.UpdateRule(a, expression.InitAndExpr(publicStr))
+					if err != nil {
+						return
+					}
+					evolved = true
+				}
+			}
+			if !evolved {
+				return nil, nil, errors.New("darc of the credential has no evolve rule")
+			}
+		} else {
+			err = newDarc.Rules.UpdateRule("invoke:evolve", expression.InitAndExpr(publicStr))
+			if err != nil {
+				return
+			}
 		}
 		err = newDarc.Rules.UpdateSign(expression.InitAndExpr(publicStr))
 		if err != nil {
@@ -271,6 +298,142 @@ func NewInstructionCredentialSpawn(dst byzcoin.InstanceID, did darc.ID,
 	return
 }
 
+// NewInstructionCredentialUpdate returns an instruction that is ready to be
+// sent to byzcoin to overwrite the credential in credIID.
+func NewInstructionCredentialUpdate(credIID byzcoin.InstanceID,
+	cred CredentialStruct) (inst byzcoin.Instruction, err error) {
+	var credBuf []byte
+	credBuf, err = protobuf.Encode(&cred)
+	if err != nil {
+		return
+	}
+	inst.InstanceID = credIID
+	inst.Invoke = &byzcoin.Invoke{
+		ContractID: ContractCredentialID,
+		Command:    "update",
+		Args: byzcoin.Arguments{
+			newArg("credential", credBuf),
+		},
+	}
+	return
+}
+
+// SetRecover replaces the "recover" credential of cs with the given
+// threshold and trustees. The trustees are credential instances, and the
+// keys in the sign rule of their darcs can approve a recovery.
+func (cs *CredentialStruct) SetRecover(threshold uint32,
+	trustees []byzcoin.InstanceID) error {
+	if threshold == 0 || int(threshold) > len(trustees) {
+		return errors.New("threshold must be between 1 and the number of trustees")
+	}
+	thresholdBuf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(thresholdBuf, threshold)
+	var trusteesBuf []byte
+	for _, t := range trustees {
+		trusteesBuf = append(trusteesBuf, t[:]...)
+	}
+	rec := Credential{
+		Name: "recover",
+		Attributes: []Attribute{
+			{Name: "threshold", Value: thresholdBuf},
+			{Name: "trustees", Value: trusteesBuf},
+		},
+	}
+	for i, c := range cs.Credentials {
+		if c.Name == "recover" {
+			cs.Credentials[i] = rec
+			return nil
+		}
+	}
+	cs.Credentials = append(cs.Credentials, rec)
+	return nil
+}
+
+// RecoverMessage returns the message the trustees sign to approve that the
+// darc of the credential credIID, at version darcVersion, is given to the
+// public key in public.
+func RecoverMessage(credIID byzcoin.InstanceID, public []byte,
+	darcVersion uint64) []byte {
+	msg := append(credIID.Slice(), public...)
+	version := make([]byte, 8)
+	binary.LittleEndian.PutUint64(version, darcVersion)
+	return append(msg, version...)
+}
+
+// SignRecover returns the approval of a trustee for the recovery message
+// msg. The approval is the public key of the trustee followed by its
+// signature.
+func SignRecover(msg []byte, priv kyber.Scalar) ([]byte, error) {
+	pub, err := cothority.Suite.Point().Mul(priv, nil).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := schnorr.Sign(cothority.Suite, priv, msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(pub, sig...), nil
+}
+
+// NewInstructionCredentialRecover returns an instruction that gives the
+// darc of the credential credIID to the public key, if enough approvals of
+// the trustees are given. The instruction doesn't need to be signed.
+func NewInstructionCredentialRecover(credIID byzcoin.InstanceID,
+	public kyber.Point, approvals [][]byte) (inst byzcoin.Instruction, err error) {
+	recoverLength := 2*cothority.Suite.PointLen() + cothority.Suite.ScalarLen()
+	var sigs []byte
+	for _, a := range approvals {
+		if len(a) != recoverLength {
+			return inst, errors.New("wrong length of approval")
+		}
+		sigs = append(sigs, a...)
+	}
+	var pubBuf []byte
+	pubBuf, err = public.MarshalBinary()
+	if err != nil {
+		return
+	}
+	inst.InstanceID = credIID
+	inst.Invoke = &byzcoin.Invoke{
+		ContractID: ContractCredentialID,
+		Command:    "recover",
+		Args: byzcoin.Arguments{
+			newArg("signatures", sigs),
+			newArg("public", pubBuf),
+		},
+	}
+	return
+}
+
+// CredentialAttribute returns the value of the attribute attr of the
+// credential cred stored in the credential instance credID.
+func CredentialAttribute(rst byzcoin.ReadOnlyStateTrie, credID byzcoin.InstanceID,
+	cred, attr string) ([]byte, error) {
+	credBuf, _, cid, _, err := rst.GetValues(credID.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if cid != ContractCredentialID {
+		return nil, errors.New("not a credential instance")
+	}
+	var cs CredentialStruct
+	err = protobuf.Decode(credBuf, &cs)
+	if err != nil {
+		return nil, errors.New("couldn't decode credential: " + err.Error())
+	}
+	for _, c := range cs.Credentials {
+		if c.Name != cred {
+			continue
+		}
+		for _, a := range c.Attributes {
+			if a.Name == attr {
+				return a.Value, nil
+			}
+		}
+	}
+	return nil, errors.New("didn't find attribute " + cred + "/" + attr)
+}
+
 func getDarc(rst byzcoin.ReadOnlyStateTrie, darcID darc.ID) (*darc.Darc, error) {
 	darcBuf, _, cid, _, err := rst.GetValues(darcID)
 	if err != nil {
@@ -3,10 +3,14 @@ package contracts
 import (
 	"testing"
 
+	"go.dedis.ch/kyber/v3/util/key"
 	"go.dedis.ch/protobuf"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 )
 
 func TestContractCredential_Spawn(t *testing.T) {
@@ -26,3 +30,82 @@ func TestContractCredential_Spawn(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, cred, cred2)
 }
+
+// Configures three trustees with a threshold of two, and recovers the darc
+// of a credential with their approvals.
+func TestContractCredential_Recover(t *testing.T) {
+	rost := byzcoin.NewROSTSimul()
+
+	// createCredential stores a credential with a darc signed by kp.
+	createCredential := func(kp *key.Pair, cs CredentialStruct) (byzcoin.InstanceID, *darc.Darc) {
+		id := darc.NewIdentityEd25519(kp.Public)
+		d := darc.NewDarc(darc.InitRules([]darc.Identity{id}, []darc.Identity{id}),
+			[]byte("credential"))
+		require.NoError(t, d.Rules.AddRule("invoke:darc.evolve",
+			expression.InitAndExpr(id.String())))
+		require.NoError(t, rost.CreateSCB(byzcoin.Create, byzcoin.ContractDarcID,
+			byzcoin.NewInstanceID(d.GetBaseID()), d, nil))
+		credIID, err := rost.CreateRandomInstance(ContractCredentialID, &cs,
+			d.GetBaseID())
+		require.NoError(t, err)
+		return credIID, d
+	}
+
+	var trustees []byzcoin.InstanceID
+	var trusteeKeys []*key.Pair
+	for i := 0; i < 3; i++ {
+		kp := key.NewKeyPair(cothority.Suite)
+		credIID, _ := createCredential(kp, CredentialStruct{})
+		trustees = append(trustees, credIID)
+		trusteeKeys = append(trusteeKeys, kp)
+	}
+	var cs CredentialStruct
+	require.Error(t, cs.SetRecover(4, trustees))
+	require.NoError(t, cs.SetRecover(2, trustees))
+	credIID, d := createCredential(key.NewKeyPair(cothority.Suite), cs)
+
+	newKey := key.NewKeyPair(cothority.Suite)
+	newPub, err := newKey.Public.MarshalBinary()
+	require.NoError(t, err)
+	msg := RecoverMessage(credIID, newPub, d.Version)
+	var approvals [][]byte
+	for _, kp := range trusteeKeys {
+		approval, err := SignRecover(msg, kp.Private)
+		require.NoError(t, err)
+		approvals = append(approvals, approval)
+	}
+
+	recoverDarc := func(approvals ...[]byte) ([]byzcoin.StateChange, error) {
+		val, _, _, _, err := rost.GetValues(credIID.Slice())
+		require.NoError(t, err)
+		cc, err := ContractCredentialFromBytes(val)
+		require.NoError(t, err)
+		inst, err := NewInstructionCredentialRecover(credIID, newKey.Public, approvals)
+		require.NoError(t, err)
+		scs, _, err := cc.(*ContractCredential).Invoke(rost, inst, nil)
+		return scs, err
+	}
+
+	// One trustee is not enough, even approving twice.
+	_, err = recoverDarc(approvals[0])
+	require.Error(t, err)
+	_, err = recoverDarc(approvals[0], approvals[0])
+	require.Error(t, err)
+	// Approvals of another key are refused.
+	other, err := SignRecover(RecoverMessage(credIID, newPub, d.Version+1),
+		trusteeKeys[1].Private)
+	require.NoError(t, err)
+	_, err = recoverDarc(approvals[0], other)
+	require.Error(t, err)
+
+	scs, err := recoverDarc(approvals[0], approvals[2])
+	require.NoError(t, err)
+	require.Equal(t, 1, len(scs))
+	newDarc, err := darc.NewFromProtobuf(scs[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, d.Version+1, newDarc.Version)
+	newID := darc.NewIdentityEd25519(newKey.Public).String()
+	require.Equal(t, expression.InitAndExpr(newID), newDarc.Rules.GetSignExpr())
+	require.Equal(t, expression.InitAndExpr(newID),
+		newDarc.Rules.Get("invoke:darc.evolve"))
+}
@@ -0,0 +1,271 @@
+package contracts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+// ContractPollID denotes a contract holding an anonymous,
+// troll-resistant poll.
+var ContractPollID = "poll"
+
+// ContractPollFromBytes returns a ContractPoll structure given a slice of
+// bytes.
+func ContractPollFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &ContractPoll{}
+	err := protobuf.Decode(in, &c.PollStruct)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+// ContractPoll embeds the BasicContract. It holds a poll that can only be
+// answered by the attendees of a pop-party. The poll is created and deleted
+// by the darc that spawned it, while the answers are only protected by a
+// linkable ring signature over the attendees of the party:
+//  - the message is 'Choice' + byte(choice)
+//  - the scope is the instance ID of the poll
+// The tag of the signature is stored with the choice, so every attendee can
+// answer only once, and answering again replaces the previous choice.
+//
+// If the Personhood of the poll is the ConfigInstanceID, the attendees of
+// any finalized pop-party can answer, and the party is given in the 'party'
+// argument of the answer.
+type ContractPoll struct {
+	byzcoin.BasicContract
+	PollStruct
+}
+
+// VerifyInstruction overrides the basic VerifyInstruction in case of an
+// "answer" command, because this command is not protected by a darc, but by
+// a linkable ring signature.
+func (c ContractPoll) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
+	if inst.GetType() == byzcoin.InvokeType && inst.Invoke.Command == "answer" {
+		log.Lvl2("not verifying darc for answering")
+		return nil
+	}
+	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
+}
+
+// Spawn creates a new poll. The following argument must be set:
+//  - struct that holds a protobuf-encoded PollStruct without choices made.
+func (c ContractPoll) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		err = xerrors.Errorf("couldn't get darc: %+v", err)
+		return
+	}
+
+	var ca byzcoin.InstanceID
+	if rst.GetVersion() >= byzcoin.VersionPreID {
+		ca, err = inst.DeriveIDArg("", "preID")
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't get deriveID: %v", err)
+		}
+	} else {
+		ca = inst.DeriveID("")
+	}
+
+	log.Lvlf3("Spawning poll to %x", ca.Slice())
+	pollBuf := inst.Spawn.Args.Search("struct")
+	if pollBuf == nil {
+		return nil, nil, errors.New("poll needs struct argument")
+	}
+	err = protobuf.Decode(pollBuf, &c.PollStruct)
+	if err != nil {
+		return nil, nil, errors.New("couldn't decode PollStruct: " + err.Error())
+	}
+	if c.Title == "" {
+		return nil, nil, errors.New("poll needs a title")
+	}
+	if len(c.Choices) == 0 || len(c.Choices) > 256 {
+		return nil, nil, errors.New("poll needs between 1 and 256 choices")
+	}
+	if len(c.Chosen) > 0 {
+		return nil, nil, errors.New("new poll cannot have answers")
+	}
+	if !c.Personhood.Equal(byzcoin.ConfigInstanceID) {
+		_, _, cid, _, err := rst.GetValues(c.Personhood.Slice())
+		if err != nil {
+			return nil, nil, errors.New("couldn't get party: " + err.Error())
+		}
+		if cid != ContractPopPartyID {
+			return nil, nil, errors.New("personhood of poll is not a pop-party")
+		}
+	}
+	c.PollID = ca.Slice()
+
+	pollBuf, err = protobuf.Encode(&c.PollStruct)
+	if err != nil {
+		return
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Create, ca, ContractPollID, pollBuf, darcID))
+	return
+}
+
+// NewInstructionPollSpawn returns a new instruction that is ready to be
+// sent to byzcoin to spawn a new poll instance.
+func NewInstructionPollSpawn(did darc.ID, ps PollStruct) (
+	inst byzcoin.Instruction, err error) {
+
+	inst.InstanceID = byzcoin.NewInstanceID(did)
+	sBuf, err := protobuf.Encode(&ps)
+	if err != nil {
+		err = xerrors.Errorf("couldn't encode PollStruct: %+v", err)
+		return
+	}
+	inst.Spawn = &byzcoin.Spawn{
+		ContractID: ContractPollID,
+		Args: byzcoin.Arguments{
+			newArg("struct", sBuf),
+		},
+	}
+	return
+}
+
+// Invoke takes the following command:
+//  - answer stores the 'choice' of an attendee, signed by the 'lrs' linkable
+//    ring signature. If the poll is open to all parties, the 'party' argument
+//    is the instance ID of the party of the attendee.
+func (c *ContractPoll) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "answer":
+		choice := inst.Invoke.Args.Search("choice")
+		if len(choice) != 1 {
+			return nil, nil, errors.New("need a 1-byte choice")
+		}
+		if int(choice[0]) >= len(c.Choices) {
+			return nil, nil, errors.New("this choice doesn't exist")
+		}
+		lrs := inst.Invoke.Args.Search("lrs")
+		if lrs == nil {
+			return nil, nil, errors.New("need lrs argument")
+		}
+		party := c.Personhood.Slice()
+		if c.Personhood.Equal(byzcoin.ConfigInstanceID) {
+			party = inst.Invoke.Args.Search("party")
+			if len(party) != 32 {
+				return nil, nil, errors.New("need a valid party")
+			}
+		}
+		pop, err := getFinalizedParty(rst, party)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg := append([]byte("Choice"), choice[0])
+		tag, err := anon.Verify(&SuiteBlake2s{}, msg, pop.Attendees.Keys,
+			inst.InstanceID[:], lrs)
+		if err != nil {
+			return nil, nil, errors.New("error while verifying signature: " + err.Error())
+		}
+		update := false
+		for i, ch := range c.Chosen {
+			if bytes.Compare(ch.LRSTag, tag) == 0 {
+				log.Lvl2("Updating choice", i)
+				c.Chosen[i].Choice = int(choice[0])
+				update = true
+				break
+			}
+		}
+		if !update {
+			c.Chosen = append(c.Chosen, PollChoice{Choice: int(choice[0]), LRSTag: tag})
+		}
+	default:
+		return nil, nil, errors.New("poll contract can only 'answer'")
+	}
+
+	buf, err := protobuf.Encode(&c.PollStruct)
+	if err != nil {
+		return
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+		ContractPollID, buf, darcID))
+	return
+}
+
+// NewInstructionPollInvokeAnswer returns an instruction that can be sent
+// to byzcoin to answer a poll. The party is only used if the poll is open
+// to all parties. The instruction doesn't need to be signed.
+func NewInstructionPollInvokeAnswer(pollID, party byzcoin.InstanceID,
+	choice int, lrs []byte) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: pollID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractPollID,
+			Command:    "answer",
+			Args: byzcoin.Arguments{
+				newArg("choice", []byte{byte(choice)}),
+				newArg("lrs", lrs),
+				newArg("party", party[:]),
+			},
+		},
+	}
+}
+
+// SignPollAnswer returns the linkable ring signature of the choice of the
+// attendee at index mine in atts.
+func SignPollAnswer(pollID byzcoin.InstanceID, choice int, atts Attendees,
+	mine int, priv kyber.Scalar) []byte {
+	msg := append([]byte("Choice"), byte(choice))
+	return anon.Sign(&SuiteBlake2s{}, msg, atts.Keys, pollID[:], mine, priv)
+}
+
+// Delete removes an existing poll instance.
+func (c *ContractPoll) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractPollID, nil, darcID),
+	}
+	return
+}
+
+// getFinalizedParty returns the pop-party stored in the given instance, if
+// it is finalized.
+func getFinalizedParty(rst byzcoin.ReadOnlyStateTrie, party []byte) (*PopPartyStruct, error) {
+	val, _, cid, _, err := rst.GetValues(party)
+	if err != nil {
+		return nil, errors.New("couldn't get party: " + err.Error())
+	}
+	if cid != ContractPopPartyID {
+		return nil, errors.New("party is not a pop-party")
+	}
+	var pop PopPartyStruct
+	err = protobuf.DecodeWithConstructors(val, &pop, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal party: " + err.Error())
+	}
+	if pop.State != FinalizedState {
+		return nil, errors.New("party is not finalized")
+	}
+	return &pop, nil
+}
@@ -0,0 +1,82 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/protobuf"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+// Creates a poll for a finalized party, answers it from two attendees,
+// changes one answer, and makes sure strangers can't answer.
+func TestContractPoll(t *testing.T) {
+	rost := byzcoin.NewROSTSimul()
+	d, err := rost.CreateBasicDarc(nil, "poll")
+	require.NoError(t, err)
+
+	var kps []*key.Pair
+	var atts Attendees
+	for i := 0; i < 3; i++ {
+		kp := key.NewKeyPair(cothority.Suite)
+		kps = append(kps, kp)
+		atts.Keys = append(atts.Keys, kp.Public)
+	}
+	party, err := rost.CreateRandomInstance(ContractPopPartyID,
+		&PopPartyStruct{State: FinalizedState, Attendees: atts}, d.GetBaseID())
+	require.NoError(t, err)
+
+	cp := &ContractPoll{}
+	inst, err := NewInstructionPollSpawn(d.GetBaseID(), PollStruct{
+		Personhood: party,
+		Title:      "Lunch",
+		Choices:    []string{"pizza", "salad"},
+	})
+	require.NoError(t, err)
+	scs, _, err := cp.Spawn(rost, inst, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(scs))
+	rost.StoreAllToReplica(scs)
+	pollID := byzcoin.NewInstanceID(scs[0].InstanceID)
+
+	answer := func(ring Attendees, kp *key.Pair, mine, choice int) (*PollStruct, error) {
+		c, err := ContractPollFromBytes(rost.Values[string(pollID[:])].Value)
+		require.NoError(t, err)
+		lrs := SignPollAnswer(pollID, choice, ring, mine, kp.Private)
+		inst := NewInstructionPollInvokeAnswer(pollID, party, choice, lrs)
+		scs, _, err := c.(*ContractPoll).Invoke(rost, inst, nil)
+		if err != nil {
+			return nil, err
+		}
+		rost.StoreAllToReplica(scs)
+		var ps PollStruct
+		require.NoError(t, protobuf.Decode(scs[0].Value, &ps))
+		return &ps, nil
+	}
+
+	ps, err := answer(atts, kps[0], 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, pollID[:], ps.PollID)
+	require.Equal(t, 1, len(ps.Chosen))
+	ps, err = answer(atts, kps[1], 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(ps.Chosen))
+
+	// Answering again replaces the choice.
+	ps, err = answer(atts, kps[0], 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(ps.Chosen))
+	require.Equal(t, 0, ps.Chosen[0].Choice)
+
+	// Unknown choices and strangers are refused.
+	_, err = answer(atts, kps[2], 2, 2)
+	require.Error(t, err)
+	stranger := key.NewKeyPair(cothority.Suite)
+	ring := Attendees{Keys: []kyber.Point{atts.Keys[0], atts.Keys[1], stranger.Public}}
+	_, err = answer(ring, stranger, 2, 0)
+	require.Error(t, err)
+}
@@ -57,13 +57,17 @@ func ContractPopPartyFromBytes(in []byte) (byzcoin.Contract, error) {
 	return c, nil
 }
 
-// VerifyInstruction overrides the basic VerifyInstruction in case of a "mine" command, because this command
-// is not protected by a darc, but by a linkable ring signature.
+// VerifyInstruction overrides the basic VerifyInstruction in case of a "mine" or "usetag" command, because
+// these commands are not protected by a darc, but by a linkable ring signature.
 func (c ContractPopParty) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
 	if inst.GetType() == byzcoin.InvokeType && inst.Invoke.Command == "mine" {
 		log.Lvl2("not verifying darc for mining")
 		return nil
 	}
+	if inst.GetType() == byzcoin.InvokeType && inst.Invoke.Command == "usetag" {
+		log.Lvl2("not verifying darc for using a tag")
+		return nil
+	}
 	return c.BasicContract.VerifyInstruction(rst, inst, ctxHash)
 }
 
@@ -149,6 +153,10 @@ func (sb SuiteBlake2s) XOF(key []byte) kyber.XOF {
 //  - mine to collect the reward. 'lrs' must hold a correct, unique linkable ring signature. If
 //    'coinIID' is set, this coin will be filled. Else 'newDarc' will be used to create a darc,
 //    derive a coin, and fill this coin.
+//  - usetag to use the tag of an attendee once in the 'scope' of the 'popparty' attribute. 'lrs'
+//    must hold the linkable ring signature of the 'instruction' argument, which is the hash returned
+//    by PopPartyMessage, and will be accepted by the attribute in the same transaction. The tag is
+//    stored in its own popPartyTag instance, so the party itself doesn't grow.
 func (c *ContractPopParty) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
 	coins []byzcoin.Coin) (scs []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
 	cout = coins
@@ -288,6 +296,42 @@ func (c *ContractPopParty) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.In
 			byzcoin.NewInstanceID(coinIID),
 			contracts.ContractCoinID, coinBuf, coinDarc))
 
+	case "usetag":
+		if c.State != FinalizedState {
+			return nil, nil, errors.New("cannot use tags when party is not finalized")
+		}
+		scope := string(inst.Invoke.Args.Search("scope"))
+		if scope == "" {
+			return nil, nil, errors.New("need scope argument")
+		}
+		msg := inst.Invoke.Args.Search("instruction")
+		if len(msg) != sha256.Size {
+			return nil, nil, errors.New("need the hash of the instruction")
+		}
+		lrs := inst.Invoke.Args.Search("lrs")
+		if lrs == nil {
+			return nil, nil, errors.New("need lrs argument")
+		}
+		tag, err := anon.Verify(&SuiteBlake2s{}, msg, c.Attendees.Keys,
+			PopPartyScope(inst.InstanceID, scope), lrs)
+		if err != nil {
+			return nil, nil, errors.New("error while verifying signature: " + err.Error())
+		}
+		tagID := PopPartyTagID(inst.InstanceID, scope, tag)
+		_, _, _, _, err = rst.GetValues(tagID.Slice())
+		if err == nil {
+			return nil, nil, errors.New("this attendee already used this scope")
+		}
+		tagBuf, err := protobuf.Encode(&PopPartyTag{Scope: scope, Tag: tag,
+			Instruction: msg})
+		if err != nil {
+			return nil, nil, errors.New("couldn't encode tag: " + err.Error())
+		}
+		// The party doesn't change, only the tag is stored.
+		scs = append(scs, byzcoin.NewStateChange(byzcoin.Create, tagID,
+			ContractPopPartyTagID, tagBuf, darcID))
+		return scs, coins, nil
+
 	default:
 		return nil, nil, errors.New("unknown command: " + inst.Invoke.Command)
 	}
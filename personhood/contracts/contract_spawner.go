@@ -1,8 +1,10 @@
 package contracts
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math"
@@ -39,14 +41,30 @@ func ContractSpawnerFromBytes(in []byte) (byzcoin.Contract, error) {
 type ContractSpawner struct {
 	byzcoin.BasicContract
 	SpawnerStruct
+	contracts byzcoin.ReadOnlyContractRegistry
+}
+
+// SetRegistry keeps the reference of the contract registry, which is needed
+// to spawn the contracts given in the prices.
+func (c *ContractSpawner) SetRegistry(r byzcoin.ReadOnlyContractRegistry) {
+	c.contracts = r
 }
 
 // VerifyInstruction allows non-darc-verified calls for instructions that send coins.
+// If a spawn asks for the discount of a credential, the instruction must be
+// signed by the holder of the credential.
 func (c ContractSpawner) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, ctxHash []byte) error {
 	if inst.GetType() != byzcoin.SpawnType {
 		if err := inst.Verify(rst, ctxHash); err != nil {
 			return err
 		}
+		return nil
+	}
+	credID := inst.Spawn.Args.Search("discountCredential")
+	if credID != nil && c.Prices != nil && len(c.Prices.Discounts) > 0 {
+		if err := verifyCredentialHolder(rst, credID, inst, ctxHash); err != nil {
+			return errors.New("not the holder of the credential: " + err.Error())
+		}
 	}
 	return nil
 }
@@ -67,7 +85,51 @@ func (c ContractSpawner) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst b
 //   - ContractPopPartyID directly calls ContractPopParty.Spawn
 //   - ContractRoPaSciID directly calls ContractRoPaSci.Spawn
 //   - ContractValueID directly calls ContractValue.Spawn
+//   - any other contract with a cost in the prices of the spawner calls the
+//     Spawn of a new instance of this contract
+//
+// If the prices of the spawner hold a cost for the contract, it replaces the
+// fixed cost, and its CostPerByte is paid for every byte of the created
+// instances. If the 'discountCredential' argument holds the ID of a
+// credential with an attribute of the discounts, the biggest of these
+// discounts is applied to all costs.
 func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	price := c.price(inst.Spawn.ContractID)
+	discount, err := c.discount(rst, inst)
+	if err != nil {
+		return nil, nil, err
+	}
+	cost := func(fixed byzcoin.Coin) byzcoin.Coin {
+		if price != nil {
+			fixed = price.Cost
+		}
+		return discounted(fixed, discount)
+	}
+
+	sc, cout, err = c.spawn(rst, inst, coins, cost)
+	if err != nil || price == nil || price.CostPerByte == 0 {
+		return
+	}
+	var size uint64
+	for _, s := range sc {
+		if s.StateAction == byzcoin.Create {
+			size += uint64(len(s.Value))
+		}
+	}
+	if size > 0 && price.CostPerByte > math.MaxUint64/size {
+		return nil, nil, errors.New("cost per byte is too big")
+	}
+	perByte := byzcoin.Coin{Name: price.Cost.Name, Value: price.CostPerByte * size}
+	if err = c.getCoins(cout, discounted(perByte, discount)); err != nil {
+		return nil, nil, err
+	}
+	return
+}
+
+// spawn creates the instance, paying the cost returned for its fixed cost.
+func (c *ContractSpawner) spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction,
+	coins []byzcoin.Coin, cost func(byzcoin.Coin) byzcoin.Coin) (sc []byzcoin.StateChange,
+	cout []byzcoin.Coin, err error) {
 	cout = coins
 
 	var darcID darc.ID
@@ -97,6 +159,7 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 		c.CostCWrite = &byzcoin.Coin{}
 		c.CostCRead = &byzcoin.Coin{}
 		c.CostValue = &byzcoin.Coin{}
+		c.Prices = nil
 		err = c.parseArgs(inst.Spawn.Args, rst.GetVersion(), true)
 		if err != nil {
 			return nil, nil, errors.New("couldn't parse args: " + err.Error())
@@ -107,7 +170,7 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 		}
 
 	case byzcoin.ContractDarcID:
-		if err = c.getCoins(cout, c.CostDarc); err != nil {
+		if err = c.getCoins(cout, cost(c.CostDarc)); err != nil {
 			return
 		}
 		instBuf = inst.Spawn.Args.Search("darc")
@@ -153,7 +216,7 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 		}
 
 	case contracts.ContractCoinID:
-		if err = c.getCoins(cout, c.CostCoin); err != nil {
+		if err = c.getCoins(cout, cost(c.CostCoin)); err != nil {
 			return
 		}
 		coin := &byzcoin.Coin{
@@ -209,7 +272,7 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 		}
 
 	case ContractCredentialID:
-		if err = c.getCoins(cout, c.CostCredential); err != nil {
+		if err = c.getCoins(cout, cost(c.CostCredential)); err != nil {
 			return
 		}
 		instBuf = inst.Spawn.Args.Search("credential")
@@ -246,31 +309,51 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 			write.Cost.Name != c.CostCRead.Name {
 			err = fmt.Errorf("spawned calypso write needs to have cost at %d", c.CostCRead.Value)
 		}
-		if err = c.getCoins(cout, *c.CostCWrite); err != nil {
+		if err = c.getCoins(cout, cost(*c.CostCWrite)); err != nil {
 			return
 		}
 		return calypso.ContractWrite{}.Spawn(rst, inst, cout)
 
 	case ContractPopPartyID:
-		if err = c.getCoins(cout, c.CostParty); err != nil {
+		if err = c.getCoins(cout, cost(c.CostParty)); err != nil {
 			return
 		}
 		return ContractPopParty{}.Spawn(rst, inst, cout)
 
 	case ContractRoPaSciID:
-		if err = c.getCoins(cout, c.CostRoPaSci); err != nil {
+		if err = c.getCoins(cout, cost(c.CostRoPaSci)); err != nil {
 			return
 		}
 		return ContractRoPaSci{}.Spawn(rst, inst, cout)
 
 	case contracts.ContractValueID:
-		if err = c.getCoins(cout, *c.CostValue); err != nil {
+		if err = c.getCoins(cout, cost(*c.CostValue)); err != nil {
 			return
 		}
 		return contracts.ContractValue{}.Spawn(rst, inst, cout)
 
 	default:
-		return nil, nil, errors.New("don't know how to spawn this type of contract")
+		if c.price(cID) == nil {
+			return nil, nil, errors.New("don't know how to spawn this type of contract")
+		}
+		if c.contracts == nil {
+			return nil, nil, errors.New("contracts registry is missing")
+		}
+		cfact, found := c.contracts.Search(cID)
+		if !found {
+			return nil, nil, errors.New("couldn't find this contract type: " + cID)
+		}
+		c2, err := cfact(nil)
+		if err != nil {
+			return nil, nil, errors.New("couldn't create new instance: " + err.Error())
+		}
+		if cwr, ok := c2.(byzcoin.ContractWithRegistry); ok {
+			cwr.SetRegistry(c.contracts)
+		}
+		if err = c.getCoins(cout, cost(byzcoin.Coin{})); err != nil {
+			return nil, nil, err
+		}
+		return c2.Spawn(rst, inst, cout)
 	}
 	log.Lvlf3("Spawning %s instance to %x", cID, ca.Slice())
 	sc = []byzcoin.StateChange{
@@ -279,6 +362,59 @@ func (c *ContractSpawner) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Inst
 	return
 }
 
+// price returns the cost of the contract in the prices of the spawner, or nil
+// if there is none.
+func (c ContractSpawner) price(contractID string) *SpawnerCost {
+	if c.Prices == nil {
+		return nil
+	}
+	for i, cost := range c.Prices.Costs {
+		if cost.ContractID == contractID {
+			return &c.Prices.Costs[i]
+		}
+	}
+	return nil
+}
+
+// discount returns the biggest discount of the credential given in the
+// 'discountCredential' argument. VerifyInstruction made sure the instruction
+// is signed by the holder of the credential.
+func (c ContractSpawner) discount(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction) (uint32, error) {
+	credID := inst.Spawn.Args.Search("discountCredential")
+	if credID == nil || c.Prices == nil || len(c.Prices.Discounts) == 0 {
+		return 0, nil
+	}
+	_, _, cid, _, err := rst.GetValues(credID)
+	if err != nil {
+		return 0, errors.New("couldn't get credential: " + err.Error())
+	}
+	if cid != ContractCredentialID {
+		return 0, errors.New("discountCredential is not a credential instance")
+	}
+	var percent uint32
+	for _, d := range c.Prices.Discounts {
+		value, err := CredentialAttribute(rst, byzcoin.NewInstanceID(credID),
+			d.Credential, d.Attribute)
+		if err != nil {
+			continue
+		}
+		if len(d.Value) > 0 && !bytes.Equal(d.Value, value) {
+			continue
+		}
+		if d.Percent > percent {
+			percent = d.Percent
+		}
+	}
+	return percent, nil
+}
+
+// discounted returns the cost without the percent of discount.
+func discounted(cost byzcoin.Coin, percent uint32) byzcoin.Coin {
+	p := uint64(percent)
+	cost.Value -= cost.Value/100*p + cost.Value%100*p/100
+	return cost
+}
+
 func (c ContractSpawner) getCoins(coins []byzcoin.Coin, cost byzcoin.Coin) error {
 	if cost.Value == 0 {
 		return nil
@@ -372,6 +508,26 @@ func (ss *SpawnerStruct) parseArgs(args byzcoin.Arguments, v byzcoin.Version,
 		}
 		log.Lvl2("Setting cost of", cost.name, "to", cost.coin.Value)
 	}
+	if v >= byzcoin.VersionSpawnerPrices {
+		if arg := args.Search("prices"); arg != nil {
+			var prices SpawnerPrices
+			err := protobuf.Decode(arg, &prices)
+			if err != nil {
+				return errors.New("couldn't decode prices: " + err.Error())
+			}
+			if err = prices.check(); err != nil {
+				return err
+			}
+			ss.Prices = &prices
+			if len(prices.Costs) == 0 && len(prices.Discounts) == 0 {
+				ss.Prices = nil
+				log.Lvl2("Clearing the prices")
+			} else {
+				log.Lvl2("Setting the prices to", len(prices.Costs), "costs and",
+					len(prices.Discounts), "discounts")
+			}
+		}
+	}
 	if v < byzcoin.VersionSpawnerCoins {
 		// This is a check to make sure that older spawn-instructions don't get
 		// a wrong data.
@@ -397,3 +553,57 @@ func (ss *SpawnerStruct) defaultCosts() {
 		ss.CostValue = &ss.CostCoin
 	}
 }
+
+// check returns an error if the prices cannot be used by a spawner.
+func (sp SpawnerPrices) check() error {
+	seen := map[string]bool{}
+	for _, cost := range sp.Costs {
+		switch cost.ContractID {
+		case "":
+			return errors.New("cost without contract")
+		case ContractSpawnerID, byzcoin.ContractConfigID:
+			return errors.New("cannot spawn contract " + cost.ContractID)
+		}
+		if seen[cost.ContractID] {
+			return errors.New("two costs for contract " + cost.ContractID)
+		}
+		seen[cost.ContractID] = true
+	}
+	for _, d := range sp.Discounts {
+		if d.Percent > 100 {
+			return fmt.Errorf("discount of %d%% is too big", d.Percent)
+		}
+	}
+	return nil
+}
+
+// verifyCredentialHolder checks that the signers of the instruction can sign
+// for the darc of the credential credID.
+func verifyCredentialHolder(rst byzcoin.ReadOnlyStateTrie, credID []byte,
+	inst byzcoin.Instruction, ctxHash []byte) error {
+	d, err := getDarcFromCredIID(rst, credID)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for i := range inst.Signatures {
+		if i < len(inst.SignerIdentities) &&
+			inst.SignerIdentities[i].Verify(ctxHash, inst.Signatures[i]) == nil {
+			ids = append(ids, inst.SignerIdentities[i].String())
+		}
+	}
+	return darc.EvalExpr(d.Rules.GetSignExpr(), func(str string, latest bool) *darc.Darc {
+		if len(str) < 5 || str[0:5] != "darc:" {
+			return nil
+		}
+		id, err := hex.DecodeString(str[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := getDarc(rst, id)
+		if err != nil {
+			return nil
+		}
+		return d
+	}, ids...)
+}
@@ -390,3 +390,108 @@ func (sr *spawnerROST) spawn(t *testing.T, inst byzcoin.Instruction) (
 	}
 	return
 }
+
+// Spawns a poll with the prices of the spawner, with and without the discount
+// of a credential.
+func TestContractSpawnerPrices(t *testing.T) {
+	s := byzcoin.NewROSTSimul()
+	iid := byzcoin.NewInstanceID([]byte("some coin"))
+	s.Values[string(iid.Slice())] = byzcoin.StateChangeBody{}
+
+	spawnSpawner := func(prices SpawnerPrices) (*ContractSpawner, error) {
+		pricesBuf, err := protobuf.Encode(&prices)
+		require.NoError(t, err)
+		cs := &ContractSpawner{}
+		scs, _, err := cs.Spawn(s, byzcoin.Instruction{
+			InstanceID: iid,
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractSpawnerID,
+				Args:       byzcoin.Arguments{{Name: "prices", Value: pricesBuf}},
+			},
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		require.Equal(t, 1, len(scs))
+		c, err := ContractSpawnerFromBytes(scs[0].Value)
+		require.NoError(t, err)
+		cs = c.(*ContractSpawner)
+		cs.SetRegistry(byzcoin.GetContractRegistry())
+		return cs, nil
+	}
+
+	_, err := spawnSpawner(SpawnerPrices{Costs: []SpawnerCost{{ContractID: ContractSpawnerID}}})
+	require.Error(t, err)
+	_, err = spawnSpawner(SpawnerPrices{Discounts: []SpawnerDiscount{{Percent: 101}}})
+	require.Error(t, err)
+
+	prices := SpawnerPrices{
+		Costs: []SpawnerCost{{
+			ContractID:  ContractPollID,
+			Cost:        byzcoin.Coin{Name: contracts.CoinName, Value: 100},
+			CostPerByte: 3,
+		}},
+		Discounts: []SpawnerDiscount{{
+			Credential: "personhood",
+			Attribute:  "member",
+			Percent:    50,
+		}},
+	}
+	cs, err := spawnSpawner(prices)
+	require.NoError(t, err)
+	require.NotNil(t, cs.Prices)
+
+	party, err := s.CreateRandomInstance(ContractPopPartyID,
+		&PopPartyStruct{State: FinalizedState}, nil)
+	require.NoError(t, err)
+	inst, err := NewInstructionPollSpawn(iid[:], PollStruct{
+		Personhood: party,
+		Title:      "Lunch",
+		Choices:    []string{"pizza", "salad"},
+	})
+	require.NoError(t, err)
+	coin := byzcoin.Coin{Name: contracts.CoinName, Value: 10000}
+	scs, out, err := cs.Spawn(s, inst, []byzcoin.Coin{coin})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(scs))
+	require.Equal(t, ContractPollID, scs[0].ContractID)
+	size := uint64(len(scs[0].Value))
+	require.Equal(t, 10000-100-3*size, out[0].Value)
+
+	_, _, err = cs.Spawn(s, inst, []byzcoin.Coin{{Name: contracts.CoinName, Value: 100}})
+	require.Error(t, err)
+
+	// Members get half of the price, other credentials pay it all.
+	credID, err := s.CreateRandomInstance(ContractCredentialID, &CredentialStruct{
+		Credentials: []Credential{{
+			Name:       "personhood",
+			Attributes: []Attribute{{Name: "member", Value: []byte("yes")}},
+		}},
+	}, nil)
+	require.NoError(t, err)
+	inst.Spawn.Args = append(inst.Spawn.Args,
+		byzcoin.Argument{Name: "discountCredential", Value: credID[:]})
+	scs, out, err = cs.Spawn(s, inst, []byzcoin.Coin{coin})
+	require.NoError(t, err)
+	size = uint64(len(scs[0].Value))
+	require.Equal(t, 10000-50-(3*size-3*size/2), out[0].Value)
+
+	otherID, err := s.CreateRandomInstance(ContractCredentialID,
+		&CredentialStruct{}, nil)
+	require.NoError(t, err)
+	inst.Spawn.Args[len(inst.Spawn.Args)-1].Value = otherID[:]
+	_, out, err = cs.Spawn(s, inst, []byzcoin.Coin{coin})
+	require.NoError(t, err)
+	require.Equal(t, 10000-100-3*size, out[0].Value)
+
+	// Contracts without prices cannot be spawned.
+	inst.Spawn.ContractID = ContractRoPaSciID + "x"
+	_, _, err = cs.Spawn(s, inst, []byzcoin.Coin{coin})
+	require.Error(t, err)
+
+	// Older versions ignore the prices.
+	s.Version = byzcoin.VersionCredentialRecover
+	cs, err = spawnSpawner(prices)
+	require.NoError(t, err)
+	require.Nil(t, cs.Prices)
+}
@@ -66,6 +66,39 @@ type SpawnerStruct struct {
 	CostCWrite     *byzcoin.Coin
 	CostCRead      *byzcoin.Coin
 	CostValue      *byzcoin.Coin
+	// Prices are the costs of the contracts that override the costs above,
+	// and the discounts for the holders of some credentials.
+	Prices *SpawnerPrices `protobuf:"opt"`
+}
+
+// SpawnerPrices holds the configurable costs of a spawner.
+type SpawnerPrices struct {
+	// Costs are the costs of the contracts the spawner can spawn.
+	Costs []SpawnerCost
+	// Discounts are the discounts of the holders of some credentials. Only
+	// the biggest discount applies.
+	Discounts []SpawnerDiscount
+}
+
+// SpawnerCost is the cost of spawning an instance of a contract.
+type SpawnerCost struct {
+	ContractID string
+	Cost       byzcoin.Coin
+	// CostPerByte is added to the cost for every byte of the created
+	// instances, in coins of the same name.
+	CostPerByte uint64
+}
+
+// SpawnerDiscount is a discount for the holders of a credential having an
+// attribute.
+type SpawnerDiscount struct {
+	Credential string
+	Attribute  string
+	// Value is the value the attribute must have. If it is empty, any
+	// value is accepted.
+	Value []byte `protobuf:"opt"`
+	// Percent is the part of the cost that is not paid, between 0 and 100.
+	Percent uint32
 }
 
 // PopPartyStruct is the data that is stored in a pop-party instance.
@@ -128,3 +161,31 @@ type Attendees struct {
 type LRSTag struct {
 	Tag []byte
 }
+
+// PopPartyTag is the tag of a linkable ring signature used in a scope of the
+// 'popparty' attribute, and the instruction it has been used for. It is the
+// value of a popPartyTag instance.
+type PopPartyTag struct {
+	Scope string
+	Tag   []byte
+	// Instruction is the hash of the instruction, as returned by
+	// PopPartyMessage.
+	Instruction []byte
+}
+
+// PollStruct represents one poll with answers. It is the value of a poll
+// instance.
+type PollStruct struct {
+	Personhood  byzcoin.InstanceID
+	PollID      []byte `protobuf:"opt"`
+	Title       string
+	Description string
+	Choices     []string
+	Chosen      []PollChoice `protobuf:"opt"`
+}
+
+// PollChoice represents one choice of one participant.
+type PollChoice struct {
+	Choice int
+	LRSTag []byte
+}
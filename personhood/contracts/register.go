@@ -2,6 +2,7 @@ package contracts
 
 import (
 	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/calypso"
 	"go.dedis.ch/onet/v3/log"
 )
 
@@ -14,6 +15,13 @@ func init() {
 		ContractCredentialFromBytes))
 	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractRoPaSciID,
 		ContractRoPaSciFromBytes))
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractPollID,
+		ContractPollFromBytes))
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractPopPartyTagID,
+		ContractPopPartyTagFromBytes))
+	log.ErrFatal(byzcoin.RegisterGlobalAttrInterpreter(AttrPopPartyID,
+		byzcoin.VersionPopPartyAttr, PopPartyAttrInterpreter))
+	calypso.SetCredentialAttribute(CredentialAttribute)
 }
 
 func newArg(name string, val []byte) byzcoin.Argument {
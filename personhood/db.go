@@ -14,13 +14,14 @@ import (
 	"golang.org/x/xerrors"
 )
 
-const dbVersion = 2
+const dbVersion = 3
 
 var storageKey = []byte("storage")
 
 func init() {
 	network.RegisterMessage(&storage1{})
 	network.RegisterMessage(&storage2{})
+	network.RegisterMessage(&storage3{})
 }
 
 // saves all data.
@@ -33,7 +34,7 @@ func (s *Service) save() error {
 // Tries to load the configuration and updates the data in the service
 // if it finds a valid config-file.
 func (s *Service) tryLoad() error {
-	s.storage = &storage2{}
+	s.storage = &storage3{}
 	curVersion, err := s.LoadVersion()
 	if err != nil {
 		return err
@@ -50,15 +51,35 @@ func (s *Service) tryLoad() error {
 		}
 		return s.SaveVersion(dbVersion)
 	case 1:
-		log.Info("Migrating personhood-database from version 1 to 2")
+		log.Info("Migrating personhood-database from version 1 to 3")
 		var s1 storage1
 		err = protobuf.DecodeWithConstructors(buf[16:], &s1,
 			network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return err
+		}
 		s.storage.RoPaSci = s1.RoPaSci
 		s.storage.Parties = s1.Parties
-		s.storage.Polls = s1.Polls
+		s.storage.LegacyPolls = legacyPolls(s1.Polls)
 		return s.SaveVersion(dbVersion)
 	case 2:
+		log.Info("Migrating personhood-database from version 2 to 3")
+		var s2 storage2
+		err = protobuf.DecodeWithConstructors(buf[16:], &s2,
+			network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return err
+		}
+		s.storage.RoPaSci = s2.RoPaSci
+		s.storage.Parties = s2.Parties
+		s.storage.Challenge = s2.Challenge
+		s.storage.AdminDarcIDs = s2.AdminDarcIDs
+		s.storage.LegacyPolls = legacyPolls(s2.Polls)
+		if err = s.save(); err != nil {
+			return err
+		}
+		return s.SaveVersion(dbVersion)
+	case 3:
 		return protobuf.DecodeWithConstructors(buf[16:], s.storage,
 			network.DefaultConstructors(cothority.Suite))
 	default:
@@ -84,6 +105,32 @@ type storage2 struct {
 	sync.Mutex
 }
 
+// storage3 doesn't hold the polls anymore, as they are stored on ByzCoin.
+// The polls kept by the node before are served read-only.
+type storage3 struct {
+	RoPaSci      []*contracts.RoPaSci
+	Parties      map[string]*Party
+	Challenge    map[string]*ChallengeCandidate
+	AdminDarcIDs []darc.ID
+	// LegacyPolls are the polls kept by the node before they were stored on
+	// ByzCoin, indexed by the ByzCoin ID.
+	LegacyPolls map[string]*storagePolls
+
+	sync.Mutex
+}
+
 type storagePolls struct {
-	Polls []*PollStruct
+	Polls []*contracts.PollStruct
+}
+
+// legacyPolls returns the polls of the older versions of the storage,
+// without the ByzCoin IDs that have no polls.
+func legacyPolls(polls map[string]*storagePolls) map[string]*storagePolls {
+	lp := make(map[string]*storagePolls)
+	for bcID, sps := range polls {
+		if sps != nil && len(sps.Polls) > 0 {
+			lp[bcID] = sps
+		}
+	}
+	return lp
 }
@@ -0,0 +1,88 @@
+package personhood
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/personhood/contracts"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestService_PollMigration(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	servers, roster, _ := local.GenTree(3, true)
+	services := local.GetServices(servers, templateID)
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:" + contracts.ContractPollID}, signer.Identity())
+	require.NoError(t, err)
+	genesisMsg.BlockInterval = 500 * time.Millisecond
+	gDarc := &genesisMsg.GenesisDarc
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	inst, err := contracts.NewInstructionPollSpawn(gDarc.GetBaseID(),
+		contracts.PollStruct{
+			Personhood: byzcoin.ConfigInstanceID,
+			Title:      "Lunch",
+			Choices:    []string{"pizza", "salad"},
+		})
+	require.NoError(t, err)
+	inst.SignerCounter = []uint64{1}
+	ctx, err := cl.CreateTransaction(inst)
+	require.NoError(t, err)
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	require.NoError(t, cl.WaitPropagation(-1))
+
+	list := func(s *Service) []contracts.PollStruct {
+		pr, err := s.Poll(&Poll{ByzCoinID: cl.ID, List: &PollList{}})
+		require.NoError(t, err)
+		return pr.Polls
+	}
+
+	// All nodes return the polls of the chain, without having been told
+	// about them.
+	for _, s := range services {
+		polls := list(s.(*Service))
+		require.Len(t, polls, 1)
+		require.Equal(t, "Lunch", polls[0].Title)
+	}
+
+	// The polls kept by a node before they were stored on ByzCoin are
+	// migrated and still listed.
+	s := services[1].(*Service)
+	legacy := &contracts.PollStruct{
+		Personhood: byzcoin.ConfigInstanceID,
+		PollID:     []byte("legacy"),
+		Title:      "Dinner",
+		Choices:    []string{"soup", "fondue"},
+	}
+	require.NoError(t, s.Save(storageKey, &storage2{
+		Polls: map[string]*storagePolls{
+			string(cl.ID): {Polls: []*contracts.PollStruct{legacy}},
+		},
+	}))
+	require.NoError(t, s.SaveVersion(2))
+	require.NoError(t, s.tryLoad())
+	ver, err := s.LoadVersion()
+	require.NoError(t, err)
+	require.Equal(t, dbVersion, ver)
+
+	polls := list(s)
+	require.Len(t, polls, 2)
+	require.Equal(t, "Lunch", polls[0].Title)
+	require.Equal(t, "Dinner", polls[1].Title)
+	require.Len(t, list(services[0].(*Service)), 1)
+
+	// The migrated polls survive a restart of the node.
+	require.NoError(t, s.tryLoad())
+	require.Len(t, list(s), 2)
+}
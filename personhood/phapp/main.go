@@ -3,10 +3,13 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"go.dedis.ch/cothority/v3/personhood/contracts"
@@ -57,6 +60,20 @@ var spawnerFlags = cli.FlagsByName{
 		Usage: "number of coins needed to spawn a rock-paper-scissors game",
 		Value: 0,
 	},
+	cli.StringSliceFlag{
+		Name: "cost",
+		Usage: "cost of any contract as contractID=coins or " +
+			"contractID=coins+coinsPerByte, replacing the costs above",
+	},
+	cli.StringSliceFlag{
+		Name: "discount",
+		Usage: "discount in percent for holders of a credential attribute, " +
+			"as credential/attribute:percent or credential/attribute=hexValue:percent",
+	},
+	cli.BoolFlag{
+		Name:  "clear-prices",
+		Usage: "remove the costs and discounts given with --cost and --discount",
+	},
 }
 
 var cmds = cli.Commands{
@@ -122,6 +139,54 @@ var cmds = cli.Commands{
 		ArgsUsage: "bc-xxx.cfg credentialIID",
 		Action:    show,
 	},
+	{
+		Name:  "credential",
+		Usage: "export and verify W3C verifiable credentials",
+		Subcommands: cli.Commands{
+			{
+				Name:      "export",
+				Usage:     "print the attributes of a credential as a verifiable credential",
+				Action:    credentialExport,
+				ArgsUsage: "bc-xxx.cfg key-xxx.cfg credentialIID credential/attribute ...",
+			},
+			{
+				Name:      "verify",
+				Usage:     "verify a verifiable credential",
+				Action:    credentialVerify,
+				ArgsUsage: "byzCoinID vc.json",
+			},
+			{
+				Name:  "recover",
+				Usage: "recover the darc of a credential with the approvals of trustees",
+				Subcommands: cli.Commands{
+					{
+						Name:      "setup",
+						Usage:     "set the trustees and the threshold of a credential",
+						Action:    recoverSetup,
+						ArgsUsage: "bc-xxx.cfg key-xxx.cfg credentialIID threshold trusteeIID ...",
+					},
+					{
+						Name:      "request",
+						Usage:     "print a request to give the darc of a credential to a new key",
+						Action:    recoverRequest,
+						ArgsUsage: "bc-xxx.cfg credentialIID newPublic",
+					},
+					{
+						Name:      "approve",
+						Usage:     "print the approval of a request by a trustee, offline",
+						Action:    recoverApprove,
+						ArgsUsage: "key-xxx.cfg request",
+					},
+					{
+						Name:      "submit",
+						Usage:     "send a request with the approvals of the trustees",
+						Action:    recoverSubmit,
+						ArgsUsage: "bc-xxx.cfg request approval ...",
+					},
+				},
+			},
+		},
+	},
 }
 
 var cliApp = cli.NewApp()
@@ -181,25 +246,14 @@ func spawnerUpdate(c *cli.Context) error {
 		return err
 	}
 
-	var args byzcoin.Arguments
-	for _, cn := range []string{"Darc", "Coin", "Credential", "Party", "RoPaSci"} {
-		coin := &byzcoin.Coin{
-			Name:  contracts.SpawnerCoin,
-			Value: c.Uint64(strings.ToLower(cn)),
-		}
-		buf, err := protobuf.Encode(coin)
-		if err != nil {
-			return err
-		}
-		args = append(args, byzcoin.Argument{
-			Name:  "cost" + cn,
-			Value: buf,
-		})
+	args, err := spawnerArgs(c)
+	if err != nil {
+		return err
 	}
 	ctx, err := combineInstrsAndSign(cl, *signer, byzcoin.Instruction{
 		InstanceID: byzcoin.NewInstanceID(spIIDBuf),
 		Invoke: &byzcoin.Invoke{
-			ContractID: contracts.ContractCredentialID,
+			ContractID: contracts.ContractSpawnerID,
 			Command:    "update",
 			Args:       args,
 		},
@@ -233,20 +287,9 @@ func spawner(c *cli.Context) error {
 		return err
 	}
 
-	var args byzcoin.Arguments
-	for _, cn := range []string{"Darc", "Coin", "Credential", "Party"} {
-		coin := &byzcoin.Coin{
-			Name:  contracts.SpawnerCoin,
-			Value: c.Uint64(strings.ToLower(cn)),
-		}
-		buf, err := protobuf.Encode(coin)
-		if err != nil {
-			return err
-		}
-		args = append(args, byzcoin.Argument{
-			Name:  "cost" + cn,
-			Value: buf,
-		})
+	args, err := spawnerArgs(c)
+	if err != nil {
+		return err
 	}
 	ctx, err := combineInstrsAndSign(cl, *signer, byzcoin.Instruction{
 		InstanceID: byzcoin.NewInstanceID(cfg.AdminDarc.GetBaseID()),
@@ -272,6 +315,89 @@ func spawner(c *cli.Context) error {
 	return nil
 }
 
+// spawnerArgs returns the arguments of the costs given in the flags. The
+// prices are only given if there are costs or discounts in the flags, or if
+// they are cleared with --clear-prices.
+func spawnerArgs(c *cli.Context) (byzcoin.Arguments, error) {
+	var args byzcoin.Arguments
+	for _, cn := range []string{"Darc", "Coin", "Credential", "Party", "RoPaSci"} {
+		flag := strings.ToLower(cn)
+		if cn == "RoPaSci" {
+			flag = "rps"
+		}
+		coin := &byzcoin.Coin{
+			Name:  contracts.SpawnerCoin,
+			Value: c.Uint64(flag),
+		}
+		buf, err := protobuf.Encode(coin)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, byzcoin.Argument{
+			Name:  "cost" + cn,
+			Value: buf,
+		})
+	}
+
+	var prices contracts.SpawnerPrices
+	for _, cost := range c.StringSlice("cost") {
+		fields := regexp.MustCompile(`^([^=]+)=(\d+)(\+(\d+))?$`).FindStringSubmatch(cost)
+		if fields == nil {
+			return nil, errors.New("cost must be contractID=coins[+coinsPerByte]: " + cost)
+		}
+		sc := contracts.SpawnerCost{
+			ContractID: fields[1],
+			Cost:       byzcoin.Coin{Name: contracts.SpawnerCoin},
+		}
+		var err error
+		if sc.Cost.Value, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+			return nil, err
+		}
+		if fields[4] != "" {
+			if sc.CostPerByte, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		prices.Costs = append(prices.Costs, sc)
+	}
+	for _, discount := range c.StringSlice("discount") {
+		fields := regexp.MustCompile(`^([^/]+)/([^=:]+)(=([0-9a-fA-F]*))?:(\d+)$`).FindStringSubmatch(discount)
+		if fields == nil {
+			return nil, errors.New("discount must be credential/attribute[=hexValue]:percent: " + discount)
+		}
+		sd := contracts.SpawnerDiscount{
+			Credential: fields[1],
+			Attribute:  fields[2],
+		}
+		var err error
+		if sd.Value, err = hex.DecodeString(fields[4]); err != nil {
+			return nil, err
+		}
+		percent, err := strconv.ParseUint(fields[5], 10, 32)
+		if err != nil || percent > 100 {
+			return nil, errors.New("percent must be between 0 and 100: " + discount)
+		}
+		sd.Percent = uint32(percent)
+		prices.Discounts = append(prices.Discounts, sd)
+	}
+	clear := c.Bool("clear-prices")
+	if clear && (len(prices.Costs) > 0 || len(prices.Discounts) > 0) {
+		return nil, errors.New("cannot clear the prices and give costs or discounts")
+	}
+	if clear || len(prices.Costs) > 0 || len(prices.Discounts) > 0 {
+		// Empty prices remove the prices of the spawner.
+		buf, err := protobuf.Encode(&prices)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, byzcoin.Argument{
+			Name:  "prices",
+			Value: buf,
+		})
+	}
+	return args, nil
+}
+
 func wipeParties(c *cli.Context) error {
 	if c.NArg() != 1 {
 		return errors.New("please give the following argument: bc-xxx.cfg")
@@ -529,6 +655,193 @@ func show(c *cli.Context) error {
 	return err
 }
 
+func credentialExport(c *cli.Context) error {
+	if c.NArg() < 4 {
+		return errors.New("please give the following arguments: " +
+			"bc-xxx.cfg key-xxx.cfg credentialIID credential/attribute ...")
+	}
+
+	_, cl, err := lib.LoadConfig(c.Args().First())
+	if err != nil {
+		return err
+	}
+	signer, err := lib.LoadSigner(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	credBuf, err := hex.DecodeString(c.Args().Get(2))
+	if err != nil {
+		return err
+	}
+
+	vc, err := personhood.CredentialExport(cl, byzcoin.NewInstanceID(credBuf),
+		c.Args()[3:], *signer)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(vc, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(buf))
+	return nil
+}
+
+func credentialVerify(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("please give the following arguments: byzCoinID vc.json")
+	}
+
+	bcID, err := hex.DecodeString(c.Args().First())
+	if err != nil {
+		return xerrors.Errorf("couldn't decode byzCoinID: %v", err)
+	}
+	buf, err := ioutil.ReadFile(c.Args().Get(1))
+	if err != nil {
+		return xerrors.Errorf("couldn't read credential: %v", err)
+	}
+	var vc personhood.VerifiableCredential
+	if err = json.Unmarshal(buf, &vc); err != nil {
+		return xerrors.Errorf("couldn't decode credential: %v", err)
+	}
+	if err = vc.Verify(bcID); err != nil {
+		return err
+	}
+
+	log.Infof("Credential %s issued by %s on %s is valid",
+		vc.CredentialSubject.ID, vc.Issuer, vc.IssuanceDate)
+	for _, a := range vc.CredentialSubject.Attributes {
+		log.Infof("\t[%s] %s: %s", a.Credential, a.Name, a.Value)
+	}
+	return nil
+}
+
+func recoverSetup(c *cli.Context) error {
+	if c.NArg() < 5 {
+		return errors.New("please give the following arguments: " +
+			"bc-xxx.cfg key-xxx.cfg credentialIID threshold trusteeIID ...")
+	}
+
+	_, cl, err := lib.LoadConfig(c.Args().First())
+	if err != nil {
+		return err
+	}
+	signer, err := lib.LoadSigner(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	credBuf, err := hex.DecodeString(c.Args().Get(2))
+	if err != nil {
+		return err
+	}
+	threshold, err := strconv.ParseUint(c.Args().Get(3), 10, 32)
+	if err != nil {
+		return xerrors.Errorf("couldn't parse threshold: %v", err)
+	}
+	var trustees []byzcoin.InstanceID
+	for _, arg := range c.Args()[4:] {
+		buf, err := hex.DecodeString(arg)
+		if err != nil || len(buf) != len(byzcoin.InstanceID{}) {
+			return xerrors.Errorf("invalid trustee '%s'", arg)
+		}
+		trustees = append(trustees, byzcoin.NewInstanceID(buf))
+	}
+
+	err = personhood.CredentialRecoverSetup(cl, byzcoin.NewInstanceID(credBuf),
+		uint32(threshold), trustees, *signer)
+	if err != nil {
+		return err
+	}
+	log.Infof("Credential can be recovered by %d of %d trustees",
+		threshold, len(trustees))
+	return nil
+}
+
+func recoverRequest(c *cli.Context) error {
+	if c.NArg() != 3 {
+		return errors.New("please give the following arguments: " +
+			"bc-xxx.cfg credentialIID newPublic")
+	}
+
+	_, cl, err := lib.LoadConfig(c.Args().First())
+	if err != nil {
+		return err
+	}
+	credBuf, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	pubBuf, err := hex.DecodeString(strings.TrimPrefix(c.Args().Get(2), "ed25519:"))
+	if err != nil {
+		return xerrors.Errorf("couldn't decode public key: %v", err)
+	}
+	public := cothority.Suite.Point()
+	if err = public.UnmarshalBinary(pubBuf); err != nil {
+		return xerrors.Errorf("couldn't decode public key: %v", err)
+	}
+
+	request, err := personhood.CredentialRecoverRequest(cl,
+		byzcoin.NewInstanceID(credBuf), public)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%x\n", request)
+	return nil
+}
+
+func recoverApprove(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("please give the following arguments: key-xxx.cfg request")
+	}
+
+	signer, err := lib.LoadSigner(c.Args().First())
+	if err != nil {
+		return err
+	}
+	request, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		return xerrors.Errorf("couldn't decode request: %v", err)
+	}
+
+	approval, err := personhood.CredentialRecoverApprove(request, *signer)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%x\n", approval)
+	return nil
+}
+
+func recoverSubmit(c *cli.Context) error {
+	if c.NArg() < 3 {
+		return errors.New("please give the following arguments: " +
+			"bc-xxx.cfg request approval ...")
+	}
+
+	_, cl, err := lib.LoadConfig(c.Args().First())
+	if err != nil {
+		return err
+	}
+	request, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		return xerrors.Errorf("couldn't decode request: %v", err)
+	}
+	var approvals [][]byte
+	for _, arg := range c.Args()[2:] {
+		approval, err := hex.DecodeString(arg)
+		if err != nil {
+			return xerrors.Errorf("couldn't decode approval: %v", err)
+		}
+		approvals = append(approvals, approval)
+	}
+
+	err = personhood.CredentialRecoverSubmit(cl, request, approvals)
+	if err != nil {
+		return err
+	}
+	log.Info("Darc of the credential has been recovered")
+	return nil
+}
+
 func adminDarcIDsGet(c *cli.Context) error {
 	if c.NArg() != 1 {
 		return errors.New("please give the following argument: public.toml")
@@ -50,6 +50,17 @@ testSpawner(){
   testFileGrep "costCoin to 234" ${COLOG}1.log
   testFileGrep "costCredential to 345" ${COLOG}1.log
   testFileGrep "costParty to 456" ${COLOG}1.log
+  testOK runPH spawner -cost poll=100+2 -discount personhood/member:50 config/bc*cfg config/key*cfg
+  testFileGrep "Setting the prices to 1 costs and 1 discounts" ${COLOG}1.log
+  testFail runPH spawner -cost poll config/bc*cfg config/key*cfg
+  testFail runPH spawner -discount personhood/member:150 config/bc*cfg config/key*cfg
+
+  runGrepSed "Creating Spawner instance" "s/.*instance: //" runPH spawner -cost poll=100 config/bc*cfg config/key*cfg
+  SPAWNER=$SED
+  [ -z "$SPAWNER" ] && fail "didn't get the spawner instance"
+  testFail runPH spawnerUpdate -clear-prices -cost poll=100 config/bc*cfg config/key*cfg $SPAWNER
+  testOK runPH spawnerUpdate -clear-prices config/bc*cfg config/key*cfg $SPAWNER
+  testFileGrep "Clearing the prices" ${COLOG}1.log
 }
 
 testWipe(){
@@ -15,6 +15,7 @@ import (
 // type :darc.ID:bytes
 // type :contracts.RoPaSci:personhood.RoPaSci
 // type :contracts.CredentialStruct:personhood.CredentialStruct
+// type :contracts.PollStruct:personhood.PollStruct
 // package personhood_service;
 //
 // import "onet.proto";
@@ -78,10 +79,15 @@ type StringReply struct {
 	Reply string
 }
 
-// Poll allows for adding, listing, and answering to storagePolls
+// Poll is a view of the poll instances of a ByzCoin chain. The polls are
+// created, answered and deleted by transactions to the poll contract. NewPoll
+// returns the poll instance given by its PollID, and List returns the polls
+// of all poll instances of the chain, as well as the polls the node kept
+// before they were stored on the chain. Answer and Delete are not supported
+// anymore.
 type Poll struct {
 	ByzCoinID skipchain.SkipBlockID
-	NewPoll   *PollStruct
+	NewPoll   *contracts.PollStruct
 	List      *PollList
 	Answer    *PollAnswer
 	Delete    *PollDelete
@@ -90,22 +96,21 @@ type Poll struct {
 // PollDelete has the poll to be deleted, and the signature proving that
 // the client has the right to do so.
 // The signature is a Schnorr signature on the PollID.
+// Polls are now deleted by a transaction to the poll instance.
 type PollDelete struct {
 	Identity  darc.Identity
 	PollID    []byte
 	Signature []byte
 }
 
-// PollList returns all known storagePolls for this byzcoinID
+// PollList returns all known polls for this byzcoinID
 type PollList struct {
 	PartyIDs []byzcoin.InstanceID
 }
 
 // PollAnswer stores one answer for a poll. It needs to be signed with a Linkable Ring Signature
-// to proof that the choice is unique. The context for the LRS must be
-//   'Poll' + ByzCoinID + PollID
-// And the message must be
-//   'Choice' + byte(Choice)
+// to proof that the choice is unique.
+// Answers are now 'answer' instructions sent to the poll instance.
 type PollAnswer struct {
 	PollID  []byte
 	Choice  int
@@ -113,27 +118,10 @@ type PollAnswer struct {
 	PartyID byzcoin.InstanceID `protobuf:"opt"`
 }
 
-// PollStruct represents one poll with answers.
-type PollStruct struct {
-	Personhood  byzcoin.InstanceID
-	PollID      []byte `protobuf:"opt"`
-	Title       string
-	Description string
-	Choices     []string
-	Chosen      []PollChoice `protobuf:"opt"`
-}
-
-// PollChoice represents one choice of one participant.
-type PollChoice struct {
-	Choice int
-	LRSTag []byte
-}
-
-// PollResponse is sent back to the client and contains all storagePolls known that
-// still have a reward left. It also returns the coinIID of the pollservice
-// itself.
+// PollResponse is sent back to the client and contains the polls as they
+// are stored on the chain.
 type PollResponse struct {
-	Polls []PollStruct
+	Polls []contracts.PollStruct
 }
 
 // Capabilities returns what the service is able to do.
@@ -6,8 +6,6 @@ runs on the node.
 */
 
 import (
-	"bytes"
-	"crypto/sha256"
 	"errors"
 	"sort"
 	"time"
@@ -18,10 +16,10 @@ import (
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/skipchain"
-	"go.dedis.ch/kyber/v3/sign/anon"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
 	"golang.org/x/xerrors"
 )
 
@@ -50,7 +48,7 @@ type Service struct {
 	// meetups is a list of last users calling.
 	meetups []UserLocation
 
-	storage *storage2
+	storage *storage3
 }
 
 // Capabilities returns the version of endpoints this conode offers:
@@ -70,7 +68,7 @@ func (s *Service) Capabilities(rq *Capabilities) (*CapabilitiesResponse, error)
 			},
 			{
 				Endpoint: "poll",
-				Version:  [3]byte{0, 0, 1},
+				Version:  [3]byte{1, 0, 0},
 			},
 			{
 				Endpoint: "ropascilist",
@@ -116,35 +114,29 @@ func (s *Service) Meetup(rq *Meetup) (*MeetupResponse, error) {
 	return reply, nil
 }
 
-// Poll handles anonymous, troll-resistant polling.
+// Poll is a read-through view of the anonymous, troll-resistant polls stored
+// in poll instances. The list is read from the state of the chain, so all
+// nodes return the same polls.
 func (s *Service) Poll(rq *Poll) (*PollResponse, error) {
-	sps := s.storage.Polls[string(rq.ByzCoinID)]
-	if sps == nil {
-		s.storage.Polls[string(rq.ByzCoinID)] = &storagePolls{}
-		return s.Poll(rq)
-	}
 	log.Lvlf2("%s: Getting %+v", s.ServerIdentity(), rq)
 	switch {
 	case rq.NewPoll != nil:
-		np := PollStruct{
-			Title:       rq.NewPoll.Title,
-			Description: rq.NewPoll.Description,
-			Choices:     rq.NewPoll.Choices,
-			Personhood:  rq.NewPoll.Personhood,
-			PollID:      rq.NewPoll.PollID,
+		id := byzcoin.NewInstanceID(rq.NewPoll.PollID)
+		poll, err := s.getPoll(rq.ByzCoinID, id)
+		if err != nil {
+			return nil, err
 		}
-		if !np.Personhood.Equal(byzcoin.ConfigInstanceID) {
-			_, err := s.getPopContract(rq.ByzCoinID, np.Personhood.Slice())
-			if err != nil {
-				return nil, err
-			}
+		if poll == nil {
+			return nil, errors.New("didn't find that poll")
 		}
-		//np.PollID = random.Bits(256, true, random.New())
-		sps.Polls = append(sps.Polls, &np)
-		return &PollResponse{Polls: []PollStruct{np}}, s.save()
+		return &PollResponse{Polls: []contracts.PollStruct{*poll}}, nil
 	case rq.List != nil:
-		pr := &PollResponse{Polls: []PollStruct{}}
-		for _, p := range sps.Polls {
+		polls, err := s.listPolls(rq.ByzCoinID)
+		if err != nil {
+			return nil, err
+		}
+		pr := &PollResponse{Polls: []contracts.PollStruct{}}
+		for _, p := range polls {
 			member := p.Personhood.Equal(byzcoin.ConfigInstanceID)
 			if !member {
 				for _, id := range rq.List.PartyIDs {
@@ -155,103 +147,87 @@ func (s *Service) Poll(rq *Poll) (*PollResponse, error) {
 				}
 			}
 			if member {
-				pr.Polls = append(pr.Polls, *p)
+				pr.Polls = append(pr.Polls, p)
 			}
 		}
-		return pr, s.save()
+		return pr, nil
 	case rq.Answer != nil:
-		var poll *PollStruct
-		for _, p := range sps.Polls {
-			if bytes.Compare(p.PollID, rq.Answer.PollID) == 0 {
-				poll = p
-				break
-			}
-		}
-		if poll == nil {
-			return nil, errors.New("didn't find that poll")
-		}
-		if rq.Answer.Choice < 0 ||
-			rq.Answer.Choice >= len(poll.Choices) {
-			return nil, errors.New("this choice doesn't exist")
-		}
+		return nil, errors.New("polls are answered by an 'answer' instruction to the poll instance")
+	case rq.Delete != nil:
+		return nil, errors.New("polls are deleted by a transaction to the poll instance")
+	default:
+		// Only the polls kept by the node before they were stored on
+		// ByzCoin can be wiped.
+		s.storage.Lock()
+		delete(s.storage.LegacyPolls, string(rq.ByzCoinID))
+		s.storage.Unlock()
+		return &PollResponse{Polls: []contracts.PollStruct{}}, s.save()
+	}
+}
 
-		msg := append([]byte("Choice"), byte(rq.Answer.Choice))
-		scope := append([]byte("Poll"), append(rq.ByzCoinID, poll.PollID...)...)
-		scopeHash := sha256.Sum256(scope)
-		var ph *contracts.ContractPopParty
-		var err error
-		if poll.Personhood.Equal(byzcoin.ConfigInstanceID) {
-			ph, err = s.getPopContract(rq.ByzCoinID, rq.Answer.PartyID.Slice())
-		} else {
-			ph, err = s.getPopContract(rq.ByzCoinID, poll.Personhood.Slice())
-		}
-		if err != nil {
-			log.Error(err)
-			return nil, err
-		}
-		tag, err := anon.Verify(&contracts.SuiteBlake2s{}, msg,
-			ph.Attendees.Keys, scopeHash[:], rq.Answer.LRS)
-		if err != nil {
-			log.Error(err)
-			return nil, err
-		}
-		var update bool
-		for i, c := range poll.Chosen {
-			if bytes.Compare(c.LRSTag, tag) == 0 {
-				log.Lvl2("Updating choice", i)
-				poll.Chosen[i].Choice = rq.Answer.Choice
-				update = true
-				break
-			}
-		}
-		if !update {
-			poll.Chosen = append(poll.Chosen, PollChoice{Choice: rq.Answer.Choice, LRSTag: tag})
+// listPolls returns the polls of all poll instances of the chain, followed
+// by the polls kept by this node before they were stored on ByzCoin.
+func (s *Service) listPolls(bcID skipchain.SkipBlockID) ([]contracts.PollStruct, error) {
+	st, err := s.byzcoinService().GetReadOnlyStateTrie(bcID)
+	if err != nil {
+		return nil, err
+	}
+	var polls []contracts.PollStruct
+	err = st.ForEach(func(k, v []byte) error {
+		var body byzcoin.StateChangeBody
+		if err := protobuf.Decode(v, &body); err != nil {
+			return err
 		}
-		return &PollResponse{Polls: []PollStruct{*poll}}, s.save()
-	case rq.Delete != nil:
-		ok, err := s.verifySignature(rq.ByzCoinID, rq.Delete.Identity, rq.Delete.PollID, rq.Delete.Signature)
-		if err != nil {
-			return nil, err
+		if body.ContractID != contracts.ContractPollID {
+			return nil
 		}
-		if !ok {
-			return nil, errors.New("user is not allowed to do admin things")
+		var poll contracts.PollStruct
+		if err := protobuf.Decode(body.Value, &poll); err != nil {
+			return errors.New("couldn't decode poll: " + err.Error())
 		}
-		for bcID, polls := range s.storage.Polls {
-			if bcID == string(rq.ByzCoinID) {
-				for i, poll := range polls.Polls {
-					if bytes.Compare(poll.PollID, rq.Delete.PollID) == 0 {
-						polls.Polls = append(polls.Polls[0:i], polls.Polls[i+1:]...)
-						break
-					}
-				}
-				return &PollResponse{Polls: []PollStruct{}}, s.save()
-			}
+		polls = append(polls, poll)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	if sps := s.storage.LegacyPolls[string(bcID)]; sps != nil {
+		for _, p := range sps.Polls {
+			polls = append(polls, *p)
 		}
-		return nil, errors.New("didn't find poll to delete")
-	default:
-		s.storage.Polls[string(rq.ByzCoinID)] = &storagePolls{Polls: []*PollStruct{}}
-		return &PollResponse{Polls: []PollStruct{}}, s.save()
 	}
+	return polls, nil
 }
 
-func (s *Service) getPopContract(bcID skipchain.SkipBlockID, phIID []byte) (*contracts.ContractPopParty, error) {
-	gpr, err := s.Service(byzcoin.ServiceName).(*byzcoin.Service).GetProof(&byzcoin.GetProof{
+// getPoll returns the poll stored in the given instance, or nil if the
+// instance doesn't exist.
+func (s *Service) getPoll(bcID skipchain.SkipBlockID, id byzcoin.InstanceID) (*contracts.PollStruct, error) {
+	gpr, err := s.byzcoinService().GetProof(&byzcoin.GetProof{
 		Version: byzcoin.CurrentVersion,
-		Key:     phIID,
+		Key:     id.Slice(),
 		ID:      bcID,
 	})
 	if err != nil {
 		return nil, err
 	}
-	val, cid, _, err := gpr.Proof.Get(phIID)
+	if !gpr.Proof.InclusionProof.Match(id.Slice()) {
+		return nil, nil
+	}
+	val, cid, _, err := gpr.Proof.Get(id.Slice())
 	if err != nil {
 		return nil, err
 	}
-	if cid != contracts.ContractPopPartyID {
-		return nil, errors.New("this is not a personhood contract")
+	if cid != contracts.ContractPollID {
+		return nil, errors.New("this is not a poll contract")
+	}
+	var poll contracts.PollStruct
+	if err = protobuf.Decode(val, &poll); err != nil {
+		return nil, errors.New("couldn't decode poll: " + err.Error())
 	}
-	cpop, err := s.byzcoinService().GetContractInstance(contracts.ContractPopPartyID, val)
-	return cpop.(*contracts.ContractPopParty), err
+	return &poll, nil
 }
 
 // RoPaSciList can either store a new rock-paper-scissors in the list, or just return the list of
@@ -486,8 +462,8 @@ func newService(c *onet.Context) (onet.Service, error) {
 	if len(s.storage.Parties) == 0 {
 		s.storage.Parties = make(map[string]*Party)
 	}
-	if len(s.storage.Polls) == 0 {
-		s.storage.Polls = make(map[string]*storagePolls)
+	if len(s.storage.LegacyPolls) == 0 {
+		s.storage.LegacyPolls = make(map[string]*storagePolls)
 	}
 	if len(s.storage.Challenge) == 0 {
 		s.storage.Challenge = make(map[string]*ChallengeCandidate)
@@ -29,6 +29,7 @@ import (
 	"go.dedis.ch/onet/v3/cfgpath"
 	"go.dedis.ch/onet/v3/log"
 	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
 	"go.etcd.io/bbolt"
 )
 
@@ -496,9 +497,127 @@ func scOptimize(c *cli.Context) error {
 	}
 
 	log.Infof("Chain optimized with %d blocks", len(reply.Proof))
+
+	cp, err := skipchain.NewCompactProof(reply.Proof)
+	if err != nil {
+		return fmt.Errorf("couldn't create the compact proof: %v", err)
+	}
+	full, err := protobuf.Encode(reply)
+	if err != nil {
+		return fmt.Errorf("couldn't encode the proof: %v", err)
+	}
+	compact, err := protobuf.Encode(&skipchain.GetCompactProofReply{Proof: cp})
+	if err != nil {
+		return fmt.Errorf("couldn't encode the compact proof: %v", err)
+	}
+	log.Infof("Proof size: %d bytes, compact proof size: %d bytes (%.1f%% saved)",
+		len(full), len(compact),
+		100*(1-float64(len(compact))/float64(len(full))))
 	return nil
 }
 
+// scForks asks the conodes for the evidences of forks of a skipchain.
+func scForks(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return errors.New("please give the group-file and the skipchain-id")
+	}
+	group := readGroupArgs(c, 0)
+	scID, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		return errors.New("couldn't decode skipchain-id: " + err.Error())
+	}
+
+	evidences, err := skipchain.NewClient().GetForkEvidence(group.Roster, scID)
+	if err != nil {
+		return fmt.Errorf("couldn't get evidences: %v", err)
+	}
+	if len(evidences) == 0 {
+		log.Info("No fork found")
+		return nil
+	}
+	for _, fe := range evidences {
+		fmt.Fprintf(c.App.Writer, "Fork after block %d / %x:\n"+
+			"  block %d / %x\n  block %d / %x\n", fe.From.Index, fe.From.Hash,
+			fe.Target1.Index, fe.Target1.Hash, fe.Target2.Index, fe.Target2.Hash)
+	}
+	return errors.New("found forks in the skipchain")
+}
+
+// scExport fetches all blocks of a skipchain from the conodes and writes
+// them to an archive.
+func scExport(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return errors.New("please give the group-file and the skipchain-id")
+	}
+	if c.String("out") == "" {
+		return errors.New("please give the archive file with --out")
+	}
+	group := readGroupArgs(c, 0)
+	scID, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		return errors.New("couldn't decode skipchain-id: " + err.Error())
+	}
+
+	cfg := getConfigOrFail(c)
+	cl := skipchain.NewClient()
+	from := skipchain.SkipBlockID(scID)
+	if sb := cfg.Db.GetByID(from); sb != nil {
+		latest, err := cfg.Db.GetLatest(sb)
+		if err != nil {
+			return fmt.Errorf("couldn't get latest block: %v", err)
+		}
+		from = latest.Hash
+	}
+	for {
+		blocks, err := cl.GetUpdateChainLevel(group.Roster, from, 1, 100)
+		if err != nil {
+			return fmt.Errorf("couldn't get blocks: %v", err)
+		}
+		if _, err := cfg.Db.StoreBlocks(blocks); err != nil {
+			return fmt.Errorf("couldn't store blocks: %v", err)
+		}
+		last := blocks[len(blocks)-1]
+		log.Infof("Got blocks up to index %d", last.Index)
+		if len(last.ForwardLink) == 0 || last.Hash.Equal(from) {
+			break
+		}
+		from = last.Hash
+	}
+
+	f, err := os.Create(c.String("out"))
+	if err != nil {
+		return fmt.Errorf("couldn't create archive: %v", err)
+	}
+	defer f.Close()
+	n, err := cfg.Db.Export(f, scID, c.Int("start"), c.Int("end"))
+	if err != nil {
+		return fmt.Errorf("couldn't export skipchain: %v", err)
+	}
+	log.Infof("Exported %d blocks", n)
+	return cfg.save(c)
+}
+
+// scImport verifies an archive and stores its blocks in the local cache.
+func scImport(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return errors.New("please give the archive")
+	}
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("couldn't open archive: %v", err)
+	}
+	defer f.Close()
+
+	cfg := getConfigOrFail(c)
+	header, n, err := cfg.Db.Import(f)
+	if err != nil {
+		return fmt.Errorf("couldn't import archive: %v", err)
+	}
+	log.Infof("Imported %d blocks of skipchain %x, up to index %d", n,
+		header.SkipChainID, header.End)
+	return cfg.save(c)
+}
+
 // Joins a given skipchain
 func dnsFetch(c *cli.Context) error {
 	if c.NArg() != 2 {
@@ -172,6 +172,42 @@ func getCommands() cli.Commands {
 						},
 					},
 				},
+				{
+					Name:      "forks",
+					Usage:     "show the evidences of forks known to the conodes",
+					Aliases:   []string{"f"},
+					ArgsUsage: groupsDef + " skipchain-id",
+					Action:    scForks,
+				},
+				{
+					Name:      "export",
+					Usage:     "fetch a skipchain and write it to a verifiable archive",
+					Aliases:   []string{"e"},
+					ArgsUsage: groupsDef + " skipchain-id",
+					Action:    scExport,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "out, o",
+							Usage: "file to write the archive to",
+						},
+						cli.IntFlag{
+							Name:  "start",
+							Usage: "index of the first block to export",
+						},
+						cli.IntFlag{
+							Name:  "end",
+							Value: -1,
+							Usage: "index of the last block to export (default: latest block)",
+						},
+					},
+				},
+				{
+					Name:      "import",
+					Usage:     "verify an archive and store its blocks in the local cache",
+					Aliases:   []string{"i"},
+					ArgsUsage: "archive",
+					Action:    scImport,
+				},
 				{
 					Name:    "optimize",
 					Usage:   "create missing forward link to optimize the proof of a given block",
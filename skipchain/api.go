@@ -247,9 +247,10 @@ func (c *Client) OptimizeProof(ro *onet.Roster, id SkipBlockID) (*OptimizeProofR
 // the most current SkipBlock of the chain. It takes a roster that knows the
 // 'latest' skipblock and the id (=hash) of the latest skipblock.
 // The returned list of blocks is linked using the highest level links available
-// to shorten the returned chain.
+// to shorten the returned chain. As it is used to find the latest block, the
+// returned blocks can have their payloads pruned.
 func (c *Client) GetUpdateChain(roster *onet.Roster, latest SkipBlockID) (reply *GetUpdateChainReply, err error) {
-	update, err := c.GetUpdateChainLevel(roster, latest, -1, -1)
+	update, err := c.getUpdateChainLevel(roster, latest, -1, -1, true)
 	if err != nil {
 		return nil, err
 	}
@@ -264,9 +265,17 @@ func (c *Client) GetUpdateChain(roster *onet.Roster, latest SkipBlockID) (reply
 //   - maxLevel: what maximum height to use. -1 means the highest height available.
 //     0 means only direct forward links, n means level-n forwardlinks.
 //   - maxBlocks: how many blocks to return at maximum.
+//
+// If the payload of one of the blocks has been pruned, an error is returned.
 func (c *Client) GetUpdateChainLevel(initRoster *onet.Roster,
 	latest SkipBlockID, maxLevel int,
 	maxBlocks int) (update []*SkipBlock, err error) {
+	return c.getUpdateChainLevel(initRoster, latest, maxLevel, maxBlocks, false)
+}
+
+func (c *Client) getUpdateChainLevel(initRoster *onet.Roster,
+	latest SkipBlockID, maxLevel int,
+	maxBlocks int, allowPruned bool) (update []*SkipBlock, err error) {
 	roster := initRoster
 	for {
 		r2 := &GetUpdateChainReply{}
@@ -279,9 +288,10 @@ func (c *Client) GetUpdateChainLevel(initRoster *onet.Roster,
 			}
 		}
 		node, err := c.SendProtobufParallel(roster.List, &GetUpdateChain{
-			LatestID:  latest,
-			MaxHeight: maxLevel,
-			MaxBlocks: mb,
+			LatestID:    latest,
+			MaxHeight:   maxLevel,
+			MaxBlocks:   mb,
+			AllowPruned: allowPruned,
 		}, r2, c.options)
 		if err != nil {
 			same, err := roster.Equal(initRoster)
@@ -421,6 +431,39 @@ func (c *Client) GetSingleBlock(roster *onet.Roster, id SkipBlockID) (*SkipBlock
 	return reply, nil
 }
 
+// GetForkEvidence asks all nodes of the roster for the evidences of forks
+// of the skipchain. Only the evidences that verify are returned, and an
+// error is returned only if no node replied.
+func (c *Client) GetForkEvidence(roster *onet.Roster, scID SkipBlockID) ([]*ForkEvidence, error) {
+	var evidences []*ForkEvidence
+	seen := make(map[string]bool)
+	replies := 0
+	for _, si := range roster.List {
+		reply := &GetForkEvidenceReply{}
+		err := c.SendProtobuf(si, &GetForkEvidence{scID}, reply)
+		if err != nil {
+			log.Warnf("Couldn't get evidences from %s: %v", si, err)
+			continue
+		}
+		replies++
+		for _, fe := range reply.Evidences {
+			if err := fe.Verify(); err != nil {
+				log.Warn("Got invalid fork evidence:", err)
+				continue
+			}
+			if !fe.From.SkipChainID().Equal(scID) || seen[string(fe.ID())] {
+				continue
+			}
+			seen[string(fe.ID())] = true
+			evidences = append(evidences, fe)
+		}
+	}
+	if replies == 0 {
+		return nil, errors.New("all nodes failed to return evidences")
+	}
+	return evidences, nil
+}
+
 // GetSingleBlockByIndex searches for a block with the given index following the genesis-block.
 // It returns that block, or an error if that block is not found.
 func (c *Client) GetSingleBlockByIndex(roster *onet.Roster, genesis SkipBlockID, index int) (reply *GetSingleBlockByIndexReply, err error) {
@@ -0,0 +1,261 @@
+package skipchain
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
+)
+
+// archiveMagic starts every archive, so that it can be recognized.
+var archiveMagic = []byte("skipchain-archive")
+
+// ArchiveVersion is the version of the archive format written by Export.
+const ArchiveVersion = 1
+
+// maxArchiveEntry protects the import from allocating too much memory with
+// a corrupted archive.
+const maxArchiveEntry = 1 << 28
+
+// importBatch is the number of blocks stored in a single transaction during
+// the import.
+const importBatch = 100
+
+// ArchiveHeader describes the content of an archive. It is followed by
+// ProofLength blocks, going from the genesis block to the block at index
+// Start using the forward-links, and then by all the blocks from
+// Start+1 up to End.
+type ArchiveHeader struct {
+	Version     int
+	SkipChainID SkipBlockID
+	Start       int
+	End         int
+	ProofLength int
+}
+
+func init() {
+	network.RegisterMessages(&ArchiveHeader{})
+}
+
+// Export writes the blocks of the skipchain scID from index start to index
+// end to w. If end is negative, all blocks up to the latest one are
+// exported. The archive is self-contained: it holds the proof from the
+// genesis block to the block at index start, so that it can be imported in
+// an empty database. It returns the number of blocks written.
+func (db *SkipBlockDB) Export(w io.Writer, scID SkipBlockID, start, end int) (int, error) {
+	latest, err := db.GetLatestByID(scID)
+	if err != nil {
+		return 0, xerrors.Errorf("couldn't get latest block: %v", err)
+	}
+	if end < 0 || end > latest.Index {
+		end = latest.Index
+	}
+	if start < 0 || start > end {
+		return 0, xerrors.Errorf("invalid range %d..%d", start, end)
+	}
+
+	// Search the block at index start by following the level-0 links.
+	sb := db.GetByID(scID)
+	for sb != nil && sb.Index < start {
+		if len(sb.ForwardLink) == 0 {
+			return 0, xerrors.Errorf("missing forward-link in block %d",
+				sb.Index)
+		}
+		sb = db.GetByID(sb.ForwardLink[0].To)
+	}
+	if sb == nil {
+		return 0, xerrors.Errorf("couldn't find block %d", start)
+	}
+	proof, err := db.GetProofForID(sb.Hash)
+	if err != nil {
+		return 0, xerrors.Errorf("couldn't get proof: %v", err)
+	}
+
+	if _, err := w.Write(archiveMagic); err != nil {
+		return 0, xerrors.Errorf("writing archive: %v", err)
+	}
+	err = writeArchiveEntry(w, &ArchiveHeader{
+		Version:     ArchiveVersion,
+		SkipChainID: scID,
+		Start:       start,
+		End:         end,
+		ProofLength: len(proof),
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("writing header: %v", err)
+	}
+	written := 0
+	for _, p := range proof {
+		if err := writeArchiveEntry(w, p); err != nil {
+			return written, xerrors.Errorf("writing proof: %v", err)
+		}
+		written++
+	}
+	for sb.Index < end {
+		if len(sb.ForwardLink) == 0 {
+			return written, xerrors.Errorf("missing forward-link in block %d",
+				sb.Index)
+		}
+		sb = db.GetByID(sb.ForwardLink[0].To)
+		if sb == nil {
+			return written, xerrors.New("couldn't find next block")
+		}
+		if err := writeArchiveEntry(w, sb); err != nil {
+			return written, xerrors.Errorf("writing block %d: %v", sb.Index, err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// Import reads an archive written by Export and stores its blocks in the
+// database. The hash and the forward-links of every block are verified,
+// and VerifyLinks is called on every stored block. It returns the header of
+// the archive and the number of blocks read.
+func (db *SkipBlockDB) Import(r io.Reader) (*ArchiveHeader, int, error) {
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, 0, xerrors.Errorf("reading archive: %v", err)
+	}
+	if string(magic) != string(archiveMagic) {
+		return nil, 0, errors.New("not a skipchain archive")
+	}
+	msg, err := readArchiveEntry(r)
+	if err != nil {
+		return nil, 0, xerrors.Errorf("reading header: %v", err)
+	}
+	header, ok := msg.(*ArchiveHeader)
+	if !ok {
+		return nil, 0, errors.New("archive doesn't start with a header")
+	}
+	if header.Version != ArchiveVersion {
+		return nil, 0, xerrors.Errorf("unknown archive version %d",
+			header.Version)
+	}
+	if header.ProofLength < 1 || header.Start < 0 ||
+		header.End < header.Start {
+		return nil, 0, errors.New("invalid archive header")
+	}
+
+	read := 0
+	readBlock := func() (*SkipBlock, error) {
+		msg, err := readArchiveEntry(r)
+		if err != nil {
+			return nil, xerrors.Errorf("reading block: %v", err)
+		}
+		sb, ok := msg.(*SkipBlock)
+		if !ok {
+			return nil, errors.New("archive entry is not a block")
+		}
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return nil, xerrors.Errorf("wrong hash for block %d", sb.Index)
+		}
+		read++
+		return sb, nil
+	}
+
+	var proof Proof
+	for i := 0; i < header.ProofLength; i++ {
+		sb, err := readBlock()
+		if err != nil {
+			return header, read, err
+		}
+		proof = append(proof, sb)
+	}
+	if err := proof.verifyPath(); err != nil {
+		return header, read, xerrors.Errorf("invalid proof: %v", err)
+	}
+	if !proof[0].Hash.Equal(header.SkipChainID) {
+		return header, read, errors.New("proof doesn't start at the genesis block")
+	}
+	prev := proof[len(proof)-1]
+	if prev.Index != header.Start {
+		return header, read, errors.New("proof doesn't end at the start block")
+	}
+	if err := db.storeVerified(proof); err != nil {
+		return header, read, err
+	}
+
+	batch := make([]*SkipBlock, 0, importBatch)
+	for prev.Index < header.End {
+		sb, err := readBlock()
+		if err != nil {
+			return header, read, err
+		}
+		if sb.Index != prev.Index+1 || len(sb.BackLinkIDs) == 0 ||
+			!sb.BackLinkIDs[0].Equal(prev.Hash) {
+			return header, read, xerrors.Errorf("block %d doesn't follow "+
+				"block %d", sb.Index, prev.Index)
+		}
+		if len(prev.ForwardLink) == 0 || !prev.ForwardLink[0].To.Equal(sb.Hash) {
+			return header, read, xerrors.Errorf("missing forward-link "+
+				"from block %d", prev.Index)
+		}
+		batch = append(batch, sb)
+		if len(batch) == importBatch {
+			if err := db.storeVerified(batch); err != nil {
+				return header, read, err
+			}
+			batch = batch[:0]
+		}
+		prev = sb
+	}
+	if err := db.storeVerified(batch); err != nil {
+		return header, read, err
+	}
+	return header, read, nil
+}
+
+// storeVerified stores the blocks, which verifies the signatures of the
+// forward-links, and then checks the links of every block.
+func (db *SkipBlockDB) storeVerified(blocks []*SkipBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	if _, err := db.StoreBlocks(blocks); err != nil {
+		return xerrors.Errorf("couldn't store blocks: %v", err)
+	}
+	for _, sb := range blocks {
+		stored := db.GetByID(sb.Hash)
+		if stored == nil {
+			return xerrors.Errorf("block %d has not been stored", sb.Index)
+		}
+		if err := db.VerifyLinks(stored); err != nil {
+			return xerrors.Errorf("wrong links in block %d: %v", sb.Index, err)
+		}
+	}
+	return nil
+}
+
+func writeArchiveEntry(w io.Writer, msg network.Message) error {
+	buf, err := network.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	l := make([]byte, 4)
+	binary.LittleEndian.PutUint32(l, uint32(len(buf)))
+	if _, err := w.Write(l); err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func readArchiveEntry(r io.Reader) (network.Message, error) {
+	l := make([]byte, 4)
+	if _, err := io.ReadFull(r, l); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(l)
+	if length > maxArchiveEntry {
+		return nil, errors.New("entry too big")
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	_, msg, err := network.Unmarshal(buf, suite)
+	return msg, err
+}
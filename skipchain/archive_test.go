@@ -0,0 +1,82 @@
+package skipchain
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestSkipBlockDB_ExportImport(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, ro, s := local.MakeSRS(cothority.Suite, 3, skipchainSID)
+	service := s.(*Service)
+
+	genesis, err := makeGenesisRosterArgs(service, ro, nil, VerificationNone, 2, 3)
+	require.NoError(t, err)
+	blocks := []*SkipBlock{genesis}
+	for i := 0; i < 10; i++ {
+		sb := NewSkipBlock()
+		sb.Roster = ro
+		sb.Payload = []byte("payload")
+		latest, err := addBlockToChain(service, genesis.Hash, sb)
+		require.NoError(t, err)
+		blocks = append(blocks, latest)
+	}
+
+	// Export the whole chain.
+	var buf bytes.Buffer
+	n, err := service.db.Export(&buf, genesis.Hash, 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	header, n, err := db.Import(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+	require.Equal(t, 10, header.End)
+	for _, sb := range blocks {
+		stored := db.GetByID(sb.Hash)
+		require.NotNil(t, stored)
+		require.Equal(t, service.db.GetByID(sb.Hash).Payload, stored.Payload)
+	}
+	latest, err := db.GetLatestByID(genesis.Hash)
+	require.NoError(t, err)
+	require.Equal(t, 10, latest.Index)
+
+	// Importing twice doesn't change anything.
+	_, _, err = db.Import(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// Export a range in an empty database.
+	buf.Reset()
+	_, err = service.db.Export(&buf, genesis.Hash, 5, 8)
+	require.NoError(t, err)
+	db2, file2 := setupSkipBlockDB(t)
+	defer os.Remove(file2)
+	_, _, err = db2.Import(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	for _, sb := range blocks[5:9] {
+		require.NotNil(t, db2.GetByID(sb.Hash))
+	}
+	require.Nil(t, db2.GetByID(blocks[9].Hash))
+
+	// Truncated and modified archives are refused.
+	db3, file3 := setupSkipBlockDB(t)
+	defer os.Remove(file3)
+	_, _, err = db3.Import(bytes.NewReader(buf.Bytes()[:buf.Len()-10]))
+	require.Error(t, err)
+	// The last occurrence of the ID of block 7 is the back-link of block 8.
+	tampered := append([]byte{}, buf.Bytes()...)
+	tampered[bytes.LastIndex(tampered, blocks[7].Hash)] ^= 1
+	_, _, err = db3.Import(bytes.NewReader(tampered))
+	require.Error(t, err)
+	_, _, err = db3.Import(bytes.NewReader([]byte("not an archive")))
+	require.Error(t, err)
+}
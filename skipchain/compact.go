@@ -0,0 +1,202 @@
+package skipchain
+
+import (
+	"errors"
+
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
+)
+
+// CompactBlock is the part of a block that is needed in a CompactProof: the
+// fixed part that is hashed, without the payload, and the single
+// forward-link leading to the next block of the proof.
+type CompactBlock struct {
+	*SkipBlockFix
+	// SignatureScheme is needed to calculate the hash and to verify the
+	// forward-link.
+	SignatureScheme uint32
+	// Link points to the next block of the proof. It is nil for the last
+	// block.
+	Link *ForwardLink `protobuf:"opt"`
+}
+
+// CompactProof is a proof of membership of a block in a skipchain that only
+// holds the headers and the forward-links of the blocks on the shortest
+// path from the genesis block to the target block. It can be verified
+// without the payloads of the blocks.
+type CompactProof []*CompactBlock
+
+// GetCompactProof requests a compact proof from the block Trusted to the
+// block ID.
+type GetCompactProof struct {
+	// Trusted is the block the proof starts from: the genesis block or a
+	// block the client verified before.
+	Trusted SkipBlockID
+	// ID is the block the proof goes to. If it is nil, the proof goes to the
+	// latest block known by the node.
+	ID SkipBlockID `protobuf:"opt"`
+}
+
+// GetCompactProofReply returns the compact proof.
+type GetCompactProofReply struct {
+	Proof CompactProof
+}
+
+func init() {
+	network.RegisterMessages(&GetCompactProof{}, &GetCompactProofReply{})
+}
+
+// Hash returns the hash of the block, which is the same as the hash of the
+// full block.
+func (cb *CompactBlock) Hash() SkipBlockID {
+	sb := &SkipBlock{
+		SkipBlockFix:    cb.SkipBlockFix,
+		SignatureScheme: cb.SignatureScheme,
+	}
+	return sb.CalculateHash()
+}
+
+// NewCompactProof converts a proof, where every block must be linked to the
+// next one by one of its forward-links, into a compact proof.
+func NewCompactProof(sbs Proof) (CompactProof, error) {
+	if len(sbs) == 0 {
+		return nil, errors.New("empty proof")
+	}
+	cp := make(CompactProof, len(sbs))
+	for i, sb := range sbs {
+		fix := sb.SkipBlockFix.Copy()
+		cp[i] = &CompactBlock{
+			SkipBlockFix:    fix,
+			SignatureScheme: sb.SignatureScheme,
+		}
+		if i == len(sbs)-1 {
+			break
+		}
+		for _, fl := range sb.ForwardLink {
+			if !fl.IsEmpty() && fl.To.Equal(sbs[i+1].Hash) {
+				cp[i].Link = fl.Copy()
+			}
+		}
+		if cp[i].Link == nil {
+			return nil, xerrors.Errorf("block %d has no forward-link to "+
+				"block %d", sb.Index, sbs[i+1].Index)
+		}
+	}
+	return cp, nil
+}
+
+// Verify checks that the proof starts at a genesis block and that every
+// block is linked to the next one by a forward-link signed by its roster.
+func (cp CompactProof) Verify() error {
+	if len(cp) == 0 {
+		return errors.New("empty proof")
+	}
+	if cp[0].SkipBlockFix == nil || cp[0].Index != 0 {
+		return errors.New("first element must be a genesis block")
+	}
+	return cp.verifyChain()
+}
+
+// VerifyFromID checks that the proof starts at the block with the given ID,
+// typically the genesis block or a trusted block, and verifies the links up
+// to the last block.
+func (cp CompactProof) VerifyFromID(id SkipBlockID) error {
+	if len(cp) == 0 {
+		return errors.New("empty proof")
+	}
+	if cp[0].SkipBlockFix == nil || !cp[0].Hash().Equal(id) {
+		return errors.New("proof doesn't start with the correct block")
+	}
+	return cp.verifyChain()
+}
+
+// Latest returns the ID of the last block of the proof.
+func (cp CompactProof) Latest() SkipBlockID {
+	if len(cp) == 0 {
+		return nil
+	}
+	return cp[len(cp)-1].Hash()
+}
+
+func (cp CompactProof) verifyChain() error {
+	hashes := make([]SkipBlockID, len(cp))
+	for i, cb := range cp {
+		if cb.SkipBlockFix == nil {
+			return errors.New("missing header")
+		}
+		hashes[i] = cb.Hash()
+	}
+	for i, cb := range cp[:len(cp)-1] {
+		next := cp[i+1]
+		if cb.Link == nil {
+			return xerrors.Errorf("missing forward-link in block %d", cb.Index)
+		}
+		if !cb.Link.From.Equal(hashes[i]) || !cb.Link.To.Equal(hashes[i+1]) {
+			return xerrors.Errorf("wrong targets for the forward-link of "+
+				"block %d", cb.Index)
+		}
+		if next.Index <= cb.Index {
+			return ErrorInconsistentForwardLink
+		}
+		if cb.Roster == nil {
+			return xerrors.Errorf("missing roster in block %d", cb.Index)
+		}
+		err := cb.Link.VerifyWithScheme(suite,
+			cb.Roster.ServicePublics(ServiceName), cb.SignatureScheme)
+		if err != nil {
+			return xerrors.Errorf("wrong signature in block %d: %v",
+				cb.Index, err)
+		}
+	}
+	return nil
+}
+
+// GetCompactProof returns the shortest proof the db can build from the
+// block trusted to the block id, or to the latest block if id is nil.
+func (db *SkipBlockDB) GetCompactProof(trusted, id SkipBlockID) (CompactProof, error) {
+	if db.GetByID(trusted) == nil {
+		return nil, errors.New("couldn't find the trusted block")
+	}
+	var sbs Proof
+	var err error
+	if len(id) == 0 {
+		sbs, err = db.GetProof(trusted)
+	} else {
+		sbs, err = db.GetProofBetween(trusted, id)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get proof: %v", err)
+	}
+	return NewCompactProof(sbs)
+}
+
+// GetCompactProof returns a compact proof for the requested blocks.
+func (s *Service) GetCompactProof(req *GetCompactProof) (*GetCompactProofReply, error) {
+	cp, err := s.db.GetCompactProof(req.Trusted, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &GetCompactProofReply{Proof: cp}, nil
+}
+
+// GetCompactProof asks the roster for a compact proof from the block
+// trusted, which is the genesis block or a block the client verified
+// before, to the block id, or to the latest block known by the node if id is
+// nil. The proof is verified to start at trusted and to end at id.
+func (c *Client) GetCompactProof(roster *onet.Roster, trusted, id SkipBlockID) (CompactProof, error) {
+	reply := &GetCompactProofReply{}
+	_, err := c.SendProtobufParallel(roster.List,
+		&GetCompactProof{Trusted: trusted, ID: id}, reply, c.options)
+	if err != nil {
+		return nil, errors.New("all nodes failed to return a proof: " + err.Error())
+	}
+	if err := reply.Proof.VerifyFromID(trusted); err != nil {
+		return nil, xerrors.Errorf("invalid proof: %v", err)
+	}
+	if len(id) > 0 && !reply.Proof.Latest().Equal(id) {
+		return nil, xerrors.Errorf("proof ends at block %d instead of the "+
+			"requested one", reply.Proof[len(reply.Proof)-1].Index)
+	}
+	return reply.Proof, nil
+}
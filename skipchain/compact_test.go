@@ -0,0 +1,69 @@
+package skipchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+func TestService_GetCompactProof(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, ro, s := local.MakeSRS(cothority.Suite, 3, skipchainSID)
+	service := s.(*Service)
+
+	genesis, err := makeGenesisRosterArgs(service, ro, nil, VerificationNone, 2, 4)
+	require.NoError(t, err)
+	var latest *SkipBlock
+	for i := 0; i < 10; i++ {
+		sb := NewSkipBlock()
+		sb.Roster = ro
+		sb.Payload = make([]byte, 1000)
+		latest, err = addBlockToChain(service, genesis.Hash, sb)
+		require.NoError(t, err)
+	}
+	require.NoError(t, waitForwardLinks(service, genesis, 4))
+
+	cl := NewClient()
+	cp, err := cl.GetCompactProof(ro, genesis.Hash, nil)
+	require.NoError(t, err)
+	require.True(t, cp.Latest().Equal(latest.Hash))
+	require.NoError(t, cp.VerifyFromID(genesis.Hash))
+
+	full, err := service.db.GetProof(genesis.Hash)
+	require.NoError(t, err)
+	require.Equal(t, len(full), len(cp))
+	fullBuf, err := protobuf.Encode(&OptimizeProofReply{Proof: full})
+	require.NoError(t, err)
+	cpBuf, err := protobuf.Encode(&GetCompactProofReply{Proof: cp})
+	require.NoError(t, err)
+	require.True(t, len(cpBuf) < len(fullBuf))
+
+	// Proof for a block in the middle of the chain.
+	sb5, err := service.GetSingleBlockByIndex(&GetSingleBlockByIndex{
+		Genesis: genesis.Hash, Index: 5})
+	require.NoError(t, err)
+	cp5, err := cl.GetCompactProof(ro, genesis.Hash, sb5.SkipBlock.Hash)
+	require.NoError(t, err)
+	require.True(t, cp5.Latest().Equal(sb5.SkipBlock.Hash))
+
+	// A client that was offline starts from the last block it verified.
+	cpHead, err := cl.GetCompactProof(ro, sb5.SkipBlock.Hash, nil)
+	require.NoError(t, err)
+	require.True(t, cpHead[0].Hash().Equal(sb5.SkipBlock.Hash))
+	require.True(t, cpHead.Latest().Equal(latest.Hash))
+	require.Error(t, cpHead.VerifyFromID(genesis.Hash))
+	_, err = cl.GetCompactProof(ro, latest.Hash, sb5.SkipBlock.Hash)
+	require.Error(t, err)
+
+	// Any modification is detected.
+	cp[len(cp)-1].Data = []byte("wrong")
+	require.Error(t, cp.Verify())
+	cp5[0].Link.Signature.Sig[0] ^= 1
+	require.Error(t, cp5.Verify())
+	require.Error(t, CompactProof{}.Verify())
+}
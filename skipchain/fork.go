@@ -0,0 +1,345 @@
+package skipchain
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+)
+
+// ForkEvidence proves that the roster of a block signed two forward-links
+// pointing to two different blocks with the same index. As the forward-links
+// are only signed by a threshold of the roster, this means that enough nodes
+// misbehaved to fork the skipchain.
+type ForkEvidence struct {
+	// From is the block where both forward-links start.
+	From *SkipBlock
+	// Link1 and Link2 are the conflicting forward-links.
+	Link1 *ForwardLink
+	Link2 *ForwardLink
+	// Target1 and Target2 are the blocks pointed to by Link1 and Link2.
+	Target1 *SkipBlock
+	Target2 *SkipBlock
+}
+
+// PropagateForkEvidence sends the evidence of a fork to the roster of the
+// forked block.
+type PropagateForkEvidence struct {
+	Evidence *ForkEvidence
+}
+
+// GetForkEvidence asks for all evidences of forks stored for a skipchain.
+type GetForkEvidence struct {
+	SkipChainID SkipBlockID
+}
+
+// GetForkEvidenceReply returns the evidences of forks of a skipchain.
+type GetForkEvidenceReply struct {
+	Evidences []*ForkEvidence
+}
+
+func init() {
+	network.RegisterMessages(&ForkEvidence{}, &PropagateForkEvidence{},
+		&GetForkEvidence{}, &GetForkEvidenceReply{})
+}
+
+// NewForkEvidence returns the evidence for the two forward-links, or nil if
+// the links don't conflict. The payloads of the blocks are dropped, as they
+// are not needed to verify the evidence.
+func NewForkEvidence(from *SkipBlock, link1, link2 *ForwardLink,
+	target1, target2 *SkipBlock) *ForkEvidence {
+	strip := func(sb *SkipBlock) *SkipBlock {
+		c := sb.Copy()
+		c.Payload = nil
+		c.ForwardLink = nil
+		return c
+	}
+	fe := &ForkEvidence{
+		From:    strip(from),
+		Link1:   link1.Copy(),
+		Link2:   link2.Copy(),
+		Target1: strip(target1),
+		Target2: strip(target2),
+	}
+	if fe.Verify() != nil {
+		return nil
+	}
+	return fe
+}
+
+// Verify returns nil if the evidence proves that the roster of From signed
+// two forward-links to different blocks at the same index.
+func (fe *ForkEvidence) Verify() error {
+	if fe.From == nil || fe.Link1 == nil || fe.Link2 == nil ||
+		fe.Target1 == nil || fe.Target2 == nil {
+		return errors.New("incomplete evidence")
+	}
+	if fe.From.Roster == nil {
+		return errors.New("missing roster in the block")
+	}
+	for _, sb := range []*SkipBlock{fe.From, fe.Target1, fe.Target2} {
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return xerrors.Errorf("wrong hash for block %d", sb.Index)
+		}
+	}
+	if fe.Target1.Index != fe.Target2.Index {
+		return errors.New("targets have different indexes")
+	}
+	if fe.Target1.Hash.Equal(fe.Target2.Hash) {
+		return errors.New("targets are the same block")
+	}
+	if fe.Target1.Index <= fe.From.Index {
+		return errors.New("targets are not after the source block")
+	}
+	publics := fe.From.Roster.ServicePublics(ServiceName)
+	for i, l := range []struct {
+		link   *ForwardLink
+		target *SkipBlock
+	}{{fe.Link1, fe.Target1}, {fe.Link2, fe.Target2}} {
+		if !l.link.From.Equal(fe.From.Hash) || !l.link.To.Equal(l.target.Hash) {
+			return xerrors.Errorf("link %d has wrong source or target", i+1)
+		}
+		err := l.link.VerifyWithScheme(suite, publics, fe.From.SignatureScheme)
+		if err != nil {
+			return xerrors.Errorf("link %d has wrong signature: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// ID returns a unique identifier of the evidence, independent of the order
+// of the links.
+func (fe *ForkEvidence) ID() []byte {
+	h := sha256.New()
+	h.Write(fe.From.Hash)
+	t1, t2 := fe.Target1.Hash, fe.Target2.Hash
+	if string(t1) > string(t2) {
+		t1, t2 = t2, t1
+	}
+	h.Write(t1)
+	h.Write(t2)
+	return h.Sum(nil)
+}
+
+// FindFork compares two proofs of the same skipchain and returns the
+// evidence of a fork if the same block links to two different blocks with
+// the same index. If no fork is found, nil is returned. The proofs must
+// have been verified before.
+func FindFork(p1, p2 Proof) *ForkEvidence {
+	type step struct {
+		link   *ForwardLink
+		target *SkipBlock
+	}
+	steps := make(map[string][]step)
+	for i := 0; i < len(p1)-1; i++ {
+		for _, fl := range p1[i].ForwardLink {
+			if !fl.IsEmpty() && fl.To.Equal(p1[i+1].Hash) {
+				steps[string(p1[i].Hash)] = append(steps[string(p1[i].Hash)],
+					step{fl, p1[i+1]})
+			}
+		}
+	}
+	for i := 0; i < len(p2)-1; i++ {
+		for _, fl := range p2[i].ForwardLink {
+			if fl.IsEmpty() || !fl.To.Equal(p2[i+1].Hash) {
+				continue
+			}
+			for _, s := range steps[string(p2[i].Hash)] {
+				if s.target.Index == p2[i+1].Index &&
+					!s.target.Hash.Equal(p2[i+1].Hash) {
+					fe := NewForkEvidence(p2[i], s.link, fl, s.target, p2[i+1])
+					if fe != nil {
+						return fe
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// forkBucketName returns the name of the bucket holding the evidences of
+// forks.
+func (db *SkipBlockDB) forkBucketName() []byte {
+	return append(append([]byte{}, db.bucketName...), []byte("_forks")...)
+}
+
+// StoreForkEvidence verifies and stores the evidence. It returns true if
+// the evidence was not known yet.
+func (db *SkipBlockDB) StoreForkEvidence(fe *ForkEvidence) (bool, error) {
+	if err := fe.Verify(); err != nil {
+		return false, xerrors.Errorf("invalid evidence: %v", err)
+	}
+	buf, err := network.Marshal(fe)
+	if err != nil {
+		return false, xerrors.Errorf("couldn't marshal evidence: %v", err)
+	}
+	key := append(append([]byte{}, fe.From.SkipChainID()...), fe.ID()...)
+	isNew := false
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(db.forkBucketName())
+		if err != nil {
+			return err
+		}
+		if b.Get(key) != nil {
+			return nil
+		}
+		isNew = true
+		return b.Put(key, buf)
+	})
+	if err != nil {
+		return false, xerrors.Errorf("couldn't store evidence: %v", err)
+	}
+	return isNew, nil
+}
+
+// GetForkEvidence returns all evidences of forks stored for the skipchain.
+func (db *SkipBlockDB) GetForkEvidence(scID SkipBlockID) ([]*ForkEvidence, error) {
+	var evidences []*ForkEvidence
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(db.forkBucketName())
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(scID); k != nil && len(k) > len(scID) &&
+			SkipBlockID(k[:len(scID)]).Equal(scID); k, v = c.Next() {
+			buf := make([]byte, len(v))
+			copy(buf, v)
+			_, msg, err := network.Unmarshal(buf, suite)
+			if err != nil {
+				return err
+			}
+			fe, ok := msg.(*ForkEvidence)
+			if !ok {
+				return errors.New("stored data is not a fork evidence")
+			}
+			evidences = append(evidences, fe)
+		}
+		return nil
+	})
+	return evidences, err
+}
+
+// detectForks compares the forward-links of a block received with the ones
+// of the stored block, and returns the evidences for all conflicting links.
+// The targets of the links are searched in the db and in the blocks being
+// stored.
+func (db *SkipBlockDB) detectForks(tx *bbolt.Tx, stored, received *SkipBlock,
+	blocks []*SkipBlock) []*ForkEvidence {
+	getBlock := func(id SkipBlockID) *SkipBlock {
+		for _, sb := range blocks {
+			if sb.Hash.Equal(id) {
+				return sb
+			}
+		}
+		sb, err := db.getFromTx(tx, id)
+		if err != nil {
+			return nil
+		}
+		return sb
+	}
+
+	var evidences []*ForkEvidence
+	for h := 0; h < len(stored.ForwardLink) && h < len(received.ForwardLink); h++ {
+		fl1, fl2 := stored.ForwardLink[h], received.ForwardLink[h]
+		if fl1.IsEmpty() || fl2.IsEmpty() || fl1.To.Equal(fl2.To) {
+			continue
+		}
+		t1, t2 := getBlock(fl1.To), getBlock(fl2.To)
+		if t1 == nil || t2 == nil {
+			log.Warnf("Found conflicting forward-links at height %d in "+
+				"block %d, but not their targets", h, stored.Index)
+			continue
+		}
+		if fe := NewForkEvidence(stored, fl1, fl2, t1, t2); fe != nil {
+			log.Errorf("Found fork after block %d of skipchain %x",
+				stored.Index, stored.SkipChainID())
+			evidences = append(evidences, fe)
+		}
+	}
+	return evidences
+}
+
+// handleForks stores the evidences and passes the new ones to the
+// callback.
+func (db *SkipBlockDB) handleForks(evidences []*ForkEvidence) {
+	for _, fe := range evidences {
+		isNew, err := db.StoreForkEvidence(fe)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if isNew && db.forkCallback != nil {
+			db.forkCallback(fe)
+		}
+	}
+}
+
+// findProofForks compares every step of a verified proof with the
+// forward-links of the blocks stored by this node. The evidences of the forks
+// found are stored and propagated to the roster of the forked block.
+func (s *Service) findProofForks(p Proof) {
+	var evidences []*ForkEvidence
+	for i := 0; i < len(p)-1; i++ {
+		stored := s.db.GetByID(p[i].Hash)
+		if stored == nil {
+			continue
+		}
+		for _, fl := range stored.ForwardLink {
+			if fl.IsEmpty() || fl.To.Equal(p[i+1].Hash) {
+				continue
+			}
+			target := s.db.GetByID(fl.To)
+			if target == nil {
+				continue
+			}
+			if fe := FindFork(Proof{stored, target}, p[i:i+2]); fe != nil {
+				log.Errorf("Found fork after block %d of skipchain %x in a proof",
+					stored.Index, stored.SkipChainID())
+				evidences = append(evidences, fe)
+			}
+		}
+	}
+	s.db.handleForks(evidences)
+}
+
+// GetForkEvidence returns the evidences of forks known to this node for the
+// skipchain.
+func (s *Service) GetForkEvidence(req *GetForkEvidence) (*GetForkEvidenceReply, error) {
+	evidences, err := s.db.GetForkEvidence(req.SkipChainID)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get evidences: %v", err)
+	}
+	return &GetForkEvidenceReply{Evidences: evidences}, nil
+}
+
+// propagateForkEvidence sends a new evidence to the roster of the forked
+// block, so that all nodes know about it.
+func (s *Service) propagateForkEvidence(fe *ForkEvidence) {
+	go func() {
+		err := s.startPropagation(s.propagateEvidence, fe.From.Roster,
+			&PropagateForkEvidence{Evidence: fe})
+		if err != nil {
+			log.Error("Couldn't propagate fork evidence:", err)
+		}
+	}()
+}
+
+// propagateForkEvidenceHandler stores the evidences sent by other nodes.
+func (s *Service) propagateForkEvidenceHandler(msg network.Message) error {
+	pfe, ok := msg.(*PropagateForkEvidence)
+	if !ok {
+		return errors.New("couldn't convert to a fork evidence propagation")
+	}
+	if pfe.Evidence == nil {
+		return errors.New("missing evidence")
+	}
+	// The evidence is not propagated further, as it has been sent to the
+	// whole roster.
+	_, err := s.db.StoreForkEvidence(pfe.Evidence)
+	return err
+}
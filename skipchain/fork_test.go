@@ -0,0 +1,109 @@
+package skipchain
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestSkipBlockDB_ForkEvidence(t *testing.T) {
+	local := onet.NewLocalTest(suite)
+	_, ro, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	db, file := setupSkipBlockDB(t)
+	defer os.Remove(file)
+	var found []*ForkEvidence
+	db.forkCallback = func(fe *ForkEvidence) {
+		found = append(found, fe)
+	}
+
+	root := NewSkipBlock()
+	root.Roster = ro
+	root.Height = 1
+	root.BaseHeight = 2
+	root.BackLinkIDs = []SkipBlockID{{1, 2, 3}}
+	root.updateHash()
+	newBlock := func(data string) *SkipBlock {
+		sb := NewSkipBlock()
+		sb.Roster = ro
+		sb.Index = 1
+		sb.Height = 1
+		sb.BaseHeight = 2
+		sb.GenesisID = root.Hash
+		sb.BackLinkIDs = []SkipBlockID{root.Hash}
+		sb.Data = []byte(data)
+		sb.updateHash()
+		return sb
+	}
+	sb1 := newBlock("one")
+	sb2 := newBlock("two")
+	fl1 := &ForwardLink{From: root.Hash, To: sb1.Hash}
+	require.NoError(t, fl1.sign(ro))
+	fl2 := &ForwardLink{From: root.Hash, To: sb2.Hash}
+	require.NoError(t, fl2.sign(ro))
+
+	root1 := root.Copy()
+	root1.ForwardLink = []*ForwardLink{fl1}
+	_, err := db.StoreBlocks([]*SkipBlock{root1, sb1})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// Receiving the other link of the root block is detected.
+	root2 := root.Copy()
+	root2.ForwardLink = []*ForwardLink{fl2}
+	_, _ = db.StoreBlocks([]*SkipBlock{root2, sb2})
+	require.Len(t, found, 1)
+	require.NoError(t, found[0].Verify())
+	evidences, err := db.GetForkEvidence(root.Hash)
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+	require.NoError(t, evidences[0].Verify())
+
+	// The same evidence is only reported once.
+	_, _ = db.StoreBlocks([]*SkipBlock{root2, sb2})
+	require.Len(t, found, 1)
+
+	// Comparing two proofs finds the fork, too.
+	fe := FindFork(Proof{root1, sb1}, Proof{root2, sb2})
+	require.NotNil(t, fe)
+	require.Equal(t, found[0].ID(), fe.ID())
+	require.Nil(t, FindFork(Proof{root1, sb1}, Proof{root1, sb1}))
+
+	// A node only holding one branch finds the fork in the proof of the
+	// other branch, and records and propagates the evidence.
+	db2, file2 := setupSkipBlockDB(t)
+	defer os.Remove(file2)
+	var propagated []*ForkEvidence
+	db2.forkCallback = func(fe *ForkEvidence) {
+		propagated = append(propagated, fe)
+	}
+	_, err = db2.StoreBlocks([]*SkipBlock{root1.Copy(), sb1.Copy()})
+	require.NoError(t, err)
+	s := &Service{db: db2}
+	s.findProofForks(Proof{root1, sb1})
+	require.Empty(t, propagated)
+	s.findProofForks(Proof{root2, sb2})
+	require.Len(t, propagated, 1)
+	require.Equal(t, fe.ID(), propagated[0].ID())
+	evidences, err = db2.GetForkEvidence(root.Hash)
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+
+	// Links that don't conflict are not an evidence.
+	require.Nil(t, NewForkEvidence(root, fl1, fl1, sb1, sb1))
+	sb3 := newBlock("three")
+	sb3.Index = 2
+	sb3.updateHash()
+	fl3 := &ForwardLink{From: root.Hash, To: sb3.Hash}
+	require.NoError(t, fl3.sign(ro))
+	require.Nil(t, NewForkEvidence(root, fl1, fl3, sb1, sb3))
+
+	// Wrong signatures are refused.
+	fe.Link2.Signature.Sig[0] ^= 1
+	require.Error(t, fe.Verify())
+	_, err = db.StoreForkEvidence(fe)
+	require.Error(t, err)
+}
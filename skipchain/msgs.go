@@ -114,6 +114,9 @@ type GetUpdateChain struct {
 	// MaxBlocks is the maximum number of blocks to be returned. If it is not
 	// given, or equal to 0, all available blocks will be returned.
 	MaxBlocks int `protobuf:"opt"`
+	// AllowPruned returns the blocks even if their payloads have been
+	// pruned, instead of ErrorBlockPruned.
+	AllowPruned bool `protobuf:"opt"`
 }
 
 // GetUpdateChainReply - returns the shortest chain to the current SkipBlock,
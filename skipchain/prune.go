@@ -0,0 +1,196 @@
+package skipchain
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"go.dedis.ch/onet/v3/log"
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+)
+
+// ErrorBlockPruned is returned when the payload of a block has been removed
+// by the pruning. The header and the forward-links of the block are still
+// available, so it can be used in proofs.
+var ErrorBlockPruned = errors.New("the payload of this block has been pruned")
+
+// pruneBatch is the number of blocks pruned in a single transaction.
+const pruneBatch = 100
+
+// SetPruning configures the database to drop the payloads of the blocks
+// that are older than keep blocks, every time new blocks are stored. A value
+// of 0 disables the pruning.
+func (db *SkipBlockDB) SetPruning(keep int) {
+	db.latestMutex.Lock()
+	db.pruneKeep = keep
+	db.latestMutex.Unlock()
+}
+
+// Prune drops the payloads of all the blocks of the skipchain scID that
+// are more than keep blocks older than its latest block. The headers and
+// forward-links are kept, so that proofs can still be created. Blocks after
+// the limit given by SetPruningLimit are kept. It returns the number of
+// pruned blocks.
+func (db *SkipBlockDB) Prune(scID SkipBlockID, keep int) (int, error) {
+	if keep < 1 {
+		return 0, xerrors.New("need to keep at least one block")
+	}
+	latest, err := db.GetLatestByID(scID)
+	if err != nil {
+		return 0, xerrors.Errorf("couldn't get latest block: %v", err)
+	}
+	return db.pruneUpTo(scID, latest.Index-keep+1)
+}
+
+// PruneBefore drops the payloads of all the blocks older than the
+// checkpoint block, up to the limit given by SetPruningLimit. The checkpoint
+// must be stored in the database and the path from the genesis block to the
+// checkpoint must be correctly signed. It returns the number of pruned
+// blocks.
+func (db *SkipBlockDB) PruneBefore(checkpoint SkipBlockID) (int, error) {
+	proof, err := db.GetProofForID(checkpoint)
+	if err != nil {
+		return 0, xerrors.Errorf("couldn't get proof for checkpoint: %v", err)
+	}
+	if err := proof.verifyPath(); err != nil {
+		return 0, xerrors.Errorf("invalid checkpoint: %v", err)
+	}
+	cp := proof[len(proof)-1]
+	return db.pruneUpTo(cp.SkipChainID(), cp.Index)
+}
+
+// SetPruningLimit sets the function returning the index of the first block
+// of a skipchain whose payload must be kept, whatever the pruning asked for,
+// or -1 if all blocks can be pruned. It lets the service using the chain keep
+// the blocks it didn't process yet.
+func (db *SkipBlockDB) SetPruningLimit(limit func(SkipBlockID) int) {
+	db.latestMutex.Lock()
+	db.pruneLimit = limit
+	db.latestMutex.Unlock()
+}
+
+// IsPruned returns true if the payload of the block has been pruned.
+func (db *SkipBlockDB) IsPruned(sb *SkipBlock) bool {
+	var pruned bool
+	err := db.View(func(tx *bbolt.Tx) error {
+		index, _ := db.getPrunedFromTx(tx, sb.SkipChainID())
+		pruned = sb.Index < index
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return pruned
+}
+
+// prunedBucketName returns the name of the bucket holding, for every
+// skipchain, the index and the ID of the first block that is not pruned.
+func (db *SkipBlockDB) prunedBucketName() []byte {
+	return append(append([]byte{}, db.bucketName...), []byte("_pruned")...)
+}
+
+// getPrunedFromTx returns the index and the ID of the first block of the
+// skipchain that has not been pruned. If nothing has been pruned yet, the
+// index is 0 and the ID is the one of the genesis block.
+func (db *SkipBlockDB) getPrunedFromTx(tx *bbolt.Tx, scID SkipBlockID) (int, SkipBlockID) {
+	b := tx.Bucket(db.prunedBucketName())
+	if b == nil {
+		return 0, scID
+	}
+	val := b.Get(scID)
+	if len(val) < 4 {
+		return 0, scID
+	}
+	id := make(SkipBlockID, len(val)-4)
+	copy(id, val[4:])
+	return int(binary.LittleEndian.Uint32(val)), id
+}
+
+// pruneUpTo drops the payloads of all the blocks of the skipchain with an
+// index smaller than index and than the pruning limit. It starts at the first
+// block that has not been pruned yet, so calling it repeatedly only touches
+// the new blocks.
+func (db *SkipBlockDB) pruneUpTo(scID SkipBlockID, index int) (int, error) {
+	db.latestMutex.Lock()
+	limit := db.pruneLimit
+	db.latestMutex.Unlock()
+	if limit != nil {
+		if l := limit(scID); l >= 0 && l < index {
+			index = l
+		}
+	}
+	pruned := 0
+	for done := false; !done; {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			start, id := db.getPrunedFromTx(tx, scID)
+			if start >= index {
+				done = true
+				return nil
+			}
+			sb, err := db.getFromTx(tx, id)
+			if err != nil {
+				return err
+			}
+			if sb == nil {
+				return xerrors.Errorf("couldn't find block %x", id)
+			}
+			for i := 0; i < pruneBatch && sb.Index < index; i++ {
+				if len(sb.ForwardLink) == 0 {
+					return xerrors.Errorf("missing forward-link in block %d",
+						sb.Index)
+				}
+				if len(sb.Payload) > 0 {
+					sb.Payload = nil
+					if err := db.storeToTx(tx, sb); err != nil {
+						return err
+					}
+				}
+				pruned++
+				next, err := db.getFromTx(tx, sb.ForwardLink[0].To)
+				if err != nil {
+					return err
+				}
+				if next == nil {
+					return xerrors.Errorf("couldn't find block %d",
+						sb.Index+1)
+				}
+				sb = next
+			}
+
+			b, err := tx.CreateBucketIfNotExists(db.prunedBucketName())
+			if err != nil {
+				return err
+			}
+			val := make([]byte, 4+len(sb.Hash))
+			binary.LittleEndian.PutUint32(val, uint32(sb.Index))
+			copy(val[4:], sb.Hash)
+			return b.Put(scID, val)
+		})
+		if err != nil {
+			return pruned, xerrors.Errorf("while pruning: %v", err)
+		}
+	}
+	return pruned, nil
+}
+
+// pruneStored is called after new blocks are stored and prunes the
+// skipchains of these blocks, if pruning is enabled.
+func (db *SkipBlockDB) pruneStored(ids []SkipBlockID) {
+	db.latestMutex.Lock()
+	keep := db.pruneKeep
+	db.latestMutex.Unlock()
+	if keep == 0 {
+		return
+	}
+	chains := make(map[string]bool)
+	for _, id := range ids {
+		sb := db.GetByID(id)
+		if sb == nil || chains[string(sb.SkipChainID())] {
+			continue
+		}
+		chains[string(sb.SkipChainID())] = true
+		if _, err := db.Prune(sb.SkipChainID(), keep); err != nil {
+			log.Errorf("Couldn't prune skipchain %x: %v", sb.SkipChainID(), err)
+		}
+	}
+}
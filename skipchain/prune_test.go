@@ -0,0 +1,101 @@
+package skipchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestSkipBlockDB_Prune(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, ro, s := local.MakeSRS(cothority.Suite, 3, skipchainSID)
+	service := s.(*Service)
+
+	genesis, err := makeGenesisRosterArgs(service, ro, nil, VerificationNone, 2, 3)
+	require.NoError(t, err)
+	blocks := []*SkipBlock{genesis}
+	for i := 0; i < 10; i++ {
+		sb := NewSkipBlock()
+		sb.Roster = ro
+		sb.Payload = []byte("payload")
+		latest, err := addBlockToChain(service, genesis.Hash, sb)
+		require.NoError(t, err)
+		blocks = append(blocks, latest)
+	}
+
+	db := service.db
+	_, err = db.Prune(genesis.Hash, 0)
+	require.Error(t, err)
+	pruned, err := db.Prune(genesis.Hash, 4)
+	require.NoError(t, err)
+	require.Equal(t, 7, pruned)
+	for _, sb := range blocks {
+		stored := db.GetByID(sb.Hash)
+		require.NotNil(t, stored)
+		require.Equal(t, sb.Index < 7, db.IsPruned(stored))
+		if sb.Index > 0 {
+			require.Equal(t, sb.Index < 7, len(stored.Payload) == 0)
+		}
+	}
+	_, err = service.GetSingleBlock(&GetSingleBlock{ID: blocks[3].Hash})
+	require.Equal(t, ErrorBlockPruned, err)
+	_, err = service.GetSingleBlock(&GetSingleBlock{ID: blocks[8].Hash})
+	require.NoError(t, err)
+	_, err = service.GetSingleBlockByIndex(&GetSingleBlockByIndex{
+		Genesis: genesis.Hash, Index: 3})
+	require.Equal(t, ErrorBlockPruned, err)
+	_, err = service.GetSingleBlockByIndex(&GetSingleBlockByIndex{
+		Genesis: genesis.Hash, Index: 8})
+	require.NoError(t, err)
+	_, err = service.GetUpdateChain(&GetUpdateChain{LatestID: genesis.Hash})
+	require.Equal(t, ErrorBlockPruned, err)
+	_, err = service.GetUpdateChain(&GetUpdateChain{LatestID: blocks[7].Hash})
+	require.NoError(t, err)
+	uc, err := service.GetUpdateChain(&GetUpdateChain{LatestID: genesis.Hash,
+		AllowPruned: true})
+	require.NoError(t, err)
+	require.True(t, uc.Update[len(uc.Update)-1].Hash.Equal(blocks[10].Hash))
+
+	// Proofs are still available for pruned blocks.
+	proof, err := db.GetProofForID(blocks[3].Hash)
+	require.NoError(t, err)
+	require.NoError(t, proof.verifyPath())
+
+	// Pruning again only touches the new blocks.
+	pruned, err = db.Prune(genesis.Hash, 4)
+	require.NoError(t, err)
+	require.Equal(t, 0, pruned)
+	pruned, err = db.PruneBefore(blocks[9].Hash)
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+	require.True(t, db.IsPruned(blocks[8]))
+	require.False(t, db.IsPruned(blocks[9]))
+
+	// With pruning enabled, new blocks prune the old ones.
+	service.SetPruning(2)
+	sb := NewSkipBlock()
+	sb.Roster = ro
+	sb.Payload = []byte("payload")
+	latest, err := addBlockToChain(service, genesis.Hash, sb)
+	require.NoError(t, err)
+	require.True(t, db.IsPruned(blocks[9]))
+	require.False(t, db.IsPruned(blocks[10]))
+	require.False(t, db.IsPruned(latest))
+
+	// The blocks after the limit are kept.
+	service.RegisterPruneLimit(func(SkipBlockID) int { return 10 })
+	sb = NewSkipBlock()
+	sb.Roster = ro
+	sb.Payload = []byte("payload")
+	_, err = addBlockToChain(service, genesis.Hash, sb)
+	require.NoError(t, err)
+	require.False(t, db.IsPruned(blocks[10]))
+	pruned, err = db.PruneBefore(latest.Hash)
+	require.NoError(t, err)
+	require.Equal(t, 0, pruned)
+	require.False(t, db.IsPruned(blocks[10]))
+}
@@ -0,0 +1,217 @@
+package skipchain
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/blscosi/schnorrproto"
+	"go.dedis.ch/cothority/v3/blscosi/thresholdbls"
+	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/onet/v3"
+	"golang.org/x/xerrors"
+)
+
+// SignatureScheme describes how the forward-links of the blocks using it are
+// signed and verified. The index of the scheme in the registry is stored in
+// SkipBlock.SignatureScheme.
+type SignatureScheme struct {
+	// Name is a human readable name of the scheme.
+	Name string
+	// NewBlockProtocol and FollowBlockProtocol are the names of the
+	// protocols used to sign the forward-links of level 0 and of higher
+	// levels.
+	NewBlockProtocol    string
+	FollowBlockProtocol string
+	// Init registers the signing protocols under the given name in the
+	// context of the skipchain service.
+	Init func(suite *pairing.SuiteBn256, c *onet.Context,
+		vf, ack protocol.VerificationFn, protoName string) error
+	// Verify returns nil if sig is a signature of msg by enough nodes of
+	// the roster given by its service public keys.
+	Verify func(suite pairing.Suite, msg, sig []byte, publics []kyber.Point) error
+	// Prepare is optional and is called by the leader before signing a
+	// forward-link with the roster.
+	Prepare func(c *onet.Context, roster *onet.Roster) error
+	// MaxRosterSize is the maximum number of nodes of a roster using the
+	// scheme, or 0 if there is no limit.
+	MaxRosterSize int
+	// Deprecated schemes are only kept to verify the existing chains. New
+	// chains cannot use them and chains cannot migrate to them.
+	Deprecated bool
+}
+
+var signatureSchemes = struct {
+	sync.Mutex
+	schemes map[uint32]SignatureScheme
+}{schemes: make(map[uint32]SignatureScheme)}
+
+// SchnorrMaxRosterSize is the maximum number of nodes of a roster using
+// the Schnorr multi-signature, as the size of the signature grows with the
+// number of signers.
+const SchnorrMaxRosterSize = 16
+
+func init() {
+	schemes := map[uint32]SignatureScheme{
+		BlsSignatureSchemeIndex: {
+			Name:                "BLS",
+			NewBlockProtocol:    bftNewBlock,
+			FollowBlockProtocol: bftFollowBlock,
+			Init:                byzcoinx.InitBFTCoSiProtocol,
+			Verify: func(suite pairing.Suite, msg, sig []byte, publics []kyber.Point) error {
+				return protocol.BlsSignature(sig).Verify(suite, msg, publics)
+			},
+			Deprecated: true,
+		},
+		BdnSignatureSchemeIndex: {
+			Name:                "BDN",
+			NewBlockProtocol:    bdnNewBlock,
+			FollowBlockProtocol: bdnFollowBlock,
+			Init:                byzcoinx.InitBDNCoSiProtocol,
+			Verify: func(suite pairing.Suite, msg, sig []byte, publics []kyber.Point) error {
+				return bdnproto.BdnSignature(sig).Verify(suite, msg, publics)
+			},
+		},
+		SchnorrSignatureSchemeIndex: {
+			Name:                "Schnorr",
+			NewBlockProtocol:    schnorrNewBlock,
+			FollowBlockProtocol: schnorrFollowBlock,
+			Init:                byzcoinx.InitSchnorrCoSiProtocol,
+			Verify: func(suite pairing.Suite, msg, sig []byte, publics []kyber.Point) error {
+				return schnorrproto.SchnorrSignature(sig).Verify(suite, msg, publics)
+			},
+			MaxRosterSize: SchnorrMaxRosterSize,
+		},
+		ThresholdBlsSignatureSchemeIndex: {
+			Name:                "ThresholdBLS",
+			NewBlockProtocol:    tblsNewBlock,
+			FollowBlockProtocol: tblsFollowBlock,
+			Init:                byzcoinx.InitThresholdBlsCoSiProtocol,
+			Verify: func(suite pairing.Suite, msg, sig []byte, publics []kyber.Point) error {
+				return thresholdbls.GroupSignature(sig).Verify(suite, msg, publics)
+			},
+			Prepare: func(c *onet.Context, roster *onet.Roster) error {
+				s, ok := c.Service(thresholdbls.ServiceName).(*thresholdbls.Service)
+				if !ok {
+					return errors.New("threshold BLS service not found")
+				}
+				return s.Prepare(roster, ServiceName)
+			},
+		},
+	}
+	for index, scheme := range schemes {
+		if err := RegisterSignatureScheme(index, scheme); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterSignatureScheme adds a signature scheme at the given index. It
+// must be called before the skipchain service is started, usually in an
+// init function, so that the signing protocols can be registered. As the
+// index is stored in the blocks, it cannot be reused for another scheme.
+func RegisterSignatureScheme(index uint32, scheme SignatureScheme) error {
+	if scheme.NewBlockProtocol == "" || scheme.FollowBlockProtocol == "" ||
+		scheme.Init == nil || scheme.Verify == nil {
+		return errors.New("incomplete signature scheme")
+	}
+
+	signatureSchemes.Lock()
+	defer signatureSchemes.Unlock()
+	if _, ok := signatureSchemes.schemes[index]; ok {
+		return xerrors.Errorf("signature scheme %d already registered", index)
+	}
+	signatureSchemes.schemes[index] = scheme
+	return nil
+}
+
+// GetSignatureScheme returns the scheme registered at the index.
+func GetSignatureScheme(index uint32) (SignatureScheme, bool) {
+	signatureSchemes.Lock()
+	defer signatureSchemes.Unlock()
+	scheme, ok := signatureSchemes.schemes[index]
+	return scheme, ok
+}
+
+// signatureSchemeIndexes returns the indexes of the registered schemes in
+// increasing order.
+func signatureSchemeIndexes() []uint32 {
+	signatureSchemes.Lock()
+	defer signatureSchemes.Unlock()
+	var indexes []uint32
+	for index := range signatureSchemes.schemes {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes
+}
+
+// verifySignatureScheme returns an error if the block cannot use its
+// signature scheme after the previous block, which is nil for a genesis
+// block. The scheme can only change together with the roster, so that the
+// old roster signs the switch to the new scheme.
+func verifySignatureScheme(prev, sb *SkipBlock) error {
+	scheme, ok := GetSignatureScheme(sb.SignatureScheme)
+	if !ok {
+		return xerrors.Errorf("unknown signature scheme %d", sb.SignatureScheme)
+	}
+	if scheme.MaxRosterSize > 0 && sb.Roster != nil &&
+		len(sb.Roster.List) > scheme.MaxRosterSize {
+		return xerrors.Errorf("the %s signature scheme supports at most %d "+
+			"nodes", scheme.Name, scheme.MaxRosterSize)
+	}
+	if prev != nil && prev.SignatureScheme == sb.SignatureScheme {
+		return nil
+	}
+	if scheme.Deprecated {
+		return xerrors.Errorf("the %s signature scheme is deprecated",
+			scheme.Name)
+	}
+	if prev != nil && (prev.Roster == nil || sb.Roster == nil ||
+		prev.Roster.ID.Equal(sb.Roster.ID)) {
+		return errors.New("the signature scheme can only change with the roster")
+	}
+	return nil
+}
+
+// initSignatureSchemes registers the signing protocols of all schemes in
+// the context of the service.
+func (s *Service) initSignatureSchemes() error {
+	for _, index := range signatureSchemeIndexes() {
+		scheme, _ := GetSignatureScheme(index)
+		err := scheme.Init(suite, s.Context, s.bftForwardLinkLevel0,
+			s.bftForwardLinkLevel0Ack, scheme.NewBlockProtocol)
+		if err != nil {
+			return xerrors.Errorf("couldn't register %s protocols: %v",
+				scheme.Name, err)
+		}
+		err = scheme.Init(suite, s.Context, s.bftForwardLink,
+			s.bftForwardLinkAck, scheme.FollowBlockProtocol)
+		if err != nil {
+			return xerrors.Errorf("couldn't register %s protocols: %v",
+				scheme.Name, err)
+		}
+	}
+	return nil
+}
+
+// prepareSignatureScheme calls the Prepare function of the scheme, if any,
+// before the node signs a forward-link with the roster.
+func (s *Service) prepareSignatureScheme(index uint32, roster *onet.Roster) error {
+	scheme, ok := GetSignatureScheme(index)
+	if !ok {
+		return xerrors.Errorf("unknown signature scheme %d", index)
+	}
+	if scheme.Prepare == nil {
+		return nil
+	}
+	if err := scheme.Prepare(s.Context, roster); err != nil {
+		return xerrors.Errorf("couldn't prepare the %s signature scheme: %v",
+			scheme.Name, err)
+	}
+	return nil
+}
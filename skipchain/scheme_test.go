@@ -0,0 +1,177 @@
+package skipchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/thresholdbls"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestSignatureScheme_Registry(t *testing.T) {
+	scheme, ok := GetSignatureScheme(SchnorrSignatureSchemeIndex)
+	require.True(t, ok)
+	require.Equal(t, "Schnorr", scheme.Name)
+	_, ok = GetSignatureScheme(123456789)
+	require.False(t, ok)
+
+	require.Error(t, RegisterSignatureScheme(123456789, SignatureScheme{}))
+	scheme.Verify = func(pairing.Suite, []byte, []byte, []kyber.Point) error {
+		return nil
+	}
+	require.Error(t, RegisterSignatureScheme(BdnSignatureSchemeIndex, scheme))
+}
+
+func TestSignatureScheme_Verify(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	_, ro, _ := local.GenTree(SchnorrMaxRosterSize+1, false)
+	small := onet.NewRoster(ro.List[:4])
+
+	newBlock := func(scheme uint32, roster *onet.Roster) *SkipBlock {
+		sb := NewSkipBlock()
+		sb.SignatureScheme = scheme
+		sb.Roster = roster
+		return sb
+	}
+	bdn := newBlock(BdnSignatureSchemeIndex, small)
+	schnorr := newBlock(SchnorrSignatureSchemeIndex, small)
+
+	require.NoError(t, verifySignatureScheme(nil, bdn))
+	require.NoError(t, verifySignatureScheme(nil, schnorr))
+	require.Error(t, verifySignatureScheme(nil, newBlock(123456789, small)))
+	require.Error(t, verifySignatureScheme(nil,
+		newBlock(BlsSignatureSchemeIndex, small)))
+	require.Error(t, verifySignatureScheme(nil,
+		newBlock(SchnorrSignatureSchemeIndex, ro)))
+
+	// Existing BLS chains can still add blocks.
+	bls := newBlock(BlsSignatureSchemeIndex, small)
+	require.NoError(t, verifySignatureScheme(bls, bls))
+	// The scheme only changes with the roster, and never back to BLS.
+	require.Error(t, verifySignatureScheme(bdn, schnorr))
+	require.Error(t, verifySignatureScheme(bdn, bls))
+	other := onet.NewRoster(ro.List[1:5])
+	require.NoError(t, verifySignatureScheme(bdn,
+		newBlock(SchnorrSignatureSchemeIndex, other)))
+	require.Error(t, verifySignatureScheme(bdn,
+		newBlock(BlsSignatureSchemeIndex, other)))
+}
+
+func TestService_SchnorrScheme(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, ro, s := local.MakeSRS(cothority.Suite, 4, skipchainSID)
+	service := s.(*Service)
+
+	// A new chain using Schnorr multi-signatures.
+	sb := NewSkipBlock()
+	sb.Roster = ro
+	sb.MaximumHeight = 3
+	sb.BaseHeight = 2
+	sb.VerifierIDs = VerificationStandard
+	sb.SignatureScheme = SchnorrSignatureSchemeIndex
+	reply, err := service.StoreSkipBlock(&StoreSkipBlock{NewBlock: sb})
+	require.NoError(t, err)
+	genesis := reply.Latest
+	require.Equal(t, SchnorrSignatureSchemeIndex, genesis.SignatureScheme)
+	for i := 0; i < 4; i++ {
+		sb := NewSkipBlock()
+		sb.Roster = ro
+		latest, err := addBlockToChain(service, genesis.Hash, sb)
+		require.NoError(t, err)
+		require.Equal(t, SchnorrSignatureSchemeIndex, latest.SignatureScheme)
+	}
+	require.NoError(t, waitForwardLinks(service, genesis, 2))
+	proof, err := service.db.GetProof(genesis.Hash)
+	require.NoError(t, err)
+	require.NoError(t, Proof(proof).Verify())
+
+	// A BDN chain migrates to Schnorr when its roster changes.
+	sb = NewSkipBlock()
+	sb.Roster = onet.NewRoster(ro.List[:3])
+	sb.MaximumHeight = 3
+	sb.BaseHeight = 2
+	sb.VerifierIDs = VerificationStandard
+	reply, err = service.StoreSkipBlock(&StoreSkipBlock{NewBlock: sb})
+	require.NoError(t, err)
+	genesis = reply.Latest
+	require.Equal(t, BdnSignatureSchemeIndex, genesis.SignatureScheme)
+
+	// Without a new roster, the scheme is kept.
+	sb = NewSkipBlock()
+	sb.Roster = genesis.Roster
+	sb.SignatureScheme = SchnorrSignatureSchemeIndex
+	latest, err := addBlockToChain(service, genesis.Hash, sb)
+	require.NoError(t, err)
+	require.Equal(t, BdnSignatureSchemeIndex, latest.SignatureScheme)
+
+	sb = NewSkipBlock()
+	sb.Roster = ro
+	sb.SignatureScheme = SchnorrSignatureSchemeIndex
+	latest, err = addBlockToChain(service, genesis.Hash, sb)
+	require.NoError(t, err)
+	require.Equal(t, SchnorrSignatureSchemeIndex, latest.SignatureScheme)
+
+	sb = NewSkipBlock()
+	sb.Roster = ro
+	latest, err = addBlockToChain(service, genesis.Hash, sb)
+	require.NoError(t, err)
+	require.Equal(t, SchnorrSignatureSchemeIndex, latest.SignatureScheme)
+
+	proof, err = service.db.GetProof(genesis.Hash)
+	require.NoError(t, err)
+	require.NoError(t, Proof(proof).Verify())
+	require.Equal(t, 3, proof[len(proof)-1].Index)
+}
+
+func TestService_ThresholdBlsScheme(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer waitPropagationFinished(t, local)
+	defer local.CloseAll()
+	_, ro, s := local.MakeSRS(cothority.Suite, 4, skipchainSID)
+	service := s.(*Service)
+
+	// A BDN chain migrates to threshold BLS when its roster changes.
+	sb := NewSkipBlock()
+	sb.Roster = onet.NewRoster(ro.List[:3])
+	sb.MaximumHeight = 3
+	sb.BaseHeight = 2
+	sb.VerifierIDs = VerificationStandard
+	reply, err := service.StoreSkipBlock(&StoreSkipBlock{NewBlock: sb})
+	require.NoError(t, err)
+	genesis := reply.Latest
+
+	sb = NewSkipBlock()
+	sb.Roster = ro
+	sb.SignatureScheme = ThresholdBlsSignatureSchemeIndex
+	latest, err := addBlockToChain(service, genesis.Hash, sb)
+	require.NoError(t, err)
+	require.Equal(t, ThresholdBlsSignatureSchemeIndex, latest.SignatureScheme)
+	for i := 0; i < 4; i++ {
+		sb = NewSkipBlock()
+		sb.Roster = ro
+		latest, err = addBlockToChain(service, genesis.Hash, sb)
+		require.NoError(t, err)
+		require.Equal(t, ThresholdBlsSignatureSchemeIndex, latest.SignatureScheme)
+	}
+	require.NoError(t, waitForwardLinks(service, genesis, 2))
+
+	proof, err := service.db.GetProof(genesis.Hash)
+	require.NoError(t, err)
+	require.NoError(t, Proof(proof).Verify())
+	require.Equal(t, 5, proof[len(proof)-1].Index)
+
+	// The group signature is only valid with the keys certifying the group.
+	prev := service.db.GetByID(latest.BackLinkIDs[0])
+	require.NotNil(t, prev)
+	link := prev.ForwardLink[0]
+	require.NoError(t, link.VerifyWithScheme(suite,
+		ro.ServicePublics(ServiceName), prev.SignatureScheme))
+	require.Error(t, link.VerifyWithScheme(suite,
+		ro.ServicePublics(thresholdbls.ServiceName), prev.SignatureScheme))
+}
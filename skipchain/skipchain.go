@@ -27,6 +27,7 @@ import (
 	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/cothority/v3/byzcoinx"
 	"go.dedis.ch/cothority/v3/messaging"
+	"go.dedis.ch/cothority/v3/metrics"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
@@ -42,6 +43,10 @@ const bftNewBlock = "SkipchainBFTNew"
 const bftFollowBlock = "SkipchainBFTFollow"
 const bdnNewBlock = "SkipchainBDNNew"
 const bdnFollowBlock = "SkipchainBDNFollow"
+const schnorrNewBlock = "SkipchainSchnorrNew"
+const schnorrFollowBlock = "SkipchainSchnorrFollow"
+const tblsNewBlock = "SkipchainTblsNew"
+const tblsFollowBlock = "SkipchainTblsFollow"
 
 var storageKey = []byte("skipchainconfig")
 var dbVersion = 1
@@ -62,6 +67,7 @@ type Service struct {
 	propagateGenesis        messaging.PropagationFunc
 	propagateForwardLink    messaging.PropagationFunc
 	propagateProof          messaging.PropagationFunc
+	propagateEvidence       messaging.PropagationFunc
 	verifiers               map[VerifierID]SkipBlockVerifier
 	storageMutex            sync.Mutex
 	Storage                 *Storage
@@ -152,6 +158,9 @@ type Storage struct {
 	// to this service. Once a client is linked to a service, only blocks signed
 	// by this client will be allowed.
 	Clients []kyber.Point
+	// PruneKeep is the number of blocks whose payload is kept. Older
+	// payloads are dropped. If it is 0, no payload is dropped.
+	PruneKeep int `protobuf:"opt"`
 }
 
 // StoreSkipBlock stores a new skipblock in the system. This can be either a
@@ -220,8 +229,14 @@ func (s *Service) StoreSkipBlockInternal(psbd *StoreSkipBlock) (*StoreSkipBlockR
 		prop.GenesisID = nil
 		// starting with release v3.1.0, new skipchains default to BDN
 		// because BLS is vulnerable a known attack (see ../README.md
-		// about the release).
-		prop.SignatureScheme = BdnSignatureSchemeIndex
+		// about the release). Another scheme of the registry can be
+		// chosen in the genesis block.
+		if prop.SignatureScheme == BlsSignatureSchemeIndex {
+			prop.SignatureScheme = BdnSignatureSchemeIndex
+		}
+		if err := verifySignatureScheme(nil, prop); err != nil {
+			return nil, err
+		}
 		prop.updateHash()
 		err := s.verifyBlock(prop)
 		if err != nil {
@@ -303,7 +318,15 @@ func (s *Service) StoreSkipBlockInternal(psbd *StoreSkipBlock) (*StoreSkipBlockR
 		prop.Index = prev.Index + 1
 		prop.GenesisID = scID
 		prop.ForwardLink = []*ForwardLink{}
-		prop.SignatureScheme = prev.SignatureScheme
+		// The scheme is kept, unless the block asks to migrate to
+		// another one together with a new roster.
+		if prop.SignatureScheme == BlsSignatureSchemeIndex ||
+			prop.Roster.ID.Equal(prev.Roster.ID) {
+			prop.SignatureScheme = prev.SignatureScheme
+		}
+		if err := verifySignatureScheme(prev, prop); err != nil {
+			return nil, err
+		}
 		// And calculate the height of that block.
 		index := prop.Index
 		for prop.Height = 1; index%prop.BaseHeight == 0; prop.Height++ {
@@ -491,6 +514,8 @@ func (s *Service) OptimizeProof(req *OptimizeProofRequest) (*OptimizeProofReply,
 // SkipBlock we know. The last block in the returned slice of blocks is
 // not guaranteed to have no forward links. It is up to the caller
 // to continue following forward links with the new roster if necessary.
+// If the payload of one of the blocks has been pruned, ErrorBlockPruned is
+// returned, unless AllowPruned is set.
 func (s *Service) GetUpdateChain(guc *GetUpdateChain) (*GetUpdateChainReply, error) {
 	block := s.db.GetByID(guc.LatestID)
 	if block == nil {
@@ -543,6 +568,14 @@ func (s *Service) GetUpdateChain(guc *GetUpdateChain) (*GetUpdateChainReply, err
 		}
 	}
 
+	if !guc.AllowPruned {
+		for _, b := range blocks {
+			if s.db.IsPruned(b) {
+				return nil, ErrorBlockPruned
+			}
+		}
+	}
+
 	log.Lvlf3("Found %d blocks", len(blocks))
 	reply := &GetUpdateChainReply{Update: blocks}
 
@@ -555,6 +588,13 @@ func (s *Service) RegisterStoreSkipblockCallback(f func(SkipBlockID) error) {
 	s.db.callback = f
 }
 
+// RegisterPruneLimit sets the function returning the index of the first
+// block of a skipchain whose payload must not be pruned, or -1 if there is
+// no limit. It is called before every pruning of the chain.
+func (s *Service) RegisterPruneLimit(f func(SkipBlockID) int) {
+	s.db.SetPruningLimit(f)
+}
+
 // SyncChain communicates with conodes in the Roster via getBlocks
 // in order traverse the chain and save the blocks locally. It starts with
 // the given 'latest' skipblockid and fetches all blocks up to the latest block.
@@ -625,6 +665,7 @@ func (s *Service) getBlocks(roster *onet.Roster, id SkipBlockID, n int) ([]*Skip
 		if err := Proof(result).VerifyFromID(id); err != nil {
 			return nil, err
 		}
+		s.findProofForks(result)
 		return result, nil
 	case <-time.After(s.propTimeout):
 		pisc.Done()
@@ -672,12 +713,29 @@ func (s *Service) GetSingleBlock(id *GetSingleBlock) (*SkipBlock, error) {
 		return nil, errors.New("No such block")
 
 	}
+	if s.db.IsPruned(sb) {
+		return nil, ErrorBlockPruned
+	}
 	return sb, nil
 }
 
 // GetSingleBlockByIndex searches for the given block and returns it. If no such block is
-// found, a nil is returned.
+// found, a nil is returned. If the payload of the block has been pruned,
+// ErrorBlockPruned is returned.
 func (s *Service) GetSingleBlockByIndex(id *GetSingleBlockByIndex) (*GetSingleBlockByIndexReply, error) {
+	reply, err := s.getSingleBlockByIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.db.IsPruned(reply.SkipBlock) {
+		return nil, ErrorBlockPruned
+	}
+	return reply, nil
+}
+
+// getSingleBlockByIndex follows the forward-links from the genesis block to
+// the block with the given index.
+func (s *Service) getSingleBlockByIndex(id *GetSingleBlockByIndex) (*GetSingleBlockByIndexReply, error) {
 	sb := s.db.GetByID(id.Genesis)
 	if sb == nil {
 		return nil, errors.New("No such genesis-block")
@@ -1042,6 +1100,21 @@ func (s *Service) SetBFTTimeout(t time.Duration) {
 	s.bftTimeout = t
 }
 
+// SetPruning makes the service drop the payloads of the blocks that are
+// older than keep blocks. The headers and the forward-links are kept for the
+// proofs. A value of 0 disables the pruning. The setting is saved to disk.
+//
+// Nodes that have pruned blocks cannot serve them anymore to new nodes
+// catching up, so these need to download the state, e.g. with
+// byzcoin.DownloadState.
+func (s *Service) SetPruning(keep int) {
+	s.storageMutex.Lock()
+	s.Storage.PruneKeep = keep
+	s.storageMutex.Unlock()
+	s.db.SetPruning(keep)
+	s.save()
+}
+
 // SetPropTimeout is used to set the propagation timeout.
 func (s *Service) SetPropTimeout(t time.Duration) {
 	s.propTimeout = t
@@ -1131,6 +1204,9 @@ func (s *Service) forwardLinkLevel0(src, dst *SkipBlock) error {
 		return fmt.Errorf("Couldn't marshal block: %s", err.Error())
 	}
 	fwd := NewForwardLink(src, dst)
+	if err := s.prepareSignatureScheme(src.SignatureScheme, roster); err != nil {
+		return err
+	}
 	protoName, _ := src.SignatureProtocol()
 	sig, err := s.startBFT(protoName, roster, dst.Roster, fwd.Hash(), data)
 	if err != nil {
@@ -1375,6 +1451,9 @@ func (s *Service) ForwardLinkHandler(req *ForwardSignature) (*ForwardSignatureRe
 			return nil, err
 		}
 		fl := NewForwardLink(from, fs.Newest)
+		if err := s.prepareSignatureScheme(from.SignatureScheme, from.Roster); err != nil {
+			return nil, err
+		}
 		_, protoName := from.SignatureProtocol()
 		sig, err := s.startBFT(protoName, from.Roster, fs.Newest.Roster, fl.Hash(), data)
 		if err != nil {
@@ -1447,12 +1526,24 @@ func (s *Service) bftForwardLink(msg, data []byte) bool {
 			return errors.New("link list should not be empty")
 		}
 
+		// Each link is signed with the scheme of its source block, which
+		// is found backwards from the newest block.
+		schemes := make([]uint32, len(fs.Links))
+		scheme := dst.SignatureScheme
+		for i := len(fs.Links) - 1; i >= 0; i-- {
+			scheme = fs.Links[i].FromSignatureScheme(scheme)
+			schemes[i] = scheme
+		}
+		if schemes[0] != src.SignatureScheme {
+			return errors.New("first link doesn't use the scheme of the source-block")
+		}
+
 		newRoster := src.Roster
 
 		for i, fl := range fs.Links {
 			publics := newRoster.ServicePublics(ServiceName)
 
-			if err := fl.VerifyWithScheme(suite, publics, src.SignatureScheme); err != nil {
+			if err := fl.VerifyWithScheme(suite, publics, schemes[i]); err != nil {
 				return errors.New("verification failed: " + err.Error())
 			}
 			if fl.NewRoster != nil {
@@ -1671,6 +1762,7 @@ func (s *Service) propagateProofHandler(msg network.Message) error {
 	if err := pc.Proof.Verify(); err != nil {
 		return fmt.Errorf("Proof verification failed with: %s", err.Error())
 	}
+	s.findProofForks(pc.Proof)
 
 	_, err := s.db.StoreBlocks(pc.Proof)
 	if err != nil {
@@ -1865,6 +1957,7 @@ func (s *Service) tryLoad() error {
 	for i := range s.Storage.Follow {
 		s.Storage.Follow[i].closing = make(chan bool)
 	}
+	s.db.SetPruning(s.Storage.PruneKeep)
 	return nil
 }
 
@@ -1876,6 +1969,21 @@ func sliceToArr(msg []byte) [32]byte {
 	return arr
 }
 
+// registerMetrics adds the size of the database to the metrics of the
+// conode. The size is only read when the metrics are collected.
+func (s *Service) registerMetrics() {
+	r := metrics.ForServer(s.ServerIdentity())
+	dbSize := r.Gauge("conode_db_size_bytes", "Size of the database of the conode")
+	r.OnCollect(func() {
+		size, err := s.db.Size()
+		if err != nil {
+			log.Error("couldn't read the size of the database:", err)
+			return
+		}
+		dbSize.Set(float64(size))
+	})
+}
+
 func newSkipchainService(c *onet.Context) (onet.Service, error) {
 	db, bucket := c.GetAdditionalBucket([]byte("skipblocks"))
 	s := &Service{
@@ -1895,8 +2003,10 @@ func newSkipchainService(c *onet.Context) (onet.Service, error) {
 		s.GetSingleBlock, s.GetSingleBlockByIndex, s.GetAllSkipchains,
 		s.GetAllSkipChainIDs, s.OptimizeProof,
 		s.CreateLinkPrivate, s.Unlink, s.AddFollow, s.ListFollow,
-		s.DelFollow, s.Listlink, s.ForwardLinkHandler))
+		s.DelFollow, s.Listlink, s.ForwardLinkHandler, s.GetForkEvidence,
+		s.GetCompactProof))
 	s.ServiceProcessor.RegisterStatusReporter("Skipblock", s.db)
+	s.registerMetrics()
 	// Deprecated: the handler should be used instead
 	s.RegisterProcessorFunc(network.RegisterMessage(&ForwardSignature{}), s.forwardLink)
 
@@ -1917,26 +2027,13 @@ func newSkipchainService(c *onet.Context) (onet.Service, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Register ByzCoinX protocols for BLS
-	err = byzcoinx.InitBFTCoSiProtocol(suite, s.Context,
-		s.bftForwardLinkLevel0, s.bftForwardLinkLevel0Ack, bftNewBlock)
+	s.propagateEvidence, err = messaging.NewPropagationFunc(c, "SkipchainPropagateEvidence", s.propagateForkEvidenceHandler, -1)
 	if err != nil {
 		return nil, err
 	}
-	err = byzcoinx.InitBFTCoSiProtocol(suite, s.Context,
-		s.bftForwardLink, s.bftForwardLinkAck, bftFollowBlock)
-	if err != nil {
-		return nil, err
-	}
-	// Register ByzCoinX protocols for BDN
-	err = byzcoinx.InitBDNCoSiProtocol(suite, s.Context,
-		s.bftForwardLinkLevel0, s.bftForwardLinkLevel0Ack, bdnNewBlock)
-	if err != nil {
-		return nil, err
-	}
-	err = byzcoinx.InitBDNCoSiProtocol(suite, s.Context,
-		s.bftForwardLink, s.bftForwardLinkAck, bdnFollowBlock)
-	if err != nil {
+	s.db.forkCallback = s.propagateForkEvidence
+	// Register the ByzCoinX protocols of all signature schemes
+	if err := s.initSignatureSchemes(); err != nil {
 		return nil, err
 	}
 
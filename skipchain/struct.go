@@ -12,8 +12,6 @@ import (
 	"sync"
 	"time"
 
-	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
-	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/cothority/v3/byzcoinx"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
@@ -488,14 +486,11 @@ func (sb *SkipBlock) pathForIndex(targetIndex int) (int, int) {
 // shall be used for forward link level 0 and the second shall be used to
 // create higher level forward links.
 func (sb *SkipBlock) SignatureProtocol() (string, string) {
-	switch sb.SignatureScheme {
-	case BlsSignatureSchemeIndex:
-		return bftNewBlock, bftFollowBlock
-	case BdnSignatureSchemeIndex:
-		return bdnNewBlock, bdnFollowBlock
-	default:
+	scheme, ok := GetSignatureScheme(sb.SignatureScheme)
+	if !ok {
 		return "", ""
 	}
+	return scheme.NewBlockProtocol, scheme.FollowBlockProtocol
 }
 
 // CalculateHash hashes all fixed fields of the skipblock.
@@ -505,7 +500,7 @@ func (sb *SkipBlock) CalculateHash() SkipBlockID {
 		sb.BaseHeight} {
 		err := binary.Write(hash, binary.LittleEndian, int32(i))
 		if err != nil {
-			panic("error writing to hash:" + err.Error())
+			panic("error writing to hash: " + err.Error())
 		}
 	}
 
@@ -626,14 +621,58 @@ func (sbs Proof) verifyChain() error {
 	return nil
 }
 
-// Signature schemes should be ordered by robustness such that for any
-// x < y, S(x) <= S(y) where S(i) quantify the security of the signature
-// scheme at index i
+// verifyPath checks that the proof starts at a genesis block and that every
+// block is linked to the next one by a correctly signed forward-link of any
+// height. Contrary to Verify, the highest forward-link doesn't need to be
+// used, which is the case for the proofs returned by GetProofForID.
+func (sbs Proof) verifyPath() error {
+	if len(sbs) == 0 {
+		return errors.New("Empty list of blocks")
+	}
+	if sbs[0].Index != 0 {
+		return errors.New("First element must be a genesis")
+	}
+	for i, sb := range sbs {
+		if !sb.CalculateHash().Equal(sb.Hash) {
+			return errors.New("Wrong hash")
+		}
+		if i == len(sbs)-1 {
+			break
+		}
+		next := sbs[i+1]
+		var link *ForwardLink
+		for _, fl := range sb.ForwardLink {
+			if !fl.IsEmpty() && fl.To.Equal(next.Hash) {
+				link = fl
+			}
+		}
+		if link == nil || !link.From.Equal(sb.Hash) {
+			return errors.New("Missing forward link")
+		}
+		if err := link.VerifyWithScheme(suite,
+			sb.Roster.ServicePublics(ServiceName), sb.SignatureScheme); err != nil {
+			return err
+		}
+		if next.Index <= sb.Index {
+			return ErrorInconsistentForwardLink
+		}
+	}
+	return nil
+}
+
+// Indexes of the signature schemes defined by the skipchain package. Other
+// schemes can be added with RegisterSignatureScheme.
 const (
 	// BlsSignatureSchemeIndex is the index for BLS signatures
 	BlsSignatureSchemeIndex = uint32(iota)
 	// BdnSignatureSchemeIndex is the index for BDN signatures
 	BdnSignatureSchemeIndex
+	// SchnorrSignatureSchemeIndex is the index for Schnorr
+	// multi-signatures
+	SchnorrSignatureSchemeIndex
+	// ThresholdBlsSignatureSchemeIndex is the index for threshold BLS
+	// signatures of the thresholdbls service
+	ThresholdBlsSignatureSchemeIndex
 )
 
 // ForwardLink can be used to jump from old blocks to newer
@@ -648,10 +687,14 @@ type ForwardLink struct {
 	// different roster from the To-block.
 	NewRoster *onet.Roster
 	// Signature is calculated on the
-	// sha256(From.Hash()|To.Hash()|NewRoster)
+	// sha256(From.Hash()|To.Hash()|NewRoster|SignatureScheme)
 	// In the case that NewRoster is nil, the signature is
 	// calculated on the sha256(From.Hash()|To.Hash())
 	Signature byzcoinx.FinalSignature
+	// SignatureScheme is only set to non-nil if the From block has a
+	// different signature scheme from the To-block. It is the scheme of
+	// the From block, used to sign the link.
+	SignatureScheme *uint32 `protobuf:"opt"`
 }
 
 // NewForwardLink creates a new forwardlink structure with
@@ -667,6 +710,10 @@ func NewForwardLink(from, to *SkipBlock) *ForwardLink {
 		!from.Roster.ID.Equal(to.Roster.ID) {
 		fl.NewRoster = to.Roster
 	}
+	if from.SignatureScheme != to.SignatureScheme {
+		scheme := from.SignatureScheme
+		fl.SignatureScheme = &scheme
+	}
 	return fl
 }
 
@@ -681,9 +728,24 @@ func (fl *ForwardLink) Hash() SkipBlockID {
 	if fl.NewRoster != nil {
 		hash.Write(fl.NewRoster.ID[:])
 	}
+	if fl.SignatureScheme != nil {
+		err := binary.Write(hash, binary.LittleEndian, *fl.SignatureScheme)
+		if err != nil {
+			panic("error writing to hash: " + err.Error())
+		}
+	}
 	return hash.Sum(nil)
 }
 
+// FromSignatureScheme returns the index of the signature scheme of the link,
+// which is the one of the From block, given the scheme of the To block.
+func (fl *ForwardLink) FromSignatureScheme(to uint32) uint32 {
+	if fl.SignatureScheme != nil {
+		return *fl.SignatureScheme
+	}
+	return to
+}
+
 // Copy makes a deep copy of a ForwardLink
 func (fl *ForwardLink) Copy() *ForwardLink {
 	var newRoster *onet.Roster
@@ -691,14 +753,20 @@ func (fl *ForwardLink) Copy() *ForwardLink {
 		newRoster = onet.NewRoster(fl.NewRoster.List)
 		newRoster.ID = onet.RosterID([uuid.Size]byte(fl.NewRoster.ID))
 	}
+	var scheme *uint32
+	if fl.SignatureScheme != nil {
+		s := *fl.SignatureScheme
+		scheme = &s
+	}
 	return &ForwardLink{
 		Signature: byzcoinx.FinalSignature{
 			Sig: append([]byte{}, fl.Signature.Sig...),
 			Msg: append([]byte{}, fl.Signature.Msg...),
 		},
-		From:      append([]byte{}, fl.From...),
-		To:        append([]byte{}, fl.To...),
-		NewRoster: newRoster,
+		From:            append([]byte{}, fl.From...),
+		To:              append([]byte{}, fl.To...),
+		NewRoster:       newRoster,
+		SignatureScheme: scheme,
 	}
 }
 
@@ -710,21 +778,19 @@ func (fl *ForwardLink) Verify(suite *pairing.SuiteBn256, pubs []kyber.Point) err
 }
 
 // VerifyWithScheme checks the signature against a list of public keys with
-// a given scheme. The list must correspond to the block roster to match the
-// signature. It returns nil if the signature is correct, or an error if not.
+// a given scheme of the registry. The list must correspond to the block
+// roster to match the signature. It returns nil if the signature is correct,
+// or an error if not.
 func (fl *ForwardLink) VerifyWithScheme(suite *pairing.SuiteBn256, pubs []kyber.Point, scheme uint32) error {
 	if bytes.Compare(fl.Signature.Msg, fl.Hash()) != 0 {
 		return errors.New("wrong hash of forward link")
 	}
 
-	switch scheme {
-	case BlsSignatureSchemeIndex:
-		return protocol.BlsSignature(fl.Signature.Sig).Verify(suite, fl.Signature.Msg, pubs)
-	case BdnSignatureSchemeIndex:
-		return bdnproto.BdnSignature(fl.Signature.Sig).Verify(suite, fl.Signature.Msg, pubs)
-	default:
+	s, ok := GetSignatureScheme(scheme)
+	if !ok {
 		return errors.New("unknown signature scheme")
 	}
+	return s.Verify(suite, fl.Signature.Msg, fl.Signature.Sig, pubs)
 }
 
 // IsEmpty indicates whether this forwardlink is merely a placeholder for
@@ -743,6 +809,14 @@ type SkipBlockDB struct {
 	latestBlocks map[string]SkipBlockID
 	latestMutex  sync.Mutex
 	callback     func(SkipBlockID) error
+	// pruneKeep is the number of blocks whose payload is kept, or 0 if
+	// pruning is disabled.
+	pruneKeep int
+	// pruneLimit returns the index of the first block of a skipchain whose
+	// payload must never be pruned, or -1 if there is no limit.
+	pruneLimit func(SkipBlockID) int
+	// forkCallback is called for every new evidence of a fork.
+	forkCallback func(*ForkEvidence)
 }
 
 // NewSkipBlockDB returns an initialized SkipBlockDB structure.
@@ -773,6 +847,16 @@ func (db *SkipBlockDB) GetStatus() *onet.Status {
 	return &onet.Status{Field: out}
 }
 
+// Size returns the size in bytes of the whole database file, which holds the
+// data of all the services of the conode.
+func (db *SkipBlockDB) Size() (size int64, err error) {
+	err = db.DB.View(func(tx *bbolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	return
+}
+
 // GetByID returns a new copy of the skip-block or nil if it doesn't exist
 func (db *SkipBlockDB) GetByID(sbID SkipBlockID) *SkipBlock {
 	var result *SkipBlock
@@ -798,7 +882,9 @@ func (db *SkipBlockDB) GetByID(sbID SkipBlockID) *SkipBlock {
 // so that the db is consistent at every moment.
 func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 	var result []SkipBlockID
+	var evidences []*ForkEvidence
 	err := db.Update(func(tx *bbolt.Tx) error {
+		evidences = nil
 		for i, sb := range blocks {
 			log.Lvlf2("Storing skipblock %d / %x", sb.Index, sb.Hash)
 			sbOld, err := db.getFromTx(tx, sb.Hash)
@@ -806,6 +892,9 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 				return errors.New("failed to get skipblock with error: " + err.Error())
 			}
 			if sbOld != nil {
+				// Conflicting forward-links are kept as evidence of a fork.
+				evidences = append(evidences,
+					db.detectForks(tx, sbOld, sb, blocks)...)
 				numFL := len(sbOld.ForwardLink)
 				// If this skipblock already exists, only copy forward-links and
 				// new children.
@@ -909,6 +998,8 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 			}
 		}
 	}
+	db.handleForks(evidences)
+	db.pruneStored(result)
 
 	return result, err
 }
@@ -1023,7 +1114,7 @@ func (db *SkipBlockDB) VerifyLinks(sb *SkipBlock) error {
 	if err := sbBack.VerifyForwardSignatures(); err != nil {
 		return err
 	}
-	if !sbBack.GetForward(0).Hash().Equal(sb.Hash) {
+	if fl := sbBack.GetForward(0); fl == nil || !fl.To.Equal(sb.Hash) {
 		return errors.New("didn't find our block in forward-links")
 	}
 	return nil
@@ -1192,8 +1283,18 @@ func (db *SkipBlockDB) GetProof(sid SkipBlockID) (sbs []*SkipBlock, err error) {
 // GetProofForID returns the shortest chain known from the genesis to the given
 // block using the highest forward-links available in the local db.
 func (db *SkipBlockDB) GetProofForID(bid SkipBlockID) (sbs Proof, err error) {
+	target := db.GetByID(bid)
+	if target == nil {
+		return nil, errors.New("couldn't find the block")
+	}
+	return db.GetProofBetween(target.SkipChainID(), bid)
+}
+
+// GetProofBetween returns the shortest list of blocks linked by forward-links
+// from the block from to the block to, which must be in the same skipchain.
+func (db *SkipBlockDB) GetProofBetween(from, to SkipBlockID) (sbs Proof, err error) {
 	err = db.View(func(tx *bbolt.Tx) error {
-		target, err := db.getFromTx(tx, bid)
+		target, err := db.getFromTx(tx, to)
 		if err != nil {
 			return err
 		}
@@ -1201,18 +1302,22 @@ func (db *SkipBlockDB) GetProofForID(bid SkipBlockID) (sbs Proof, err error) {
 			return errors.New("couldn't find the block")
 		}
 
-		sb, err := db.getFromTx(tx, target.SkipChainID())
+		sb, err := db.getFromTx(tx, from)
 		if err != nil {
 			return err
 		}
 		if sb == nil {
-			// It should never happen if the previous is found.
-			return errors.New("couldn't find the genesis block")
+			return errors.New("couldn't find the first block")
+		}
+		if !sb.SkipChainID().Equal(target.SkipChainID()) ||
+			sb.Index > target.Index {
+			return errors.New("the first block is not before the target " +
+				"in the same skipchain")
 		}
 
 		sbs = append(sbs, sb)
 
-		for !sb.Hash.Equal(bid) && len(sb.ForwardLink) > 0 {
+		for !sb.Hash.Equal(to) && len(sb.ForwardLink) > 0 {
 			diff := math.Log(float64(target.Index - sb.Index))
 			base := math.Log(float64(sb.BaseHeight))
 			maxHeight := 0
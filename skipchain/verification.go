@@ -34,9 +34,11 @@ func (s *Service) verifyFuncBase(newID []byte, newSB *SkipBlock) bool {
 	if prev.Index+1 != newSB.Index {
 		return false
 	}
-	if prev.SignatureScheme > newSB.SignatureScheme {
-		// the signature scheme can only have an index higher than the previous blocks
-		// so that no one can downgrade the verification
+	if err := verifySignatureScheme(prev, newSB); err != nil {
+		// the signature scheme can only change together with the roster
+		// and never to a deprecated scheme, so that no one can downgrade
+		// the verification
+		log.Lvl2("Wrong signature scheme:", err)
 		return false
 	}
 
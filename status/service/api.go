@@ -33,6 +33,16 @@ func (c *Client) Request(dst *network.ServerIdentity) (*Response, error) {
 	return resp, nil
 }
 
+// Metrics returns the metrics of the conode.
+func (c *Client) Metrics(dst *network.ServerIdentity) (*MetricsResponse, error) {
+	resp := &MetricsResponse{}
+	err := c.SendProtobuf(dst, &MetricsRequest{}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // CheckConnectivity sends a message from all nodes to all nodes in the list
 // and checks if all messages are received correctly. If findFaulty == true,
 // then the service will try very hard to get a list of nodes that can
@@ -46,3 +46,37 @@ type CheckConnectivity struct {
 type CheckConnectivityReply struct {
 	Nodes []*network.ServerIdentity
 }
+
+// MetricsRequest asks a conode for its metrics.
+type MetricsRequest struct {
+}
+
+// MetricsResponse holds the metrics of the conode, both structured and in the
+// text format of Prometheus.
+type MetricsResponse struct {
+	Families []MetricFamily
+	Text     string
+}
+
+// MetricFamily is a metric with all its label values. The Type is one of
+// counter, gauge or histogram.
+type MetricFamily struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels []string
+	Series []MetricSeries
+}
+
+// MetricSeries is the value of a metric for a set of label values. Counters
+// and gauges only have a Value. Histograms have the upper bounds of their
+// Buckets, the cumulative Counts of the observations in each bucket, and the
+// Sum and Count of all observations.
+type MetricSeries struct {
+	LabelValues []string
+	Value       float64
+	Buckets     []float64
+	Counts      []uint64
+	Sum         float64
+	Count       uint64
+}
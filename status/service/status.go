@@ -7,9 +7,11 @@
 package status
 
 import (
+	"bytes"
 	"errors"
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/messaging"
+	"go.dedis.ch/cothority/v3/metrics"
 	"go.dedis.ch/kyber/v3/sign/schnorr"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -54,6 +56,38 @@ func (st *Stat) Request(req *Request) (network.Message, error) {
 	}, nil
 }
 
+// Metrics returns the metrics of the conode. The same metrics can be
+// scraped by Prometheus if the conode serves them over HTTP.
+func (st *Stat) Metrics(req *MetricsRequest) (*MetricsResponse, error) {
+	families := metrics.ForServer(st.ServerIdentity()).Snapshot()
+	var text bytes.Buffer
+	if err := metrics.WriteText(&text, families); err != nil {
+		return nil, errors.New("couldn't write metrics: " + err.Error())
+	}
+
+	resp := &MetricsResponse{Text: text.String()}
+	for _, f := range families {
+		mf := MetricFamily{
+			Name:   f.Name,
+			Help:   f.Help,
+			Type:   f.Type,
+			Labels: f.Labels,
+		}
+		for _, s := range f.Series {
+			mf.Series = append(mf.Series, MetricSeries{
+				LabelValues: s.LabelValues,
+				Value:       s.Value,
+				Buckets:     s.Buckets,
+				Counts:      s.Counts,
+				Sum:         s.Sum,
+				Count:       s.Count,
+			})
+		}
+		resp.Families = append(resp.Families, mf)
+	}
+	return resp, nil
+}
+
 var errTimeout = errors.New("timeout while waiting for replies")
 
 // CheckConnectivity does an all-by-all connectivity test
@@ -140,7 +174,7 @@ func newStatService(c *onet.Context) (onet.Service, error) {
 	s := &Stat{
 		ServiceProcessor: onet.NewServiceProcessor(c),
 	}
-	err := s.RegisterHandlers(s.Request, s.CheckConnectivity)
+	err := s.RegisterHandlers(s.Request, s.CheckConnectivity, s.Metrics)
 	if err != nil {
 		return nil, errors.New("couldn't register handlers: " + err.Error())
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.dedis.ch/cothority/v3/metrics"
 	"go.dedis.ch/kyber/v3/suites"
 	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
@@ -70,3 +71,25 @@ func TestStat_Request(t *testing.T) {
 	log.Lvl1(stat)
 	assert.NotEmpty(t, stat.Status["Generic"].Field["Available_Services"])
 }
+
+func TestStat_Metrics(t *testing.T) {
+	local := onet.NewTCPTest(tSuite)
+	servers, el, _ := local.GenTree(2, false)
+	defer local.CloseAll()
+
+	// Every conode has its own registry.
+	metrics.ForServer(servers[0].ServerIdentity).Counter("test_total",
+		"Test counter.", "kind").Inc("a")
+
+	client := NewTestClient(local)
+	resp, err := client.Metrics(el.List[0])
+	require.NoError(t, err)
+	require.Contains(t, resp.Text, `test_total{kind="a"} 1`)
+	require.Len(t, resp.Families, 1)
+	require.Equal(t, "counter", resp.Families[0].Type)
+	require.Equal(t, []string{"a"}, resp.Families[0].Series[0].LabelValues)
+
+	resp, err = client.Metrics(el.List[1])
+	require.NoError(t, err)
+	require.Empty(t, resp.Families)
+}
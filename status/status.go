@@ -70,6 +70,13 @@ func main() {
 			},
 			Action: connectivity,
 		},
+		{
+			Name:      "metrics",
+			Usage:     "print the metrics of all nodes in the text format of Prometheus",
+			Aliases:   []string{"m"},
+			ArgsUsage: "group.toml",
+			Action:    printMetrics,
+		},
 	}
 	app.Action = func(c *cli.Context) error {
 		log.SetUseColors(false)
@@ -190,6 +197,26 @@ func connectivity(c *cli.Context) error {
 	return nil
 }
 
+func printMetrics(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("please give the group.toml")
+	}
+	ro, err := readGroup(c.Args().First())
+	if err != nil {
+		return errors.New("couldn't read file: " + err.Error())
+	}
+	cl := status.NewClient()
+	for _, si := range ro.List {
+		resp, err := cl.Metrics(si)
+		if err != nil {
+			log.Errorf("could not get metrics from %v: %v", si, err)
+			continue
+		}
+		fmt.Printf("# conode %s\n%s", si.Address, resp.Text)
+	}
+	return nil
+}
+
 // readGroup takes a toml file name and reads the file, returning the entities
 // within.
 func readGroup(tomlFileName string) (*onet.Roster, error) {
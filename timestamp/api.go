@@ -0,0 +1,60 @@
+package timestamp
+
+import (
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
+)
+
+// Client is a structure to communicate with the timestamp service.
+type Client struct {
+	*onet.Client
+}
+
+// NewClient instantiates a new timestamp.Client
+func NewClient() *Client {
+	return &Client{Client: onet.NewClient(cothority.Suite, ServiceName)}
+}
+
+// Stamp asks the first node of the roster to timestamp the hash. It returns
+// once the round of the hash is signed.
+func (c *Client) Stamp(r *onet.Roster, hash []byte) (*StampResponse, error) {
+	if len(r.List) == 0 {
+		return nil, xerrors.New("got an empty roster-list")
+	}
+	reply := &StampResponse{}
+	err := c.SendProtobuf(r.List[0], &StampRequest{
+		Roster: r,
+		Hash:   hash,
+	}, reply)
+	return reply, cothority.ErrorOrNil(err, "sending stamp request")
+}
+
+// SetAnchor asks the node to anchor the rounds it leads for the roster into
+// the given timestampAnchor instance. The request is signed with the private
+// key of the node, and the node must be allowed to invoke "anchor" on the
+// instance.
+func (c *Client) SetAnchor(who *network.ServerIdentity, r *onet.Roster,
+	bcID skipchain.SkipBlockID, bcRoster *onet.Roster,
+	instID byzcoin.InstanceID) error {
+	req := &SetAnchor{
+		Roster:        r,
+		ByzCoinID:     bcID,
+		ByzCoinRoster: bcRoster,
+		InstanceID:    instID,
+		Timestamp:     time.Now().Unix(),
+	}
+	sig, err := schnorr.Sign(cothority.Suite, who.GetPrivate(), req.Hash())
+	if err != nil {
+		return xerrors.Errorf("creating schnorr signature: %v", err)
+	}
+	req.Signature = sig
+	err = c.SendProtobuf(who, req, &SetAnchorResponse{})
+	return cothority.ErrorOrNil(err, "sending SetAnchor message")
+}
@@ -0,0 +1,101 @@
+package timestamp
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ContractTimestampAnchorID is the ID of the contract anchoring the rounds
+// of a timestamp roster into ByzCoin.
+const ContractTimestampAnchorID = "timestampAnchor"
+
+// contractAnchor keeps the latest round of a timestamp roster. It is spawned
+// with the roster in the "roster" argument. The "anchor" command takes a
+// round in the "round" argument and only accepts it if it is signed by the
+// roster and newer than the current one.
+type contractAnchor struct {
+	byzcoin.BasicContract
+	AnchorData
+}
+
+func contractAnchorFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractAnchor{}
+	err := protobuf.DecodeWithConstructors(in, &c.AnchorData,
+		network.DefaultConstructors(cothority.Suite))
+	return c, cothority.ErrorOrNil(err, "decoding anchor data")
+}
+
+// Spawn creates an anchor instance for the roster of the "roster" argument.
+func (c *contractAnchor) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("getting values: %v", err)
+	}
+
+	var roster onet.Roster
+	err = protobuf.DecodeWithConstructors(inst.Spawn.Args.Search("roster"),
+		&roster, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("decoding roster: %v", err)
+	}
+	if len(roster.List) == 0 {
+		return nil, nil, xerrors.New("need a roster in the 'roster' argument")
+	}
+	buf, err := protobuf.Encode(&AnchorData{Roster: roster})
+	if err != nil {
+		return nil, nil, xerrors.Errorf("encoding anchor data: %v", err)
+	}
+
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""),
+			ContractTimestampAnchorID,
+			buf, darcID),
+	}
+	return
+}
+
+// Invoke anchors a new round with the "anchor" command.
+func (c *contractAnchor) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("getting values: %v", err)
+	}
+	if inst.Invoke.Command != "anchor" {
+		return nil, nil, xerrors.Errorf("unknown command: %s",
+			inst.Invoke.Command)
+	}
+
+	var round Round
+	err = protobuf.Decode(inst.Invoke.Args.Search("round"), &round)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("decoding round: %v", err)
+	}
+	if round.Time <= c.Round.Time {
+		return nil, nil, xerrors.New("round is not newer than the anchored one")
+	}
+	if err := round.Verify(&c.Roster); err != nil {
+		return nil, nil, err
+	}
+
+	c.Round = round
+	buf, err := protobuf.Encode(&c.AnchorData)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("encoding anchor data: %v", err)
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID,
+			ContractTimestampAnchorID, buf, darcID),
+	}
+	return
+}
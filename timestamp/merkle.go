@@ -0,0 +1,98 @@
+package timestamp
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// The hashes of a round are the leaves of a Merkle tree. Leaves and inner
+// nodes are hashed with different prefixes, so that an inner node cannot be
+// presented as a leaf. If a level has an odd number of nodes, the last one is
+// carried up to the next level unchanged.
+const (
+	leafPrefix = 0
+	nodePrefix = 1
+)
+
+// PathStep is one level of an inclusion path.
+type PathStep struct {
+	// Hash is the hash of the sibling node.
+	Hash []byte
+	// Left is true if the sibling is on the left.
+	Left bool
+}
+
+// Path is the inclusion path of a hash in the Merkle tree of a round, from the
+// leaf up to the root.
+type Path []PathStep
+
+// Root returns the root of the tree that is obtained by following the path
+// from the given hash.
+func (p Path) Root(hash []byte) []byte {
+	h := leafHash(hash)
+	for _, step := range p {
+		if step.Left {
+			h = nodeHash(step.Hash, h)
+		} else {
+			h = nodeHash(h, step.Hash)
+		}
+	}
+	return h
+}
+
+// Includes returns true if the path leads from the hash to the root.
+func (p Path) Includes(hash, root []byte) bool {
+	return bytes.Equal(p.Root(hash), root)
+}
+
+func leafHash(hash []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(hash)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// buildTree returns the root of the Merkle tree of the hashes and the
+// inclusion path of every hash. There must be at least one hash.
+func buildTree(hashes [][]byte) ([]byte, []Path) {
+	paths := make([]Path, len(hashes))
+	level := make([][]byte, len(hashes))
+	// members[i] holds the indexes of the leaves below the i-th node of
+	// the current level.
+	members := make([][]int, len(hashes))
+	for i, hash := range hashes {
+		level[i] = leafHash(hash)
+		members[i] = []int{i}
+	}
+
+	for len(level) > 1 {
+		var next [][]byte
+		var nextMembers [][]int
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				nextMembers = append(nextMembers, members[i])
+				continue
+			}
+			for _, m := range members[i] {
+				paths[m] = append(paths[m], PathStep{Hash: level[i+1]})
+			}
+			for _, m := range members[i+1] {
+				paths[m] = append(paths[m], PathStep{Hash: level[i], Left: true})
+			}
+			next = append(next, nodeHash(level[i], level[i+1]))
+			nextMembers = append(nextMembers,
+				append(append([]int{}, members[i]...), members[i+1]...))
+		}
+		level, members = next, nextMembers
+	}
+	return level[0], paths
+}
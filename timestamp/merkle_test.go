@@ -0,0 +1,32 @@
+package timestamp
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTree(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		var hashes [][]byte
+		for i := 0; i < n; i++ {
+			h := sha256.Sum256([]byte{byte(n), byte(i)})
+			hashes = append(hashes, h[:])
+		}
+		root, paths := buildTree(hashes)
+		require.Equal(t, n, len(paths))
+		for i, hash := range hashes {
+			require.True(t, paths[i].Includes(hash, root))
+			require.False(t, paths[i].Includes(root, root))
+			if n > 1 {
+				require.False(t, paths[(i+1)%n].Includes(hash, root))
+			}
+		}
+	}
+
+	// A single hash has an empty path up to its leaf.
+	root, paths := buildTree([][]byte{{1}})
+	require.Equal(t, leafHash([]byte{1}), root)
+	require.Empty(t, paths[0])
+}
@@ -0,0 +1,344 @@
+// Package timestamp implements a timestamping service on top of blscosi. The
+// hashes sent to a node during a round are the leaves of a Merkle tree, whose
+// root is collectively signed with the time of the round. Every client gets
+// the inclusion path of its hash and the signature of the round, which can be
+// verified offline with the roster. The rounds can be anchored into a ByzCoin
+// chain.
+package timestamp
+
+import (
+	"bytes"
+	"math"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+	"golang.org/x/xerrors"
+)
+
+// ServiceName is the name to refer to the timestamp service.
+const ServiceName = "Timestamp"
+
+const (
+	signProtocolName    = "TimestampCoSi"
+	signSubProtocolName = "TimestampSubCoSi"
+
+	defaultRoundInterval = 2 * time.Second
+	protocolTimeout      = 20 * time.Second
+	// maxClockSkew is how far the time of a round can be from the clock of
+	// a node for it to sign the round.
+	maxClockSkew  = 30 * time.Second
+	maxHashLength = 64
+	anchorWait    = 10
+)
+
+var storageKey = []byte("storage")
+
+var suite = suites.MustFind("bn256.adapter").(*pairing.SuiteBn256)
+
+// ServiceID is the key to get the service later.
+var ServiceID onet.ServiceID
+
+func init() {
+	var err error
+	ServiceID, err = onet.RegisterNewServiceWithSuite(ServiceName, suite, newService)
+	log.ErrFatal(err)
+	log.ErrFatal(byzcoin.RegisterGlobalContract(ContractTimestampAnchorID,
+		contractAnchorFromBytes))
+}
+
+// Service collects the hashes into rounds and signs them.
+type Service struct {
+	*onet.ServiceProcessor
+	// RoundInterval is how long a round collects hashes before it is
+	// signed.
+	RoundInterval time.Duration
+	Timeout       time.Duration
+
+	storage *storage
+	// batches are the rounds being collected, indexed by roster ID.
+	batches     map[string]*batch
+	batchesLock sync.Mutex
+	// anchorLock makes sure the rounds are anchored one after the other,
+	// so that the counters of the node don't collide.
+	anchorLock sync.Mutex
+}
+
+// batch is a round collecting hashes. Once the round is signed, or failed,
+// done is closed.
+type batch struct {
+	roster *onet.Roster
+	hashes [][]byte
+	round  *Round
+	paths  []Path
+	err    error
+	done   chan struct{}
+}
+
+// Stamp adds the hash to the current round of the roster and returns the
+// proof once the round is signed.
+func (s *Service) Stamp(req *StampRequest) (*StampResponse, error) {
+	if len(req.Hash) == 0 || len(req.Hash) > maxHashLength {
+		return nil, xerrors.Errorf("hash must have between 1 and %d bytes",
+			maxHashLength)
+	}
+	if req.Roster == nil {
+		return nil, xerrors.New("no roster given")
+	}
+	if i, _ := req.Roster.Search(s.ServerIdentity().ID); i < 0 {
+		return nil, xerrors.New("we're not in the roster")
+	}
+
+	b, index := s.addHash(req.Roster, req.Hash)
+	<-b.done
+	if b.err != nil {
+		return nil, xerrors.Errorf("signing round: %v", b.err)
+	}
+	return &StampResponse{Proof: Proof{
+		Hash:  req.Hash,
+		Path:  b.paths[index],
+		Round: *b.round,
+	}}, nil
+}
+
+// SetAnchor stores the ByzCoin instance where the rounds led by this node
+// are anchored.
+func (s *Service) SetAnchor(req *SetAnchor) (*SetAnchorResponse, error) {
+	if req.Roster == nil || req.ByzCoinRoster == nil {
+		return nil, xerrors.New("missing roster")
+	}
+	if len(req.ByzCoinID) == 0 {
+		return nil, xerrors.New("empty ByzCoin ID")
+	}
+	if math.Abs(time.Now().Sub(time.Unix(req.Timestamp, 0)).Seconds()) > 60 {
+		return nil, xerrors.New("signature is too old")
+	}
+	err := schnorr.Verify(cothority.Suite, s.ServerIdentity().Public,
+		req.Hash(), req.Signature)
+	if err != nil {
+		return nil, xerrors.Errorf("signature verification failed: %v", err)
+	}
+
+	s.storage.Lock()
+	s.storage.Anchors[req.Roster.ID.String()] = &anchor{
+		ByzCoinID:     req.ByzCoinID,
+		ByzCoinRoster: req.ByzCoinRoster,
+		InstanceID:    req.InstanceID,
+	}
+	s.storage.Unlock()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &SetAnchorResponse{}, nil
+}
+
+// addHash adds the hash to the current round of the roster, which is created
+// if needed. It returns the round and the index of the hash.
+func (s *Service) addHash(roster *onet.Roster, hash []byte) (*batch, int) {
+	s.batchesLock.Lock()
+	defer s.batchesLock.Unlock()
+	id := roster.ID.String()
+	b, ok := s.batches[id]
+	if !ok {
+		b = &batch{roster: roster, done: make(chan struct{})}
+		s.batches[id] = b
+		time.AfterFunc(s.RoundInterval, func() { s.closeBatch(id, b) })
+	}
+	b.hashes = append(b.hashes, hash)
+	return b, len(b.hashes) - 1
+}
+
+// closeBatch stops the round from collecting hashes and signs it. New hashes
+// go to a new round.
+func (s *Service) closeBatch(id string, b *batch) {
+	s.batchesLock.Lock()
+	delete(s.batches, id)
+	s.batchesLock.Unlock()
+	defer close(b.done)
+
+	root, paths := buildTree(b.hashes)
+	round := &Round{Time: time.Now().UnixNano(), Root: root}
+	sig, err := s.signRound(b.roster, round)
+	if err != nil {
+		b.err = err
+		return
+	}
+	round.Signature = sig
+	b.round, b.paths = round, paths
+	log.Lvlf2("%s: signed round with %d hashes", s.ServerIdentity(),
+		len(b.hashes))
+
+	s.storage.Lock()
+	a := s.storage.Anchors[id]
+	s.storage.Unlock()
+	if a != nil {
+		go func() {
+			if err := s.anchorRound(a, *round); err != nil {
+				log.Error("couldn't anchor round:", err)
+			}
+		}()
+	}
+}
+
+// signRound asks the roster to sign the round.
+func (s *Service) signRound(roster *onet.Roster, r *Round) ([]byte, error) {
+	data, err := protobuf.Encode(r)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding round: %v", err)
+	}
+	rooted := roster.NewRosterWithRoot(s.ServerIdentity())
+	if rooted == nil {
+		return nil, xerrors.New("we're not in the roster")
+	}
+	tree := rooted.GenerateNaryTree(len(roster.List))
+	if tree == nil {
+		return nil, xerrors.New("failed to generate tree")
+	}
+	pi, err := s.CreateProtocol(signProtocolName, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("creating protocol: %v", err)
+	}
+	p := pi.(*protocol.BlsCosi)
+	p.CreateProtocol = s.CreateProtocol
+	p.Timeout = s.Timeout
+	p.Msg = r.Hash()
+	p.Data = data
+	if err := p.Start(); err != nil {
+		return nil, xerrors.Errorf("starting protocol: %v", err)
+	}
+
+	select {
+	case sig := <-p.FinalSignature:
+		if sig == nil {
+			return nil, xerrors.New("couldn't sign the round")
+		}
+		return sig, nil
+	case <-time.After(2 * s.Timeout):
+		return nil, xerrors.New("timeout while waiting for the signature")
+	}
+}
+
+// verifyRound is called by every node before signing a round. The round
+// must match the message and have a time close to the one of the node.
+func (s *Service) verifyRound(msg, data []byte) bool {
+	var r Round
+	if err := protobuf.Decode(data, &r); err != nil {
+		log.Error(s.ServerIdentity(), "couldn't decode round:", err)
+		return false
+	}
+	if !bytes.Equal(r.Hash(), msg) {
+		log.Error(s.ServerIdentity(), "round doesn't match the message")
+		return false
+	}
+	skew := time.Since(time.Unix(0, r.Time))
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		log.Errorf("%s: time of the round is off by %s",
+			s.ServerIdentity(), skew)
+		return false
+	}
+	return true
+}
+
+// anchorRound sends the round to the anchor instance, signed by the node.
+func (s *Service) anchorRound(a *anchor, r Round) error {
+	s.anchorLock.Lock()
+	defer s.anchorLock.Unlock()
+
+	buf, err := protobuf.Encode(&r)
+	if err != nil {
+		return xerrors.Errorf("encoding round: %v", err)
+	}
+	cl := byzcoin.NewClient(a.ByzCoinID, *a.ByzCoinRoster)
+	signer := darc.NewSignerEd25519(s.ServerIdentity().Public,
+		s.ServerIdentity().GetPrivate())
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return xerrors.Errorf("getting signer counters: %v", err)
+	}
+	ctx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion,
+		byzcoin.Instruction{
+			InstanceID: a.InstanceID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractTimestampAnchorID,
+				Command:    "anchor",
+				Args:       byzcoin.Arguments{{Name: "round", Value: buf}},
+			},
+			SignerCounter: []uint64{counters.Counters[0] + 1},
+		})
+	if err := ctx.FillSignersAndSignWith(signer); err != nil {
+		return xerrors.Errorf("signing transaction: %v", err)
+	}
+	_, err = cl.AddTransactionAndWait(ctx, anchorWait)
+	return cothority.ErrorOrNil(err, "adding transaction")
+}
+
+func (s *Service) save() error {
+	s.storage.Lock()
+	defer s.storage.Unlock()
+	err := s.Save(storageKey, s.storage)
+	if err != nil {
+		log.Error("Couldn't save data:", err)
+		return xerrors.Errorf("saving data: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) tryLoad() error {
+	s.storage = &storage{}
+	defer func() {
+		if s.storage.Anchors == nil {
+			s.storage.Anchors = make(map[string]*anchor)
+		}
+	}()
+	msg, err := s.Load(storageKey)
+	if err != nil {
+		return xerrors.Errorf("loading storage: %v", err)
+	}
+	if msg == nil {
+		return nil
+	}
+	var ok bool
+	s.storage, ok = msg.(*storage)
+	if !ok {
+		return xerrors.New("data of wrong type")
+	}
+	return nil
+}
+
+func newService(c *onet.Context) (onet.Service, error) {
+	s := &Service{
+		ServiceProcessor: onet.NewServiceProcessor(c),
+		RoundInterval:    defaultRoundInterval,
+		Timeout:          protocolTimeout,
+		batches:          make(map[string]*batch),
+	}
+	if err := s.RegisterHandlers(s.Stamp, s.SetAnchor); err != nil {
+		return nil, xerrors.Errorf("registering handlers: %v", err)
+	}
+	_, err := s.ProtocolRegister(signSubProtocolName, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return bdnproto.NewSubBdnCosi(n, s.verifyRound, suite)
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("registering protocol: %v", err)
+	}
+	_, err = s.ProtocolRegister(signProtocolName, func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
+		return bdnproto.NewBdnCosi(n, s.verifyRound, signSubProtocolName, suite)
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("registering protocol: %v", err)
+	}
+	if err := s.tryLoad(); err != nil {
+		return nil, xerrors.Errorf("loading configuration: %v", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,159 @@
+package timestamp
+
+import (
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestService_Stamp(t *testing.T) {
+	local := onet.NewLocalTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(4, false)
+	services := local.GetServices(hosts, ServiceID)
+	service := services[0].(*Service)
+	service.RoundInterval = 500 * time.Millisecond
+
+	_, err := service.Stamp(&StampRequest{Roster: roster})
+	require.Error(t, err)
+	_, err = service.Stamp(&StampRequest{Roster: roster, Hash: make([]byte, 65)})
+	require.Error(t, err)
+	other := onet.NewRoster(roster.List[1:])
+	_, err = service.Stamp(&StampRequest{Roster: other, Hash: []byte{1}})
+	require.Error(t, err)
+
+	// The hashes sent during the same round share its signature.
+	n := 5
+	hashes := make([][]byte, n)
+	proofs := make([]*Proof, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range hashes {
+		h := sha256.Sum256([]byte{byte(i)})
+		hashes[i] = h[:]
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reply, err := service.Stamp(&StampRequest{
+				Roster: roster,
+				Hash:   hashes[i],
+			})
+			if err == nil {
+				proofs[i] = &reply.Proof
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, proof := range proofs {
+		require.NoError(t, errs[i])
+		require.Equal(t, proofs[0].Round, proof.Round)
+		require.NoError(t, proof.Verify(hashes[i], roster))
+		require.Error(t, proof.Verify(hashes[(i+1)%n], roster))
+		require.Error(t, proof.Verify(hashes[i], other))
+		require.True(t, time.Since(proof.Time()) < time.Minute)
+	}
+
+	// A later hash goes to a new round.
+	reply, err := service.Stamp(&StampRequest{Roster: roster, Hash: []byte{1}})
+	require.NoError(t, err)
+	require.NoError(t, reply.Proof.Verify([]byte{1}, roster))
+	require.True(t, reply.Proof.Round.Time > proofs[0].Round.Time)
+
+	// A round too far from the clock of the nodes is refused.
+	service.Timeout = time.Second
+	round := &Round{Time: time.Now().Add(-time.Hour).UnixNano(), Root: []byte{1}}
+	_, err = service.signRound(roster, round)
+	require.Error(t, err)
+}
+
+func TestService_Anchor(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(4, true)
+	service := local.GetServices(hosts, ServiceID)[0].(*Service)
+	service.RoundInterval = 500 * time.Millisecond
+	leader := roster.List[0]
+
+	// The leader must be allowed to anchor the rounds.
+	admin := darc.NewSignerEd25519(nil, nil)
+	node := darc.NewSignerEd25519(leader.Public, leader.GetPrivate())
+	msg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:" + ContractTimestampAnchorID}, admin.Identity())
+	require.NoError(t, err)
+	require.NoError(t, msg.GenesisDarc.Rules.AddRule(
+		darc.Action("invoke:"+ContractTimestampAnchorID+".anchor"),
+		expression.Expr(node.Identity().String())))
+	msg.BlockInterval = 500 * time.Millisecond
+	cl, _, err := byzcoin.NewLedger(msg, false)
+	require.NoError(t, err)
+
+	rosterBuf, err := protobuf.Encode(roster)
+	require.NoError(t, err)
+	ctx := byzcoin.NewClientTransaction(byzcoin.CurrentVersion,
+		byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(msg.GenesisDarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractTimestampAnchorID,
+				Args:       byzcoin.Arguments{{Name: "roster", Value: rosterBuf}},
+			},
+			SignerCounter: []uint64{1},
+		})
+	require.NoError(t, ctx.FillSignersAndSignWith(admin))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	instID := ctx.Instructions[0].DeriveID("")
+
+	// Only the node itself can set its anchor.
+	c := NewClient()
+	req := &SetAnchor{
+		Roster:        roster,
+		ByzCoinID:     cl.ID,
+		ByzCoinRoster: roster,
+		InstanceID:    instID,
+		Timestamp:     time.Now().Unix(),
+	}
+	req.Signature, err = schnorr.Sign(cothority.Suite,
+		key.NewKeyPair(cothority.Suite).Private, req.Hash())
+	require.NoError(t, err)
+	require.Error(t, c.SendProtobuf(leader, req, &SetAnchorResponse{}))
+	// The signature covers the roster of ByzCoin.
+	req.Signature, err = schnorr.Sign(cothority.Suite, leader.GetPrivate(),
+		req.Hash())
+	require.NoError(t, err)
+	req.ByzCoinRoster = onet.NewRoster(roster.List[1:])
+	require.Error(t, c.SendProtobuf(leader, req, &SetAnchorResponse{}))
+	require.NoError(t, c.SetAnchor(leader, roster, cl.ID, roster, instID))
+
+	reply, err := c.Stamp(roster, []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, reply.Proof.Verify([]byte{1, 2, 3}, roster))
+
+	// The round is anchored in the background.
+	var data AnchorData
+	for i := 0; i < 20 && data.Round.Time != reply.Proof.Round.Time; i++ {
+		time.Sleep(msg.BlockInterval)
+		pr, err := cl.GetProof(instID.Slice())
+		require.NoError(t, err)
+		require.NoError(t, pr.Proof.VerifyAndDecode(cothority.Suite,
+			ContractTimestampAnchorID, &data))
+	}
+	require.Equal(t, reply.Proof.Round, data.Round)
+}
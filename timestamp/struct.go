@@ -0,0 +1,144 @@
+package timestamp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/blscosi/bdnproto"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	network.RegisterMessages(&StampRequest{}, &StampResponse{},
+		&SetAnchor{}, &SetAnchorResponse{}, &storage{})
+}
+
+// StampRequest asks the roster to timestamp a hash. The node receiving the
+// request adds the hash to its current round and replies once the round is
+// signed.
+type StampRequest struct {
+	Roster *onet.Roster
+	// Hash is the hash of the document, of at most 64 bytes. The service
+	// doesn't care about the hash function.
+	Hash []byte
+}
+
+// StampResponse holds the proof that the hash has been timestamped.
+type StampResponse struct {
+	Proof Proof
+}
+
+// SetAnchor asks a node to anchor the rounds it leads for the roster into a
+// ByzCoin instance of the timestampAnchor contract. The request must be
+// signed by the private key of the node, as found in private.toml.
+type SetAnchor struct {
+	Roster        *onet.Roster
+	ByzCoinID     skipchain.SkipBlockID
+	ByzCoinRoster *onet.Roster
+	InstanceID    byzcoin.InstanceID
+	// Timestamp is in seconds since the Unix epoch. Requests older than a
+	// minute are refused.
+	Timestamp int64
+	// Signature is the Schnorr signature of Hash by the node.
+	Signature []byte
+}
+
+// SetAnchorResponse is empty.
+type SetAnchorResponse struct{}
+
+// Hash returns the hash signed by the node in a SetAnchor request.
+func (req *SetAnchor) Hash() []byte {
+	h := sha256.New()
+	h.Write([]byte("timestamp anchor"))
+	h.Write(req.Roster.ID[:])
+	h.Write(req.ByzCoinID)
+	h.Write(req.ByzCoinRoster.ID[:])
+	h.Write(req.InstanceID[:])
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(req.Timestamp))
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// Round is a batch of hashes timestamped together. The roster collectively
+// signs the Merkle root of the hashes and the time of the round.
+type Round struct {
+	// Time is the time of the round in nanoseconds since the Unix epoch.
+	Time int64
+	Root []byte
+	// Signature is the BDN collective signature of Hash.
+	Signature []byte
+}
+
+// Hash returns the message signed by the roster.
+func (r *Round) Hash() []byte {
+	h := sha256.New()
+	h.Write([]byte("timestamp round"))
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(r.Time))
+	h.Write(buf)
+	h.Write(r.Root)
+	return h.Sum(nil)
+}
+
+// Verify checks the collective signature of the round by the roster.
+func (r *Round) Verify(roster *onet.Roster) error {
+	publics := roster.ServicePublics(ServiceName)
+	err := bdnproto.BdnSignature(r.Signature).Verify(suite, r.Hash(), publics)
+	if err != nil {
+		return xerrors.Errorf("verifying round signature: %v", err)
+	}
+	return nil
+}
+
+// Proof shows that a hash has been timestamped in a round.
+type Proof struct {
+	Hash  []byte
+	Path  Path
+	Round Round
+}
+
+// Time returns the time of the round of the proof.
+func (p *Proof) Time() time.Time {
+	return time.Unix(0, p.Round.Time)
+}
+
+// Verify checks that the proof holds for the hash and that its round has
+// been signed by the roster. It doesn't contact the roster, so it can be
+// used offline.
+func (p *Proof) Verify(hash []byte, roster *onet.Roster) error {
+	if !bytes.Equal(p.Hash, hash) {
+		return xerrors.New("the proof is for another hash")
+	}
+	if !p.Path.Includes(hash, p.Round.Root) {
+		return xerrors.New("the hash is not included in the round")
+	}
+	return p.Round.Verify(roster)
+}
+
+// AnchorData is the value of the timestampAnchor instances. It holds the
+// roster of the timestamp service and the latest round anchored.
+type AnchorData struct {
+	Roster onet.Roster
+	Round  Round
+}
+
+// anchor is the ByzCoin instance where the rounds of a roster are anchored.
+type anchor struct {
+	ByzCoinID     skipchain.SkipBlockID
+	ByzCoinRoster *onet.Roster
+	InstanceID    byzcoin.InstanceID
+}
+
+type storage struct {
+	// Anchors are indexed by the ID of the roster of the timestamp service.
+	Anchors map[string]*anchor
+	sync.Mutex
+}
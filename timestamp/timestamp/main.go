@@ -0,0 +1,173 @@
+// The timestamp binary asks a roster to timestamp files and verifies the
+// proofs offline, with only the group definition of the roster.
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+	"go.dedis.ch/cothority/v3/timestamp"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/app"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+const (
+	optionGroup      = "group"
+	optionGroupShort = "g"
+	optionProof      = "proof"
+	optionProofShort = "p"
+
+	// proofExtension is added to the name of the file to get the default
+	// name of its proof.
+	proofExtension = ".tsp"
+)
+
+func main() {
+	cliApp := createApp()
+	log.ErrFatal(cliApp.Run(os.Args))
+}
+
+func createApp() *cli.App {
+	cliApp := cli.NewApp()
+	cliApp.Name = "timestamp"
+	cliApp.Usage = "timestamp files with a cothority and verify the proofs"
+	cliApp.Version = "1.00"
+
+	flags := []cli.Flag{
+		cli.StringFlag{
+			Name:  optionGroup + ", " + optionGroupShort,
+			Value: app.DefaultGroupFile,
+			Usage: "Group definition file of the timestamp roster",
+		},
+		cli.StringFlag{
+			Name:  optionProof + ", " + optionProofShort,
+			Usage: "Proof file, 'file" + proofExtension + "' by default",
+		},
+	}
+	cliApp.Commands = []cli.Command{
+		{
+			Name:      "stamp",
+			Aliases:   []string{"s"},
+			Usage:     "Timestamp a 'file' and write its proof",
+			ArgsUsage: "file",
+			Action:    stampFile,
+			Flags:     flags,
+		},
+		{
+			Name:      "verify",
+			Aliases:   []string{"v"},
+			Usage:     "Verify the proof of a 'file' offline and print its time",
+			ArgsUsage: "file",
+			Action:    verifyFile,
+			Flags:     flags,
+		},
+	}
+	cliApp.Flags = []cli.Flag{
+		cli.IntFlag{
+			Name:  "debug, d",
+			Value: 0,
+			Usage: "debug-level: 1 for terse, 5 for maximal",
+		},
+	}
+	cliApp.Before = func(c *cli.Context) error {
+		log.SetDebugVisible(c.GlobalInt("debug"))
+		return nil
+	}
+	return cliApp
+}
+
+func stampFile(c *cli.Context) error {
+	hash, err := hashFile(c)
+	if err != nil {
+		return err
+	}
+	roster, err := readRoster(c.String(optionGroup))
+	if err != nil {
+		return err
+	}
+
+	reply, err := timestamp.NewClient().Stamp(roster, hash)
+	if err != nil {
+		return fmt.Errorf("couldn't timestamp file: %v", err)
+	}
+	buf, err := protobuf.Encode(&reply.Proof)
+	if err != nil {
+		return fmt.Errorf("couldn't encode proof: %v", err)
+	}
+	proofFile := proofFileName(c)
+	if err := ioutil.WriteFile(proofFile, buf, 0644); err != nil {
+		return fmt.Errorf("couldn't write proof: %v", err)
+	}
+	fmt.Fprintf(c.App.Writer, "[+] Timestamped at %s, proof written to %s\n",
+		reply.Proof.Time().Format(time.RFC3339Nano), proofFile)
+	return nil
+}
+
+func verifyFile(c *cli.Context) error {
+	hash, err := hashFile(c)
+	if err != nil {
+		return err
+	}
+	roster, err := readRoster(c.String(optionGroup))
+	if err != nil {
+		return err
+	}
+	buf, err := ioutil.ReadFile(proofFileName(c))
+	if err != nil {
+		return fmt.Errorf("couldn't read proof: %v", err)
+	}
+	var proof timestamp.Proof
+	if err := protobuf.Decode(buf, &proof); err != nil {
+		return fmt.Errorf("couldn't decode proof: %v", err)
+	}
+
+	if err := proof.Verify(hash, roster); err != nil {
+		return fmt.Errorf("Invalid: proof verification failed: %v", err)
+	}
+	fmt.Fprintf(c.App.Writer, "[+] OK: File existed at %s\n",
+		proof.Time().Format(time.RFC3339Nano))
+	return nil
+}
+
+// hashFile returns the SHA-256 hash of the file given as argument.
+func hashFile(c *cli.Context) ([]byte, error) {
+	if c.Args().First() == "" {
+		return nil, errors.New("please give the file")
+	}
+	buf, err := ioutil.ReadFile(c.Args().First())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read file: %v", err)
+	}
+	hash := sha256.Sum256(buf)
+	return hash[:], nil
+}
+
+func proofFileName(c *cli.Context) string {
+	if c.String(optionProof) != "" {
+		return c.String(optionProof)
+	}
+	return c.Args().First() + proofExtension
+}
+
+func readRoster(tomlFileName string) (*onet.Roster, error) {
+	f, err := os.Open(tomlFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open group file: %v", err)
+	}
+	defer f.Close()
+	g, err := app.ReadGroupDescToml(f)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read group file: %v", err)
+	}
+	if len(g.Roster.List) == 0 {
+		return nil, fmt.Errorf("empty or invalid group file: %s", tomlFileName)
+	}
+	return g.Roster, nil
+}
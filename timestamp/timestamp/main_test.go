@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/timestamp"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/app"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestMain_StampVerify(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "timestamp")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(3, true)
+	for _, s := range local.GetServices(hosts, timestamp.ServiceID) {
+		s.(*timestamp.Service).RoundInterval = 0
+	}
+	publicToml := path.Join(tmp, "public.toml")
+	require.NoError(t, (&app.Group{Roster: roster}).Save(cothority.Suite, publicToml))
+	file := path.Join(tmp, "document")
+	require.NoError(t, ioutil.WriteFile(file, []byte("hello"), 0644))
+
+	cliApp := createApp()
+	require.Error(t, cliApp.Run([]string{"", "stamp", "-g", publicToml}))
+	require.Error(t, cliApp.Run([]string{"", "verify", "-g", publicToml, file}))
+	require.NoError(t, cliApp.Run([]string{"", "stamp", "-g", publicToml, file}))
+	require.NoError(t, cliApp.Run([]string{"", "verify", "-g", publicToml, file}))
+
+	// The proof doesn't hold for another content.
+	require.NoError(t, ioutil.WriteFile(file, []byte("hello!"), 0644))
+	require.Error(t, cliApp.Run([]string{"", "verify", "-g", publicToml, file}))
+}